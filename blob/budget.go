@@ -0,0 +1,154 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blob
+
+import (
+	"slices"
+	"sync"
+)
+
+// A Budget enforces a shared limit on the memory used by a collection of
+// components, such as caches and write buffers, that hold blob data.
+//
+// Each component registers an [Account] with the budget, and reserves memory
+// from the account before holding it. When a reservation would exceed the
+// limit, the budget asks the registered accounts to reclaim memory, in turn,
+// until the reservation fits or no more memory can be reclaimed.
+//
+// A Budget is safe for concurrent use by multiple goroutines.
+type Budget struct {
+	limit int64
+
+	μ     sync.Mutex
+	used  int64
+	accts []*Account
+	next  int // index of the next account to reclaim from
+}
+
+// NewBudget constructs a new Budget with the given limit in bytes.
+// It will panic if limit <= 0.
+func NewBudget(limit int64) *Budget {
+	if limit <= 0 {
+		panic("budget limit must be positive")
+	}
+	return &Budget{limit: limit}
+}
+
+// Limit reports the limit of b in bytes.
+func (b *Budget) Limit() int64 { return b.limit }
+
+// Used reports the total number of bytes currently reserved from b.
+func (b *Budget) Used() int64 {
+	b.μ.Lock()
+	defer b.μ.Unlock()
+	return b.used
+}
+
+// Register adds a new account to b. If reclaim != nil, the budget calls it
+// when memory is needed, with the number of bytes it would like to have
+// released. The reclaim function should release what it can, up to that
+// amount, by calling Release on the account, and then return.
+//
+// Reclaim may be called from any goroutine, including one that is reserving
+// memory from the same account, so it must not acquire a lock that a caller
+// of Reserve may hold.
+func (b *Budget) Register(reclaim func(n int64)) *Account {
+	a := &Account{b: b, reclaim: reclaim}
+	b.μ.Lock()
+	defer b.μ.Unlock()
+	b.accts = append(b.accts, a)
+	return a
+}
+
+// An Account records the memory reserved by one component of a [Budget].
+type Account struct {
+	b       *Budget
+	reclaim func(int64)
+	used    int64 // protected by b.μ
+}
+
+// Used reports the number of bytes currently reserved by a.
+func (a *Account) Used() int64 {
+	a.b.μ.Lock()
+	defer a.b.μ.Unlock()
+	return a.used
+}
+
+// Reserve attempts to reserve n bytes for a, and reports whether it succeeded.
+// If the reservation would exceed the limit of the budget, Reserve first asks
+// the accounts of the budget to reclaim memory. Reserve does not block for
+// memory to become available otherwise; if it reports false, the caller
+// should proceed without holding the memory.
+func (a *Account) Reserve(n int64) bool {
+	b := a.b
+	b.μ.Lock()
+	defer b.μ.Unlock()
+	if n > b.limit {
+		return false
+	}
+	for tries := 0; ; tries++ {
+		if b.used+n <= b.limit {
+			b.used += n
+			a.used += n
+			return true
+		}
+
+		// Ask each account in turn to reclaim what it can, until we have
+		// asked all of them once without success.
+		victim := b.nextVictimLocked()
+		if victim == nil || tries >= len(b.accts) {
+			return false
+		}
+		want := b.used + n - b.limit
+		b.μ.Unlock()
+		victim.reclaim(want)
+		b.μ.Lock()
+	}
+}
+
+// nextVictimLocked returns the next account to ask to reclaim memory, in
+// round-robin order, or nil if no account holds reclaimable memory.
+// The caller must hold b.μ.
+func (b *Budget) nextVictimLocked() *Account {
+	for range len(b.accts) {
+		a := b.accts[b.next%len(b.accts)]
+		b.next = (b.next + 1) % len(b.accts)
+		if a.reclaim != nil && a.used > 0 {
+			return a
+		}
+	}
+	return nil
+}
+
+// Release returns n bytes previously reserved by a to the budget.
+func (a *Account) Release(n int64) {
+	b := a.b
+	b.μ.Lock()
+	defer b.μ.Unlock()
+	n = min(n, a.used)
+	a.used -= n
+	b.used -= n
+}
+
+// Close releases all the memory reserved by a and removes it from the budget.
+// After Close, a must not be used.
+func (a *Account) Close() {
+	b := a.b
+	b.μ.Lock()
+	defer b.μ.Unlock()
+	b.used -= a.used
+	a.used = 0
+	b.accts = slices.DeleteFunc(b.accts, func(x *Account) bool { return x == a })
+}
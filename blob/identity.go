@@ -0,0 +1,146 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blob
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// IdentityKeyspace is the name of the keyspace reserved for the identity
+// record of a store. See [Identity].
+const IdentityKeyspace = "ffs.identity"
+
+// identityKey is the key of the identity record in IdentityKeyspace.
+const identityKey = "identity"
+
+// CASHash is the name of the hash function used by [CASFromKV] to compute
+// content addresses, for use in an [Identity].
+const CASHash = "sha3-256"
+
+// ErrIdentityMismatch is reported by [CheckIdentity] when the identity of a
+// store does not match what the caller expects.
+var ErrIdentityMismatch = errors.New("store identity mismatch")
+
+// An Identity is a record that identifies a store and the parameters used to
+// write its data. By convention, the identity is written once, when a store
+// is initialized, to the [IdentityKeyspace] of the store. Tools that open a
+// store can check its identity to fail fast if they have been pointed at the
+// wrong store, or at one created with incompatible settings.
+type Identity struct {
+	ID       string    `json:"id"`                 // a unique identifier for the store
+	CASHash  string    `json:"casHash,omitempty"`  // the name of the content address hash
+	Chunking string    `json:"chunking,omitempty"` // a fingerprint of the block splitting settings
+	Created  time.Time `json:"created"`            // when the store was initialized
+}
+
+// InitIdentity writes id as the identity record of s, and returns the
+// identity recorded. If id.ID is empty, a random UUID is assigned. If
+// id.Created is zero, the current time is used.
+//
+// If s already has an identity, InitIdentity does not replace it, but
+// returns the existing identity with an error satisfying ErrKeyExists.
+func InitIdentity(ctx context.Context, s Store, id Identity) (Identity, error) {
+	kv, err := s.KV(ctx, IdentityKeyspace)
+	if err != nil {
+		return Identity{}, err
+	}
+	if id.ID == "" {
+		id.ID = newUUID()
+	}
+	if id.Created.IsZero() {
+		id.Created = time.Now().UTC()
+	}
+	data, err := json.Marshal(id)
+	if err != nil {
+		return Identity{}, err
+	}
+	if err := kv.Put(ctx, PutOptions{Key: identityKey, Data: data}); IsKeyExists(err) {
+		old, lerr := loadIdentity(ctx, kv)
+		if lerr != nil {
+			return Identity{}, lerr
+		}
+		return old, err
+	} else if err != nil {
+		return Identity{}, err
+	}
+	return id, nil
+}
+
+// LoadIdentity returns the identity record of s. If s does not have an
+// identity, LoadIdentity reports an error satisfying ErrKeyNotFound.
+func LoadIdentity(ctx context.Context, s Store) (Identity, error) {
+	kv, err := s.KV(ctx, IdentityKeyspace)
+	if err != nil {
+		return Identity{}, err
+	}
+	return loadIdentity(ctx, kv)
+}
+
+// CheckIdentity loads the identity record of s and verifies that it matches
+// want. Only the fields of want that are set are compared; for example, a
+// caller that does not know the ID of the store may leave it empty to check
+// only the hash and chunking settings. If the identity does not match,
+// CheckIdentity returns the identity of s with an error satisfying
+// ErrIdentityMismatch. If s has no identity, the error satisfies
+// ErrKeyNotFound.
+func CheckIdentity(ctx context.Context, s Store, want Identity) (Identity, error) {
+	got, err := LoadIdentity(ctx, s)
+	if err != nil {
+		return Identity{}, err
+	}
+	check := func(field, got, want string) error {
+		if want != "" && got != want {
+			return fmt.Errorf("%w: %s is %q, want %q", ErrIdentityMismatch, field, got, want)
+		}
+		return nil
+	}
+	if err := errors.Join(
+		check("id", got.ID, want.ID),
+		check("CAS hash", got.CASHash, want.CASHash),
+		check("chunking", got.Chunking, want.Chunking),
+	); err != nil {
+		return got, err
+	}
+	if !want.Created.IsZero() && !got.Created.Equal(want.Created) {
+		return got, fmt.Errorf("%w: created %v, want %v", ErrIdentityMismatch, got.Created, want.Created)
+	}
+	return got, nil
+}
+
+func loadIdentity(ctx context.Context, kv KV) (Identity, error) {
+	data, err := kv.Get(ctx, identityKey)
+	if err != nil {
+		return Identity{}, err
+	}
+	var id Identity
+	if err := json.Unmarshal(data, &id); err != nil {
+		return Identity{}, fmt.Errorf("invalid identity record: %w", err)
+	}
+	return id, nil
+}
+
+// newUUID returns a random (version 4) UUID in canonical string form.
+func newUUID() string {
+	var u [16]byte
+	rand.Read(u[:])
+	u[6] = (u[6] & 0x0f) | 0x40 // version 4
+	u[8] = (u[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+}
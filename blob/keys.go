@@ -0,0 +1,225 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blob
+
+import (
+	"context"
+	"iter"
+	"slices"
+	"strings"
+)
+
+// CompareKeys compares keys a and b in the canonical key order, returning -1
+// if a < b, 0 if a == b, and +1 if a > b.
+//
+// Keys are ordered lexicographically by their bytes, treating each byte as an
+// unsigned value. Keys need not be valid UTF-8, and no normalization or case
+// folding is applied. A key that is a proper prefix of another sorts before
+// it, and the empty key sorts before all others. This is the order in which
+// the List method of a [KVCore] must report keys.
+func CompareKeys(a, b string) int { return strings.Compare(a, b) }
+
+// A KeyRange denotes the half-open interval of keys k such that Start ≤ k and
+// k < End, in the order defined by [CompareKeys]. If End == "", the range has
+// no upper bound. The zero value denotes the range of all keys.
+type KeyRange struct {
+	Start string // the first key in the range (inclusive)
+	End   string // the end of the range (exclusive), or "" for unbounded
+}
+
+// IsEmpty reports whether r contains no keys.
+func (r KeyRange) IsEmpty() bool { return r.End != "" && CompareKeys(r.End, r.Start) <= 0 }
+
+// Contains reports whether key is in r.
+func (r KeyRange) Contains(key string) bool {
+	return CompareKeys(key, r.Start) >= 0 && (r.End == "" || CompareKeys(key, r.End) < 0)
+}
+
+// List returns an iterator over the keys of ks in r, in key order. It lists
+// ks from r.Start and stops at the first key not less than r.End. The
+// iterator has the same contract as the List method of [KVCore].
+func (r KeyRange) List(ctx context.Context, ks KVCore) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		if r.IsEmpty() {
+			return
+		}
+		for key, err := range ks.List(ctx, r.Start) {
+			if err != nil {
+				yield("", err)
+				return
+			} else if r.End != "" && CompareKeys(key, r.End) >= 0 {
+				return
+			}
+			if !yield(key, nil) {
+				return
+			}
+		}
+	}
+}
+
+// CountOptions are optional settings for [CountRange]. A nil *CountOptions is
+// ready for use and provides default values as described.
+type CountOptions struct {
+	// If non-nil, Progress is called with the number of keys counted so far,
+	// after every Interval keys and once more when counting is complete.
+	Progress func(n int64)
+
+	// The number of keys counted between calls to Progress. A value ≤ 0
+	// defaults to 1000.
+	Interval int64
+}
+
+func (o *CountOptions) progress() func(int64) {
+	if o == nil || o.Progress == nil {
+		return func(int64) {}
+	}
+	return o.Progress
+}
+
+func (o *CountOptions) interval() int64 {
+	if o == nil || o.Interval <= 0 {
+		return 1000
+	}
+	return o.Interval
+}
+
+// CountRange reports the number of keys k of ks such that start ≤ k and
+// k < end. If end == "", the range has no upper bound.
+//
+// CountRange lists the keys in the range, so it may be slow for a large
+// keyspace. Unlike the Len method of a [KVCore], however, it reports its
+// progress to opts.Progress as it goes, and it stops promptly when ctx ends.
+// In that case, CountRange reports the number of keys counted so far along
+// with the error from ctx.
+func CountRange(ctx context.Context, ks KVCore, start, end string, opts *CountOptions) (int64, error) {
+	report, every := opts.progress(), opts.interval()
+	var n int64
+	for _, err := range (KeyRange{Start: start, End: end}).List(ctx, ks) {
+		if err != nil {
+			return n, err
+		}
+		n++
+		if n%every == 0 {
+			report(n)
+			if err := ctx.Err(); err != nil {
+				return n, err
+			}
+		}
+	}
+	report(n)
+	return n, ctx.Err()
+}
+
+// SnapshotMode selects how [ListSnapshot] combines the keys from several
+// listings of a keyspace.
+type SnapshotMode int
+
+const (
+	// SnapshotUnion reports the keys seen by any listing. Use it when a key
+	// must not be missed, for example to find the roots of a store.
+	SnapshotUnion SnapshotMode = iota
+
+	// SnapshotIntersect reports only the keys seen by every listing. Use it
+	// when a key must not be reported unless it is stable, for example to
+	// choose candidates for garbage collection.
+	SnapshotIntersect
+)
+
+// SnapshotOptions are optional settings for [ListSnapshot]. A nil
+// *SnapshotOptions is ready for use and provides default values as described.
+type SnapshotOptions struct {
+	// How to combine the keys from each listing. The default is SnapshotUnion.
+	Mode SnapshotMode
+
+	// The number of times to list the keyspace. A value ≤ 0 defaults to 2.
+	Passes int
+
+	// The number of times a listing may fail and be resumed before the error
+	// is reported. If zero, the default is 3; if negative, a listing that
+	// fails is not resumed.
+	Retries int
+}
+
+func (o *SnapshotOptions) mode() SnapshotMode {
+	if o == nil {
+		return SnapshotUnion
+	}
+	return o.Mode
+}
+
+func (o *SnapshotOptions) passes() int {
+	if o == nil || o.Passes <= 0 {
+		return 2
+	}
+	return o.Passes
+}
+
+func (o *SnapshotOptions) retries() int {
+	if o == nil || o.Retries == 0 {
+		return 3
+	}
+	return max(o.Retries, 0)
+}
+
+// ListSnapshot lists the keys of ks several times, and returns the keys from
+// the listings combined as selected by opts, in order. This gives a best-effort
+// stable view of a keyspace that is being modified concurrently, for which a
+// single listing may include keys that are deleted before it finishes, and
+// omit keys that are added while it runs.
+//
+// If a listing fails partway through, ListSnapshot resumes it after the last
+// key reported, up to the number of retries set by opts; if a listing still
+// fails, ListSnapshot reports the error. Errors from ctx are not retried.
+func ListSnapshot(ctx context.Context, ks KVCore, opts *SnapshotOptions) ([]string, error) {
+	seen := make(map[string]int) // key → number of passes that reported it
+	passes, retries := opts.passes(), opts.retries()
+	for range passes {
+		var start, last string
+		var listed bool
+		for try := 0; ; try++ {
+			err := func() error {
+				for key, err := range ks.List(ctx, start) {
+					if err != nil {
+						return err
+					}
+					seen[key]++
+					last, listed = key, true
+				}
+				return nil
+			}()
+			if err == nil {
+				break
+			} else if ctx.Err() != nil || try >= retries {
+				return nil, err
+			}
+			if listed {
+				start = last + "\x00" // the first key after last
+			}
+		}
+	}
+
+	out := make([]string, 0, len(seen))
+	want := 1
+	if opts.mode() == SnapshotIntersect {
+		want = passes
+	}
+	for key, n := range seen {
+		if n >= want {
+			out = append(out, key)
+		}
+	}
+	slices.SortFunc(out, CompareKeys)
+	return out, nil
+}
@@ -0,0 +1,204 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lock implements leases stored in a [blob.KV], which processes that
+// share a store can use to coordinate exclusive operations such as garbage
+// collection, compaction, or editing a root.
+//
+// A lease is a key in the keyspace whose value records a random token
+// identifying its holder and the time when the lease expires. A lease is
+// acquired by writing its key without replacement, so that at most one
+// process succeeds; the holder must [Lease.Renew] the lease before it
+// expires, and should [Lease.Release] it when done:
+//
+//	l, err := lock.Acquire(ctx, kv, "gc", time.Minute)
+//	if errors.Is(err, lock.ErrHeld) {
+//	   return // someone else is collecting
+//	} else if err != nil {
+//	   return err
+//	}
+//	defer l.Release(ctx)
+//
+// A lease that has expired may be taken over by another process. Because a
+// keyspace does not support an atomic compare-and-swap, takeover of an expired
+// lease is best-effort: The new holder verifies its token after writing, but
+// a holder that is slow to renew may briefly overlap with its successor. The
+// expiration is compared against the local clock of each process, so the
+// lease duration should be long relative to the clock skew between them.
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/creachadair/ffs/blob"
+)
+
+var (
+	// ErrHeld is reported by Acquire when the lease is held by another
+	// process and has not expired.
+	ErrHeld = errors.New("lease is held")
+
+	// ErrLost is reported by Renew and Release when the lease is no longer
+	// held by the caller, either because it was released or because it
+	// expired and was taken over by another process.
+	ErrLost = errors.New("lease is not held")
+)
+
+// A Lease is a lease held by the caller. A Lease is not safe for concurrent
+// use by multiple goroutines without external synchronization.
+type Lease struct {
+	kv      blob.KV
+	name    string
+	token   string
+	expires time.Time
+}
+
+// Name returns the name of the lease, which is its key in the keyspace.
+func (l *Lease) Name() string { return l.name }
+
+// Token returns the unique token identifying this holder of the lease.
+func (l *Lease) Token() string { return l.token }
+
+// Expires returns the time when l expires unless it is renewed.
+func (l *Lease) Expires() time.Time { return l.expires }
+
+// record is the encoded value of a lease.
+type record struct {
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires"`
+}
+
+// Acquire attempts to acquire the lease with the given name in kv for the
+// specified duration. If the lease is held by another process and has not
+// expired, Acquire reports ErrHeld. Acquire does not wait for the lease to
+// become available. It will panic if ttl ≤ 0.
+func Acquire(ctx context.Context, kv blob.KV, name string, ttl time.Duration) (*Lease, error) {
+	if ttl <= 0 {
+		panic("lease duration must be positive")
+	}
+	l := &Lease{kv: kv, name: name, token: newToken()}
+	err := l.put(ctx, ttl, false)
+	if err == nil {
+		return l, nil
+	} else if !blob.IsKeyExists(err) {
+		return nil, err
+	}
+
+	// Reaching here, the lease already exists. If it has not expired, we are
+	// done; otherwise remove it and try again.
+	old, err := load(ctx, kv, name)
+	if blob.IsKeyNotFound(err) {
+		// Released between our write and our read; fall through and retry.
+	} else if err != nil {
+		return nil, err
+	} else if time.Now().Before(old.Expires) {
+		return nil, ErrHeld
+	} else if err := kv.Delete(ctx, name); err != nil && !blob.IsKeyNotFound(err) {
+		return nil, err
+	}
+	if err := l.put(ctx, ttl, false); blob.IsKeyExists(err) {
+		return nil, ErrHeld // someone else got there first
+	} else if err != nil {
+		return nil, err
+	}
+	if err := l.check(ctx); errors.Is(err, ErrLost) {
+		return nil, ErrHeld // taken over by someone else after our write
+	} else if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Renew extends the lease to expire after ttl from the present. If the lease
+// is no longer held by l, Renew reports ErrLost. It will panic if ttl ≤ 0.
+//
+// A lease that has expired but has not been taken over by another process
+// can still be renewed.
+func (l *Lease) Renew(ctx context.Context, ttl time.Duration) error {
+	if ttl <= 0 {
+		panic("lease duration must be positive")
+	}
+	if err := l.check(ctx); err != nil {
+		return err
+	}
+	if err := l.put(ctx, ttl, true); err != nil {
+		return err
+	}
+	return l.check(ctx)
+}
+
+// Release releases the lease. If the lease is no longer held by l, Release
+// reports ErrLost and does not modify the keyspace.
+func (l *Lease) Release(ctx context.Context) error {
+	if err := l.check(ctx); err != nil {
+		return err
+	}
+	if err := l.kv.Delete(ctx, l.name); err != nil && !blob.IsKeyNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// put writes a record for l that expires after ttl.
+func (l *Lease) put(ctx context.Context, ttl time.Duration, replace bool) error {
+	exp := time.Now().Add(ttl)
+	data, err := json.Marshal(record{Token: l.token, Expires: exp})
+	if err != nil {
+		return err
+	}
+	if err := l.kv.Put(ctx, blob.PutOptions{Key: l.name, Data: data, Replace: replace}); err != nil {
+		return err
+	}
+	l.expires = exp
+	return nil
+}
+
+// check reports ErrLost if the stored lease does not have the token of l.
+func (l *Lease) check(ctx context.Context) error {
+	rec, err := load(ctx, l.kv, l.name)
+	if blob.IsKeyNotFound(err) {
+		return ErrLost
+	} else if err != nil {
+		return err
+	} else if rec.Token != l.token {
+		return ErrLost
+	}
+	return nil
+}
+
+// load reads and decodes the lease record stored for name in kv.
+func load(ctx context.Context, kv blob.KV, name string) (record, error) {
+	data, err := kv.Get(ctx, name)
+	if err != nil {
+		return record{}, err
+	}
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return record{}, fmt.Errorf("invalid lease %q: %w", name, err)
+	}
+	return rec, nil
+}
+
+// newToken returns a random token to identify a lease holder.
+func newToken() string {
+	var buf [16]byte
+	rand.Read(buf[:])
+	return hex.EncodeToString(buf[:])
+}
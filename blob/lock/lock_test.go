@@ -0,0 +1,99 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lock_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/creachadair/ffs/blob/lock"
+	"github.com/creachadair/ffs/blob/memstore"
+)
+
+func TestLease(t *testing.T) {
+	ctx := context.Background()
+	kv := memstore.NewKV()
+
+	l1, err := lock.Acquire(ctx, kv, "gc", time.Hour)
+	if err != nil {
+		t.Fatalf("Acquire: unexpected error: %v", err)
+	}
+	if l1.Name() != "gc" || l1.Token() == "" || !l1.Expires().After(time.Now()) {
+		t.Errorf("Lease: got name %q token %q expires %v", l1.Name(), l1.Token(), l1.Expires())
+	}
+
+	// While l1 is held, nobody else can acquire it.
+	if _, err := lock.Acquire(ctx, kv, "gc", time.Hour); !errors.Is(err, lock.ErrHeld) {
+		t.Errorf("Acquire held: got %v, want %v", err, lock.ErrHeld)
+	}
+	// A different name is independent.
+	if _, err := lock.Acquire(ctx, kv, "compact", time.Hour); err != nil {
+		t.Errorf("Acquire other: unexpected error: %v", err)
+	}
+
+	old := l1.Expires()
+	if err := l1.Renew(ctx, 2*time.Hour); err != nil {
+		t.Errorf("Renew: unexpected error: %v", err)
+	} else if !l1.Expires().After(old) {
+		t.Errorf("Renew: expiry %v not after %v", l1.Expires(), old)
+	}
+
+	if err := l1.Release(ctx); err != nil {
+		t.Errorf("Release: unexpected error: %v", err)
+	}
+	if err := l1.Release(ctx); !errors.Is(err, lock.ErrLost) {
+		t.Errorf("Release again: got %v, want %v", err, lock.ErrLost)
+	}
+	if err := l1.Renew(ctx, time.Hour); !errors.Is(err, lock.ErrLost) {
+		t.Errorf("Renew after release: got %v, want %v", err, lock.ErrLost)
+	}
+
+	// Once released, the lease can be acquired again.
+	l2, err := lock.Acquire(ctx, kv, "gc", time.Hour)
+	if err != nil {
+		t.Fatalf("Acquire after release: unexpected error: %v", err)
+	}
+	if err := l2.Release(ctx); err != nil {
+		t.Errorf("Release: unexpected error: %v", err)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	ctx := context.Background()
+	kv := memstore.NewKV()
+
+	l1, err := lock.Acquire(ctx, kv, "root", time.Millisecond)
+	if err != nil {
+		t.Fatalf("Acquire: unexpected error: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	// An expired lease can be taken over, after which the old holder has lost it.
+	l2, err := lock.Acquire(ctx, kv, "root", time.Hour)
+	if err != nil {
+		t.Fatalf("Acquire expired: unexpected error: %v", err)
+	}
+	if err := l1.Renew(ctx, time.Hour); !errors.Is(err, lock.ErrLost) {
+		t.Errorf("Renew lost: got %v, want %v", err, lock.ErrLost)
+	}
+	if err := l1.Release(ctx); !errors.Is(err, lock.ErrLost) {
+		t.Errorf("Release lost: got %v, want %v", err, lock.ErrLost)
+	}
+	if err := l2.Release(ctx); err != nil {
+		t.Errorf("Release: unexpected error: %v", err)
+	}
+}
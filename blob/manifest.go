@@ -0,0 +1,187 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blob
+
+import (
+	"bufio"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	manifestHeader = "# ffs blob manifest v1"
+	manifestSigTag = "# ed25519ph "
+)
+
+// ErrManifestSignature is reported by [VerifyManifest] when the signature of
+// a manifest is missing or invalid.
+var ErrManifestSignature = errors.New("invalid manifest signature")
+
+// WriteManifest writes a manifest of the blobs in ks to w, and reports the
+// number of blobs listed. If key != nil, the manifest is signed with key.
+//
+// A manifest is a text file that records the key, size, and SHA-256 digest of
+// each blob, so that the contents of a store can later be checked against it
+// by [VerifyManifest], or by independent tools. The first line is the header
+//
+//	# ffs blob manifest v1
+//
+// followed by one line for each blob, in key order, of the form
+//
+//	<key> <size> <sha256>
+//
+// where the key and digest are encoded in hexadecimal and the size is in
+// decimal bytes. If the manifest is signed, the last line is
+//
+//	# ed25519ph <signature>
+//
+// giving the base64-encoded Ed25519ph signature (RFC 8032) of the SHA-512
+// digest of all the preceding lines, including their newlines.
+func WriteManifest(ctx context.Context, w io.Writer, ks KVCore, key ed25519.PrivateKey) (int64, error) {
+	bw := bufio.NewWriter(w)
+	h := sha512.New()
+	mw := io.MultiWriter(bw, h)
+	fmt.Fprintln(mw, manifestHeader)
+
+	var n int64
+	for k, err := range ks.List(ctx, "") {
+		if err != nil {
+			return n, err
+		}
+		data, err := ks.Get(ctx, k)
+		if IsKeyNotFound(err) {
+			continue // deleted since it was listed
+		} else if err != nil {
+			return n, err
+		}
+		sum := sha256.Sum256(data)
+		if _, err := fmt.Fprintf(mw, "%x %d %x\n", k, len(data), sum[:]); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if key != nil {
+		sig, err := key.Sign(nil, h.Sum(nil), &ed25519.Options{Hash: crypto.SHA512})
+		if err != nil {
+			return n, fmt.Errorf("signing manifest: %w", err)
+		}
+		fmt.Fprintln(bw, manifestSigTag+base64.StdEncoding.EncodeToString(sig))
+	}
+	return n, bw.Flush()
+}
+
+// A ManifestReport is the result of checking a store against a manifest with
+// [VerifyManifest].
+type ManifestReport struct {
+	Checked int64    // the number of blobs listed in the manifest
+	Missing []string // keys listed in the manifest that are not in the store
+	Corrupt []string // keys whose size or digest does not match the manifest
+}
+
+// OK reports whether the store matched the manifest, that is, whether every
+// blob listed in the manifest is present and unchanged.
+func (r ManifestReport) OK() bool { return len(r.Missing) == 0 && len(r.Corrupt) == 0 }
+
+// VerifyManifest reads a manifest written by [WriteManifest] from r, and
+// checks each blob it lists against the contents of ks. Blobs in ks that are
+// not listed in the manifest are ignored.
+//
+// If pub != nil, the manifest must be signed by the corresponding private key;
+// if it is unsigned or the signature does not verify, VerifyManifest reports
+// an error satisfying [ErrManifestSignature] along with the report. Blobs that
+// are missing or do not match are recorded in the report, and are not errors.
+func VerifyManifest(ctx context.Context, r io.Reader, ks KVCore, pub ed25519.PublicKey) (ManifestReport, error) {
+	var rep ManifestReport
+	h := sha512.New()
+	var sig []byte
+
+	sc := bufio.NewScanner(r)
+	for i := 0; sc.Scan(); i++ {
+		line := sc.Text()
+		if sig != nil {
+			return rep, fmt.Errorf("line %d: unexpected content after signature", i+1)
+		} else if i == 0 {
+			if line != manifestHeader {
+				return rep, errors.New("invalid manifest header")
+			}
+		} else if s, ok := strings.CutPrefix(line, manifestSigTag); ok {
+			dec, err := base64.StdEncoding.DecodeString(s)
+			if err != nil || len(dec) == 0 {
+				return rep, fmt.Errorf("line %d: %w", i+1, ErrManifestSignature)
+			}
+			sig = dec
+			continue // not covered by the signature
+		} else if err := rep.check(ctx, ks, line); err != nil {
+			return rep, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		h.Write([]byte(line + "\n"))
+	}
+	if err := sc.Err(); err != nil {
+		return rep, err
+	}
+	if pub != nil {
+		if sig == nil {
+			return rep, fmt.Errorf("manifest is not signed: %w", ErrManifestSignature)
+		}
+		if err := ed25519.VerifyWithOptions(pub, h.Sum(nil), sig, &ed25519.Options{Hash: crypto.SHA512}); err != nil {
+			return rep, fmt.Errorf("%w: %w", ErrManifestSignature, err)
+		}
+	}
+	return rep, nil
+}
+
+// check verifies the blob described by a manifest line against ks, and
+// updates r with the result.
+func (r *ManifestReport) check(ctx context.Context, ks KVCore, line string) error {
+	fields := strings.Fields(line)
+	if len(fields) != 3 {
+		return errors.New("invalid manifest entry")
+	}
+	key, err := hex.DecodeString(fields[0])
+	if err != nil {
+		return fmt.Errorf("invalid key: %w", err)
+	}
+	size, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return fmt.Errorf("invalid size: %w", err)
+	}
+	want, err := hex.DecodeString(fields[2])
+	if err != nil || len(want) != sha256.Size {
+		return errors.New("invalid digest")
+	}
+
+	r.Checked++
+	data, err := ks.Get(ctx, string(key))
+	if IsKeyNotFound(err) {
+		r.Missing = append(r.Missing, string(key))
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if sum := sha256.Sum256(data); len(data) != size || string(sum[:]) != string(want) {
+		r.Corrupt = append(r.Corrupt, string(key))
+	}
+	return nil
+}
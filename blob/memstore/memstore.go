@@ -19,6 +19,7 @@ package memstore
 
 import (
 	"context"
+	"fmt"
 	"iter"
 	"strings"
 	"sync"
@@ -108,10 +109,11 @@ type entry struct {
 
 func compareEntries(a, b entry) int { return strings.Compare(a.key, b.key) }
 
-// Opener constructs a [blob.StoreCloser] for use with the [store] package.
-// The concrete type of the result is [memstore.Store]. The address is ignored,
-// and an error is never returned.
+// Opener constructs a [blob.StoreCloser] for use with the [storage] registry
+// or the [store] package. The concrete type of the result is [memstore.Store].
+// The address is ignored, and an error is never returned.
 //
+// [storage]: https://godoc.org/github.com/creachadair/ffs/storage
 // [store]: https://godoc.org/github.com/creachadair/ffstools/lib/store
 func Opener(_ context.Context, _ string) (blob.StoreCloser, error) { return New(nil), nil }
 
@@ -163,6 +165,21 @@ func (s *KV) Get(_ context.Context, key string) ([]byte, error) {
 	return nil, blob.KeyNotFound(key)
 }
 
+// GetRange implements the [blob.RangeGetter] interface.
+func (s *KV) GetRange(_ context.Context, key string, offset, length int64) ([]byte, error) {
+	if offset < 0 || length < 0 {
+		return nil, fmt.Errorf("invalid range: offset %d, length %d", offset, length)
+	}
+	s.μ.RLock()
+	defer s.μ.RUnlock()
+
+	if e, ok := s.m.Get(entry{key: key}); ok {
+		val := e.val[min(offset, int64(len(e.val))):]
+		return []byte(val[:min(length, int64(len(val)))]), nil
+	}
+	return nil, blob.KeyNotFound(key)
+}
+
 // Has implements part of [blob.KV].
 func (s *KV) Has(_ context.Context, keys ...string) (blob.KeySet, error) {
 	s.μ.RLock()
@@ -190,6 +207,27 @@ func (s *KV) Put(_ context.Context, opts blob.PutOptions) error {
 	return nil
 }
 
+// PutAll implements the [blob.MultiPutter] interface.
+func (s *KV) PutAll(_ context.Context, opts []blob.PutOptions) error {
+	s.μ.Lock()
+	defer s.μ.Unlock()
+
+	// Check all the writes before applying any of them.
+	added := make(map[string]bool)
+	for _, p := range opts {
+		if p.Replace {
+			continue
+		} else if _, ok := s.m.Get(entry{key: p.Key}); ok || added[p.Key] {
+			return blob.KeyExists(p.Key)
+		}
+		added[p.Key] = true
+	}
+	for _, p := range opts {
+		s.m.Replace(entry{p.Key, string(p.Data)})
+	}
+	return nil
+}
+
 // Delete implements part of [blob.KV].
 func (s *KV) Delete(_ context.Context, key string) error {
 	s.μ.Lock()
@@ -201,6 +239,25 @@ func (s *KV) Delete(_ context.Context, key string) error {
 	return nil
 }
 
+// DeleteRange implements the [blob.RangeDeleter] interface.
+// The keys in the range are removed atomically.
+func (s *KV) DeleteRange(_ context.Context, start, end string) (int64, error) {
+	s.μ.Lock()
+	defer s.μ.Unlock()
+
+	var victims []entry
+	for e := range s.m.InorderAfter(entry{key: start}) {
+		if end != "" && e.key >= end {
+			break
+		}
+		victims = append(victims, e)
+	}
+	for _, e := range victims {
+		s.m.Remove(e)
+	}
+	return int64(len(victims)), nil
+}
+
 // List implements part of [blob.KV].
 func (s *KV) List(_ context.Context, start string) iter.Seq2[string, error] {
 	return func(yield func(string, error) bool) {
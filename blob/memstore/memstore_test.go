@@ -29,6 +29,8 @@ func TestStore(t *testing.T) {
 	storetest.Run(t, &s)
 }
 
+func FuzzStore(f *testing.F) { storetest.Fuzz(f, memstore.New(nil), nil) }
+
 func TestSnapshot(t *testing.T) {
 	kv := memstore.NewKV()
 	kv.Put(context.Background(), blob.PutOptions{
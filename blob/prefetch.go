@@ -0,0 +1,89 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blob
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"sync"
+)
+
+// PrefetchOptions are optional settings for [Prefetch]. A nil *PrefetchOptions
+// is ready for use and provides default values as described.
+type PrefetchOptions struct {
+	// The maximum number of concurrent fetches. A value ≤ 0 defaults to 16.
+	Concurrency int
+
+	// If true, keys not found in the store are skipped. Otherwise, a missing
+	// key is reported as an error.
+	IgnoreMissing bool
+}
+
+func (o *PrefetchOptions) concurrency() int {
+	if o == nil || o.Concurrency <= 0 {
+		return 16
+	}
+	return o.Concurrency
+}
+
+func (o *PrefetchOptions) ignoreMissing() bool { return o != nil && o.IgnoreMissing }
+
+// Prefetch fetches each of the specified keys from kv, with up to the
+// specified number of fetches in progress at once, and discards the results.
+// It returns when all the keys have been fetched, or when a fetch fails.
+//
+// Prefetch is useful to warm a caching layer, such as the one provided by
+// storage/cachestore, with a known set of keys before a file tree is mounted
+// or served, so that the first accesses to those keys need not wait for a
+// remote backend.
+//
+// If a fetch fails, Prefetch stops issuing new fetches and reports the first
+// error once the fetches already in progress are complete.
+func Prefetch(ctx context.Context, kv KVCore, keys iter.Seq[string], opts *PrefetchOptions) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var ferr error
+	fail := func(err error) {
+		errOnce.Do(func() { ferr = err; cancel() })
+	}
+
+	sem := make(chan struct{}, opts.concurrency())
+	ignore := opts.ignoreMissing()
+loop:
+	for key := range keys {
+		select {
+		case <-ctx.Done():
+			break loop
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			_, err := kv.Get(ctx, key)
+			if err != nil && !(ignore && errors.Is(err, ErrKeyNotFound)) {
+				fail(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if ferr != nil {
+		return ferr
+	}
+	return ctx.Err()
+}
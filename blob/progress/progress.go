@@ -0,0 +1,151 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package progress implements wrappers for [blob.KV] and [blob.CAS] values
+// that report the progress of data transfers to a callback.
+//
+// This is intended for use by command-line tools that need to render progress
+// for long-running operations. A [Tracker] accumulates counts of keys and
+// bytes fetched and stored through the keyspaces it wraps:
+//
+//	t := progress.NewTracker(func(e progress.Event) {
+//	   log.Printf("%v %d keys, %d bytes", e.Op, e.Total.Keys, e.Total.Bytes)
+//	})
+//	kv := t.KV(base)
+//	// ... use kv as usual
+package progress
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/creachadair/ffs/blob"
+)
+
+// Op identifies the kind of a transfer operation.
+type Op int
+
+const (
+	Get Op = iota // data fetched from storage
+	Put           // data written to storage
+)
+
+func (o Op) String() string {
+	switch o {
+	case Get:
+		return "get"
+	case Put:
+		return "put"
+	default:
+		return "unknown"
+	}
+}
+
+// Stats record cumulative transfer counts.
+type Stats struct {
+	Keys  int64 // the number of keys transferred
+	Bytes int64 // the total number of bytes transferred
+}
+
+// An Event is the argument to the report callback of a [Tracker].
+type Event struct {
+	Op    Op     // the operation that completed
+	Key   string // the key transferred
+	Bytes int    // the number of bytes transferred for this key
+	Total Stats  // cumulative totals for this Op, including this event
+}
+
+// A Tracker accumulates counts of keys and bytes transferred through the
+// keyspaces it wraps, and reports each successful transfer to a callback.
+// A Tracker is safe for concurrent use by multiple goroutines.
+type Tracker struct {
+	report func(Event)
+
+	getKeys, getBytes atomic.Int64
+	putKeys, putBytes atomic.Int64
+}
+
+// NewTracker constructs a new Tracker that calls report after each successful
+// Get or Put through a wrapped keyspace. The report function may be called
+// concurrently by multiple goroutines. If report == nil, the tracker only
+// accumulates counts.
+func NewTracker(report func(Event)) *Tracker { return &Tracker{report: report} }
+
+// Gets reports the cumulative totals of data fetched through t.
+func (t *Tracker) Gets() Stats { return Stats{Keys: t.getKeys.Load(), Bytes: t.getBytes.Load()} }
+
+// Puts reports the cumulative totals of data stored through t.
+func (t *Tracker) Puts() Stats { return Stats{Keys: t.putKeys.Load(), Bytes: t.putBytes.Load()} }
+
+func (t *Tracker) add(op Op, key string, nb int) {
+	var tot Stats
+	switch op {
+	case Get:
+		tot = Stats{Keys: t.getKeys.Add(1), Bytes: t.getBytes.Add(int64(nb))}
+	case Put:
+		tot = Stats{Keys: t.putKeys.Add(1), Bytes: t.putBytes.Add(int64(nb))}
+	}
+	if t.report != nil {
+		t.report(Event{Op: op, Key: key, Bytes: nb, Total: tot})
+	}
+}
+
+// KV returns a [blob.KV] that delegates to kv and records transfers in t.
+func (t *Tracker) KV(kv blob.KV) blob.KV { return trackKV{KV: kv, t: t} }
+
+// CAS returns a [blob.CAS] that delegates to cas and records transfers in t.
+// Content addresses are computed by cas.
+func (t *Tracker) CAS(cas blob.CAS) blob.CAS { return trackCAS{CAS: cas, t: t} }
+
+type trackKV struct {
+	blob.KV
+	t *Tracker
+}
+
+func (k trackKV) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := k.KV.Get(ctx, key)
+	if err == nil {
+		k.t.add(Get, key, len(data))
+	}
+	return data, err
+}
+
+func (k trackKV) Put(ctx context.Context, opts blob.PutOptions) error {
+	err := k.KV.Put(ctx, opts)
+	if err == nil {
+		k.t.add(Put, opts.Key, len(opts.Data))
+	}
+	return err
+}
+
+type trackCAS struct {
+	blob.CAS
+	t *Tracker
+}
+
+func (c trackCAS) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := c.CAS.Get(ctx, key)
+	if err == nil {
+		c.t.add(Get, key, len(data))
+	}
+	return data, err
+}
+
+func (c trackCAS) CASPut(ctx context.Context, data []byte) (string, error) {
+	key, err := c.CAS.CASPut(ctx, data)
+	if err == nil {
+		c.t.add(Put, key, len(data))
+	}
+	return key, err
+}
@@ -0,0 +1,79 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package progress_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/blob/memstore"
+	"github.com/creachadair/ffs/blob/progress"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTracker(t *testing.T) {
+	ctx := context.Background()
+
+	var μ sync.Mutex
+	var events []progress.Event
+	tr := progress.NewTracker(func(e progress.Event) {
+		μ.Lock()
+		defer μ.Unlock()
+		events = append(events, e)
+	})
+	base := memstore.NewKV()
+	kv := tr.KV(base)
+	cas := tr.CAS(blob.CASFromKV(base))
+
+	if err := kv.Put(ctx, blob.PutOptions{Key: "a", Data: []byte("apple")}); err != nil {
+		t.Fatalf("Put a: %v", err)
+	}
+	// A failed put is not counted.
+	if err := kv.Put(ctx, blob.PutOptions{Key: "a", Data: []byte("avocado")}); !blob.IsKeyExists(err) {
+		t.Fatalf("Put a again: got %v, want %v", err, blob.ErrKeyExists)
+	}
+	key, err := cas.CASPut(ctx, []byte("banana"))
+	if err != nil {
+		t.Fatalf("CASPut: %v", err)
+	}
+	if _, err := kv.Get(ctx, "a"); err != nil {
+		t.Fatalf("Get a: %v", err)
+	}
+	if _, err := cas.Get(ctx, key); err != nil {
+		t.Fatalf("Get %x: %v", key, err)
+	}
+	// A failed get is not counted.
+	if _, err := kv.Get(ctx, "nonesuch"); !blob.IsKeyNotFound(err) {
+		t.Fatalf("Get nonesuch: got %v, want %v", err, blob.ErrKeyNotFound)
+	}
+
+	if diff := cmp.Diff(progress.Stats{Keys: 2, Bytes: 11}, tr.Puts()); diff != "" {
+		t.Errorf("Puts (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(progress.Stats{Keys: 2, Bytes: 11}, tr.Gets()); diff != "" {
+		t.Errorf("Gets (-want, +got):\n%s", diff)
+	}
+	want := []progress.Event{
+		{Op: progress.Put, Key: "a", Bytes: 5, Total: progress.Stats{Keys: 1, Bytes: 5}},
+		{Op: progress.Put, Key: key, Bytes: 6, Total: progress.Stats{Keys: 2, Bytes: 11}},
+		{Op: progress.Get, Key: "a", Bytes: 5, Total: progress.Stats{Keys: 1, Bytes: 5}},
+		{Op: progress.Get, Key: key, Bytes: 6, Total: progress.Stats{Keys: 2, Bytes: 11}},
+	}
+	if diff := cmp.Diff(want, events); diff != "" {
+		t.Errorf("Events (-want, +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blob
+
+import (
+	"context"
+	"iter"
+	"math/rand/v2"
+	"slices"
+	"sync"
+)
+
+// numShards is the number of partitions used by ListSharded.
+const numShards = 256
+
+// shardRange returns the range of keys in shard i, the keys whose first byte
+// is i. The empty key is included in shard 0.
+func shardRange(i int) KeyRange {
+	r := KeyRange{Start: string([]byte{byte(i)}), End: string([]byte{byte(i + 1)})}
+	if i == 0 {
+		r.Start = ""
+	}
+	if i == numShards-1 {
+		r.End = ""
+	}
+	return r
+}
+
+// ListSharded returns an iterator over all the keys of ks in order, as the
+// List method of ks would with an empty start key. Unlike List, ListSharded
+// partitions the keyspace by the first byte of each key, and lists up to
+// nWorkers partitions concurrently. If nWorkers ≤ 0, it uses 16.
+//
+// This can be much faster than a single List for a store where each List
+// call has high latency, such as a remote store. The keys of partitions not
+// yet reached by the iterator are buffered in memory, so the memory used is
+// proportional to the number of keys listed ahead of the caller.
+//
+// If any partition reports an error, the iterator reports that error after
+// all the keys in the partitions preceding it.
+func ListSharded(ctx context.Context, ks KVCore, nWorkers int) iter.Seq2[string, error] {
+	if nWorkers <= 0 {
+		nWorkers = 16
+	}
+	return func(yield func(string, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+
+		type shard struct {
+			keys []string
+			err  error
+			done chan struct{}
+		}
+		shards := make([]shard, numShards)
+		for i := range shards {
+			shards[i].done = make(chan struct{})
+		}
+
+		// Workers claim shards in order, so that the shards the caller
+		// needs first are listed first.
+		var μ sync.Mutex
+		next := 0
+		var wg sync.WaitGroup
+		defer func() { cancel(); wg.Wait() }() // stop the workers before waiting
+		for range min(nWorkers, numShards) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					μ.Lock()
+					i := next
+					next++
+					μ.Unlock()
+					if i >= numShards {
+						return
+					}
+					sh := &shards[i]
+					if err := ctx.Err(); err != nil {
+						sh.err = err
+						close(sh.done)
+						return
+					}
+					for key, err := range shardRange(i).List(ctx, ks) {
+						if err != nil {
+							sh.err = err
+							break
+						}
+						sh.keys = append(sh.keys, key)
+					}
+					close(sh.done)
+					if sh.err != nil {
+						return
+					}
+				}
+			}()
+		}
+
+		for i := range shards {
+			sh := &shards[i]
+			<-sh.done
+			for _, key := range sh.keys {
+				if !yield(key, nil) {
+					return
+				}
+			}
+			if sh.err != nil {
+				yield("", sh.err)
+				return
+			}
+		}
+	}
+}
+
+// samplesPerPartition is the number of keys Partitions samples for each
+// partition requested.
+const samplesPerPartition = 256
+
+// Partitions returns up to n contiguous, non-overlapping key ranges that
+// together cover the whole keyspace of ks in order, each containing
+// approximately the same number of keys. The ranges may be processed by
+// separate workers, e.g., by listing them with [KeyRange.List]. The first
+// range has an empty Start and the last an empty End. If n ≤ 1, or if ks
+// has too few keys to divide, fewer ranges are returned, but always at least
+// one.
+//
+// Unlike the fixed first-byte partitions of [ListSharded], the boundaries
+// follow the actual distribution of keys, so keys with a common prefix or
+// a restricted alphabet (such as hex digits) are divided evenly.
+//
+// Partitions lists the keyspace once, choosing boundaries from a uniform
+// random sample of the keys, so it uses memory proportional to n but not to
+// the number of keys. Changes to ks after Partitions returns do not affect
+// the validity of the ranges, only their balance.
+func Partitions(ctx context.Context, ks KVCore, n int) ([]KeyRange, error) {
+	if n <= 1 {
+		return []KeyRange{{}}, nil
+	}
+
+	// Reservoir-sample keys from the keyspace.
+	sample := make([]string, 0, n*samplesPerPartition)
+	var seen int
+	for key, err := range ks.List(ctx, "") {
+		if err != nil {
+			return nil, err
+		}
+		seen++
+		if len(sample) < cap(sample) {
+			sample = append(sample, key)
+		} else if i := rand.IntN(seen); i < len(sample) {
+			sample[i] = key
+		}
+	}
+	if len(sample) == 0 {
+		return []KeyRange{{}}, nil
+	}
+	slices.Sort(sample)
+
+	var out []KeyRange
+	var start string
+	for i := 1; i < n; i++ {
+		b := sample[i*len(sample)/n]
+		if b == "" || b <= start {
+			continue // too few keys to separate these partitions
+		}
+		out = append(out, KeyRange{Start: start, End: b})
+		start = b
+	}
+	return append(out, KeyRange{Start: start}), nil
+}
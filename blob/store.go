@@ -58,6 +58,7 @@ package blob
 import (
 	"context"
 	"errors"
+	"fmt"
 	"iter"
 
 	"github.com/creachadair/mds/mapset"
@@ -120,6 +121,11 @@ type StoreCloser interface {
 // KVCore is the common interface shared by implementations of a key-value
 // namespace. Users will generally not use this interface directly; it is
 // included by reference in [KV] and [CAS].
+//
+// An implementation that does not support a key, such as the empty string,
+// must report an error satisfying ErrKeyNotFound from operations on that key.
+// Implementations, particularly wrappers, should use [CheckKey] to validate
+// keys, so that invalid keys fail the same way regardless of the backend.
 type KVCore interface {
 	// Get fetches the contents of a blob from the store. If the key is not
 	// found in the store, Get must report an ErrKeyNotFound error.
@@ -140,6 +146,11 @@ type KVCore interface {
 	//
 	// Requirements:
 	//
+	// Keys MUST be reported in strictly increasing order as defined by
+	// [CompareKeys], that is, byte-wise without regard to UTF-8 encoding. The
+	// start key is inclusive: If start is itself a key of the store, it MUST be
+	// the first key reported. Use a [KeyRange] to list a bounded range.
+	//
 	// Each pair reported by the iterator MUST be either a valid key and a nil
 	// error, or an empty key and a non-nil error.
 	//
@@ -168,7 +179,7 @@ type KVCore interface {
 // identified by a unique, opaque string key.  An implementation of KV is
 // permitted (but not required) to report an error from Put when given an empty
 // key.  If the implementation cannot store empty keys, it must report
-// ErrKeyNotFound when operating on an empty key.
+// ErrKeyNotFound when operating on an empty key, as [CheckKey] does.
 //
 // Implementations of this interface must be safe for concurrent use by
 // multiple goroutines.  Moreover, any sequence of operations on a KV that does
@@ -200,6 +211,43 @@ type CAS interface {
 	CASKey(ctx context.Context, data []byte) string
 }
 
+// RangeDeleter is an optional extension interface for a keyspace that can
+// efficiently delete all the keys in a contiguous range, without the caller
+// having to list and delete each key separately. Use [DeleteRange] to delete
+// a range of keys from any keyspace.
+type RangeDeleter interface {
+	// DeleteRange removes all the keys k in the keyspace such that start ≤ k
+	// and k < end, and reports the number of keys removed. If end == "", the
+	// range has no upper bound.
+	//
+	// DeleteRange is not required to be atomic. If it fails partway through,
+	// some keys in the range may have been removed.
+	DeleteRange(ctx context.Context, start, end string) (int64, error)
+}
+
+// MultiPutter is an optional extension interface for a keyspace that can
+// write several keys atomically, so that either all the writes take effect or
+// none of them do.
+type MultiPutter interface {
+	// PutAll applies the specified writes in order, as Put would. If any of
+	// the writes fails, for example because its key exists and Replace is
+	// false, none of them take effect and PutAll reports the error for that
+	// write.
+	PutAll(ctx context.Context, opts []PutOptions) error
+}
+
+// RangeGetter is an optional extension interface for a keyspace that can
+// efficiently fetch a portion of a blob, without the caller having to fetch
+// the whole blob. Use [GetRange] to fetch a portion of a blob from any
+// keyspace.
+type RangeGetter interface {
+	// GetRange fetches up to length bytes of the contents of key, starting at
+	// the specified offset. If the range extends past the end of the blob,
+	// only the bytes up to the end are returned. If key is not found,
+	// GetRange reports an error satisfying errors.Is(err, ErrKeyNotFound).
+	GetRange(ctx context.Context, key string, offset, length int64) ([]byte, error)
+}
+
 // PutOptions regulate the behaviour of the Put method of a [KV]
 // implementation.
 type PutOptions struct {
@@ -210,11 +258,14 @@ type PutOptions struct {
 
 // CASFromKV converts a [KV] into a [CAS]. This is intended for use by storage
 // implementations to support the CAS method of the [Store] interface.
+// If kv implements [RangeGetter], so does the resulting CAS.
 //
 // Content addresses computed by this implementation use SHA3-256 of the content.
 func CASFromKV(kv KV) CAS {
 	if cas, ok := kv.(CAS); ok {
 		return cas
+	} else if _, ok := kv.(RangeGetter); ok {
+		return rangeCAS{hashCAS{kv}}
 	}
 	return hashCAS{kv}
 }
@@ -237,8 +288,29 @@ var (
 	// ErrKeyNotFound is reported by Get or Size when given a key that does not
 	// exist in the store.
 	ErrKeyNotFound = errors.New("key not found")
+
+	// ErrContentMismatch is reported by a verified Get from a CAS when the
+	// content fetched for a key does not match its content address.
+	ErrContentMismatch = errors.New("content does not match key")
+
+	// ErrInvalidKey is reported when a key cannot be used with a store.
+	ErrInvalidKey = errors.New("invalid key")
+
+	// errEmptyKey is reported by CheckKey for an empty key. It wraps
+	// ErrKeyNotFound as required by KVCore for unsupported keys.
+	errEmptyKey = fmt.Errorf("%w: %w", ErrInvalidKey, ErrKeyNotFound)
 )
 
+// CheckKey reports an error if key is not valid for use with a store, or nil.
+// Currently the only invalid key is the empty string. The error satisfies both
+// ErrInvalidKey and ErrKeyNotFound, and its concrete type is *blob.KeyError.
+func CheckKey(key string) error {
+	if key == "" {
+		return &KeyError{Key: key, Err: errEmptyKey}
+	}
+	return nil
+}
+
 // IsKeyNotFound reports whether err or is or wraps ErrKeyNotFound.
 // It is false if err == nil.
 func IsKeyNotFound(err error) bool {
@@ -273,6 +345,11 @@ func KeyNotFound(key string) error { return &KeyError{Key: key, Err: ErrKeyNotFo
 // The concrete type is *blob.KeyError.
 func KeyExists(key string) error { return &KeyError{Key: key, Err: ErrKeyExists} }
 
+// ContentMismatch returns an ErrContentMismatch error reporting that the
+// content stored for key does not match it. The concrete type is
+// *blob.KeyError.
+func ContentMismatch(key string) error { return &KeyError{Key: key, Err: ErrContentMismatch} }
+
 // KeySet represents a set of keys. It is aliased here so the caller does not
 // need to explicitly import [mapset].
 type KeySet = mapset.Set[string]
@@ -282,6 +359,7 @@ type KeySet = mapset.Set[string]
 type hashCAS struct{ KV }
 
 // hash is the digest function used to compute content addresses for hashCAS.
+// If this changes, update CASHash to match.
 var hash = sha3.Sum256
 
 // key computes the content key for data using the provided hash.
@@ -317,6 +395,77 @@ func (c hashCAS) CASPut(ctx context.Context, data []byte) (string, error) {
 // CASKey constructs the content address for the specified data.
 func (c hashCAS) CASKey(_ context.Context, data []byte) string { return c.key(data) }
 
+// rangeCAS is a hashCAS whose underlying KV implements RangeGetter.
+type rangeCAS struct{ hashCAS }
+
+// GetRange implements the [RangeGetter] interface.
+func (c rangeCAS) GetRange(ctx context.Context, key string, offset, length int64) ([]byte, error) {
+	return c.KV.(RangeGetter).GetRange(ctx, key, offset, length)
+}
+
+// GetRange fetches up to length bytes of the contents of key from ks, starting
+// at the specified offset, with the semantics of [RangeGetter]. If ks
+// implements RangeGetter, its GetRange method is used; otherwise GetRange
+// fetches the whole blob and returns the requested portion.
+func GetRange(ctx context.Context, ks KVCore, key string, offset, length int64) ([]byte, error) {
+	if offset < 0 || length < 0 {
+		return nil, fmt.Errorf("invalid range: offset %d, length %d", offset, length)
+	}
+	if rg, ok := ks.(RangeGetter); ok {
+		return rg.GetRange(ctx, key, offset, length)
+	}
+	data, err := ks.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return SliceRange(data, offset, length), nil
+}
+
+// SliceRange returns the portion of data selected by offset and length, with
+// the semantics of [RangeGetter]. This is intended for use by storage
+// implementations that hold blobs in memory. It will panic if offset < 0 or
+// length < 0.
+func SliceRange(data []byte, offset, length int64) []byte {
+	if offset < 0 || length < 0 {
+		panic("invalid range")
+	}
+	data = data[min(offset, int64(len(data))):]
+	return data[:min(length, int64(len(data)))]
+}
+
+// DeleteRange removes all the keys k of ks such that start ≤ k and k < end,
+// and reports the number of keys removed. If end == "", the range has no
+// upper bound. If ks implements [RangeDeleter], its DeleteRange method is
+// used; otherwise DeleteRange lists the keys in the range and deletes them.
+func DeleteRange(ctx context.Context, ks KVCore, start, end string) (int64, error) {
+	r := KeyRange{Start: start, End: end}
+	if r.IsEmpty() {
+		return 0, nil
+	}
+	if rd, ok := ks.(RangeDeleter); ok {
+		return rd.DeleteRange(ctx, start, end)
+	}
+
+	// Collect the victims first, since we cannot modify the keyspace while
+	// listing it.
+	var victims []string
+	for key, err := range r.List(ctx, ks) {
+		if err != nil {
+			return 0, err
+		}
+		victims = append(victims, key)
+	}
+	var nr int64
+	for _, key := range victims {
+		if err := ks.Delete(ctx, key); err == nil {
+			nr++
+		} else if !IsKeyNotFound(err) {
+			return nr, err
+		}
+	}
+	return nr, nil
+}
+
 // SyncKeys reports which of the given keys are not present in the key space.
 // If all the keys are present, SyncKeys returns an empty [KeySet].
 func SyncKeys(ctx context.Context, ks KVCore, keys []string) (KeySet, error) {
@@ -15,13 +15,21 @@
 package blob_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/ed25519"
 	"errors"
 	"fmt"
+	"iter"
 	"path"
 	"reflect"
 	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/creachadair/ffs/blob"
 	"github.com/creachadair/ffs/blob/memstore"
@@ -118,6 +126,67 @@ func TestKeyError(t *testing.T) {
 	}
 }
 
+func TestCheckKey(t *testing.T) {
+	if err := blob.CheckKey("ok"); err != nil {
+		t.Errorf("CheckKey(ok): unexpected error: %v", err)
+	}
+	err := blob.CheckKey("")
+	if !errors.Is(err, blob.ErrInvalidKey) || !blob.IsKeyNotFound(err) {
+		t.Errorf("CheckKey(empty): got %v, want %v and %v", err, blob.ErrInvalidKey, blob.ErrKeyNotFound)
+	}
+	if _, ok := err.(*blob.KeyError); !ok {
+		t.Errorf("CheckKey(empty): got %T, want *blob.KeyError", err)
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	ctx := context.Background()
+	s := memstore.New(nil)
+
+	if _, err := blob.LoadIdentity(ctx, s); !blob.IsKeyNotFound(err) {
+		t.Errorf("LoadIdentity empty: got %v, want %v", err, blob.ErrKeyNotFound)
+	}
+	id, err := blob.InitIdentity(ctx, s, blob.Identity{CASHash: blob.CASHash, Chunking: "abc"})
+	if err != nil {
+		t.Fatalf("InitIdentity: unexpected error: %v", err)
+	} else if id.ID == "" || id.Created.IsZero() {
+		t.Errorf("InitIdentity: got %+v, want ID and Created set", id)
+	}
+
+	// A second initialization does not replace the first.
+	if old, err := blob.InitIdentity(ctx, s, blob.Identity{Chunking: "xyz"}); !blob.IsKeyExists(err) {
+		t.Errorf("InitIdentity again: got %v, want %v", err, blob.ErrKeyExists)
+	} else if old.ID != id.ID {
+		t.Errorf("InitIdentity again: got ID %q, want %q", old.ID, id.ID)
+	}
+
+	if got, err := blob.LoadIdentity(ctx, s); err != nil {
+		t.Errorf("LoadIdentity: unexpected error: %v", err)
+	} else if diff := gocmp.Diff(got, id); diff != "" {
+		t.Errorf("LoadIdentity (-got, +want):\n%s", diff)
+	}
+
+	for _, want := range []blob.Identity{
+		{},
+		{ID: id.ID},
+		{CASHash: blob.CASHash, Chunking: "abc"},
+		id,
+	} {
+		if _, err := blob.CheckIdentity(ctx, s, want); err != nil {
+			t.Errorf("CheckIdentity %+v: unexpected error: %v", want, err)
+		}
+	}
+	for _, want := range []blob.Identity{
+		{ID: "other"},
+		{CASHash: "md5"},
+		{Chunking: "xyz"},
+	} {
+		if _, err := blob.CheckIdentity(ctx, s, want); !errors.Is(err, blob.ErrIdentityMismatch) {
+			t.Errorf("CheckIdentity %+v: got %v, want %v", want, err, blob.ErrIdentityMismatch)
+		}
+	}
+}
+
 func TestSyncKeys(t *testing.T) {
 	kv := memstore.NewKV().Init(map[string]string{
 		"1": "one",
@@ -159,3 +228,664 @@ func TestSyncKeys(t *testing.T) {
 		t.Run("CAS", check(cas, []string{"10", "50", "90", "0", "8"}, "0", "10", "50", "8", "90"))
 	})
 }
+
+// plainKV hides any extension methods of the KV it wraps.
+type plainKV struct{ blob.KV }
+
+func TestDeleteRange(t *testing.T) {
+	ctx := context.Background()
+	keys := []string{"", "a", "apple", "b", "banana", "c", "cherry", "d"}
+
+	tests := []struct {
+		start, end string
+		want       []string // keys remaining
+	}{
+		{"", "", nil},
+		{"b", "", []string{"", "a", "apple"}},
+		{"", "b", []string{"b", "banana", "c", "cherry", "d"}},
+		{"apple", "cherry", []string{"", "a", "cherry", "d"}},
+		{"a", "a", keys},
+		{"z", "a", keys},
+		{"x", "", keys},
+	}
+	for _, impl := range []struct {
+		name string
+		wrap func(blob.KV) blob.KV
+	}{
+		{"Native", func(kv blob.KV) blob.KV { return kv }},
+		{"Fallback", func(kv blob.KV) blob.KV { return plainKV{kv} }},
+	} {
+		t.Run(impl.name, func(t *testing.T) {
+			for _, tc := range tests {
+				kv := impl.wrap(memstore.NewKV())
+				for _, key := range keys {
+					if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte(key)}); err != nil {
+						t.Fatalf("Put %q: %v", key, err)
+					}
+				}
+				nr, err := blob.DeleteRange(ctx, kv, tc.start, tc.end)
+				if err != nil {
+					t.Fatalf("DeleteRange(%q, %q): unexpected error: %v", tc.start, tc.end, err)
+				}
+				if want := int64(len(keys) - len(tc.want)); nr != want {
+					t.Errorf("DeleteRange(%q, %q): removed %d, want %d", tc.start, tc.end, nr, want)
+				}
+				var got []string
+				for key, err := range kv.List(ctx, "") {
+					if err != nil {
+						t.Fatalf("List: %v", err)
+					}
+					got = append(got, key)
+				}
+				if diff := gocmp.Diff(tc.want, got); diff != "" {
+					t.Errorf("DeleteRange(%q, %q) remaining (-want, +got):\n%s", tc.start, tc.end, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestKeyRange(t *testing.T) {
+	tests := []struct {
+		r     blob.KeyRange
+		empty bool
+		in    []string
+		out   []string
+	}{
+		{blob.KeyRange{}, false, []string{"", "a", "\xff"}, nil},
+		{blob.KeyRange{Start: "b"}, false, []string{"b", "b\x00", "\xff"}, []string{"", "a", "a\xff"}},
+		{blob.KeyRange{Start: "a", End: "c"}, false, []string{"a", "b", "bzzz"}, []string{"", "c", "c\x00", "\xc3\xa9"}},
+		{blob.KeyRange{Start: "a", End: "a\x00"}, false, []string{"a"}, []string{"a\x00", "", "b"}},
+		{blob.KeyRange{Start: "c", End: "c"}, true, nil, []string{"c"}},
+		{blob.KeyRange{Start: "d", End: "c"}, true, nil, []string{"c", "d"}},
+	}
+	for _, tc := range tests {
+		if got := tc.r.IsEmpty(); got != tc.empty {
+			t.Errorf("%+v IsEmpty: got %v, want %v", tc.r, got, tc.empty)
+		}
+		for _, key := range tc.in {
+			if !tc.r.Contains(key) {
+				t.Errorf("%+v Contains(%q): got false, want true", tc.r, key)
+			}
+		}
+		for _, key := range tc.out {
+			if tc.r.Contains(key) {
+				t.Errorf("%+v Contains(%q): got true, want false", tc.r, key)
+			}
+		}
+	}
+
+	// Non-UTF-8 bytes sort by value, after all ASCII.
+	if blob.CompareKeys("\x80", "z") <= 0 || blob.CompareKeys("\xc3\xa9", "\xc3") <= 0 {
+		t.Error("CompareKeys: wrong byte-wise order")
+	}
+}
+
+func TestCountRange(t *testing.T) {
+	ctx := context.Background()
+	kv := memstore.NewKV()
+	for i := range 25 {
+		key := fmt.Sprintf("key-%02d", i)
+		if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte(key)}); err != nil {
+			t.Fatalf("Put %q: %v", key, err)
+		}
+	}
+
+	var calls []int64
+	opts := &blob.CountOptions{Interval: 10, Progress: func(n int64) { calls = append(calls, n) }}
+	if n, err := blob.CountRange(ctx, kv, "", "", opts); err != nil || n != 25 {
+		t.Errorf("CountRange all: got (%d, %v), want (25, nil)", n, err)
+	}
+	if diff := gocmp.Diff(calls, []int64{10, 20, 25}); diff != "" {
+		t.Errorf("Progress (-got, +want):\n%s", diff)
+	}
+	if n, err := blob.CountRange(ctx, kv, "key-05", "key-15", nil); err != nil || n != 10 {
+		t.Errorf("CountRange: got (%d, %v), want (10, nil)", n, err)
+	}
+
+	// Cancellation stops the count early and reports the partial total.
+	cctx, cancel := context.WithCancel(ctx)
+	opts = &blob.CountOptions{Interval: 5, Progress: func(n int64) {
+		if n >= 10 {
+			cancel()
+		}
+	}}
+	if n, err := blob.CountRange(cctx, kv, "", "", opts); !errors.Is(err, context.Canceled) || n != 10 {
+		t.Errorf("CountRange cancelled: got (%d, %v), want (10, %v)", n, err, context.Canceled)
+	}
+}
+
+// churnKV is a KV that calls onList at the start of each listing, and fails
+// listings after reporting failAfter keys, while failures > 0.
+type churnKV struct {
+	blob.KV
+	onList    func(pass int)
+	passes    int
+	failAfter int
+	failures  int
+}
+
+func (c *churnKV) List(ctx context.Context, start string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		if start == "" {
+			c.passes++
+			c.onList(c.passes)
+		}
+		var n int
+		for key, err := range c.KV.List(ctx, start) {
+			if err == nil && c.failures > 0 && n == c.failAfter {
+				c.failures--
+				yield("", errors.New("list failed"))
+				return
+			}
+			n++
+			if !yield(key, err) {
+				return
+			}
+		}
+	}
+}
+
+func TestListSnapshot(t *testing.T) {
+	ctx := context.Background()
+	put := func(kv blob.KV, keys ...string) {
+		t.Helper()
+		for _, key := range keys {
+			if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte(key), Replace: true}); err != nil {
+				t.Fatalf("Put %q: %v", key, err)
+			}
+		}
+	}
+	base := memstore.NewKV()
+	put(base, "a", "b", "c", "d", "e")
+
+	// Each listing deletes the key added by the previous one, and adds a new
+	// one, so that no single listing sees both.
+	var last string
+	kv := &churnKV{KV: base, onList: func(pass int) {
+		base.Delete(ctx, last)
+		last = fmt.Sprintf("tmp%d", pass)
+		put(base, last)
+	}}
+
+	t.Run("Union", func(t *testing.T) {
+		got, err := blob.ListSnapshot(ctx, kv, nil)
+		if err != nil {
+			t.Fatalf("ListSnapshot: unexpected error: %v", err)
+		}
+		want := []string{"a", "b", "c", "d", "e", "tmp1", "tmp2"}
+		if diff := gocmp.Diff(got, want); diff != "" {
+			t.Errorf("ListSnapshot (-got, +want):\n%s", diff)
+		}
+	})
+
+	t.Run("Intersect", func(t *testing.T) {
+		kv.passes = 0
+		got, err := blob.ListSnapshot(ctx, kv, &blob.SnapshotOptions{
+			Mode:   blob.SnapshotIntersect,
+			Passes: 3,
+		})
+		if err != nil {
+			t.Fatalf("ListSnapshot: unexpected error: %v", err)
+		}
+		want := []string{"a", "b", "c", "d", "e"}
+		if diff := gocmp.Diff(got, want); diff != "" {
+			t.Errorf("ListSnapshot (-got, +want):\n%s", diff)
+		}
+	})
+
+	t.Run("Resume", func(t *testing.T) {
+		kv.passes, kv.failAfter, kv.failures = 0, 2, 2
+		got, err := blob.ListSnapshot(ctx, kv, &blob.SnapshotOptions{Mode: blob.SnapshotIntersect})
+		if err != nil {
+			t.Fatalf("ListSnapshot: unexpected error: %v", err)
+		}
+		want := []string{"a", "b", "c", "d", "e"}
+		if diff := gocmp.Diff(got, want); diff != "" {
+			t.Errorf("ListSnapshot (-got, +want):\n%s", diff)
+		}
+		if kv.passes != 2 {
+			t.Errorf("Got %d listings, want 2", kv.passes)
+		}
+	})
+
+	t.Run("Fail", func(t *testing.T) {
+		kv.failAfter, kv.failures = 0, 10
+		got, err := blob.ListSnapshot(ctx, kv, &blob.SnapshotOptions{Retries: -1})
+		if err == nil {
+			t.Errorf("ListSnapshot: got %q, want error", got)
+		}
+	})
+}
+
+func TestListSharded(t *testing.T) {
+	ctx := context.Background()
+	kv := memstore.NewKV()
+
+	var want []string
+	for _, key := range []string{"", "\x00", "\x00\x01", "a", "ab", "b", "\x7f", "\x80", "\xc3\xa9", "\xff", "\xff\xff"} {
+		if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte(key)}); err != nil {
+			t.Fatalf("Put %q: %v", key, err)
+		}
+		want = append(want, key)
+	}
+	for _, nw := range []int{0, 1, 3, 300} {
+		var got []string
+		for key, err := range blob.ListSharded(ctx, kv, nw) {
+			if err != nil {
+				t.Fatalf("ListSharded(%d): unexpected error: %v", nw, err)
+			}
+			got = append(got, key)
+		}
+		if diff := gocmp.Diff(got, want); diff != "" {
+			t.Errorf("ListSharded(%d) keys (-got, +want):\n%s", nw, diff)
+		}
+	}
+
+	// Stopping early should not leak or block.
+	var n int
+	for range blob.ListSharded(ctx, kv, 4) {
+		n++
+		if n == 3 {
+			break
+		}
+	}
+
+	// Stopping early cancels the listing of the remaining shards, rather than
+	// waiting for them to finish.
+	t.Run("Stop", func(t *testing.T) {
+		big := memstore.NewKV()
+		for i := range numShardKeys {
+			key := string([]byte{byte(i % 256), byte(i / 256)})
+			if err := big.Put(ctx, blob.PutOptions{Key: key}); err != nil {
+				t.Fatalf("Put %q: %v", key, err)
+			}
+		}
+		skv := &slowListKV{KV: big}
+		for range blob.ListSharded(ctx, skv, 4) {
+			break
+		}
+		if n := skv.listed.Load(); n > numShardKeys/256+1 {
+			t.Errorf("ListSharded stopped early: listed %d keys, want at most %d", n, numShardKeys/256+1)
+		}
+	})
+
+	// A canceled context is reported as an error.
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	var gotErr error
+	for _, err := range blob.ListSharded(cctx, kv, 4) {
+		if err != nil {
+			gotErr = err
+		}
+	}
+	if !errors.Is(gotErr, context.Canceled) {
+		t.Errorf("ListSharded canceled: got %v, want %v", gotErr, context.Canceled)
+	}
+}
+
+const numShardKeys = 12800
+
+// slowListKV is a blob.KV that counts the keys listed from it. Listings that
+// do not start at the beginning of the keyspace wait until their context ends
+// or a short delay passes, whichever is first.
+type slowListKV struct {
+	blob.KV
+	listed atomic.Int64
+}
+
+func (s *slowListKV) List(ctx context.Context, start string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		if start != "" {
+			select {
+			case <-ctx.Done():
+				yield("", ctx.Err())
+				return
+			case <-time.After(50 * time.Millisecond):
+			}
+		}
+		for key, err := range s.KV.List(ctx, start) {
+			if err == nil {
+				s.listed.Add(1)
+			}
+			if !yield(key, err) {
+				return
+			}
+		}
+	}
+}
+
+func TestPartitions(t *testing.T) {
+	ctx := context.Background()
+	kv := memstore.NewKV()
+
+	// An empty keyspace has a single partition.
+	if got, err := blob.Partitions(ctx, kv, 4); err != nil {
+		t.Fatalf("Partitions: unexpected error: %v", err)
+	} else if diff := gocmp.Diff(got, []blob.KeyRange{{}}); diff != "" {
+		t.Errorf("Partitions (-got, +want):\n%s", diff)
+	}
+
+	// Hex-formatted keys all begin with one of 16 bytes, which first-byte
+	// sharding divides poorly.
+	const numKeys = 1000
+	for i := range numKeys {
+		key := fmt.Sprintf("%08x", i*i)
+		if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte(key)}); err != nil {
+			t.Fatalf("Put %q: %v", key, err)
+		}
+	}
+	for _, n := range []int{0, 1, 3, 4, 10} {
+		got, err := blob.Partitions(ctx, kv, n)
+		if err != nil {
+			t.Fatalf("Partitions(%d): unexpected error: %v", n, err)
+		}
+		if want := max(n, 1); len(got) != want {
+			t.Fatalf("Partitions(%d): got %d ranges, want %d", n, len(got), want)
+		}
+
+		// The ranges must be contiguous, cover the keyspace, and be balanced.
+		if got[0].Start != "" || got[len(got)-1].End != "" {
+			t.Errorf("Partitions(%d): ranges %q do not cover the keyspace", n, got)
+		}
+		var total int
+		for i, r := range got {
+			if i > 0 && r.Start != got[i-1].End {
+				t.Errorf("Partitions(%d): range %d starts at %q, want %q", n, i, r.Start, got[i-1].End)
+			}
+			nk, err := blob.CountRange(ctx, kv, r.Start, r.End, nil)
+			if err != nil {
+				t.Fatalf("CountRange: unexpected error: %v", err)
+			}
+			if lo, hi := numKeys*8/10/len(got), numKeys*12/10/len(got); nk < int64(lo) || nk > int64(hi) {
+				t.Errorf("Partitions(%d): range %d has %d keys, want %d..%d", n, i, nk, lo, hi)
+			}
+			total += int(nk)
+		}
+		if total != numKeys {
+			t.Errorf("Partitions(%d): ranges hold %d keys, want %d", n, total, numKeys)
+		}
+	}
+
+	// Asking for more partitions than keys yields fewer ranges.
+	small := memstore.NewKV()
+	for _, key := range []string{"", "a", "b"} {
+		small.Put(ctx, blob.PutOptions{Key: key, Data: []byte("x")})
+	}
+	if got, err := blob.Partitions(ctx, small, 10); err != nil {
+		t.Fatalf("Partitions: unexpected error: %v", err)
+	} else if diff := gocmp.Diff(got, []blob.KeyRange{{End: "a"}, {Start: "a", End: "b"}, {Start: "b"}}); diff != "" {
+		t.Errorf("Partitions (-got, +want):\n%s", diff)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	b := blob.NewBudget(100)
+
+	// An account that can give back everything it holds.
+	var a1 *blob.Account
+	a1 = b.Register(func(n int64) { a1.Release(n) })
+	a2 := b.Register(nil) // cannot reclaim
+
+	if !a1.Reserve(60) || !a2.Reserve(30) {
+		t.Fatal("Initial reservations failed")
+	}
+	if got := b.Used(); got != 90 {
+		t.Errorf("Used: got %d, want 90", got)
+	}
+
+	// Reserving past the limit reclaims from a1, but not a2.
+	if !a2.Reserve(40) {
+		t.Error("Reserve 40: got false, want true")
+	}
+	if u1, u2 := a1.Used(), a2.Used(); u1 != 30 || u2 != 70 {
+		t.Errorf("After reclaim: got a1=%d a2=%d, want 30, 70", u1, u2)
+	}
+
+	// Requests that cannot be satisfied fail without blocking.
+	if a2.Reserve(101) {
+		t.Error("Reserve 101: got true, want false")
+	}
+	if !a2.Reserve(30) {
+		t.Error("Reserve 30: got false, want true") // reclaims the rest of a1
+	}
+	if a2.Reserve(1) {
+		t.Error("Reserve 1: got true, want false")
+	}
+	if got := b.Used(); got != 100 {
+		t.Errorf("Used: got %d, want 100", got)
+	}
+
+	a2.Close()
+	if got := b.Used(); got != 0 {
+		t.Errorf("Used after Close: got %d, want 0", got)
+	}
+}
+
+func TestGetVerified(t *testing.T) {
+	ctx := context.Background()
+	kv := memstore.NewKV()
+	cas := blob.CASFromKV(kv)
+
+	key, err := cas.CASPut(ctx, []byte("good content"))
+	if err != nil {
+		t.Fatalf("CASPut: %v", err)
+	}
+	vcas := blob.VerifyCAS(cas)
+	if got, err := vcas.Get(ctx, key); err != nil || string(got) != "good content" {
+		t.Errorf("Get: got (%q, %v), want good content", got, err)
+	}
+
+	// Corrupt the stored content behind the back of the CAS.
+	if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte("bad content"), Replace: true}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, err := cas.Get(ctx, key); err != nil {
+		t.Errorf("Unverified Get: unexpected error: %v", err)
+	}
+	_, err = vcas.Get(ctx, key)
+	if !errors.Is(err, blob.ErrContentMismatch) {
+		t.Fatalf("Verified Get: got %v, want %v", err, blob.ErrContentMismatch)
+	}
+	var kerr *blob.KeyError
+	if !errors.As(err, &kerr) || kerr.Key != key {
+		t.Errorf("Verified Get: got %#v, want key %x", err, key)
+	}
+
+	// Missing keys are reported as usual.
+	if _, err := blob.GetVerified(ctx, cas, "nonesuch"); !blob.IsKeyNotFound(err) {
+		t.Errorf("GetVerified missing: got %v, want %v", err, blob.ErrKeyNotFound)
+	}
+}
+
+func TestPrefetch(t *testing.T) {
+	ctx := context.Background()
+	kv := &fetchKV{KV: memstore.NewKV(), seen: mapset.New[string]()}
+	var keys []string
+	for i := range 50 {
+		key := fmt.Sprintf("key-%02d", i)
+		keys = append(keys, key)
+		if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte(key)}); err != nil {
+			t.Fatalf("Put %q: %v", key, err)
+		}
+	}
+
+	opts := &blob.PrefetchOptions{Concurrency: 4}
+	if err := blob.Prefetch(ctx, kv, slices.Values(keys), opts); err != nil {
+		t.Fatalf("Prefetch: unexpected error: %v", err)
+	}
+	if want := mapset.New(keys...); !kv.seen.Equals(want) {
+		t.Errorf("Prefetch fetched %d keys, want %d", kv.seen.Len(), want.Len())
+	}
+	if kv.peak > 4 {
+		t.Errorf("Prefetch: %d concurrent fetches, want at most 4", kv.peak)
+	}
+
+	// A missing key is reported, unless the caller asks to ignore it.
+	missing := slices.Values(append(keys, "nonesuch"))
+	if err := blob.Prefetch(ctx, kv, missing, nil); !blob.IsKeyNotFound(err) {
+		t.Errorf("Prefetch missing: got %v, want %v", err, blob.ErrKeyNotFound)
+	}
+	if err := blob.Prefetch(ctx, kv, missing, &blob.PrefetchOptions{IgnoreMissing: true}); err != nil {
+		t.Errorf("Prefetch missing: got %v, want nil", err)
+	}
+
+	// Cancellation is reported.
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := blob.Prefetch(cctx, kv, slices.Values(keys), nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Prefetch cancelled: got %v, want %v", err, context.Canceled)
+	}
+}
+
+// fetchKV is a blob.KV that records the keys fetched by Get, and the peak
+// number of concurrent calls to Get.
+type fetchKV struct {
+	blob.KV
+
+	μ      sync.Mutex
+	seen   mapset.Set[string]
+	active int
+	peak   int
+}
+
+func (f *fetchKV) Get(ctx context.Context, key string) ([]byte, error) {
+	f.μ.Lock()
+	f.active++
+	f.peak = max(f.peak, f.active)
+	f.seen.Add(key)
+	f.μ.Unlock()
+	defer func() { f.μ.Lock(); f.active--; f.μ.Unlock() }()
+
+	runtime.Gosched()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return f.KV.Get(ctx, key)
+}
+
+func TestScanSizes(t *testing.T) {
+	ctx := context.Background()
+	kv := &fetchKV{KV: memstore.NewKV(), seen: mapset.New[string]()}
+	for i, size := range []int{0, 1, 2, 3, 4, 7, 100, 1000} {
+		key := fmt.Sprintf("key-%02d", i)
+		if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: make([]byte, size)}); err != nil {
+			t.Fatalf("Put %q: %v", key, err)
+		}
+	}
+
+	var calls int
+	h, err := blob.ScanSizes(ctx, kv, &blob.ScanSizesOptions{
+		Concurrency: 3,
+		Progress:    func(*blob.SizeHistogram) { calls++ },
+	})
+	if err != nil {
+		t.Fatalf("ScanSizes: unexpected error: %v", err)
+	}
+	if calls != 8 {
+		t.Errorf("ScanSizes: got %d progress calls, want 8", calls)
+	}
+	if kv.peak > 3 {
+		t.Errorf("ScanSizes: %d concurrent fetches, want at most 3", kv.peak)
+	}
+	want := &blob.SizeHistogram{
+		Count: 8,
+		Bytes: 1117,
+		Buckets: []blob.SizeBucket{
+			{Limit: 1, Count: 1, Bytes: 0},
+			{Limit: 2, Count: 1, Bytes: 1},
+			{Limit: 4, Count: 2, Bytes: 5},
+			{Limit: 8, Count: 2, Bytes: 11},
+			{Limit: 16}, {Limit: 32}, {Limit: 64},
+			{Limit: 128, Count: 1, Bytes: 100},
+			{Limit: 256}, {Limit: 512},
+			{Limit: 1024, Count: 1, Bytes: 1000},
+		},
+	}
+	if diff := gocmp.Diff(h, want); diff != "" {
+		t.Errorf("ScanSizes (-got, +want):\n%s", diff)
+	}
+
+	// An empty keyspace has an empty histogram.
+	if h, err := blob.ScanSizes(ctx, memstore.NewKV(), nil); err != nil || h.Count != 0 || len(h.Buckets) != 0 {
+		t.Errorf("ScanSizes empty: got (%+v, %v), want empty", h, err)
+	}
+
+	// Cancellation is reported.
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := blob.ScanSizes(cctx, kv, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("ScanSizes cancelled: got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	ctx := context.Background()
+	kv := memstore.NewKV()
+	for _, key := range []string{"apple", "cherry", "plum"} {
+		if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte("a " + key)}); err != nil {
+			t.Fatalf("Put %q: %v", key, err)
+		}
+	}
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if n, err := blob.WriteManifest(ctx, &buf, kv, priv); err != nil || n != 3 {
+		t.Fatalf("WriteManifest: got (%d, %v), want (3, nil)", n, err)
+	}
+	manifest := buf.String()
+	t.Logf("Manifest:\n%s", manifest)
+
+	verify := func(m string, pub ed25519.PublicKey) (blob.ManifestReport, error) {
+		return blob.VerifyManifest(ctx, strings.NewReader(m), kv, pub)
+	}
+	if rep, err := verify(manifest, pub); err != nil || !rep.OK() || rep.Checked != 3 {
+		t.Errorf("VerifyManifest: got (%+v, %v), want 3 OK", rep, err)
+	}
+
+	// Changes to the store are reported.
+	kv.Put(ctx, blob.PutOptions{Key: "cherry", Data: []byte("a cherry pit"), Replace: true})
+	kv.Delete(ctx, "plum")
+	kv.Put(ctx, blob.PutOptions{Key: "quince", Data: []byte("unlisted")})
+	rep, err := verify(manifest, pub)
+	if err != nil {
+		t.Fatalf("VerifyManifest: unexpected error: %v", err)
+	}
+	if diff := gocmp.Diff(rep, blob.ManifestReport{
+		Checked: 3,
+		Missing: []string{"plum"},
+		Corrupt: []string{"cherry"},
+	}); diff != "" {
+		t.Errorf("VerifyManifest (-got, +want):\n%s", diff)
+	}
+
+	// A manifest that has been altered, or signed by another key, or not
+	// signed at all, does not verify.
+	other, _, _ := ed25519.GenerateKey(nil)
+	lines := strings.SplitAfter(manifest, "\n")
+	unsigned := strings.Join(lines[:len(lines)-2], "")
+	altered := strings.Join(append(lines[:2:2], lines[3:]...), "")
+	for _, tc := range []struct {
+		name, manifest string
+		pub            ed25519.PublicKey
+	}{
+		{"OtherKey", manifest, other},
+		{"Unsigned", unsigned, pub},
+		{"Altered", altered, pub},
+	} {
+		if _, err := verify(tc.manifest, tc.pub); !errors.Is(err, blob.ErrManifestSignature) {
+			t.Errorf("VerifyManifest %s: got %v, want %v", tc.name, err, blob.ErrManifestSignature)
+		}
+	}
+
+	// Without a public key, the signature is not required.
+	if _, err := verify(unsigned, nil); err != nil {
+		t.Errorf("VerifyManifest unsigned: unexpected error: %v", err)
+	}
+	if _, err := verify("bogus\n", nil); err == nil {
+		t.Error("VerifyManifest bogus: got nil, want error")
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storetest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/creachadair/ffs/blob"
+)
+
+// FuzzOptions are optional settings for [Fuzz]. A nil *FuzzOptions is ready
+// for use and provides default values as described.
+type FuzzOptions struct {
+	// If positive, inputs with keys longer than this many bytes are skipped.
+	// Use this for stores that document a limit on key length.
+	MaxKeyLen int
+}
+
+func (o *FuzzOptions) keyTooLong(key string) bool {
+	return o != nil && o.MaxKeyLen > 0 && len(key) > o.MaxKeyLen
+}
+
+// fuzzSeeds are adversarial keys used to seed the corpus for Fuzz.
+var fuzzSeeds = []string{
+	"", "a", "\x00", "\x00\x00", "a\x00b", "/", "a/b", "../x", "./", "-",
+	"\xff\xfe", "\xc3\x28", "é", "CON", "a\\b", " ", strings.Repeat("k", 100),
+}
+
+// Fuzz runs a fuzz test on f that stores and retrieves arbitrary keys and
+// values in keyspaces of s, and checks that each value is read back intact,
+// that no other key observes it, and that it does not leak into a keyspace
+// whose name shares a prefix. The keyspaces used are left empty.
+//
+// Stores may reject an empty key, as permitted by [blob.KV]; otherwise any
+// error from the store is a failure. A fuzz target for a store typically
+// looks like:
+//
+//	func FuzzStore(f *testing.F) { storetest.Fuzz(f, newStore(), nil) }
+func Fuzz(f *testing.F, s blob.Store, opts *FuzzOptions) {
+	ctx := context.Background()
+	k1, err := s.KV(ctx, "fuzz")
+	if err != nil {
+		f.Fatalf("Create keyspace: %v", err)
+	}
+	k2, err := s.KV(ctx, "fuzzy")
+	if err != nil {
+		f.Fatalf("Create keyspace: %v", err)
+	}
+
+	for _, key := range fuzzSeeds {
+		f.Add(key, []byte(nil))
+		f.Add(key, []byte(key))
+	}
+	f.Add("big", bytes.Repeat([]byte("0123456789abcdef"), 1<<12))
+
+	f.Fuzz(func(t *testing.T, key string, value []byte) {
+		if opts.keyTooLong(key) {
+			t.Skipf("Key length %d exceeds limit", len(key))
+		}
+		err := k1.Put(ctx, blob.PutOptions{Key: key, Data: value, Replace: true})
+		if err != nil {
+			if key == "" {
+				t.Skipf("Put empty key: %v", err)
+			}
+			t.Fatalf("Put %q: unexpected error: %v", key, err)
+		}
+		defer func() {
+			if err := k1.Delete(ctx, key); err != nil {
+				t.Errorf("Delete %q: unexpected error: %v", key, err)
+			}
+			if _, err := k1.Get(ctx, key); !errors.Is(err, blob.ErrKeyNotFound) {
+				t.Errorf("Get %q after delete: got %v, want %v", key, err, blob.ErrKeyNotFound)
+			}
+		}()
+
+		if got, err := k1.Get(ctx, key); err != nil {
+			t.Fatalf("Get %q: unexpected error: %v", key, err)
+		} else if !bytes.Equal(got, value) {
+			t.Errorf("Get %q: got %d bytes, want %d (values differ)", key, len(got), len(value))
+		}
+
+		// The key is the only one in its keyspace, and is listed intact.
+		if n, err := k1.Len(ctx); err != nil || n != 1 {
+			t.Errorf("Len: got (%d, %v), want (1, nil)", n, err)
+		}
+		for got, err := range k1.List(ctx, "") {
+			if err != nil {
+				t.Fatalf("List: unexpected error: %v", err)
+			} else if got != key {
+				t.Errorf("List: got key %q, want %q", got, key)
+			}
+		}
+
+		// Neighbouring keys do not collide with the key.
+		nbrs := []string{key + "\x00", key + "0", "\x00" + key}
+		if n := len(key); n > 0 {
+			nbrs = append(nbrs, key[:n-1], key[:n-1]+string(key[n-1]^1))
+		}
+		for _, nbr := range nbrs {
+			if nbr == key || opts.keyTooLong(nbr) {
+				continue
+			}
+			if _, err := k1.Get(ctx, nbr); !errors.Is(err, blob.ErrKeyNotFound) {
+				t.Errorf("Get neighbour %q of %q: got %v, want %v", nbr, key, err, blob.ErrKeyNotFound)
+			}
+		}
+
+		// Another keyspace does not see the key.
+		if n, err := k2.Len(ctx); err != nil || n != 0 {
+			t.Errorf("Other keyspace Len: got (%d, %v), want (0, nil)", n, err)
+		}
+		if _, err := k2.Get(ctx, key); !errors.Is(err, blob.ErrKeyNotFound) {
+			t.Errorf("Other keyspace Get %q: got %v, want %v", key, err, blob.ErrKeyNotFound)
+		}
+	})
+}
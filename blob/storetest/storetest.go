@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -250,6 +251,10 @@ func Run(t *testing.T, s blob.StoreCloser) {
 		t.Run("Basic", runCheck(k1, k2))
 		t.Run("Cleanup", cleanup(k1))
 		t.Run("CAS", casTest(s))
+		t.Run("Order", orderCheck(ctx, k1))
+		t.Run("Range", rangeCheck(ctx, k1))
+		t.Run("Coherent", coherenceCheck(ctx, s))
+		t.Run("EmptyKey", emptyKeyCheck(ctx, k1))
 	})
 
 	t.Run("Sub", func(t *testing.T) {
@@ -264,6 +269,7 @@ func Run(t *testing.T, s blob.StoreCloser) {
 		t.Run("Basic", runCheck(k3, k1))
 		t.Run("Cleanup", cleanup(k3))
 		t.Run("CAS", casTest(s))
+		t.Run("Coherent", coherenceCheck(ctx, sub))
 	})
 
 	// Exercise concurrency.
@@ -344,6 +350,203 @@ func Run(t *testing.T, s blob.StoreCloser) {
 	}
 }
 
+// orderKeys are keys whose byte-wise order differs from a naive ordering of
+// their characters, including keys that are not valid UTF-8.
+var orderKeys = []string{
+	"\x00", "\x00\x00", "\x01", "A", "Z", "a", "a\x00", "a\x00b", "ab", "z",
+	"\x7f", "\x80", "\xc3\xa9", "\xc3\xa9t\xc3\xa9", "\xe2\x82\xac", "\xff", "\xff\xff",
+}
+
+// rangeCheck returns a test that, if kv implements [blob.RangeGetter],
+// verifies that its GetRange method agrees with [blob.SliceRange].
+// The keyspace is left empty.
+func rangeCheck(ctx context.Context, kv blob.KV) func(t *testing.T) {
+	return func(t *testing.T) {
+		rg, ok := kv.(blob.RangeGetter)
+		if !ok {
+			t.Skip("Keyspace does not implement blob.RangeGetter")
+		}
+		const key, value = "range", "0123456789"
+		if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte(value)}); err != nil {
+			t.Fatalf("Put %q: unexpected error: %v", key, err)
+		}
+		defer kv.Delete(ctx, key)
+
+		for _, r := range [][2]int64{{0, 0}, {0, 10}, {0, 100}, {3, 4}, {9, 1}, {9, 5}, {10, 1}, {20, 5}} {
+			got, err := rg.GetRange(ctx, key, r[0], r[1])
+			if err != nil {
+				t.Errorf("GetRange(%d, %d): unexpected error: %v", r[0], r[1], err)
+			} else if want := blob.SliceRange([]byte(value), r[0], r[1]); string(got) != string(want) {
+				t.Errorf("GetRange(%d, %d): got %q, want %q", r[0], r[1], got, want)
+			}
+		}
+		if _, err := rg.GetRange(ctx, "nonesuch", 0, 1); !blob.IsKeyNotFound(err) {
+			t.Errorf("GetRange(nonesuch): got %v, want %v", err, blob.ErrKeyNotFound)
+		}
+	}
+}
+
+// coherenceCheck verifies that the KV and CAS views of the same keyspace of s
+// observe each other's writes immediately: A value written through either
+// view can be read back, is reported by Has, and is replaced or deleted,
+// through the other view and through a view obtained later.
+// The keyspace is left empty.
+func coherenceCheck(ctx context.Context, s blob.Store) func(t *testing.T) {
+	return func(t *testing.T) {
+		const name = "coherent"
+		kv, err := s.KV(ctx, name)
+		if err != nil {
+			t.Fatalf("Create keyspace: %v", err)
+		}
+		cas, err := s.CAS(ctx, name)
+		if err != nil {
+			t.Fatalf("Create CAS: %v", err)
+		}
+		views := func() []blob.KVCore {
+			later, err := s.KV(ctx, name)
+			if err != nil {
+				t.Fatalf("Create keyspace again: %v", err)
+			}
+			return []blob.KVCore{kv, cas, later}
+		}
+		check := func(key, want string) {
+			t.Helper()
+			for i, v := range views() {
+				got, err := v.Get(ctx, key)
+				if want == "" {
+					if !blob.IsKeyNotFound(err) {
+						t.Errorf("View %d: Get %q: got (%q, %v), want %v", i, key, got, err, blob.ErrKeyNotFound)
+					}
+				} else if err != nil || string(got) != want {
+					t.Errorf("View %d: Get %q: got (%q, %v), want %q", i, key, got, err, want)
+				}
+				wantHas := mapset.New[string]()
+				if want != "" {
+					wantHas.Add(key)
+				}
+				if has, err := v.Has(ctx, key, "nonesuch"); err != nil {
+					t.Errorf("View %d: Has %q: unexpected error: %v", i, key, err)
+				} else if !has.Equals(wantHas) {
+					t.Errorf("View %d: Has %q: got %v, want %v", i, key, has, wantHas)
+				}
+			}
+		}
+
+		// A write through the CAS is visible to the KV views.
+		ckey, err := cas.CASPut(ctx, []byte("content"))
+		if err != nil {
+			t.Fatalf("CASPut: unexpected error: %v", err)
+		}
+		check(ckey, "content")
+		if err := kv.Delete(ctx, ckey); err != nil {
+			t.Errorf("Delete %q: unexpected error: %v", ckey, err)
+		}
+		check(ckey, "")
+
+		// Writes through the KV are visible to the CAS.
+		const key = "key"
+		if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte("v1")}); err != nil {
+			t.Fatalf("Put %q: unexpected error: %v", key, err)
+		}
+		check(key, "v1")
+		if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte("v2"), Replace: true}); err != nil {
+			t.Fatalf("Put %q: unexpected error: %v", key, err)
+		}
+		check(key, "v2")
+		if err := cas.Delete(ctx, key); err != nil {
+			t.Errorf("Delete %q: unexpected error: %v", key, err)
+		}
+		check(key, "")
+		if n, err := kv.Len(ctx); err != nil || n != 0 {
+			t.Errorf("Len: got (%d, %v), want (0, nil)", n, err)
+		}
+	}
+}
+
+// orderCheck verifies that k lists keys in the order defined by
+// blob.CompareKeys, and that the start key of a List is inclusive.
+// Precondition: k is initially empty.
+func orderCheck(ctx context.Context, k blob.KV) func(t *testing.T) {
+	return func(t *testing.T) {
+		want := slices.Clone(orderKeys)
+		slices.SortFunc(want, blob.CompareKeys)
+
+		// Write the keys out of order, to avoid flattering an implementation
+		// that preserves insertion order.
+		for i := len(orderKeys) - 1; i >= 0; i-- {
+			key := orderKeys[i]
+			if err := k.Put(ctx, blob.PutOptions{Key: key, Data: []byte("x")}); err != nil {
+				t.Fatalf("Put %q: unexpected error: %v", key, err)
+			}
+		}
+		defer func() {
+			for _, key := range orderKeys {
+				if err := k.Delete(ctx, key); err != nil {
+					t.Errorf("Delete %q: unexpected error: %v", key, err)
+				}
+			}
+		}()
+
+		opList("", want...)(ctx, t, k)
+		for i, key := range want {
+			opList(key, want[i:]...)(ctx, t, k) // start is inclusive
+
+			// The successor of key is the least string greater than key.
+			succ := key + "\x00"
+			var rest []string
+			for _, w := range want {
+				if blob.CompareKeys(w, succ) >= 0 {
+					rest = append(rest, w)
+				}
+			}
+			opList(succ, rest...)(ctx, t, k)
+		}
+
+		r := blob.KeyRange{Start: "a", End: "\x80"}
+		var got []string
+		for key, err := range r.List(ctx, k) {
+			if err != nil {
+				t.Fatalf("List %+v: unexpected error: %v", r, err)
+			}
+			got = append(got, key)
+		}
+		if diff := gocmp.Diff(got, []string{"a", "a\x00", "a\x00b", "ab", "z", "\x7f"}); diff != "" {
+			t.Errorf("List %+v: wrong keys (-got, +want):\n%s", r, diff)
+		}
+	}
+}
+
+// emptyKeyCheck verifies that k handles the empty key consistently: Either
+// it stores the empty key like any other, or it rejects the write and reports
+// the key as not found by all other operations.
+func emptyKeyCheck(ctx context.Context, k blob.KV) func(t *testing.T) {
+	return func(t *testing.T) {
+		const value = "nothing"
+		perr := k.Put(ctx, blob.PutOptions{Key: "", Data: []byte(value)})
+		if perr == nil {
+			// The store supports empty keys, so it must behave like any other.
+			opGet("", value, nil)(ctx, t, k)
+			opList("", "")(ctx, t, k)
+			opDelete("", nil)(ctx, t, k)
+			opLen(0)(ctx, t, k)
+			return
+		}
+		t.Logf("Put empty key: %v", perr)
+		if errors.Is(perr, blob.ErrInvalidKey) && !blob.IsKeyNotFound(perr) {
+			t.Errorf("Put empty key: got %v, want it to wrap %v", perr, blob.ErrKeyNotFound)
+		}
+		opGet("", "", blob.ErrKeyNotFound)(ctx, t, k)
+		opDelete("", blob.ErrKeyNotFound)(ctx, t, k)
+		if got, err := k.Has(ctx, ""); err != nil {
+			t.Errorf("Has empty key: unexpected error: %v", err)
+		} else if got.Has("") {
+			t.Error("Has empty key: reported present after a failed Put")
+		}
+		opList("")(ctx, t, k)
+		opLen(0)(ctx, t, k)
+	}
+}
+
 type nopStoreCloser struct {
 	blob.Store
 }
@@ -0,0 +1,147 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blob
+
+import (
+	"context"
+	"errors"
+	"math/bits"
+	"sync"
+)
+
+// A SizeHistogram records the number and total size of blobs, grouped into
+// buckets by size. The zero value is ready for use.
+//
+// Bucket 0 holds blobs of size 0, and for i > 0, bucket i holds blobs whose
+// size is at least 2^(i-1) and less than 2^i. Buckets are added as needed to
+// hold the largest size recorded, so the histogram may include empty buckets
+// but none beyond the last nonempty one.
+type SizeHistogram struct {
+	Count   int64        // the total number of blobs recorded
+	Bytes   int64        // the total size in bytes of blobs recorded
+	Buckets []SizeBucket // in increasing order of Limit
+}
+
+// A SizeBucket is a single bucket of a [SizeHistogram].
+type SizeBucket struct {
+	Limit int64 // each blob in this bucket is smaller than Limit
+	Count int64 // the number of blobs in this bucket
+	Bytes int64 // the total size in bytes of blobs in this bucket
+}
+
+// Add records a blob of the given size in h. It will panic if size < 0.
+func (h *SizeHistogram) Add(size int64) {
+	if size < 0 {
+		panic("negative blob size")
+	}
+	i := bits.Len64(uint64(size))
+	for len(h.Buckets) <= i {
+		h.Buckets = append(h.Buckets, SizeBucket{Limit: 1 << len(h.Buckets)})
+	}
+	b := &h.Buckets[i]
+	b.Count++
+	b.Bytes += size
+	h.Count++
+	h.Bytes += size
+}
+
+// ScanSizesOptions are optional settings for [ScanSizes]. A nil
+// *ScanSizesOptions is ready for use and provides default values as described.
+type ScanSizesOptions struct {
+	// The maximum number of concurrent fetches. A value ≤ 0 defaults to 16.
+	Concurrency int
+
+	// If non-nil, Progress is called with the histogram after each blob is
+	// recorded. The histogram must not be retained or modified.
+	Progress func(*SizeHistogram)
+}
+
+func (o *ScanSizesOptions) concurrency() int {
+	if o == nil || o.Concurrency <= 0 {
+		return 16
+	}
+	return o.Concurrency
+}
+
+func (o *ScanSizesOptions) progress() func(*SizeHistogram) {
+	if o == nil || o.Progress == nil {
+		return func(*SizeHistogram) {}
+	}
+	return o.Progress
+}
+
+// ScanSizes lists the keys of ks and reports a histogram of the sizes of the
+// blobs they name. Keys deleted while the scan is in progress are skipped.
+//
+// Because a keyspace does not report the sizes of its blobs, ScanSizes must
+// fetch each blob, with up to opts.Concurrency fetches in progress at once,
+// so it may be slow and costly for a large or remote keyspace. A caller that
+// already knows the sizes, for example from a manifest, can instead construct
+// a histogram directly using [SizeHistogram.Add].
+//
+// If listing or fetching fails, or ctx ends, ScanSizes stops and reports the
+// histogram of the blobs recorded so far along with the first error.
+func ScanSizes(ctx context.Context, ks KVCore, opts *ScanSizesOptions) (*SizeHistogram, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var μ sync.Mutex
+	var ferr error
+	h := new(SizeHistogram)
+	report := opts.progress()
+	fail := func(err error) {
+		μ.Lock()
+		defer μ.Unlock()
+		if ferr == nil {
+			ferr = err
+			cancel()
+		}
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, opts.concurrency())
+loop:
+	for key, err := range ks.List(ctx, "") {
+		if err != nil {
+			fail(err)
+			break
+		}
+		select {
+		case <-ctx.Done():
+			break loop
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			data, err := ks.Get(ctx, key)
+			if errors.Is(err, ErrKeyNotFound) {
+				return
+			} else if err != nil {
+				fail(err)
+				return
+			}
+			μ.Lock()
+			defer μ.Unlock()
+			h.Add(int64(len(data)))
+			report(h)
+		}()
+	}
+	wg.Wait()
+	if ferr != nil {
+		return h, ferr
+	}
+	return h, ctx.Err()
+}
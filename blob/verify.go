@@ -0,0 +1,44 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blob
+
+import "context"
+
+// GetVerified fetches the content of key from cas, and verifies that its
+// content address is key. If not, it reports an error satisfying
+// errors.Is(err, ErrContentMismatch), whose concrete type is *KeyError.
+//
+// Use GetVerified for reads where undetected corruption in the underlying
+// storage would be costly, such as the root and node blobs of a file tree.
+func GetVerified(ctx context.Context, cas CAS, key string) ([]byte, error) {
+	data, err := cas.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	} else if cas.CASKey(ctx, data) != key {
+		return nil, ContentMismatch(key)
+	}
+	return data, nil
+}
+
+// VerifyCAS returns a [CAS] that delegates to cas, but whose Get method
+// verifies the content it fetches as [GetVerified] does.
+func VerifyCAS(cas CAS) CAS { return verifyCAS{cas} }
+
+type verifyCAS struct{ CAS }
+
+// Get implements part of the [CAS] interface.
+func (v verifyCAS) Get(ctx context.Context, key string) ([]byte, error) {
+	return GetVerified(ctx, v.CAS, key)
+}
@@ -19,6 +19,7 @@ import (
 	"testing"
 
 	"github.com/creachadair/ffs/block"
+	"github.com/creachadair/ffs/block/hashtest"
 )
 
 func BenchmarkSplitter_Next(b *testing.B) {
@@ -42,3 +43,5 @@ func BenchmarkHasher_Update(b *testing.B) {
 		}
 	})
 }
+
+func BenchmarkDefaultHasher(b *testing.B) { hashtest.Benchmark(b, block.DefaultHasher) }
@@ -15,9 +15,12 @@
 package block_test
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/creachadair/ffs/block"
+	"github.com/creachadair/ffs/block/hashtest"
 )
 
 func max(a, b int) int {
@@ -103,6 +106,24 @@ func TestModHash(t *testing.T) {
 	}
 }
 
+func TestHasherProperties(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		hashtest.Run(t, block.DefaultHasher, 48)
+		hashtest.CheckVectors(t, block.DefaultHasher, []hashtest.Vector{
+			{Input: "", Want: 0x0},
+			{Input: "a", Want: 0x61},
+			{Input: "hello, world", Want: 0x59f7b3bb},
+			{Input: "The quick brown fox jumps over the lazy dog", Want: 0x67feb31b},
+			{Input: strings.Repeat("0123456789", 10), Want: 0x1d4b9216},
+		})
+	})
+	for _, size := range []int{1, 5, 64} {
+		t.Run(fmt.Sprintf("RabinKarp/%d", size), func(t *testing.T) {
+			hashtest.Run(t, block.RabinKarpHasher(1031, 2147483659, size), size)
+		})
+	}
+}
+
 func windowTest(t *testing.T, h block.Hasher, size int) {
 	// Make sure that we get the same hash value when the window has the same
 	// contents.
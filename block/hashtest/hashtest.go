@@ -0,0 +1,182 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package hashtest provides correctness tests and benchmarks for
+// implementations of the [block.Hasher] interface.
+//
+// A hasher for a [block.Splitter] must be a rolling hash: Once it has been
+// given at least a window's worth of input, the value it reports must depend
+// only on the most recent window of bytes. [Run] checks this property, along
+// with determinism and the independence of hash instances. [CheckVectors]
+// checks the values reported by a hasher against known answers, and
+// [Benchmark] measures the throughput of a hasher alone and in a splitter.
+package hashtest
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/creachadair/ffs/block"
+)
+
+// Run runs correctness tests on h, whose window size is window bytes.
+func Run(t *testing.T, h block.Hasher, window int) {
+	t.Helper()
+	if window <= 0 {
+		t.Fatalf("Invalid window size %d", window)
+	}
+	t.Run("NotNil", func(t *testing.T) {
+		if h.Hash() == nil {
+			t.Fatal("Hash returned nil")
+		}
+	})
+	t.Run("Deterministic", func(t *testing.T) { checkDeterministic(t, h, window) })
+	t.Run("Independent", func(t *testing.T) { checkIndependent(t, h, window) })
+	t.Run("Rolling", func(t *testing.T) { checkRolling(t, h, window) })
+	t.Run("Sensitive", func(t *testing.T) { checkSensitive(t, h, window) })
+}
+
+// update feeds data to a hash and returns the last value it reports.
+func update(h block.Hash, data []byte) (v uint64) {
+	for _, b := range data {
+		v = h.Update(b)
+	}
+	return v
+}
+
+// randBytes returns n pseudo-random bytes from rng.
+func randBytes(rng *rand.Rand, n int) []byte {
+	buf := make([]byte, n)
+	rng.Read(buf)
+	return buf
+}
+
+// checkDeterministic checks that two fresh hashes given the same input report
+// the same sequence of values.
+func checkDeterministic(t *testing.T, h block.Hasher, window int) {
+	rng := rand.New(rand.NewSource(1))
+	input := randBytes(rng, 8*window)
+	h1, h2 := h.Hash(), h.Hash()
+	for i, b := range input {
+		if v1, v2 := h1.Update(b), h2.Update(b); v1 != v2 {
+			t.Fatalf("At offset %d: got %x and %x for the same input", i, v1, v2)
+		}
+	}
+}
+
+// checkIndependent checks that updating one hash does not affect another
+// constructed from the same hasher.
+func checkIndependent(t *testing.T, h block.Hasher, window int) {
+	rng := rand.New(rand.NewSource(2))
+	input := randBytes(rng, 4*window)
+	want := update(h.Hash(), input)
+
+	// Interleave updates to a second hash with other input.
+	h1, h2 := h.Hash(), h.Hash()
+	noise := randBytes(rng, len(input))
+	var got uint64
+	for i, b := range input {
+		got = h1.Update(b)
+		h2.Update(noise[i])
+	}
+	if got != want {
+		t.Errorf("Hash with interleaved updates: got %x, want %x", got, want)
+	}
+}
+
+// checkRolling checks that once a hash has seen at least a window of input,
+// its value depends only on the last window bytes.
+func checkRolling(t *testing.T, h block.Hasher, window int) {
+	rng := rand.New(rand.NewSource(3))
+	for i := range 50 {
+		suffix := randBytes(rng, window)
+		want := update(h.Hash(), suffix)
+
+		for _, n := range []int{1, window - 1, window, window + 1, 3*window + rng.Intn(window)} {
+			prefix := randBytes(rng, n)
+			hp := h.Hash()
+			update(hp, prefix)
+			if got := update(hp, suffix); got != want {
+				t.Errorf("Trial %d: after %d-byte prefix: got %x, want %x", i, n, got, want)
+			}
+		}
+	}
+}
+
+// checkSensitive checks that changing a byte in the window usually changes
+// the hash value. A good rolling hash rarely collides, so a small fraction of
+// collisions is tolerated, in case the hash has a small range.
+func checkSensitive(t *testing.T, h block.Hasher, window int) {
+	const trials = 1000
+	rng := rand.New(rand.NewSource(4))
+
+	var same int
+	for range trials {
+		input := randBytes(rng, 2*window)
+		want := update(h.Hash(), input)
+
+		pos := len(input) - 1 - rng.Intn(window)
+		input[pos] ^= byte(1 + rng.Intn(255))
+		if update(h.Hash(), input) == want {
+			same++
+		}
+	}
+	if same > trials/20 {
+		t.Errorf("Changing a byte in the window did not change the hash in %d of %d trials", same, trials)
+	}
+}
+
+// A Vector is a known answer for a hasher: The last value reported by a fresh
+// hash after it is given Input.
+type Vector struct {
+	Input string
+	Want  uint64
+}
+
+// CheckVectors checks that the value reported by a fresh hash from h for the
+// input of each vector matches its expected value.
+func CheckVectors(t *testing.T, h block.Hasher, vecs []Vector) {
+	t.Helper()
+	for _, v := range vecs {
+		if got := update(h.Hash(), []byte(v.Input)); got != v.Want {
+			t.Errorf("Hash %q: got %#x, want %#x", v.Input, got, v.Want)
+		}
+	}
+}
+
+// Benchmark runs benchmarks for h. The "Update" benchmark measures the cost
+// of updating a hash with one byte, and the "Split" benchmark measures the
+// throughput of a [block.Splitter] using h with its default block sizes.
+func Benchmark(b *testing.B, h block.Hasher) {
+	b.Run("Update", func(b *testing.B) {
+		hash := h.Hash()
+		b.SetBytes(1)
+		for i := 0; i < b.N; i++ {
+			hash.Update(byte(i))
+		}
+	})
+
+	const inputSize = 1 << 20
+	input := randBytes(rand.New(rand.NewSource(5)), inputSize)
+	b.Run("Split", func(b *testing.B) {
+		b.SetBytes(inputSize)
+		for i := 0; i < b.N; i++ {
+			s := block.NewSplitter(bytes.NewReader(input), &block.SplitConfig{Hasher: h})
+			if err := s.Split(func([]byte) error { return nil }); err != nil {
+				b.Fatalf("Split: %v", err)
+			}
+		}
+	})
+}
@@ -25,7 +25,8 @@
 //
 // This package provides an implementation of the Rabin-Karp modular rolling
 // hash algorithm; other algorithms can be plugged in by implementing the
-// Hasher and Hash interfaces.
+// Hasher and Hash interfaces. Package hashtest provides correctness tests and
+// benchmarks for implementations.
 package block
 
 // TODO(Sep 2021): The LBFS paper seems to be inaccessible from MIT.
@@ -33,6 +34,11 @@ package block
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
 )
 
@@ -77,6 +83,112 @@ func (c *SplitConfig) Hash() Hash {
 	return c.Hasher.Hash()
 }
 
+// Validate reports whether c describes a usable splitter configuration.  It
+// reports an error if any of the sizes is negative, if the sizes that are set
+// are not ordered Min ≤ Size ≤ Max, if Size or Max is 1 (the hash never cuts
+// modulo 1), or if the Hasher produces a nil Hash.
+//
+// Fields left zero select their defaults, and are not checked against the
+// values set explicitly. A nil *SplitConfig is valid.
+func (c *SplitConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.Min < 0 || c.Size < 0 || c.Max < 0 {
+		return fmt.Errorf("invalid split sizes: min=%d, size=%d, max=%d", c.Min, c.Size, c.Max)
+	}
+	if c.Size == 1 || c.Max == 1 {
+		return errors.New("invalid split sizes: size and max must be at least 2")
+	}
+	if c.Min > 0 && c.Size > 0 && c.Min > c.Size {
+		return fmt.Errorf("invalid split sizes: min %d > size %d", c.Min, c.Size)
+	}
+	if c.Size > 0 && c.Max > 0 && c.Size > c.Max {
+		return fmt.Errorf("invalid split sizes: size %d > max %d", c.Size, c.Max)
+	}
+	if c.Min > 0 && c.Max > 0 && c.Min > c.Max {
+		return fmt.Errorf("invalid split sizes: min %d > max %d", c.Min, c.Max)
+	}
+	if c.Hasher != nil && c.Hasher.Hash() == nil {
+		return errors.New("invalid split hasher: Hash returned nil")
+	}
+	return nil
+}
+
+// Normalize fills in any zero fields of c with explicit values, so that the
+// result satisfies Min ≤ Size ≤ Max. Each zero field is set to its default,
+// clamped to the range allowed by the fields that are set. If c is valid, so
+// is the result.
+//
+// A splitter does not clamp defaults in this way, so normalizing a config
+// whose defaults conflict with its explicit settings (for example, a Max
+// smaller than DefaultSize) changes how its input is partitioned.
+func (c *SplitConfig) Normalize() {
+	if c.Hasher == nil {
+		c.Hasher = DefaultHasher
+	}
+	if c.Size == 0 {
+		c.Size = max(DefaultSize, c.Min, 2)
+		if c.Max > 0 {
+			c.Size = min(c.Size, c.Max)
+		}
+	}
+	if c.Min == 0 {
+		c.Min = min(DefaultMin, c.Size)
+	}
+	if c.Max == 0 {
+		c.Max = max(DefaultMax, c.Size)
+	}
+}
+
+// Fingerprint returns a string that identifies how c partitions its input, so
+// that a store can record the settings used to split its data and detect an
+// incompatible configuration later.
+//
+// The fingerprint is computed from the blocks c makes of a fixed
+// pseudo-random sample, so it reflects the behavior of the Hasher rather than
+// its identity. Configurations that split data differently are very likely
+// to have different fingerprints. If c is not valid, Fingerprint returns "".
+func (c *SplitConfig) Fingerprint() string {
+	if c.Validate() != nil {
+		return ""
+	}
+
+	// The sample is long enough to yield about 256 blocks, so that a change
+	// in any of the sizes is likely to affect at least one of them.
+	r := &sampleReader{v: 0x9e3779b97f4a7c15, n: 256 * int64(c.size())}
+	h := sha256.New()
+	var buf [8]byte
+	NewSplitter(r, c).Split(func(blk []byte) error {
+		binary.BigEndian.PutUint64(buf[:], uint64(len(blk)))
+		h.Write(buf[:])
+		return nil
+	})
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// sampleReader generates n bytes of a fixed xorshift sequence, so that the
+// sample does not depend on the behavior of a library generator.
+type sampleReader struct {
+	v uint64
+	n int64
+}
+
+func (r *sampleReader) Read(buf []byte) (int, error) {
+	if r.n <= 0 {
+		return 0, io.EOF
+	}
+	buf = buf[:min(int64(len(buf)), r.n)]
+	for i := range buf {
+		r.v ^= r.v << 13
+		r.v ^= r.v >> 7
+		r.v ^= r.v << 17
+		buf[i] = byte(r.v)
+	}
+	r.n -= int64(len(buf))
+	return len(buf), nil
+}
+
 func (c *SplitConfig) min() int {
 	if c == nil || c.Min <= 0 {
 		return DefaultMin
@@ -101,7 +213,13 @@ func (c *SplitConfig) max() int {
 // NewSplitter constructs a Splitter that reads its data from r and partitions
 // it into blocks using the rolling hash from c. A nil *SplitConfig is ready
 // for use with default sizes and hash settings.
+//
+// If c is not valid (see [SplitConfig.Validate]), the resulting splitter
+// reports the validation error from its first call to Next or Split.
 func NewSplitter(r io.Reader, c *SplitConfig) *Splitter {
+	if err := c.Validate(); err != nil {
+		return &Splitter{config: c, err: err}
+	}
 	var buf *bufio.Reader
 	if v, ok := r.(*bufio.Reader); ok {
 		buf = v
@@ -131,15 +249,35 @@ type Splitter struct {
 	next int    // Next unused offset in buf.
 	end  int    // End of previous block.
 	buf  []byte // Incoming data buffer.
+	err  error  // If non-nil, the config was invalid.
+	pos  int64  // Total bytes in blocks returned so far.
+
+	onBlock func(start, end int64) // If non-nil, called for each block.
 }
 
 // Config returns the SplitConfig used to construct s, which may be nil.
 func (s *Splitter) Config() *SplitConfig { return s.config }
 
+// BytesRead reports the number of bytes of input consumed by the blocks s has
+// returned so far, which is the offset in the input of the next block. Since
+// s buffers its input, this may be less than the number of bytes it has read
+// from the underlying reader.
+func (s *Splitter) BytesRead() int64 { return s.pos }
+
+// OnBlock sets f to be called for each block returned by s, with the offsets
+// in the input of the start (inclusive) and end (exclusive) of the block. The
+// callback is invoked before the block is returned to the caller of Next or
+// passed to the function of Split. If f == nil, any callback is removed.
+func (s *Splitter) OnBlock(f func(start, end int64)) { s.onBlock = f }
+
 // Next returns the next available block, or an error.  The slice returned is
 // only valid until a subsequent call of Next.  Returns nil, io.EOF when no
 // further blocks are available.
 func (s *Splitter) Next() ([]byte, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
 	// Shift out the previous block, if any.  This invalidates any previous
 	// slice returned by this method, as the data have moved.
 	if s.end > 0 {
@@ -174,6 +312,11 @@ func (s *Splitter) Next() ([]byte, error) {
 		if isCut || i >= len(s.buf) || (i > s.end && err == io.EOF) {
 			block := s.buf[s.end:i]
 			s.end = i
+			start := s.pos
+			s.pos += int64(len(block))
+			if s.onBlock != nil {
+				s.onBlock(start, s.pos)
+			}
 			return block, nil
 		}
 
@@ -153,6 +153,38 @@ func TestSplitterBlocks(t *testing.T) {
 	}
 }
 
+func TestSplitterProgress(t *testing.T) {
+	const input = "a|bc|defg|hijklmno|pqrst"
+	s := block.NewSplitter(strings.NewReader(input), &block.SplitConfig{
+		Hasher: dummyHash{magic: '|', hash: 12345, size: 5},
+		Min:    2,
+		Max:    8,
+	})
+
+	var spans [][2]int64
+	s.OnBlock(func(start, end int64) { spans = append(spans, [2]int64{start, end}) })
+	if err := s.Split(func(b []byte) error {
+		// The callback has already run for this block.
+		last := spans[len(spans)-1]
+		if got := input[last[0]:last[1]]; got != string(b) {
+			t.Errorf("Block at %v: got %q, want %q", last, got, b)
+		}
+		if got := s.BytesRead(); got != last[1] {
+			t.Errorf("BytesRead: got %d, want %d", got, last[1])
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]int64{{0, 4}, {4, 9}, {9, 17}, {17, 24}}
+	if !reflect.DeepEqual(spans, want) {
+		t.Errorf("Spans: got %v, want %v", spans, want)
+	}
+	if got := s.BytesRead(); got != int64(len(input)) {
+		t.Errorf("BytesRead: got %d, want %d", got, len(input))
+	}
+}
+
 func TestLongValue(t *testing.T) {
 	rng := rand.New(rand.NewSource(1)) // change to update test data
 
@@ -189,3 +221,103 @@ func TestLongValue(t *testing.T) {
 		t.Errorf("Total size of blocks: got %d, want %d", total, inputLen)
 	}
 }
+
+func TestSplitConfigValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *block.SplitConfig
+		ok   bool
+	}{
+		{"Nil", nil, true},
+		{"Zero", &block.SplitConfig{}, true},
+		{"MaxOnly", &block.SplitConfig{Max: 10}, true},
+		{"Ordered", &block.SplitConfig{Min: 5, Size: 10, Max: 20}, true},
+		{"Equal", &block.SplitConfig{Min: 4, Size: 4, Max: 4}, true},
+		{"NegMin", &block.SplitConfig{Min: -1}, false},
+		{"NegMax", &block.SplitConfig{Max: -5}, false},
+		{"SizeOne", &block.SplitConfig{Size: 1}, false},
+		{"MinSize", &block.SplitConfig{Min: 20, Size: 10}, false},
+		{"SizeMax", &block.SplitConfig{Size: 30, Max: 20}, false},
+		{"MinMax", &block.SplitConfig{Min: 30, Max: 20}, false},
+		{"NilHash", &block.SplitConfig{Hasher: nilHasher{}}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.Validate()
+			if ok := err == nil; ok != tc.ok {
+				t.Errorf("Validate: got %v, want ok=%v", err, tc.ok)
+			}
+			if tc.ok {
+				return
+			}
+			// An invalid config should be reported by the splitter.
+			s := block.NewSplitter(strings.NewReader("hello"), tc.cfg)
+			if _, err := s.Next(); err == nil || err == io.EOF {
+				t.Errorf("Next: got %v, want validation error", err)
+			}
+		})
+	}
+}
+
+func TestSplitConfigNormalize(t *testing.T) {
+	tests := []struct {
+		input, want block.SplitConfig
+	}{
+		{block.SplitConfig{},
+			block.SplitConfig{Min: block.DefaultMin, Size: block.DefaultSize, Max: block.DefaultMax}},
+		{block.SplitConfig{Size: 1000},
+			block.SplitConfig{Min: 1000, Size: 1000, Max: block.DefaultMax}},
+		{block.SplitConfig{Max: 10},
+			block.SplitConfig{Min: 10, Size: 10, Max: 10}},
+		{block.SplitConfig{Min: 100000},
+			block.SplitConfig{Min: 100000, Size: 100000, Max: 100000}},
+		{block.SplitConfig{Min: 5, Max: 2000},
+			block.SplitConfig{Min: 5, Size: 2000, Max: 2000}},
+	}
+	for _, tc := range tests {
+		cfg := tc.input
+		cfg.Normalize()
+		if cfg.Hasher != block.DefaultHasher {
+			t.Errorf("Normalize %+v: Hasher is %v, want default", tc.input, cfg.Hasher)
+		}
+		cfg.Hasher = nil
+		if cfg != tc.want {
+			t.Errorf("Normalize %+v: got %+v, want %+v", tc.input, cfg, tc.want)
+		}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Validate %+v: unexpected error: %v", cfg, err)
+		}
+	}
+}
+
+type nilHasher struct{}
+
+func (nilHasher) Hash() block.Hash { return nil }
+
+func TestFingerprint(t *testing.T) {
+	base := (*block.SplitConfig)(nil).Fingerprint()
+	if base == "" {
+		t.Fatal("Fingerprint of nil config is empty")
+	}
+	t.Logf("Default fingerprint: %s", base)
+
+	// Explicit defaults behave the same as the zero config.
+	same := &block.SplitConfig{Min: block.DefaultMin, Size: block.DefaultSize, Max: block.DefaultMax}
+	if got := same.Fingerprint(); got != base {
+		t.Errorf("Fingerprint of explicit defaults: got %q, want %q", got, base)
+	}
+
+	for _, sc := range []*block.SplitConfig{
+		{Size: 4096},
+		{Min: 1024},
+		{Max: 32768},
+		{Hasher: block.RabinKarpHasher(1031, 2147483659, 32)},
+	} {
+		if got := sc.Fingerprint(); got == base {
+			t.Errorf("Fingerprint of %+v: got %q, same as default", sc, got)
+		}
+	}
+	if got := (&block.SplitConfig{Min: -1}).Fingerprint(); got != "" {
+		t.Errorf("Fingerprint of invalid config: got %q, want empty", got)
+	}
+}
@@ -15,9 +15,16 @@
 package file_test
 
 import (
+	"context"
 	"fmt"
+	"math/rand/v2"
 	"testing"
 	"unsafe"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/blob/memstore"
+	"github.com/creachadair/ffs/block"
+	"github.com/creachadair/ffs/file"
 )
 
 func isZeroUnsafe(data []byte) bool {
@@ -67,3 +74,88 @@ func BenchmarkZeroTest(b *testing.B) {
 		})
 	}
 }
+
+// BenchmarkFragmented measures small reads and writes on a file assembled
+// from many pieces copied end to end, as by a long editing session.
+func BenchmarkFragmented(b *testing.B) {
+	ctx := context.Background()
+	cas := blob.CASFromKV(memstore.NewKV())
+	sc := &block.SplitConfig{Min: 64, Size: 256, Max: 1024}
+
+	const pieceSize = 512
+	src := file.New(cas, &file.NewOptions{Split: sc})
+	piece := make([]byte, pieceSize)
+	for i := range piece {
+		piece[i] = byte(i%251) + 1
+	}
+	if _, err := src.WriteAt(ctx, piece, 0); err != nil {
+		b.Fatalf("WriteAt: %v", err)
+	}
+
+	for _, n := range []int{100, 1000, 5000} {
+		f := file.New(cas, &file.NewOptions{Split: sc})
+		for i := range n {
+			if _, err := file.CopyRange(ctx, f, int64(i*pieceSize), src, 0, pieceSize); err != nil {
+				b.Fatalf("CopyRange: %v", err)
+			}
+		}
+		size := f.Data().Size()
+		rng := rand.New(rand.NewPCG(1, 2))
+		buf := make([]byte, 64)
+
+		b.Run(fmt.Sprintf("Read-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := f.ReadAt(ctx, buf, rng.Int64N(size-int64(len(buf)))); err != nil {
+					b.Fatalf("ReadAt: %v", err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("Write-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := f.WriteAt(ctx, piece[:len(buf)], rng.Int64N(size-int64(len(buf)))); err != nil {
+					b.Fatalf("WriteAt: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkSparse measures small reads and writes on a file with many
+// extents separated by unstored gaps, as from a random-write workload.
+func BenchmarkSparse(b *testing.B) {
+	ctx := context.Background()
+	cas := blob.CASFromKV(memstore.NewKV())
+	sc := &block.SplitConfig{Min: 64, Size: 256, Max: 1024}
+	chunk := make([]byte, 64)
+	for i := range chunk {
+		chunk[i] = byte(i) + 1
+	}
+
+	for _, n := range []int{100, 1000, 20000} {
+		f := file.New(cas, &file.NewOptions{Split: sc})
+		for i := range n {
+			if _, err := f.WriteAt(ctx, chunk, int64(i)*4096); err != nil {
+				b.Fatalf("WriteAt: %v", err)
+			}
+		}
+		size := f.Data().Size()
+		rng := rand.New(rand.NewPCG(1, 2))
+		buf := make([]byte, 64)
+
+		b.Run(fmt.Sprintf("Read-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := f.ReadAt(ctx, buf, rng.Int64N(size-int64(len(buf)))); err != nil {
+					b.Fatalf("ReadAt: %v", err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("Write-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				off := rng.Int64N(int64(n)) * 4096
+				if _, err := f.WriteAt(ctx, chunk, off); err != nil {
+					b.Fatalf("WriteAt: %v", err)
+				}
+			}
+		})
+	}
+}
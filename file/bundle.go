@@ -0,0 +1,144 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/creachadair/ffs/blob"
+)
+
+// bundleMagic is the header of a bundle stream.
+const bundleMagic = "FFSBDL\x00\x01"
+
+// Limits on the lengths of keys and blobs in a bundle, to guard against
+// allocating huge buffers when reading a corrupted stream.
+const (
+	maxBundleKey  = 1 << 10
+	maxBundleBlob = 1 << 31
+)
+
+// ExportBundle writes a bundle containing root and everything reachable from
+// it to w: The nodes of the file and its descendants, their data blocks, and
+// extended attribute values stored apart from their nodes. Each blob is
+// written once, even if it is shared by several files. The resulting stream
+// is self-contained, and can be imported into another store by ImportBundle.
+//
+// ExportBundle flushes root before writing the bundle, to ensure that the
+// bundle reflects its current state.
+func ExportBundle(ctx context.Context, w io.Writer, root *File) error {
+	rootKey, err := root.Flush(ctx)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	bw.WriteString(bundleMagic)
+	writeBundleBlob(bw, []byte(rootKey))
+
+	for rk, err := range reachableKeys(ctx, root.s, rootKey) {
+		if err != nil {
+			return err
+		}
+		var data []byte
+		if rk.block {
+			data, err = root.blocks().Get(ctx, rk.key)
+		} else {
+			data, err = root.s.Get(ctx, rk.key)
+		}
+		if err != nil {
+			return fmt.Errorf("export %x: %w", rk.key, err)
+		}
+		writeBundleBlob(bw, []byte(rk.key))
+		if err := writeBundleBlob(bw, data); err != nil {
+			return err
+		}
+	}
+	writeBundleBlob(bw, nil) // end of blobs
+	return bw.Flush()
+}
+
+// ImportBundle reads a bundle written by ExportBundle from r, stores each of
+// the blobs it contains in s, and returns the root file of the bundle opened
+// from s. Blobs already present in s are not modified.
+//
+// The key of each blob stored in s must match its key in the bundle, which
+// requires that s use the same content addressing as the store from which
+// the bundle was exported. If a key does not match, ImportBundle reports an
+// error satisfying blob.ErrContentMismatch.
+//
+// If the bundle is incomplete or invalid, ImportBundle reports an error, but
+// blobs stored before the error was detected remain in s.
+func ImportBundle(ctx context.Context, s blob.CAS, r io.Reader) (*File, error) {
+	br := bufio.NewReader(r)
+	var hdr [len(bundleMagic)]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return nil, fmt.Errorf("import: reading header: %w", err)
+	} else if string(hdr[:]) != bundleMagic {
+		return nil, errors.New("import: invalid bundle header")
+	}
+	rootKey, err := readBundleBlob(br, maxBundleKey)
+	if err != nil {
+		return nil, fmt.Errorf("import: reading root key: %w", err)
+	} else if len(rootKey) == 0 {
+		return nil, errors.New("import: empty root key")
+	}
+
+	for {
+		key, err := readBundleBlob(br, maxBundleKey)
+		if err != nil {
+			return nil, fmt.Errorf("import: reading key: %w", err)
+		} else if len(key) == 0 {
+			break // end of blobs
+		}
+		data, err := readBundleBlob(br, maxBundleBlob)
+		if err != nil {
+			return nil, fmt.Errorf("import %x: reading data: %w", key, err)
+		}
+		got, err := s.CASPut(ctx, data)
+		if err != nil {
+			return nil, fmt.Errorf("import %x: %w", key, err)
+		} else if got != string(key) {
+			return nil, fmt.Errorf("import %x: %w", key, blob.ContentMismatch(string(key)))
+		}
+	}
+	return Open(ctx, s, string(rootKey))
+}
+
+// writeBundleBlob writes data to w prefixed by its length as a uvarint.
+func writeBundleBlob(w *bufio.Writer, data []byte) error {
+	w.Write(binary.AppendUvarint(nil, uint64(len(data))))
+	_, err := w.Write(data)
+	return err
+}
+
+// readBundleBlob reads a length-prefixed blob of at most limit bytes from r.
+func readBundleBlob(r *bufio.Reader, limit uint64) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	} else if n > limit {
+		return nil, fmt.Errorf("blob length %d exceeds limit %d", n, limit)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
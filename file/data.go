@@ -15,9 +15,14 @@
 package file
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"io"
+	"slices"
+	"sort"
 
 	"github.com/creachadair/ffs/blob"
 	"github.com/creachadair/ffs/block"
@@ -25,21 +30,54 @@ import (
 	"github.com/creachadair/mds/mbits"
 )
 
+// A BlockStore is the interface to storage used for the data blocks of a
+// file. Any [blob.CAS] satisfies this interface, but a caller may supply a
+// separate implementation to interpose caching, deduplication, or other
+// processing specific to file data without affecting the storage of nodes.
+type BlockStore interface {
+	// Get fetches the contents of the block with the given storage key.
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// CASPut writes data to a content-addressed block and returns its key.
+	CASPut(ctx context.Context, data []byte) (string, error)
+}
+
 // A data value represents an ordered sequence of bytes stored in a blob.Store.
 // Other than length, no metadata are preserved. File data are recorded as a
 // flat array of discontiguous extents.
 type fileData struct {
 	sc         *block.SplitConfig
+	zc         *ZeroConfig
 	totalBytes int64
 	extents    []*extent
 
+	// If inlineLimit > 0, data up to that many bytes are stored inline.
+	// If inline != nil, it holds the data, and extents is empty.
+	inlineLimit int64
+	inline      []byte
+
 	// Cache of last successfully-read block. This helps avoid reloading the
 	// same block repeatedly during incremental reads.
 	lastKey  string
 	lastData []byte
+
+	// Key and end offset of the last ranged read of a block, used to detect
+	// sequential reads that would be better served by fetching the block.
+	rangeKey string
+	rangeEnd int64
+
+	// Running fingerprint of the stored blocks. This is computed on demand by
+	// the first call to hash, and thereafter maintained incrementally.
+	digest digest
+
+	// Number of stored blocks, if nblocksValid. Like digest, this is computed
+	// on demand by the first call to fragments, and thereafter maintained
+	// incrementally.
+	nblocks      int
+	nblocksValid bool
 }
 
-func (d *fileData) getBlock(ctx context.Context, s blob.CAS, key string) ([]byte, error) {
+func (d *fileData) getBlock(ctx context.Context, s BlockStore, key string) ([]byte, error) {
 	if key == d.lastKey {
 		return d.lastData, nil
 	}
@@ -65,6 +103,14 @@ func (d *fileData) toWireType() *wiretype.Index {
 		return nil
 	}
 
+	// Very small files may store their data inline, without blocks.
+	if len(d.inline) != 0 {
+		return &wiretype.Index{
+			TotalBytes: uint64(d.totalBytes),
+			Inline:     bytes.Clone(d.inline),
+		}
+	}
+
 	// Many small files contain just one block of data spanning the entire file.
 	// When that occurs, just store the key of that block. No normalization is
 	// required in this case and we save a few bytes.
@@ -106,6 +152,15 @@ func (d *fileData) fromWireType(pb *wiretype.Index) error {
 	}
 
 	d.totalBytes = int64(pb.TotalBytes)
+	if len(pb.Inline) != 0 {
+		if len(pb.Single) != 0 || len(pb.Extents) != 0 {
+			return errors.New("invalid index: inline data and blocks both set")
+		} else if int64(len(pb.Inline)) > d.totalBytes {
+			return errors.New("invalid index: inline data exceed total size")
+		}
+		d.inline = bytes.Clone(pb.Inline)
+		return nil
+	}
 	if len(pb.Single) != 0 {
 		if len(pb.Extents) != 0 {
 			return errors.New("invalid index: single-block and extents both set")
@@ -152,16 +207,39 @@ func (d *fileData) blocks(f func(int64, string)) {
 
 // truncate modifies the length of the file to end at offset, extending or
 // contracting it as necessary. Contraction may require splitting a block.
-func (d *fileData) truncate(ctx context.Context, s blob.CAS, offset int64) error {
+func (d *fileData) truncate(ctx context.Context, s BlockStore, offset int64) error {
 	if offset >= d.totalBytes {
 		d.totalBytes = offset
 		return nil
+	} else if d.inline != nil {
+		if offset < int64(len(d.inline)) {
+			d.inline = d.inline[:offset]
+		}
+		d.totalBytes = offset
+		return nil
+	}
+	pre, span, post := d.splitSpan(0, offset)
+	var old digest
+	if d.digest.valid {
+		// Extents starting after the offset are discarded, and the last
+		// extent in the span is rewritten, so their blocks are removed.
+		old.addExtents(post...)
+		if len(span) != 0 {
+			old.addExtents(span[len(span)-1])
+		}
+	}
+	var dropped int // the number of blocks removed, as for the digest
+	if d.nblocksValid {
+		dropped = countBlocks(post)
+		if len(span) != 0 {
+			dropped += len(span[len(span)-1].blocks)
+		}
 	}
-	pre, span, _ := d.splitSpan(0, offset)
+	n := len(span)
 	if len(span) != 0 {
-		n := len(span) - 1
+		n = len(span) - 1
 		last := span[n]
-		span = span[:n]
+		span = slices.Clip(span[:n])
 
 		// If the offset transects a block, read that block and write back its
 		// prefix. If the offset is exactly at the start of the block, we can
@@ -185,18 +263,31 @@ func (d *fileData) truncate(ctx context.Context, s blob.CAS, offset int64) error
 	}
 	d.extents = append(pre, span...)
 	d.totalBytes = offset
+	d.digest.update(old, d.extents[len(pre)+n:])
+	if d.nblocksValid {
+		d.nblocks += countBlocks(d.extents[len(pre)+n:]) - dropped
+	}
+	d.extents = mergeAdjacent(d.extents, len(pre)-1, len(d.extents))
+	if d.canInline(offset) {
+		return d.demote(ctx, s)
+	}
 	return nil
 }
 
 // writeAt writes the contents of data at the specified offset in d.  It
 // returns the number of bytes successfully written, and satisfies the
 // semantics of io.WriterAt.
-func (d *fileData) writeAt(ctx context.Context, s blob.CAS, data []byte, offset int64) (int, error) {
+func (d *fileData) writeAt(ctx context.Context, s BlockStore, data []byte, offset int64) (int, error) {
 	if len(data) == 0 {
 		return 0, nil
 	}
 	end := offset + int64(len(data))
-	pre, span, post := d.splitSpan(offset, end)
+	if d.canInline(max(end, d.totalBytes)) {
+		return d.writeInline(ctx, s, data, offset)
+	} else if err := d.promote(ctx, s); err != nil {
+		return 0, err
+	}
+	pre, span, _ := d.splitSpan(offset, end)
 
 	var left, right []cblock
 	var parts [][]byte
@@ -212,15 +303,22 @@ func (d *fileData) writeAt(ctx context.Context, s blob.CAS, data []byte, offset
 			// The first extent starts before the write. Find the first block
 			// split by or contiguous to the write, preserve everything before
 			// that, and read in the contents to set up the split.
+			//
+			// As a special case, if the write overwrites the extent starting
+			// exactly at a block boundary, the blocks before the write are
+			// preserved intact and nothing needs to be read.
 			newBase = span[0].base
+			spanEnd := span[0].base + span[0].bytes
 
 			pos := span[0].base
 			for _, blk := range span[0].blocks {
 				next := pos + blk.bytes
-				if next < offset {
+				if next < offset || (next == offset && next < spanEnd) {
 					left = append(left, blk)
 					pos = next
 					continue
+				} else if pos == offset {
+					break // the write is aligned to this block
 				}
 
 				bits, err := s.Get(ctx, blk.key)
@@ -239,11 +337,15 @@ func (d *fileData) writeAt(ctx context.Context, s blob.CAS, data []byte, offset
 			// The last extent ends after the write. Find the last block split by
 			// or contiguous to the write, preserve everything after that, and
 			// read in the contents to set up the split.
+			//
+			// As with the start, if the write overwrites the extent ending
+			// exactly at a block boundary, the blocks after the write are
+			// preserved intact and nothing needs to be read.
 			newEnd = last.base + last.bytes
 
 			pos := last.base
 			for i, blk := range last.blocks {
-				if pos > end {
+				if pos > end || (pos == end && i > 0) {
 					// Preserve the rest of this extent
 					right = append(right, last.blocks[i:]...)
 					break
@@ -271,21 +373,29 @@ func (d *fileData) writeAt(ctx context.Context, s blob.CAS, data []byte, offset
 		return 0, err
 	}
 
-	// N.B. It is possible that this write has created contiguous extents.
-	// Rather than fix it here, we rely on the normalization that happens during
-	// conversion to wire format, which includes this merge check.
+	merged := splitExtent(&extent{
+		base:   newBase,
+		bytes:  newEnd - newBase,
+		blocks: append(left, append(body, right...)...),
+	})
+	if d.digest.valid {
+		var old digest
+		old.addExtents(span...)
+		d.digest.update(old, merged)
+	}
+	if d.nblocksValid {
+		d.nblocks += countBlocks(merged) - countBlocks(span)
+	}
 
-	d.extents = make([]*extent, 0, len(pre)+1+len(post))
 	//
 	// d.extents = [ ...pre... | ...merged ... | ...post... ]
 	//
-	d.extents = append(d.extents, pre...)
-	d.extents = append(d.extents, splitExtent(&extent{
-		base:   newBase,
-		bytes:  newEnd - newBase,
-		blocks: append(left, append(body, right...)...),
-	})...)
-	d.extents = append(d.extents, post...)
+	d.extents = slices.Replace(d.extents, len(pre), len(pre)+len(span), merged...)
+
+	// Merge any extents made contiguous by this write, so that they do not
+	// accumulate and slow down later operations. Only the neighborhood of
+	// the write needs to be checked.
+	d.extents = mergeAdjacent(d.extents, len(pre)-1, len(pre)+len(merged)+1)
 	if end > d.totalBytes {
 		d.totalBytes = end
 	}
@@ -293,10 +403,106 @@ func (d *fileData) writeAt(ctx context.Context, s blob.CAS, data []byte, offset
 	return len(data), nil
 }
 
+// extract returns the stored extents of d covering the range [lo, hi), with
+// offsets relative to lo. Blocks that lie entirely within the range are shared
+// by key. Blocks that straddle either end of the range are read from s, and
+// the portions within the range are re-blocked and written to put.
+func (d *fileData) extract(ctx context.Context, s, put BlockStore, lo, hi int64) ([]*extent, error) {
+	_, span, _ := d.splitSpan(lo, hi)
+
+	var out []*extent
+	for _, ext := range span {
+		var blks []cblock
+		var base, nb int64 // base and size of the kept blocks
+
+		pos := ext.base
+		for _, blk := range ext.blocks {
+			end := pos + blk.bytes
+			if pos >= hi {
+				break // nothing more in range
+			} else if end <= lo {
+				pos = end
+				continue // not yet in range
+			}
+			if len(blks) == 0 {
+				base = pos
+				if base < lo {
+					base = lo
+				}
+			}
+
+			if pos >= lo && end <= hi {
+				// The whole block is in range; share it.
+				blks = append(blks, blk)
+				nb += blk.bytes
+			} else {
+				// The block straddles an end of the range; copy the part in range.
+				bits, err := s.Get(ctx, blk.key)
+				if err != nil {
+					return nil, err
+				}
+				i, j := int64(0), blk.bytes
+				if pos < lo {
+					i = lo - pos
+				}
+				if end > hi {
+					j = hi - pos
+				}
+				part, err := d.splitBlobs(ctx, put, bits[i:j])
+				if err != nil {
+					return nil, err
+				}
+				blks = append(blks, part...)
+				nb += j - i
+			}
+			pos = end
+		}
+		if len(blks) != 0 {
+			out = append(out, splitExtent(&extent{base: base - lo, bytes: nb, blocks: blks})...)
+		}
+	}
+	return out, nil
+}
+
+// splice replaces the contents of d in the range [lo, hi) with the stored
+// extents in exts, whose offsets are relative to lo, extending d if necessary.
+// Any part of the range not covered by exts reads as zeroes. Blocks of d that
+// straddle either end of the range are read from s and the portions outside
+// the range are rewritten.
+func (d *fileData) splice(ctx context.Context, s BlockStore, lo, hi int64, exts []*extent) error {
+	left, err := d.extract(ctx, s, s, 0, lo)
+	if err != nil {
+		return err
+	}
+	right, err := d.extract(ctx, s, s, hi, d.totalBytes)
+	if err != nil {
+		return err
+	}
+
+	out := make([]*extent, 0, len(left)+len(exts)+len(right))
+	out = append(out, left...)
+	for _, ext := range exts {
+		out = append(out, &extent{base: ext.base + lo, bytes: ext.bytes, blocks: ext.blocks})
+	}
+	for _, ext := range right {
+		out = append(out, &extent{base: ext.base + hi, bytes: ext.bytes, blocks: ext.blocks})
+	}
+	d.extents = mergeAdjacent(out, 0, len(out))
+	if hi > d.totalBytes {
+		d.totalBytes = hi
+	}
+	d.digest.valid = false // recomputed on demand
+	d.nblocksValid = false
+	return nil
+}
+
 // readAt reads the content of d into data from the specified offset, returning
 // the number of bytes successfully read. It satisfies the semantics of the
 // io.ReaderAt interface.
-func (d *fileData) readAt(ctx context.Context, s blob.CAS, data []byte, offset int64) (int, error) {
+func (d *fileData) readAt(ctx context.Context, s BlockStore, data []byte, offset int64) (int, error) {
+	if d.inline != nil {
+		return d.readInline(data, offset)
+	}
 	if offset > d.totalBytes {
 		return 0, io.EOF
 	}
@@ -346,14 +552,13 @@ walkSpan:
 			}
 
 			// Fetch the block contents and copy whatever we can.
-			bits, err := d.getBlock(ctx, s, blk.key)
+			pos := int(offset - base)
+			bits, err := d.readBlock(ctx, s, blk, pos, len(data)-nr)
 			if err != nil {
 				return 0, err
 			}
-
-			pos := int(offset - base)
-			cp := min(len(bits)-pos, len(data)-nr)
-			nr += copy(data[nr:], bits[pos:pos+cp])
+			cp := copy(data[nr:], bits)
+			nr += cp
 			if nr == len(data) {
 				break walkSpan
 			}
@@ -381,30 +586,55 @@ walkSpan:
 	return nr, nil
 }
 
+// readBlock returns up to n bytes of the contents of blk, starting at offset
+// pos within the block.
+//
+// If s supports ranged reads and only a small portion of the block is needed,
+// readBlock fetches just that portion. Otherwise, or if the read continues
+// where the previous ranged read of the same block ended, it fetches the whole
+// block, which is retained for subsequent reads.
+func (d *fileData) readBlock(ctx context.Context, s BlockStore, blk cblock, pos, n int) ([]byte, error) {
+	n = min(n, int(blk.bytes)-pos)
+	if rg, ok := s.(blob.RangeGetter); ok && blk.key != d.lastKey && 4*int64(n) <= blk.bytes {
+		if blk.key != d.rangeKey || int64(pos) != d.rangeEnd {
+			bits, err := rg.GetRange(ctx, blk.key, int64(pos), int64(n))
+			if err == nil {
+				d.rangeKey, d.rangeEnd = blk.key, int64(pos+len(bits))
+			}
+			return bits, err
+		}
+	}
+	bits, err := d.getBlock(ctx, s, blk.key)
+	if err != nil {
+		return nil, err
+	}
+	return bits[pos:min(pos+n, len(bits))], nil
+}
+
 // splitBlobs re-blocks the concatenation of the specified blobs and returns
 // the resulting blocks. Zero-valued blocks are not stored, the caller can
 // detect this by looking for a key of "".
-func (d *fileData) splitBlobs(ctx context.Context, s blob.CAS, blobs ...[]byte) ([]cblock, error) {
+func (d *fileData) splitBlobs(ctx context.Context, s BlockStore, blobs ...[]byte) ([]cblock, error) {
 	data := newBlockReader(blobs)
 
 	var blks []cblock
 	if err := block.NewSplitter(data, d.sc).Split(func(blk []byte) error {
 		// We do not store blocks of zeroes. They count against the total file
 		// size, but we do not explicitly record them.
-		zhead, ztail, n := zeroCheck(blk)
+		zhead, ztail, n := d.zc.check(blk)
 		if zhead == n {
 			// This block is all zeroes.
 			blks = append(blks, cblock{bytes: int64(len(blk))})
 			return nil
 		}
 
-		if isWorthTrimming(zhead, n) {
+		if d.zc.worthTrimming(zhead, n, isWorthTrimming) {
 			// There is a tranch of zeroes at the head. Inject a "fake" zero block
 			// for the prefix, and remove it from the block to be stored.
 			blks = append(blks, cblock{bytes: int64(zhead)})
 			blk = blk[zhead:]
 		}
-		wantTail := isWorthTrimming(ztail, n)
+		wantTail := d.zc.worthTrimming(ztail, n, isWorthTrimming)
 		if wantTail {
 			// There is a block of zeroes at the tail. Remove the suffix from the
 			// block to be stored, and store a fake block for the suffix after it.
@@ -434,26 +664,35 @@ func (d *fileData) splitBlobs(ctx context.Context, s blob.CAS, blobs ...[]byte)
 //
 // If span is empty, the range fully spans unstored data. Otherwise, the first
 // and last elements of span are "split" by the range.
+//
+// The extents of d are ordered by offset and do not overlap, so splitSpan
+// finds the boundaries by binary search. The results share storage with the
+// extents of d, but have no spare capacity, so appending to them does not
+// modify d.
 func (d *fileData) splitSpan(lo, hi int64) (pre, span, post []*extent) {
-	for i, ext := range d.extents {
-		if lo > ext.base+ext.bytes {
-			pre = append(pre, ext)
-		} else if hi < ext.base {
-			post = append(post, d.extents[i:]...)
-			break // nothing more to do; everything else is bigger
-		} else {
-			span = append(span, ext)
-		}
-	}
+	i, j := d.spanIndex(lo, hi)
+	return d.extents[:i:i], d.extents[i:j:j], d.extents[j:]
+}
 
-	return
+// spanIndex returns the indexes in d.extents of the first extent that does
+// not end before lo, and of the first extent that begins after hi.
+func (d *fileData) spanIndex(lo, hi int64) (i, j int) {
+	i = sort.Search(len(d.extents), func(k int) bool {
+		ext := d.extents[k]
+		return ext.base+ext.bytes >= lo
+	})
+	j = i + sort.Search(len(d.extents)-i, func(k int) bool {
+		return d.extents[i+k].base > hi
+	})
+	return i, j
 }
 
 // newfileData constructs a new fileData value containing exactly the data from
-// s.  For each data block, newFileData calls put to store the block and return
-// its key. An error from put stops construction and is reported to the caller.
-func newFileData(s *block.Splitter, put func([]byte) (string, error)) (fileData, error) {
-	fd := fileData{sc: s.Config()}
+// s, suppressing zeroes as specified by zc.  For each data block, newFileData
+// calls put to store the block and return its key. An error from put stops
+// construction and is reported to the caller.
+func newFileData(s *block.Splitter, zc *ZeroConfig, put func([]byte) (string, error)) (fileData, error) {
+	fd := fileData{sc: s.Config(), zc: zc}
 
 	ext := new(extent)
 	push := func() {
@@ -466,7 +705,7 @@ func newFileData(s *block.Splitter, put func([]byte) (string, error)) (fileData,
 	err := s.Split(func(data []byte) error {
 		dlen := int64(len(data))
 
-		zhead, ztail, n := zeroCheck(data)
+		zhead, ztail, n := zc.check(data)
 		// A block of zeroes ends the current extent. We count the block against
 		// the total file size, but do not explicitly store it.
 		if zhead == n {
@@ -479,7 +718,7 @@ func newFileData(s *block.Splitter, put func([]byte) (string, error)) (fileData,
 
 		// If a block has a lot of zeroes at its head or tail, chop them.  We
 		// define "a lot" as a fraction of the block size.
-		if zhead*zhead >= n {
+		if zc.worthTrimming(zhead, n, isLongRun) {
 			fd.totalBytes += int64(zhead)
 			push()
 			data = data[zhead:]
@@ -489,7 +728,7 @@ func newFileData(s *block.Splitter, put func([]byte) (string, error)) (fileData,
 		// Update the total length regardless whether we have trailing zeroes to
 		// remove from the block. Do this BEFORE adjusting the block.
 		fd.totalBytes += dlen
-		if ztail*ztail >= n {
+		if zc.worthTrimming(ztail, n, isLongRun) {
 			data = data[:len(data)-ztail]
 			dlen = int64(len(data))
 			defer push() // start a new extent after this block
@@ -515,6 +754,36 @@ func newFileData(s *block.Splitter, put func([]byte) (string, error)) (fileData,
 	return fd, nil
 }
 
+// maxMergeBlocks is the largest number of blocks mergeAdjacent will combine
+// into one extent. Since a write rebuilds the extents it touches, merging
+// without bound would make small writes to a long extent expensive.
+const maxMergeBlocks = 256
+
+// mergeAdjacent merges each run of contiguous extents in exts[i:j] into a
+// single extent of up to maxMergeBlocks blocks, and returns the updated slice.
+// The indices are clamped to the bounds of exts. The merged extents are
+// replaced, not modified.
+func mergeAdjacent(exts []*extent, i, j int) []*extent {
+	i, j = max(i, 0), min(j, len(exts))
+	if j-i < 2 {
+		return exts
+	}
+	out := exts[:i+1]
+	for _, ext := range exts[i+1 : j] {
+		last := out[len(out)-1]
+		if last.base+last.bytes != ext.base || len(last.blocks)+len(ext.blocks) > maxMergeBlocks {
+			out = append(out, ext)
+			continue
+		}
+		out[len(out)-1] = &extent{
+			base:   last.base,
+			bytes:  last.bytes + ext.bytes,
+			blocks: append(slices.Clip(last.blocks), ext.blocks...),
+		}
+	}
+	return append(out, exts[j:]...)
+}
+
 // An extent represents a single contiguous stored subrange of a file. The
 // blocks record the offsets and block storage keys for the extent.
 type extent struct {
@@ -579,3 +848,71 @@ type cblock struct {
 // square root of the block size. That's cheaper than a log, and accuracy is
 // not important on short sizes.
 func isWorthTrimming(nz, n int) bool { return nz >= 13 || nz*nz >= n }
+
+// hash returns a fingerprint of the stored blocks of d and its total size.
+// The first call computes the fingerprint over all the blocks of d; after
+// that, it is updated incrementally as d is modified.
+func (d *fileData) hash() []byte {
+	if d.inline != nil {
+		// Inline data have no blocks; fingerprint the content directly.
+		h := sha256.New()
+		h.Write([]byte("inline\x00"))
+		h.Write(binary.BigEndian.AppendUint64(nil, uint64(d.totalBytes)))
+		h.Write(d.inline)
+		return h.Sum(nil)
+	}
+	if !d.digest.valid {
+		d.digest = digest{valid: true}
+		d.digest.addExtents(d.extents...)
+	}
+	var buf [3 * 8]byte
+	binary.BigEndian.PutUint64(buf[0:], uint64(d.totalBytes))
+	binary.BigEndian.PutUint64(buf[8:], d.digest.sum[0])
+	binary.BigEndian.PutUint64(buf[16:], d.digest.sum[1])
+	h := sha256.Sum256(buf[:])
+	return h[:]
+}
+
+// A digest is an order-sensitive fingerprint of a collection of stored blocks.
+// Each block contributes a hash of its offset, size, and storage key, and the
+// contributions are summed so that blocks can be added and removed without
+// visiting the rest of the collection.
+type digest struct {
+	valid bool      // whether sum is current
+	sum   [2]uint64 // sum of block contributions, mod 2^64 in each lane
+}
+
+// addExtents adds the contributions of the blocks of exts to g.
+func (g *digest) addExtents(exts ...*extent) {
+	for _, ext := range exts {
+		pos := ext.base
+		for _, blk := range ext.blocks {
+			var buf [16]byte
+			binary.BigEndian.PutUint64(buf[0:], uint64(pos))
+			binary.BigEndian.PutUint64(buf[8:], uint64(blk.bytes))
+			h := sha256.New()
+			h.Write(buf[:])
+			h.Write([]byte(blk.key))
+			v := h.Sum(nil)
+			g.sum[0] += binary.BigEndian.Uint64(v[0:])
+			g.sum[1] += binary.BigEndian.Uint64(v[8:])
+			pos += blk.bytes
+		}
+	}
+}
+
+// update replaces the contributions of old in g with those of the blocks of
+// exts. It does nothing if g is not valid.
+func (g *digest) update(old digest, exts []*extent) {
+	if !g.valid {
+		return
+	}
+	g.sum[0] -= old.sum[0]
+	g.sum[1] -= old.sum[1]
+	g.addExtents(exts...)
+}
+
+// isLongRun reports whether a prefix or suffix of nz zeroes is long relative
+// to a block of length n. This is the default trimming rule for data written
+// in bulk, which is stricter than isWorthTrimming.
+func isLongRun(nz, n int) bool { return nz*nz >= n }
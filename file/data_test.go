@@ -20,6 +20,7 @@ import (
 	"crypto/sha1"
 	"io"
 	"math/rand"
+	"slices"
 	"strconv"
 	"strings"
 	"testing"
@@ -36,7 +37,7 @@ import (
 
 var cmpFileDataOpts = []cmp.Option{
 	cmp.AllowUnexported(fileData{}, extent{}, cblock{}),
-	cmpopts.IgnoreFields(fileData{}, "sc"),
+	cmpopts.IgnoreFields(fileData{}, "sc", "zc", "digest"),
 }
 
 func TestIndex(t *testing.T) {
@@ -255,6 +256,35 @@ func TestWireEncoding(t *testing.T) {
 			t.Errorf("Wrong decoded block (-want, +got)\n%s", diff)
 		}
 	})
+
+	t.Run("Inline", func(t *testing.T) {
+		d := &fileData{totalBytes: 12, inline: []byte("hello")}
+		idx := d.toWireType()
+		if s := string(idx.Inline); s != "hello" {
+			t.Errorf("Index inline: got %q, want hello", s)
+		}
+		if len(idx.Single) != 0 || len(idx.Extents) != 0 {
+			t.Errorf("Index has single=%q, extents=%d; want empty", idx.Single, len(idx.Extents))
+		}
+
+		dx := new(fileData)
+		if err := dx.fromWireType(idx); err != nil {
+			t.Errorf("Decoding index failed: %v", err)
+		}
+		if diff := cmp.Diff(d, dx, cmpFileDataOpts...); diff != "" {
+			t.Errorf("Wrong decoded block (-want, +got)\n%s", diff)
+		}
+
+		// Inline data may not be combined with blocks, or exceed the size.
+		for _, bad := range []*wiretype.Index{
+			{TotalBytes: 5, Inline: []byte("hello"), Single: []byte("foo")},
+			{TotalBytes: 3, Inline: []byte("hello")},
+		} {
+			if err := new(fileData).fromWireType(bad); err == nil {
+				t.Errorf("Decoding %v: got nil, want error", bad)
+			}
+		}
+	})
 }
 
 func TestWriteBlocking(t *testing.T) {
@@ -276,6 +306,57 @@ func TestWriteBlocking(t *testing.T) {
 	}
 }
 
+func TestZeroConfig(t *testing.T) {
+	ti := newTestInput("\x00\x00\x00\x00foo\x00\x00\x00\x00|barf\x00\x00\x00|\x00\x00\x00bazzu")
+	tests := []struct {
+		name string
+		zc   *ZeroConfig
+		want []*extent
+	}{
+		{"Disable", &ZeroConfig{Disable: true}, []*extent{
+			{base: 0, bytes: 26, blocks: []cblock{
+				{bytes: 11, key: hashOf("\x00\x00\x00\x00foo\x00\x00\x00\x00")},
+				{bytes: 7, key: hashOf("barf\x00\x00\x00")},
+				{bytes: 8, key: hashOf("\x00\x00\x00bazzu")},
+			}},
+		}},
+		{"MinRun", &ZeroConfig{MinRun: 4}, []*extent{
+			{base: 4, bytes: 3, blocks: []cblock{{bytes: 3, key: hashOf("foo")}}},
+			{base: 11, bytes: 15, blocks: []cblock{
+				{bytes: 7, key: hashOf("barf\x00\x00\x00")},
+				{bytes: 8, key: hashOf("\x00\x00\x00bazzu")},
+			}},
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			want := &fileData{totalBytes: int64(ti.inputLen()), extents: tc.want}
+
+			// Check both incremental writes and bulk construction.
+			ti.pos = 0
+			d := newDataTester(t, &block.SplitConfig{Hasher: ti, Min: 5, Size: 16, Max: 100})
+			d.fd.zc = tc.zc
+			d.writeString(ti.input, 0)
+			if diff := cmp.Diff(want, d.fd, cmpFileDataOpts...); diff != "" {
+				t.Errorf("Wrong written data (-want, +got)\n%s", diff)
+			}
+
+			ti.pos = 0
+			s := block.NewSplitter(ti.reader(), &block.SplitConfig{Hasher: ti, Min: 5, Size: 16, Max: 100})
+			fd, err := newFileData(s, tc.zc, func(data []byte) (string, error) {
+				return d.cas.CASPut(d.ctx, data)
+			})
+			if err != nil {
+				t.Fatalf("newFileData failed: %v", err)
+			}
+			if diff := cmp.Diff(want, &fd, cmpFileDataOpts...); diff != "" {
+				t.Errorf("Wrong constructed data (-want, +got)\n%s", diff)
+			}
+			d.checkString(0, int64(ti.inputLen()), ti.input)
+		})
+	}
+}
+
 func TestReblocking(t *testing.T) {
 	d := newDataTester(t, &block.SplitConfig{Min: 200, Size: 1024, Max: 8192})
 	rng := rand.New(rand.NewSource(1)) // change to update test data
@@ -334,6 +415,125 @@ func TestReblocking(t *testing.T) {
 	})
 }
 
+func TestAlignedWrite(t *testing.T) {
+	d := newDataTester(t, &block.SplitConfig{Min: 200, Size: 1024, Max: 8192})
+	gc := &getCounter{CAS: d.cas}
+	d.cas = gc
+	rng := rand.New(rand.NewSource(1)) // same data as TestReblocking
+
+	const alphabet = "0123456789abcdef"
+	var buf bytes.Buffer
+	for buf.Len() < 4000 {
+		buf.WriteByte(alphabet[rng.Intn(len(alphabet))])
+	}
+	want := buf.Bytes()
+	d.writeString(string(want), 0)
+
+	blockSizes := func() (out []int64) {
+		d.fd.blocks(func(size int64, _ string) { out = append(out, size) })
+		return
+	}
+	if diff := cmp.Diff(blockSizes(), []int64{481, 2329, 413, 255, 522}); diff != "" {
+		t.Fatalf("Wrong initial block sizes (-got, +want):\n%s", diff)
+	}
+
+	// Each of these writes begins and ends on block boundaries within the
+	// existing data, so no existing blocks should need to be read.
+	tests := []struct {
+		offset, size int64
+		sizes        []int64
+	}{
+		{2810, 413, []int64{481, 2329, 413, 255, 522}}, // one block in the middle
+		{481, 2742, []int64{481, 2742, 255, 522}},      // several blocks
+		{3223, 255, []int64{481, 2742, 255, 522}},      // one block, adjacent to the last
+	}
+	for _, tc := range tests {
+		chunk := bytes.Repeat([]byte("x"), int(tc.size))
+		copy(want[tc.offset:], chunk)
+
+		gc.n = 0
+		d.writeString(string(chunk), tc.offset)
+		if gc.n != 0 {
+			t.Errorf("Write %d at %d: got %d block reads, want 0", tc.size, tc.offset, gc.n)
+		}
+		if diff := cmp.Diff(blockSizes(), tc.sizes); diff != "" {
+			t.Errorf("Write %d at %d: wrong block sizes (-got, +want):\n%s", tc.size, tc.offset, diff)
+		}
+		d.checkString(0, int64(len(want)), string(want))
+	}
+
+	// A write that splits blocks must read them.
+	gc.n = 0
+	d.writeString("yyyy", 479)
+	if gc.n != 2 {
+		t.Errorf("Unaligned write: got %d block reads, want 2", gc.n)
+	}
+	copy(want[479:], "yyyy")
+	d.checkString(0, int64(len(want)), string(want))
+}
+
+func TestRangeRead(t *testing.T) {
+	d := newDataTester(t, &block.SplitConfig{Min: 4096, Size: 8192, Max: 16384})
+	rc := &rangeCounter{CAS: d.cas}
+	d.cas = rc
+
+	rng := rand.New(rand.NewSource(1))
+	var buf bytes.Buffer
+	for buf.Len() < 40000 {
+		buf.WriteByte(byte(rng.Intn(256)))
+	}
+	content := buf.String()
+	d.writeString(content, 0)
+
+	// A small read from the middle of a block fetches only what it needs.
+	d.checkString(2000, 100, content[2000:2100])
+	if rc.gets != 0 || rc.ranges != 1 {
+		t.Errorf("Small read: got %d gets, %d ranged reads; want 0, 1", rc.gets, rc.ranges)
+	}
+
+	// A read continuing where the last one ended fetches the block, and
+	// subsequent reads within it need not fetch anything.
+	d.checkString(2100, 100, content[2100:2200])
+	d.checkString(2200, 100, content[2200:2300])
+	if rc.gets != 1 || rc.ranges != 1 {
+		t.Errorf("Sequential reads: got %d gets, %d ranged reads; want 1, 1", rc.gets, rc.ranges)
+	}
+
+	// A large read fetches whole blocks.
+	d.checkString(0, int64(len(content)), content)
+	if rc.ranges != 1 {
+		t.Errorf("Large read: got %d ranged reads, want 1", rc.ranges)
+	}
+}
+
+// rangeCounter is a blob.CAS that supports ranged reads, and counts calls to
+// Get and GetRange.
+type rangeCounter struct {
+	blob.CAS
+	gets, ranges int
+}
+
+func (r *rangeCounter) Get(ctx context.Context, key string) ([]byte, error) {
+	r.gets++
+	return r.CAS.Get(ctx, key)
+}
+
+func (r *rangeCounter) GetRange(ctx context.Context, key string, offset, length int64) ([]byte, error) {
+	r.ranges++
+	return blob.GetRange(ctx, r.CAS, key, offset, length)
+}
+
+// getCounter is a blob.CAS that counts calls to Get.
+type getCounter struct {
+	blob.CAS
+	n int
+}
+
+func (g *getCounter) Get(ctx context.Context, key string) ([]byte, error) {
+	g.n++
+	return g.CAS.Get(ctx, key)
+}
+
 type testInput struct {
 	template string
 	splits   mapset.Set[int]
@@ -372,7 +572,7 @@ func TestNewFileData(t *testing.T) {
 
 			// Generate a new data index from the input. We don't actually store
 			// any data here, just generate some plausible keys as if we did.
-			fd, err := newFileData(s, func(data []byte) (string, error) {
+			fd, err := newFileData(s, nil, func(data []byte) (string, error) {
 				t.Logf("Block: %q", string(data))
 				h := sha1.New()
 				h.Write(data)
@@ -511,3 +711,128 @@ func (ti *testInput) Update(b byte) uint64 {
 	}
 	return 2
 }
+
+func TestMergeAdjacent(t *testing.T) {
+	blk := func(key string) []cblock { return []cblock{{bytes: 1, key: key}} }
+	ext := func(base int64, key string) *extent { return &extent{base: base, bytes: 1, blocks: blk(key)} }
+
+	// Extents a, b, c are contiguous; d follows a gap; e is contiguous with d.
+	input := []*extent{ext(0, "a"), ext(1, "b"), ext(2, "c"), ext(5, "d"), ext(6, "e")}
+	tests := []struct {
+		i, j int
+		want []*extent
+	}{
+		{0, 0, input},
+		{0, 1, input},
+		{-1, 99, []*extent{
+			{base: 0, bytes: 3, blocks: []cblock{{1, "a"}, {1, "b"}, {1, "c"}}},
+			{base: 5, bytes: 2, blocks: []cblock{{1, "d"}, {1, "e"}}},
+		}},
+		{1, 3, []*extent{
+			ext(0, "a"),
+			{base: 1, bytes: 2, blocks: []cblock{{1, "b"}, {1, "c"}}},
+			ext(5, "d"), ext(6, "e"),
+		}},
+		{2, 4, input}, // c and d are not contiguous
+	}
+	for _, tc := range tests {
+		got := mergeAdjacent(slices.Clone(input), tc.i, tc.j)
+		if diff := cmp.Diff(got, tc.want, cmpFileDataOpts...); diff != "" {
+			t.Errorf("mergeAdjacent(%d, %d) (-got, +want):\n%s", tc.i, tc.j, diff)
+		}
+	}
+
+	// Merging stops at the block limit.
+	var long []*extent
+	for i := range maxMergeBlocks + 1 {
+		long = append(long, ext(int64(i), strconv.Itoa(i)))
+	}
+	got := mergeAdjacent(long, 0, len(long))
+	if len(got) != 2 || len(got[0].blocks) != maxMergeBlocks {
+		t.Errorf("mergeAdjacent long: got %d extents, want 2 with %d blocks first", len(got), maxMergeBlocks)
+	}
+}
+
+func TestSpliceMerge(t *testing.T) {
+	d := newDataTester(t, &block.SplitConfig{Min: 16, Size: 32, Max: 64})
+	d.writeString(strings.Repeat("abcdefgh", 8), 0)
+
+	// Copying the data end to end leaves one extent, not one per copy.
+	for i := int64(1); i <= 4; i++ {
+		exts, err := d.fd.extract(d.ctx, d.cas, d.cas, 0, 64)
+		if err != nil {
+			t.Fatalf("extract: %v", err)
+		}
+		if err := d.fd.splice(d.ctx, d.cas, 64*i, 64*(i+1), exts); err != nil {
+			t.Fatalf("splice: %v", err)
+		}
+	}
+	if n := len(d.fd.extents); n != 1 {
+		t.Errorf("After splicing: got %d extents, want 1", n)
+	}
+	d.checkString(0, 320, strings.Repeat("abcdefgh", 40))
+}
+
+func TestDataHash(t *testing.T) {
+	d := newDataTester(t, &block.SplitConfig{Min: 16, Size: 32, Max: 64})
+	rng := rand.New(rand.NewSource(20251015))
+
+	// checkHash verifies that the incrementally-maintained hash of d matches
+	// a hash computed from scratch over the same blocks.
+	checkHash := func(step int) {
+		t.Helper()
+		got := d.fd.hash()
+		fresh := fileData{totalBytes: d.fd.totalBytes, extents: d.fd.extents}
+		if want := fresh.hash(); !bytes.Equal(got, want) {
+			t.Fatalf("Step %d: hash mismatch:\n got %x\nwant %x", step, got, want)
+		}
+	}
+
+	checkHash(0)
+	for i := 1; i <= 200; i++ {
+		if i%10 == 0 {
+			d.truncate(rng.Int63n(d.fd.totalBytes + 1))
+		} else {
+			buf := make([]byte, 1+rng.Intn(100))
+			rng.Read(buf)
+			if rng.Intn(4) == 0 {
+				clear(buf) // exercise zero-block trimming
+			}
+			d.writeString(string(buf), rng.Int63n(d.fd.totalBytes+50))
+		}
+		checkHash(i)
+	}
+}
+
+func TestDataFragments(t *testing.T) {
+	d := newDataTester(t, &block.SplitConfig{Min: 16, Size: 32, Max: 64})
+	rng := rand.New(rand.NewSource(20251015))
+
+	// checkCount verifies that the incrementally-maintained block count of d
+	// matches a count taken from scratch over the same extents.
+	checkCount := func(step int) {
+		t.Helper()
+		exts, blks := d.fd.fragments()
+		if exts != len(d.fd.extents) {
+			t.Fatalf("Step %d: got %d extents, want %d", step, exts, len(d.fd.extents))
+		}
+		if want := countBlocks(d.fd.extents); blks != want {
+			t.Fatalf("Step %d: got %d blocks, want %d", step, blks, want)
+		}
+	}
+
+	checkCount(0)
+	for i := 1; i <= 200; i++ {
+		if i%10 == 0 {
+			d.truncate(rng.Int63n(d.fd.totalBytes + 1))
+		} else {
+			buf := make([]byte, 1+rng.Intn(100))
+			rng.Read(buf)
+			if rng.Intn(4) == 0 {
+				clear(buf) // exercise zero-block trimming
+			}
+			d.writeString(string(buf), rng.Int63n(d.fd.totalBytes+50))
+		}
+		checkCount(i)
+	}
+}
@@ -67,12 +67,18 @@
 package file
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"maps"
 	"slices"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -90,9 +96,14 @@ func New(s blob.CAS, opts *NewOptions) *File {
 	}
 	f := &File{
 		s:        s,
+		bs:       opts.Blocks,
+		check:    opts.CheckName,
+		norm:     opts.NormalizeName,
+		limits:   opts.Limits,
 		name:     opts.Name,
 		saveStat: opts.PersistStat,
-		data:     fileData{sc: opts.Split},
+		keepTime: opts.PreserveModTime,
+		data:     fileData{sc: opts.Split, zc: opts.Zeroes, inlineLimit: int64(opts.InlineLimit)},
 		xattr:    make(map[string]string),
 	}
 	// If the options contain stat metadata, copy them in.
@@ -117,42 +128,218 @@ type NewOptions struct {
 	// to storage when the file is written out.
 	PersistStat bool
 
+	// PreserveModTime, if true, prevents changes to the file from updating its
+	// modification time automatically. The modification time changes only when
+	// set explicitly via the Stat view. This allows a caller restoring a file
+	// to write its content and children in any order, then set the original
+	// modification time once. Like the split configuration, the choice is not
+	// persisted in storage, and descendants created from a file inherit it.
+	PreserveModTime bool
+
 	// The block splitter configuration to use. If omitted, the default values
 	// from the split package are used. Split configurations are not persisted
 	// in storage, but descendants created from a file (via the New method) will
 	// inherit the parent file config if they do not specify their own.
+	// If the configuration is not valid, writes to the file report an error.
 	Split *block.SplitConfig
+
+	// Zeroes, if non-nil, controls the suppression of zero bytes in the data
+	// of the file. If omitted, defaults are used as described by ZeroConfig.
+	// Like the split configuration, the choice is not persisted in storage,
+	// and descendants created from a file inherit it.
+	Zeroes *ZeroConfig
+
+	// InlineLimit, if positive, is the size in bytes up to which the data of
+	// the file are stored inline in its node rather than in separate blocks.
+	// This saves a blob and a round trip per file in trees with many tiny
+	// files, but inline data are not shared between files, so the limit
+	// should be small, at most a few KiB. Data are moved between inline and
+	// block storage automatically as the file changes size.
+	//
+	// Like the split configuration, the limit is not persisted in storage,
+	// and descendants created from a file inherit it.
+	InlineLimit int
+
+	// Blocks, if non-nil, is used to store and fetch the data blocks of the
+	// file instead of the store for file nodes. Like the split configuration,
+	// descendants created from a file inherit its block store, and the choice
+	// is not persisted in storage.
+	Blocks BlockStore
+
+	// CheckName, if non-nil, is used by the SetE method of the Child view to
+	// check the names of new children. If nil, the package function CheckName
+	// is used. Like the split configuration, descendants created from a file
+	// inherit its name check.
+	CheckName func(name string) error
+
+	// NormalizeName, if non-nil, maps child names to a canonical form used to
+	// compare them, for example Unicode NFC via norm.NFC.String from the
+	// golang.org/x/text/unicode/norm package. Names with the same canonical
+	// form denote the same child: Looking up or setting a child by any such
+	// name finds the existing child, whose stored name is not changed. A new
+	// child is stored under the name given when it is added. This prevents
+	// producers that spell names differently (such as NFD on macOS and NFC on
+	// Linux) from creating distinct children that collide when restored.
+	//
+	// Lookups by a name that is not stored exactly take time proportional to
+	// the number of children. Like the split configuration, descendants
+	// created from a file inherit its normalizer, and the choice is not
+	// persisted in storage.
+	NormalizeName func(name string) string
+
+	// Limits, if non-nil, are soft limits on the fragmentation of the data of
+	// the file. Like the split configuration, descendants created from a file
+	// inherit its limits, and the choice is not persisted in storage.
+	Limits *FragmentLimits
 }
 
 // Open opens an existing file given its storage key in s.
 func Open(ctx context.Context, s blob.CAS, key string) (*File, error) {
+	return OpenWith(ctx, s, key, nil)
+}
+
+// OpenOptions control the opening of existing files. A nil *OpenOptions is
+// ready for use and provides default values as described.
+type OpenOptions struct {
+	// Blocks, if non-nil, is used to store and fetch the data blocks of the
+	// file and its descendants, instead of the store for file nodes.
+	Blocks BlockStore
+
+	// CheckName, if non-nil, is used to check the names of new children of
+	// the file and its descendants, as described for NewOptions.
+	CheckName func(name string) error
+
+	// NormalizeName, if non-nil, is used to compare the names of children of
+	// the file and its descendants, as described for NewOptions.
+	NormalizeName func(name string) string
+
+	// PreserveModTime, if true, prevents changes to the file and its
+	// descendants from updating their modification times, as described for
+	// NewOptions.
+	PreserveModTime bool
+
+	// InlineLimit, if positive, is the inline data limit for the file and its
+	// descendants, as described for NewOptions. Files with data stored inline
+	// can be opened regardless of this setting.
+	InlineLimit int
+
+	// ReadOnly, if true, marks the file and its descendants as read-only, for
+	// example when serving an immutable snapshot. Methods that modify a
+	// read-only file and report errors return ErrReadOnly, and those that do
+	// not report errors (such as the Set method of the Child view) panic.
+	// Files created from a read-only file with File.New are writable.
+	ReadOnly bool
+
+	// Limits, if non-nil, are soft limits on the fragmentation of the data of
+	// the file and its descendants, as described for NewOptions.
+	Limits *FragmentLimits
+}
+
+func (o *OpenOptions) blocks() BlockStore {
+	if o == nil {
+		return nil
+	}
+	return o.Blocks
+}
+
+func (o *OpenOptions) checkName() func(string) error {
+	if o == nil {
+		return nil
+	}
+	return o.CheckName
+}
+
+func (o *OpenOptions) normalizeName() func(string) string {
+	if o == nil {
+		return nil
+	}
+	return o.NormalizeName
+}
+
+func (o *OpenOptions) preserveModTime() bool { return o != nil && o.PreserveModTime }
+
+func (o *OpenOptions) readOnly() bool { return o != nil && o.ReadOnly }
+
+func (o *OpenOptions) limits() *FragmentLimits {
+	if o == nil {
+		return nil
+	}
+	return o.Limits
+}
+
+func (o *OpenOptions) inlineLimit() int64 {
+	if o == nil {
+		return 0
+	}
+	return int64(o.InlineLimit)
+}
+
+// OpenWith opens an existing file given its storage key in s, using the
+// specified options. If opts == nil, OpenWith is equivalent to Open.
+func OpenWith(ctx context.Context, s blob.CAS, key string, opts *OpenOptions) (*File, error) {
 	var obj wiretype.Object
 	if err := wiretype.Load(ctx, s, key, &obj); err != nil {
 		return nil, fmt.Errorf("loading file %x: %w", key, err)
 	}
-	f := &File{s: s, key: key}
+	f := &File{
+		s:        s,
+		bs:       opts.blocks(),
+		check:    opts.checkName(),
+		norm:     opts.normalizeName(),
+		keepTime: opts.preserveModTime(),
+		readOnly: opts.readOnly(),
+		limits:   opts.limits(),
+		key:      key,
+	}
 	if err := f.fromWireType(&obj); err != nil {
 		return nil, fmt.Errorf("decoding file %x: %w", key, err)
 	}
+	f.data.inlineLimit = opts.inlineLimit()
+	for _, d := range f.streams {
+		d.inlineLimit = f.data.inlineLimit
+	}
+	for name, xkey := range f.xkeys {
+		val, err := s.Get(ctx, xkey)
+		if err != nil {
+			return nil, fmt.Errorf("loading xattr %q of file %x: %w", name, key, err)
+		}
+		f.xattr[name] = string(val)
+	}
 	return f, nil
 }
 
 // A File represents a writable file stored in a content-addressable blobstore.
 type File struct {
-	s blob.CAS
+	s     blob.CAS
+	bs    BlockStore          // if nil, use s
+	check func(string) error  // if nil, use CheckName
+	norm  func(string) string // if non-nil, normalize names for comparison
+
+	readOnly bool            // if true, reject modifications (immutable after open)
+	limits   *FragmentLimits // if non-nil, limits on data fragmentation
 
 	mu   sync.RWMutex
 	name string // if this file is a child, its attributed name
 	key  string // the storage key for the file record (wiretype.Node)
+	gen  uint64 // incremented each time the file is invalidated
 
 	stat     Stat // file metadata
 	saveStat bool // whether to persist file metadata
+	keepTime bool // whether to leave the modification time alone on changes
 
 	data  fileData          // binary file data
 	kids  []child           // ordered lexicographically by name
 	xattr map[string]string // extended attributes
+	xkeys map[string]string // storage keys of large xattr values, by name
+
+	streams map[string]*fileData // named data streams
 }
 
+// MaxInlineXAttr is the length in bytes of the largest extended attribute
+// value that is stored inline in a file node. Longer values are stored as
+// separate blobs, referenced by their storage key.
+const MaxInlineXAttr = 1024
+
 // A child records the name and storage key of a child file.
 type child struct {
 	Name string
@@ -167,13 +354,29 @@ type child struct {
 }
 
 // findChildLocked reports whether f has a child with the specified name and
-// its index in the slice if so, or otherwise -1.
+// its index in the slice if so, or otherwise -1. If f has a name normalizer,
+// a child whose name has the same normal form as name also matches.
 func (f *File) findChildLocked(name string) (int, bool) {
-	if n := sort.Search(len(f.kids), func(i int) bool {
-		return f.kids[i].Name >= name
-	}); n < len(f.kids) && f.kids[n].Name == name {
+	return findChild(f.kids, f.norm, name)
+}
+
+// findChild reports whether kids has an entry with the specified name and its
+// index in the slice if so, or otherwise -1. If norm != nil, an entry whose
+// name has the same normal form as name also matches.
+func findChild(kids []child, norm func(string) string, name string) (int, bool) {
+	if n := sort.Search(len(kids), func(i int) bool {
+		return kids[i].Name >= name
+	}); n < len(kids) && kids[n].Name == name {
 		return n, true
 	}
+	if norm != nil {
+		want := norm(name)
+		for i, kid := range kids {
+			if norm(kid.Name) == want {
+				return i, true
+			}
+		}
+	}
 	return -1, false
 }
 
@@ -184,25 +387,103 @@ func (f *File) setStatLocked(s Stat) {
 	}
 }
 
-func (f *File) invalLocked() { f.key = "" }
+func (f *File) invalLocked() { f.key = ""; f.gen++ }
+
+// blocks returns the store to use for data blocks of f.
+func (f *File) blocks() BlockStore {
+	if f.bs != nil {
+		return f.bs
+	}
+	return f.s
+}
+
+// openChild opens the file with the given storage key, sharing the storage
+// settings of f.
+func (f *File) openChild(ctx context.Context, key string) (*File, error) {
+	return OpenWith(ctx, f.s, key, &OpenOptions{
+		Blocks:          f.bs,
+		CheckName:       f.check,
+		NormalizeName:   f.norm,
+		PreserveModTime: f.keepTime,
+		InlineLimit:     int(f.data.inlineLimit),
+		ReadOnly:        f.readOnly,
+		Limits:          f.limits,
+	})
+}
+
+// IsReadOnly reports whether f is read-only (see OpenOptions.ReadOnly).
+func (f *File) IsReadOnly() bool { return f.readOnly }
 
-func (f *File) modifyLocked() { f.invalLocked(); f.stat.ModTime = time.Now() }
+// checkWritable reports ErrReadOnly if f is read-only.
+func (f *File) checkWritable() error {
+	if f.readOnly {
+		return ErrReadOnly
+	}
+	return nil
+}
+
+// mustWritable panics if f is read-only. It is for methods that modify f but
+// do not report errors.
+func (f *File) mustWritable(op string) {
+	if f.readOnly {
+		panic(op + ": " + ErrReadOnly.Error())
+	}
+}
+
+// modifyLocked invalidates f after a change, and updates its modification
+// time unless f preserves it.
+func (f *File) modifyLocked() {
+	f.invalLocked()
+	if !f.keepTime {
+		f.stat.ModTime = time.Now()
+	}
+}
+
+// modifyDataLocked is as modifyLocked, for a change to the content of f.
+// It also discards the content fingerprint of f, which no longer applies.
+func (f *File) modifyDataLocked() {
+	delete(f.xattr, FingerprintXAttr)
+	delete(f.xkeys, FingerprintXAttr)
+	f.modifyLocked()
+}
 
 // New constructs a new empty node backed by the same store as f.
 // If f persists stat metadata, then the new file does too, even if
 // opts.PersistStat is false. The caller can override this default via the Stat
-// view after the file is created.
+// view after the file is created. Likewise, if f preserves its modification
+// time, the new file does too.
 func (f *File) New(opts *NewOptions) *File {
 	out := New(f.s, opts)
 	if f.saveStat {
 		out.saveStat = true
 	}
+	if f.keepTime {
+		out.keepTime = true
+	}
 
-	// Propagate the parent split settings to the child, if the child did not
-	// have any specifically defined.
+	// Propagate the parent split settings and block store to the child, if
+	// the child did not have any specifically defined.
 	if opts == nil || opts.Split == nil {
 		out.data.sc = f.data.sc
 	}
+	if opts == nil || opts.Zeroes == nil {
+		out.data.zc = f.data.zc
+	}
+	if opts == nil || opts.InlineLimit == 0 {
+		out.data.inlineLimit = f.data.inlineLimit
+	}
+	if opts == nil || opts.Blocks == nil {
+		out.bs = f.bs
+	}
+	if opts == nil || opts.CheckName == nil {
+		out.check = f.check
+	}
+	if opts == nil || opts.NormalizeName == nil {
+		out.norm = f.norm
+	}
+	if opts == nil || opts.Limits == nil {
+		out.limits = f.limits
+	}
 	return out
 }
 
@@ -223,8 +504,32 @@ func (f *File) Data() Data { return Data{f: f} }
 var (
 	// ErrChildNotFound indicates that a requested child file does not exist.
 	ErrChildNotFound = errors.New("child file not found")
+
+	// ErrInvalidName indicates that a child name is not valid.
+	ErrInvalidName = errors.New("invalid child name")
+
+	// ErrReadOnly indicates an attempt to modify a read-only file.
+	ErrReadOnly = errors.New("file is read-only")
 )
 
+// CheckName reports an error wrapping ErrInvalidName if name is not a valid
+// name for a child file. A valid name is non-empty, is not "." or "..", and
+// does not contain "/" or NUL characters.
+func CheckName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\x00") {
+		return fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	return nil
+}
+
+// checkName checks name using the name check for f.
+func (f *File) checkName(name string) error {
+	if f.check != nil {
+		return f.check(name)
+	}
+	return CheckName(name)
+}
+
 // Open opens the specified child file of f, or returns ErrChildNotFound if no
 // such child exists.
 func (f *File) Open(ctx context.Context, name string) (*File, error) {
@@ -237,18 +542,19 @@ func (f *File) Open(ctx context.Context, name string) (*File, error) {
 	if c := f.kids[i].File; c != nil {
 		return c, nil
 	}
-	c, err := Open(ctx, f.s, f.kids[i].Key)
+	c, err := f.openChild(ctx, f.kids[i].Key)
 	if err == nil {
-		c.name = name // remember the name the file was opened with
+		c.name = f.kids[i].Name // remember the stored name of the child
 		f.kids[i].File = c
 	}
 	return c, err
 }
 
 // Load loads an existing file given its storage key in the store used by f.
-// The specified file need not necessarily be a child of f.
+// The specified file need not necessarily be a child of f. The loaded file
+// uses the same block store as f.
 func (f *File) Load(ctx context.Context, key string) (*File, error) {
-	return Open(ctx, f.s, key)
+	return f.openChild(ctx, key)
 }
 
 // Child returns a view of the children of f.
@@ -259,26 +565,29 @@ func (f *File) Child() Child { return Child{f: f} }
 func (f *File) ReadAt(ctx context.Context, data []byte, offset int64) (int, error) {
 	f.mu.RLock()
 	defer f.mu.RUnlock()
-	return f.data.readAt(ctx, f.s, data, offset)
+	return f.data.readAt(ctx, f.blocks(), data, offset)
 }
 
 // WriteAt writes len(data) bytes from data at the given offset, and reports
 // the number of bytes successfully written, as io.WriterAt.
+//
+// To also learn the resulting size of the file, use [Data.WriteAt].
 func (f *File) WriteAt(ctx context.Context, data []byte, offset int64) (int, error) {
-	f.mu.Lock()
-	defer f.mu.Unlock()
-	defer f.modifyLocked()
-	return f.data.writeAt(ctx, f.s, data, offset)
+	nw, _, err := f.Data().WriteAt(ctx, data, offset)
+	return nw, err
 }
 
 // Flush flushes the current state of the file to storage if necessary, and
 // returns the resulting storage key. This is the canonical way to obtain the
 // storage key for a file.
-func (f *File) Flush(ctx context.Context) (string, error) {
-	f.mu.Lock()
-	defer f.mu.Unlock()
-	return f.recFlushLocked(ctx, nil)
-}
+//
+// If Flush fails partway through, for example because ctx ends, the files
+// that were successfully flushed retain their updated keys, so that a later
+// Flush resumes from where the failed one stopped.
+//
+// Flush does not copy the state of the files it writes. To flush a consistent
+// snapshot while other goroutines modify the tree, use [File.FlushWith].
+func (f *File) Flush(ctx context.Context) (string, error) { return f.FlushWith(ctx, nil) }
 
 // Key returns the storage key of f if it is known, or "" if the file has not
 // been flushed to storage in its current form.
@@ -292,7 +601,6 @@ func (f *File) recFlushLocked(ctx context.Context, path []*File) (string, error)
 	if ctx.Err() != nil {
 		return "", ctx.Err()
 	}
-	needsUpdate := f.key == ""
 
 	// Flush any cached children.
 	for i, kid := range f.kids {
@@ -316,13 +624,19 @@ func (f *File) recFlushLocked(ctx context.Context, path []*File) (string, error)
 				return "", err
 			}
 			if fkey != kid.Key {
-				needsUpdate = true
+				// Record the new key and invalidate f immediately, so that if a
+				// later step of the flush fails, a retry will resume from here
+				// and still write out the new state of f.
+				f.kids[i].Key = fkey
+				f.invalLocked()
 			}
-			f.kids[i].Key = fkey
 		}
 	}
 
-	if needsUpdate {
+	if f.key == "" {
+		if err := f.spillXAttrsLocked(ctx); err != nil {
+			return "", err
+		}
 		key, err := wiretype.Save(ctx, f.s, f.toWireTypeLocked())
 		if err != nil {
 			return "", fmt.Errorf("flushing file %x: %w", key, err)
@@ -332,23 +646,132 @@ func (f *File) recFlushLocked(ctx context.Context, path []*File) (string, error)
 	return f.key, nil
 }
 
+// spillXAttrsLocked writes any extended attribute values of f longer than
+// MaxInlineXAttr that are not already stored to separate blobs.
+func (f *File) spillXAttrsLocked(ctx context.Context) error {
+	for name, value := range f.xattr {
+		if len(value) <= MaxInlineXAttr {
+			continue
+		} else if _, ok := f.xkeys[name]; ok {
+			continue // already stored
+		}
+		xkey, err := f.s.CASPut(ctx, []byte(value))
+		if err != nil {
+			return fmt.Errorf("storing xattr %q: %w", name, err)
+		}
+		if f.xkeys == nil {
+			f.xkeys = make(map[string]string)
+		}
+		f.xkeys[name] = xkey
+	}
+	return nil
+}
+
 // Truncate modifies the length of f to end at offset, extending or contracting
 // it as necessary.
 func (f *File) Truncate(ctx context.Context, offset int64) error {
-	f.mu.Lock()
-	defer f.mu.Unlock()
-	defer f.modifyLocked()
-	return f.data.truncate(ctx, f.s, offset)
+	_, err := f.Data().Truncate(ctx, offset)
+	return err
+}
+
+// CopyRange copies n bytes of data from src starting at offset srcOff into
+// dst starting at offset dstOff, and reports the number of bytes copied.  If
+// the range extends past the end of src, only the data up to the end of src
+// are copied. The copy overwrites existing data in dst, and extends dst if
+// necessary.
+//
+// Blocks of src that lie entirely within the range are shared with dst by
+// reference, so that only the blocks at either end of the range (in src and
+// in dst) are read and rewritten. This requires that src and dst use the same
+// storage for their data blocks. The src and dst may be the same file, and
+// the ranges may overlap.
+func CopyRange(ctx context.Context, dst *File, dstOff int64, src *File, srcOff, n int64) (int64, error) {
+	if dstOff < 0 || srcOff < 0 || n < 0 {
+		return 0, fmt.Errorf("copy range: invalid offset or length (%d, %d, %d)", dstOff, srcOff, n)
+	} else if err := dst.checkWritable(); err != nil {
+		return 0, err
+	}
+	src.mu.RLock()
+	end := srcOff + n
+	if end > src.data.totalBytes {
+		end = src.data.totalBytes
+	}
+	if end <= srcOff {
+		src.mu.RUnlock()
+		return 0, nil
+	}
+	nc := end - srcOff
+
+	// Inline data have no blocks to share, so copy them directly.
+	if src.data.inline != nil {
+		buf := make([]byte, nc)
+		_, err := src.data.readInline(buf, srcOff)
+		src.mu.RUnlock()
+		if err != nil {
+			return 0, err
+		}
+		dst.mu.Lock()
+		defer dst.mu.Unlock()
+		if _, err := dst.data.writeAt(ctx, dst.blocks(), buf, dstOff); err != nil {
+			return 0, err
+		}
+		dst.modifyDataLocked()
+		return nc, nil
+	}
+
+	exts, err := src.data.extract(ctx, src.blocks(), dst.blocks(), srcOff, end)
+	src.mu.RUnlock()
+	if err != nil {
+		return 0, err
+	}
+
+	dst.mu.Lock()
+	defer dst.mu.Unlock()
+	if err := dst.data.promote(ctx, dst.blocks()); err != nil {
+		return 0, err
+	}
+	if err := dst.data.splice(ctx, dst.blocks(), dstOff, dstOff+nc, exts); err != nil {
+		return 0, err
+	}
+	if dst.data.canInline(dst.data.totalBytes) {
+		if err := dst.data.demote(ctx, dst.blocks()); err != nil {
+			return 0, err
+		}
+	}
+	dst.modifyDataLocked()
+	return nc, nil
 }
 
 // SetData fully reads r replaces the binary contents of f with its data.
 // On success, any existing data for f are discarded. In case of error, the
-// contents of f are not changed.
-func (f *File) SetData(ctx context.Context, r io.Reader) error {
-	s := block.NewSplitter(r, f.data.sc)
-	fd, err := newFileData(s, func(data []byte) (string, error) {
-		return f.s.CASPut(ctx, data)
-	})
+// contents of f are not changed. A call to f.SetData(ctx, r) is equivalent to
+// f.SetDataWith(ctx, r, nil).
+func (f *File) SetData(ctx context.Context, r io.Reader) error { return f.SetDataWith(ctx, r, nil) }
+
+// SetDataOptions control the behavior of [File.SetDataWith]. A nil
+// *SetDataOptions is ready for use and provides default values as described.
+type SetDataOptions struct {
+	// If true, record a fingerprint of the new content in the extended
+	// attribute [FingerprintXAttr]. Otherwise, any existing fingerprint is
+	// removed.
+	Fingerprint bool
+}
+
+// FingerprintXAttr is the name of the extended attribute that records the
+// content fingerprint of a file. See [File.Fingerprint].
+const FingerprintXAttr = "ffs.fingerprint"
+
+// SetDataWith is as SetData, using the specified options.
+func (f *File) SetDataWith(ctx context.Context, r io.Reader, opts *SetDataOptions) error {
+	if err := f.checkWritable(); err != nil {
+		return err
+	}
+	var h hash.Hash
+	if opts != nil && opts.Fingerprint {
+		h = sha256.New()
+		r = io.TeeReader(r, h)
+	}
+	fd, err := f.readData(ctx, r)
 	if err != nil {
 		return err
 	}
@@ -356,9 +779,150 @@ func (f *File) SetData(ctx context.Context, r io.Reader) error {
 	defer f.mu.Unlock()
 	f.invalLocked()
 	f.data = fd
+	delete(f.xkeys, FingerprintXAttr)
+	if h != nil {
+		f.xattr[FingerprintXAttr] = fingerprintPrefix + hex.EncodeToString(h.Sum(nil))
+	} else {
+		delete(f.xattr, FingerprintXAttr)
+	}
 	return nil
 }
 
+// readData reads the contents of r into a new fileData for f. If the content
+// fits within the inline limit of f, it is stored inline.
+func (f *File) readData(ctx context.Context, r io.Reader) (fileData, error) {
+	if lim := f.data.inlineLimit; lim > 0 {
+		head := make([]byte, lim+1)
+		nr, err := io.ReadFull(r, head)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return fileData{
+				sc:          f.data.sc,
+				zc:          f.data.zc,
+				inlineLimit: lim,
+				inline:      head[:nr:nr],
+				totalBytes:  int64(nr),
+			}, nil
+		} else if err != nil {
+			return fileData{}, err
+		}
+		r = io.MultiReader(bytes.NewReader(head), r)
+	}
+	bs := f.blocks()
+	fd, err := newFileData(block.NewSplitter(r, f.data.sc), f.data.zc, func(data []byte) (string, error) {
+		return bs.CASPut(ctx, data)
+	})
+	fd.inlineLimit = f.data.inlineLimit
+	return fd, err
+}
+
+const fingerprintPrefix = "sha256:"
+
+// Fingerprint returns the content fingerprint recorded for f by SetDataWith,
+// or "" if none is recorded. A fingerprint is discarded by any operation that
+// modifies the content of f, so unless the attribute is set directly via the
+// XAttr view, a non-empty fingerprint describes the current content.
+//
+// The fingerprint has the form "sha256:" followed by the hex-encoded SHA-256
+// digest of the content. Use [ContentFingerprint] to compute a comparable
+// fingerprint for data from another source.
+func (f *File) Fingerprint() string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.xattr[FingerprintXAttr]
+}
+
+// ContentFingerprint reads r to completion and returns a fingerprint of its
+// contents, in the format reported by [File.Fingerprint].
+func ContentFingerprint(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return fingerprintPrefix + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// RechunkStats report the effect of a call to [Rechunk].
+type RechunkStats struct {
+	Blocks      int   // the number of data blocks after rechunking
+	Reused      int   // blocks whose keys were already used by the file
+	ReusedBytes int64 // total size of reused blocks
+	NewBytes    int64 // total size of blocks written to storage
+}
+
+// Rechunk rewrites the data of f by splitting its existing content with sc,
+// and replaces the index of f with the result. The content of f, and its
+// modification time, are not changed. Subsequent writes to f also use sc.
+// If sc == nil, default settings from the block package are used.
+//
+// Rechunking is useful when migrating files to new chunking parameters.
+// Where a new block boundary coincides with an old one, so that the new block
+// has the same content as an existing block of f, the existing block is
+// reused without writing it to storage again. This requires that the block
+// store of f can compute content addresses, as a [blob.CAS] can; otherwise
+// every block is written.
+func Rechunk(ctx context.Context, f *File, sc *block.SplitConfig) (RechunkStats, error) {
+	if err := sc.Validate(); err != nil {
+		return RechunkStats{}, err
+	} else if err := f.checkWritable(); err != nil {
+		return RechunkStats{}, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rechunkLocked(ctx, sc)
+}
+
+// rechunkLocked implements Rechunk. The caller must hold f.mu exclusively.
+func (f *File) rechunkLocked(ctx context.Context, sc *block.SplitConfig) (RechunkStats, error) {
+	// Inline data have no blocks to split.
+	if f.data.inline != nil {
+		f.data.sc = sc
+		return RechunkStats{}, nil
+	}
+
+	old := make(map[string]bool)
+	f.data.blocks(func(_ int64, key string) { old[key] = true })
+
+	bs := f.blocks()
+	ck, _ := bs.(interface {
+		CASKey(context.Context, []byte) string
+	})
+	var st RechunkStats
+	r := &dataReader{ctx: ctx, d: &f.data, s: bs}
+	fd, err := newFileData(block.NewSplitter(r, sc), f.data.zc, func(data []byte) (string, error) {
+		st.Blocks++
+		if ck != nil {
+			if key := ck.CASKey(ctx, data); old[key] {
+				st.Reused++
+				st.ReusedBytes += int64(len(data))
+				return key, nil
+			}
+		}
+		st.NewBytes += int64(len(data))
+		return bs.CASPut(ctx, data)
+	})
+	if err != nil {
+		return RechunkStats{}, err
+	}
+	fd.inlineLimit = f.data.inlineLimit
+	f.data = fd
+	f.invalLocked()
+	return st, nil
+}
+
+// dataReader implements io.Reader over the content of a fileData.
+type dataReader struct {
+	ctx context.Context
+	d   *fileData
+	s   BlockStore
+	pos int64
+}
+
+func (r *dataReader) Read(buf []byte) (int, error) {
+	nr, err := r.d.readAt(r.ctx, r.s, buf, r.pos)
+	r.pos += int64(nr)
+	return nr, err
+}
+
 // Name reports the attributed name of f, which may be "" if f is not a child
 // file and was not assigned a name at creation.
 func (f *File) Name() string { f.mu.RLock(); defer f.mu.RUnlock(); return f.name }
@@ -368,6 +932,31 @@ type ScanItem struct {
 	*File // the current file being visited
 
 	Name string // the name of File within its parent ("" at the root)
+
+	// Parent is the parent of File, or nil at the root. The scan holds the
+	// lock of Parent while visit runs, so the visitor must not call methods
+	// of Parent.
+	Parent *File
+
+	// RecordedKey is the storage key recorded for File in its parent when the
+	// parent was last loaded or flushed, or "" if there is none (including at
+	// the root). Unlike File.Key, it does not reflect changes to File that
+	// have not been flushed.
+	RecordedKey string
+}
+
+// Keys returns the storage keys of the blobs referenced by the current file
+// other than its own node and its children: the keys of its data blocks (see
+// Data.Keys), of the blocks of its named streams in order by name (see
+// Stream.Keys), and of its attribute values stored apart from the node (see
+// XAttr.Keys). A collector that walks a tree with Scan must retain these keys,
+// along with the key of each file, to keep the tree intact.
+func (s ScanItem) Keys() []string {
+	keys := s.Data().Keys()
+	for _, name := range s.Streams() {
+		keys = append(keys, s.Stream(name).Keys()...)
+	}
+	return append(keys, s.XAttr().Keys()...)
 }
 
 // Scan recursively visits f and all its descendants in depth-first
@@ -380,7 +969,7 @@ type ScanItem struct {
 func (f *File) Scan(ctx context.Context, visit func(ScanItem) bool) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
-	return f.recScanLocked(ctx, "", func(s ScanItem) bool {
+	return f.recScanLocked(ctx, ScanItem{File: f}, func(s ScanItem) bool {
 		// Yield the lock while the caller visitor runs, then reacquire it.  We
 		// do this so that the visitor can use methods that may themselves update
 		// the file, without deadlocking on the scan.
@@ -391,12 +980,12 @@ func (f *File) Scan(ctx context.Context, visit func(ScanItem) bool) error {
 }
 
 // recScanLocked recursively scans f and all its child nodes in depth-first
-// left-to-right order, calling visit for each file.
-func (f *File) recScanLocked(ctx context.Context, name string, visit func(ScanItem) bool) error {
+// left-to-right order, calling visit for each file. The item describes f.
+func (f *File) recScanLocked(ctx context.Context, item ScanItem, visit func(ScanItem) bool) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
-	if !visit(ScanItem{File: f, Name: name}) {
+	if !visit(item) {
 		return nil // skip the descendants of f
 	}
 	for i, kid := range f.kids {
@@ -405,7 +994,7 @@ func (f *File) recScanLocked(ctx context.Context, name string, visit func(ScanIt
 			// If the child was not already open, we need to do so to scan it, but
 			// we won't persist it in the parent unless the visitor invalidated it.
 			var err error
-			fp, err = Open(ctx, f.s, kid.Key)
+			fp, err = f.openChild(ctx, kid.Key)
 			if err != nil {
 				return err
 			}
@@ -413,7 +1002,12 @@ func (f *File) recScanLocked(ctx context.Context, name string, visit func(ScanIt
 		err := func() error {
 			fp.mu.Lock()
 			defer fp.mu.Unlock()
-			return fp.recScanLocked(ctx, kid.Name, visit)
+			return fp.recScanLocked(ctx, ScanItem{
+				File:        fp,
+				Name:        kid.Name,
+				Parent:      f,
+				RecordedKey: kid.Key,
+			}, visit)
 		}()
 		if err != nil {
 			return err
@@ -452,7 +1046,15 @@ func (f *File) fromWireType(obj *wiretype.Object) error {
 	f.saveStat = pb.Node.Stat != nil
 
 	f.xattr = make(map[string]string)
+	f.xkeys = nil
 	for _, xa := range pb.Node.XAttrs {
+		if len(xa.Key) != 0 {
+			if f.xkeys == nil {
+				f.xkeys = make(map[string]string)
+			}
+			f.xkeys[xa.Name] = string(xa.Key) // the caller loads the value
+			continue
+		}
 		f.xattr[xa.Name] = string(xa.Value)
 	}
 
@@ -463,6 +1065,9 @@ func (f *File) fromWireType(obj *wiretype.Object) error {
 			Key:  string(kid.Key),
 		})
 	}
+	if err := f.streamsFromWireType(pb.Node.Streams); err != nil {
+		return fmt.Errorf("streams: %w", err)
+	}
 	return nil
 }
 
@@ -471,10 +1076,14 @@ func (f *File) toWireTypeLocked() *wiretype.Object {
 	if f.saveStat {
 		n.Stat = f.stat.toWireType()
 	}
-	for name, value := range f.xattr {
+	for _, name := range slices.Sorted(maps.Keys(f.xattr)) {
+		if xkey, ok := f.xkeys[name]; ok {
+			n.XAttrs = append(n.XAttrs, &wiretype.XAttr{Name: name, Key: []byte(xkey)})
+			continue
+		}
 		n.XAttrs = append(n.XAttrs, &wiretype.XAttr{
 			Name:  name,
-			Value: []byte(value),
+			Value: []byte(f.xattr[name]),
 		})
 	}
 	for _, kid := range f.kids {
@@ -483,6 +1092,7 @@ func (f *File) toWireTypeLocked() *wiretype.Object {
 			Key:  []byte(kid.Key),
 		})
 	}
+	n.Streams = f.streamsToWireType()
 	n.Normalize()
 	return &wiretype.Object{Value: &wiretype.Object_Node{Node: n}}
 }
@@ -15,15 +15,19 @@
 package file_test
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"log"
 	"math/rand"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -32,6 +36,7 @@ import (
 	"github.com/creachadair/ffs/block"
 	"github.com/creachadair/ffs/file"
 	"github.com/creachadair/ffs/file/wiretype"
+	"github.com/creachadair/mds/mapset"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"google.golang.org/protobuf/encoding/prototext"
@@ -182,10 +187,27 @@ func TestScan(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Open %x failed: %v", key, err)
 	}
+	// Each item reports its parent and the key recorded for it there, which
+	// matches its own key since nothing has changed since the flush.
+	parents := map[string]string{"4": "1", "B": "A", "2": "1", "3": "2", "6": "5", "7": "6", "8": "7"}
+	names := make(map[*file.File]string)
 	if err := alt.Scan(ctx, func(e file.ScanItem) bool {
+		names[e.File] = e.Name
 		if got := e.File.XAttr().Get("name"); got != e.Name {
 			t.Errorf("File %p name: got %q, want %q", e.File, got, e.Name)
 		}
+		if e.Parent == nil {
+			if e.File != alt || e.RecordedKey != "" {
+				t.Errorf("Root item: got (%p, %q), want (%p, empty key)", e.File, e.RecordedKey, alt)
+			}
+			return true
+		}
+		if got, want := names[e.Parent], parents[e.Name]; got != want {
+			t.Errorf("File %q parent: got %q, want %q", e.Name, got, want)
+		}
+		if e.RecordedKey == "" || e.RecordedKey != e.File.Key() {
+			t.Errorf("File %q recorded key: got %x, want %x", e.Name, e.RecordedKey, e.File.Key())
+		}
 		return true
 	}); err != nil {
 		t.Errorf("Scan failed: %v", err)
@@ -231,6 +253,145 @@ func TestChild(t *testing.T) {
 	}
 }
 
+func TestNormalizeName(t *testing.T) {
+	cas := blob.CASFromKV(memstore.NewKV())
+	ctx := context.Background()
+
+	// A stand-in for NFC normalization of the names used here.
+	nfc := strings.NewReplacer("e\u0301", "\u00e9").Replace
+	const nfd, composed = "cafe\u0301", "caf\u00e9"
+
+	root := file.New(cas, &file.NewOptions{NormalizeName: nfc})
+	kid := root.New(nil)
+	root.Child().Set(nfd, kid)
+	if !root.Child().Has(composed) {
+		t.Errorf("Has(%q): got false, want true", composed)
+	}
+
+	// Setting the other spelling replaces the child and keeps its name.
+	other := root.New(nil)
+	if _, replaced := root.Child().Swap(composed, other); !replaced {
+		t.Errorf("Swap(%q): got replaced false, want true", composed)
+	}
+	if diff := cmp.Diff(root.Child().Names(), []string{nfd}); diff != "" {
+		t.Errorf("Names (-got, +want):\n%s", diff)
+	}
+	if got := other.Name(); got != nfd {
+		t.Errorf("Name: got %q, want %q", got, nfd)
+	}
+
+	// The normalizer is inherited by opened files.
+	rkey, err := root.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	rc, err := file.OpenWith(ctx, cas, rkey, &file.OpenOptions{NormalizeName: nfc})
+	if err != nil {
+		t.Fatalf("OpenWith: %v", err)
+	}
+	if c, err := rc.Open(ctx, composed); err != nil {
+		t.Errorf("Open(%q): %v", composed, err)
+	} else if got := c.Name(); got != nfd {
+		t.Errorf("Open(%q): got name %q, want %q", composed, got, nfd)
+	}
+	if !rc.Child().Remove(composed) {
+		t.Errorf("Remove(%q): got false, want true", composed)
+	}
+
+	// Without a normalizer, the names are distinct.
+	plain := file.New(cas, nil)
+	plain.Child().Set(nfd, plain.New(nil))
+	plain.Child().Set(composed, plain.New(nil))
+	if n := plain.Child().Len(); n != 2 {
+		t.Errorf("Len: got %d, want 2", n)
+	}
+}
+
+func TestDataChange(t *testing.T) {
+	cas := blob.CASFromKV(memstore.NewKV())
+	ctx := context.Background()
+	f := file.New(cas, nil)
+	d := f.Data()
+
+	nw, dc, err := d.WriteAt(ctx, []byte("hello"), 0)
+	if err != nil {
+		t.Fatalf("WriteAt: %v", err)
+	} else if nw != 5 || dc != (file.DataChange{Size: 5, Blocks: 1}) {
+		t.Errorf("WriteAt: got (%d, %+v), want (5, {5 1})", nw, dc)
+	}
+	if _, dc, err := d.WriteAt(ctx, []byte("world"), 10); err != nil {
+		t.Fatalf("WriteAt: %v", err)
+	} else if dc.Size != 15 {
+		t.Errorf("WriteAt: got size %d, want 15", dc.Size)
+	}
+
+	// Extending the file does not store any blocks.
+	if dc, err := d.Truncate(ctx, 100); err != nil {
+		t.Fatalf("Truncate: %v", err)
+	} else if dc != (file.DataChange{Size: 100}) {
+		t.Errorf("Truncate: got %+v, want {100 0}", dc)
+	}
+	if dc, err := d.Truncate(ctx, 3); err != nil {
+		t.Fatalf("Truncate: %v", err)
+	} else if dc.Size != 3 || dc.Size != d.Size() {
+		t.Errorf("Truncate: got size %d, want 3", dc.Size)
+	}
+}
+
+func TestPreserveModTime(t *testing.T) {
+	cas := blob.CASFromKV(memstore.NewKV())
+	ctx := context.Background()
+	orig := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+
+	root := file.New(cas, &file.NewOptions{
+		Stat:            &file.Stat{Mode: fs.ModeDir | 0755, ModTime: orig},
+		PersistStat:     true,
+		PreserveModTime: true,
+	})
+	kid := root.New(&file.NewOptions{Stat: &file.Stat{Mode: 0644, ModTime: orig}})
+	if _, err := kid.WriteAt(ctx, []byte("hello"), 0); err != nil {
+		t.Fatalf("WriteAt: %v", err)
+	}
+	kid.XAttr().Set("a", "b")
+	root.Child().Set("kid", kid)
+	for _, f := range []*file.File{root, kid} {
+		if got := f.Stat().ModTime; !got.Equal(orig) {
+			t.Errorf("ModTime of %q: got %v, want %v", f.Name(), got, orig)
+		}
+	}
+
+	// An explicit update still takes effect.
+	later := orig.Add(time.Hour)
+	st := kid.Stat()
+	st.ModTime = later
+	st.Update()
+	if got := kid.Stat().ModTime; !got.Equal(later) {
+		t.Errorf("ModTime after update: got %v, want %v", got, later)
+	}
+
+	// Opened files inherit the setting, and otherwise changes set the time.
+	rkey, err := root.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	for _, keep := range []bool{true, false} {
+		rc, err := file.OpenWith(ctx, cas, rkey, &file.OpenOptions{PreserveModTime: keep})
+		if err != nil {
+			t.Fatalf("OpenWith: %v", err)
+		}
+		c, err := rc.Open(ctx, "kid")
+		if err != nil {
+			t.Fatalf("Open kid: %v", err)
+		}
+		if _, err := c.WriteAt(ctx, []byte("!"), 5); err != nil {
+			t.Fatalf("WriteAt: %v", err)
+		}
+		if got := c.Stat().ModTime.Equal(later); got != keep {
+			t.Errorf("Keep %v: ModTime unchanged is %v, want %v", keep, got, keep)
+		}
+	}
+}
+
 func TestCycleCheck(t *testing.T) {
 	cas := blob.CASFromKV(memstore.NewKV())
 	ctx := context.Background()
@@ -246,6 +407,11 @@ func TestCycleCheck(t *testing.T) {
 	} else {
 		t.Logf("Cyclic flush correctly failed: %v", err)
 	}
+
+	key, err = root.FlushWith(ctx, &file.FlushOptions{Snapshot: true})
+	if err == nil {
+		t.Errorf("Cyclic snapshot flush: got %q, nil, want error", key)
+	}
 }
 
 func TestSetData(t *testing.T) {
@@ -390,3 +556,1621 @@ func (lineHash) Update(b byte) uint64 {
 	}
 	return 2
 }
+
+func TestReadOnly(t *testing.T) {
+	cas := blob.CASFromKV(memstore.NewKV())
+	ctx := context.Background()
+
+	root := file.New(cas, &file.NewOptions{
+		Stat:        &file.Stat{Mode: 0755 | fs.ModeDir},
+		PersistStat: true,
+	})
+	root.XAttr().Set("color", "blue")
+	kid := root.New(nil)
+	if _, err := kid.WriteAt(ctx, []byte("hello, world"), 0); err != nil {
+		t.Fatalf("WriteAt failed: %v", err)
+	}
+	root.Child().Set("kid", kid)
+	rkey, err := root.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	v, err := file.OpenReadOnly(ctx, cas, rkey)
+	if err != nil {
+		t.Fatalf("OpenReadOnly %x: %v", rkey, err)
+	}
+	if got := v.Key(); got != rkey {
+		t.Errorf("Key: got %x, want %x", got, rkey)
+	}
+	if got := v.Stat().Mode; got != 0755|fs.ModeDir {
+		t.Errorf("Stat mode: got %v, want %v", got, 0755|fs.ModeDir)
+	}
+	if got, ok := v.XAttr("color"); !ok || got != "blue" {
+		t.Errorf("XAttr color: got %q, %v; want blue, true", got, ok)
+	}
+	if diff := cmp.Diff([]string{"kid"}, v.ChildNames()); diff != "" {
+		t.Errorf("ChildNames (-want, +got):\n%s", diff)
+	}
+
+	kv, err := v.Open(ctx, "kid")
+	if err != nil {
+		t.Fatalf("Open kid: %v", err)
+	}
+	if got, want := kv.Size(), int64(len("hello, world")); got != want {
+		t.Errorf("Size: got %d, want %d", got, want)
+	}
+	bits, err := io.ReadAll(kv.Reader(ctx))
+	if err != nil {
+		t.Fatalf("Read kid: %v", err)
+	} else if got := string(bits); got != "hello, world" {
+		t.Errorf("Read kid: got %q, want %q", got, "hello, world")
+	}
+	fi := kv.FileInfo()
+	if fi.Name() != "kid" || fi.Size() != int64(len("hello, world")) {
+		t.Errorf("FileInfo: got (%q, %d), want (kid, %d)", fi.Name(), fi.Size(), len("hello, world"))
+	}
+	if _, ok := fi.Sys().(*file.File); ok {
+		t.Error("FileInfo: Sys exposes the underlying *File")
+	} else if _, ok := fi.Sys().(file.Meta); !ok {
+		t.Errorf("FileInfo: Sys is %T, want file.Meta", fi.Sys())
+	}
+	if _, err := v.Open(ctx, "nonesuch"); !errors.Is(err, file.ErrChildNotFound) {
+		t.Errorf("Open nonesuch: got %v, want %v", err, file.ErrChildNotFound)
+	}
+
+	// Reading through the view must not invalidate the file.
+	if got := v.Key(); got != rkey {
+		t.Errorf("Key after read: got %x, want %x", got, rkey)
+	}
+}
+
+func TestReadOnlyTree(t *testing.T) {
+	cas := blob.CASFromKV(memstore.NewKV())
+	ctx := context.Background()
+
+	root := file.New(cas, &file.NewOptions{Stat: &file.Stat{Mode: fs.ModeDir | 0755}})
+	kid := root.New(nil)
+	if err := kid.SetData(ctx, strings.NewReader("hello, world")); err != nil {
+		t.Fatalf("SetData: %v", err)
+	}
+	root.Child().Set("kid", kid)
+	rkey, err := root.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	ro, err := file.OpenWith(ctx, cas, rkey, &file.OpenOptions{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("OpenWith %x: %v", rkey, err)
+	}
+	rk, err := ro.Open(ctx, "kid")
+	if err != nil {
+		t.Fatalf("Open kid: %v", err)
+	}
+	if !ro.IsReadOnly() || !rk.IsReadOnly() {
+		t.Errorf("IsReadOnly: got (%v, %v), want (true, true)", ro.IsReadOnly(), rk.IsReadOnly())
+	}
+	if ro.New(nil).IsReadOnly() {
+		t.Error("New file from a read-only file should be writable")
+	}
+
+	// Reading works as usual.
+	buf := make([]byte, 5)
+	if _, err := rk.ReadAt(ctx, buf, 0); err != nil {
+		t.Errorf("ReadAt: %v", err)
+	} else if got := string(buf); got != "hello" {
+		t.Errorf("ReadAt: got %q, want %q", got, "hello")
+	}
+
+	// Modifications that report errors fail.
+	checkErr := func(op string, err error) {
+		t.Helper()
+		if !errors.Is(err, file.ErrReadOnly) {
+			t.Errorf("%s: got %v, want %v", op, err, file.ErrReadOnly)
+		}
+	}
+	_, err = rk.WriteAt(ctx, []byte("x"), 0)
+	checkErr("WriteAt", err)
+	checkErr("Truncate", rk.Truncate(ctx, 0))
+	checkErr("SetData", rk.SetData(ctx, strings.NewReader("x")))
+	_, err = file.CopyRange(ctx, rk, 0, kid, 0, 5)
+	checkErr("CopyRange", err)
+	_, err = file.Rechunk(ctx, rk, nil)
+	checkErr("Rechunk", err)
+	checkErr("SetE", ro.Child().SetE("other", ro.New(nil)))
+	_, err = rk.Stream("s").WriteAt(ctx, []byte("x"), 0)
+	checkErr("Stream WriteAt", err)
+
+	// Modifications that do not report errors panic.
+	mustPanic := func(op string, f func()) {
+		t.Helper()
+		defer func() {
+			if recover() == nil {
+				t.Errorf("%s: did not panic", op)
+			}
+		}()
+		f()
+	}
+	mustPanic("Set", func() { ro.Child().Set("other", ro.New(nil)) })
+	mustPanic("Swap", func() { ro.Child().Swap("kid", ro.New(nil)) })
+	mustPanic("SetNew", func() { ro.Child().SetNew("other", ro.New(nil)) })
+	mustPanic("Remove", func() { ro.Child().Remove("kid") })
+	mustPanic("XAttr Set", func() { rk.XAttr().Set("a", "b") })
+	mustPanic("XAttr Remove", func() { rk.XAttr().Remove("a") })
+	mustPanic("XAttr Clear", func() { rk.XAttr().Clear() })
+	mustPanic("Stat Update", func() { rk.Stat().Update() })
+	mustPanic("Stat Clear", func() { rk.Stat().Clear() })
+	mustPanic("Stat Persist", func() { rk.Stat().Persist(true) })
+	mustPanic("Stream Remove", func() { rk.Stream("s").Remove() })
+
+	// A copy out of a read-only file is fine.
+	if _, err := file.CopyRange(ctx, kid, 0, rk, 0, 5); err != nil {
+		t.Errorf("CopyRange from read-only: %v", err)
+	}
+
+	// Nothing was modified, so flushing does not write anything new.
+	if got, err := ro.Flush(ctx); err != nil {
+		t.Errorf("Flush: %v", err)
+	} else if got != rkey {
+		t.Errorf("Flush: got key %x, want %x", got, rkey)
+	}
+}
+
+func TestBlockStore(t *testing.T) {
+	nodes := memstore.NewKV()
+	blocks := memstore.NewKV()
+	cas := blob.CASFromKV(nodes)
+	bs := blob.CASFromKV(blocks)
+	ctx := context.Background()
+
+	const testData = "Where the bee sucks, there suck I"
+	root := file.New(cas, &file.NewOptions{Blocks: bs})
+	kid := root.New(nil)
+	if _, err := kid.WriteAt(ctx, []byte(testData), 0); err != nil {
+		t.Fatalf("WriteAt failed: %v", err)
+	}
+	root.Child().Set("kid", kid)
+	rkey, err := root.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	// The data block should be in the block store, not with the nodes.
+	dkeys := kid.Data().Keys()
+	if len(dkeys) != 1 {
+		t.Fatalf("Data keys: got %d, want 1", len(dkeys))
+	}
+	if got, err := blocks.Get(ctx, dkeys[0]); err != nil || string(got) != testData {
+		t.Errorf("Block get: got (%q, %v), want (%q, nil)", got, err, testData)
+	}
+	if _, err := nodes.Get(ctx, dkeys[0]); !blob.IsKeyNotFound(err) {
+		t.Errorf("Node get: got %v, want %v", err, blob.ErrKeyNotFound)
+	}
+	if _, err := blocks.Get(ctx, rkey); !blob.IsKeyNotFound(err) {
+		t.Errorf("Block get root: got %v, want %v", err, blob.ErrKeyNotFound)
+	}
+
+	// Reopening with the block store should let children read their data.
+	alt, err := file.OpenWith(ctx, cas, rkey, &file.OpenOptions{Blocks: bs})
+	if err != nil {
+		t.Fatalf("OpenWith failed: %v", err)
+	}
+	akid, err := alt.Open(ctx, "kid")
+	if err != nil {
+		t.Fatalf("Open kid: %v", err)
+	}
+	bits, err := io.ReadAll(akid.Cursor(ctx))
+	if err != nil {
+		t.Fatalf("Read kid: %v", err)
+	} else if got := string(bits); got != testData {
+		t.Errorf("Read kid: got %q, want %q", got, testData)
+	}
+}
+
+// cancelCAS is a blob.CAS that calls a cancel function after each CASPut.
+type cancelCAS struct {
+	blob.CAS
+	cancel func()
+}
+
+func (c cancelCAS) CASPut(ctx context.Context, data []byte) (string, error) {
+	defer c.cancel()
+	return c.CAS.CASPut(ctx, data)
+}
+
+func TestFlushResume(t *testing.T) {
+	base := blob.CASFromKV(memstore.NewKV())
+	ctx := context.Background()
+	cas := &cancelCAS{CAS: base, cancel: func() {}}
+
+	root := file.New(cas, nil)
+	root.Child().Set("a", root.New(nil))
+	root.Child().Set("b", root.New(nil))
+	okey, err := root.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	// Modify a but not b, and arrange for the context to end right after a is
+	// saved, so that the flush fails before visiting b.
+	a, err := root.Open(ctx, "a")
+	if err != nil {
+		t.Fatalf("Open a: %v", err)
+	}
+	a.XAttr().Set("modified", "yes")
+
+	cctx, cancel := context.WithCancel(ctx)
+	cas.cancel = cancel
+	if key, err := root.Flush(cctx); err == nil {
+		t.Fatalf("Flush: got %x, want error", key)
+	}
+	cas.cancel = func() {}
+
+	// The retry should notice that a changed, even though it is now clean.
+	nkey, err := root.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush retry failed: %v", err)
+	}
+	if nkey == okey {
+		t.Errorf("Flush retry: key %x did not change", nkey)
+	}
+
+	alt, err := file.Open(ctx, base, nkey)
+	if err != nil {
+		t.Fatalf("Open %x: %v", nkey, err)
+	}
+	aa, err := alt.Open(ctx, "a")
+	if err != nil {
+		t.Fatalf("Open a: %v", err)
+	}
+	if got := aa.XAttr().Get("modified"); got != "yes" {
+		t.Errorf("Reloaded a: got xattr %q, want yes", got)
+	}
+}
+
+func TestCopyRange(t *testing.T) {
+	kv := memstore.NewKV()
+	cas := blob.CASFromKV(kv)
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(20250101))
+
+	randBytes := func(n int) []byte {
+		const alphabet = "abcdefghijklmnopqrstuvwxyz\x00\x00\x00"
+		buf := make([]byte, n)
+		for i := range buf {
+			buf[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		return buf
+	}
+	copyModel := func(dst []byte, dstOff int, src []byte, srcOff, n int) ([]byte, int) {
+		end := min(srcOff+n, len(src))
+		if end <= srcOff {
+			return dst, 0
+		}
+		chunk := append([]byte(nil), src[srcOff:end]...)
+		if need := dstOff + len(chunk); need > len(dst) {
+			dst = append(dst, make([]byte, need-len(dst))...)
+		}
+		copy(dst[dstOff:], chunk)
+		return dst, len(chunk)
+	}
+	check := func(t *testing.T, f *file.File, want []byte) {
+		t.Helper()
+		if got := f.Data().Size(); got != int64(len(want)) {
+			t.Errorf("Size: got %d, want %d", got, len(want))
+		}
+		got, err := io.ReadAll(f.Cursor(ctx))
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("Contents differ after copy (got %d bytes, want %d)", len(got), len(want))
+		}
+	}
+
+	sc := &block.SplitConfig{Min: 64, Size: 256, Max: 1024}
+	src := file.New(cas, &file.NewOptions{Split: sc})
+	dst := src.New(nil)
+	srcData := randBytes(20000)
+	dstData := randBytes(5000)
+	if _, err := src.WriteAt(ctx, srcData, 0); err != nil {
+		t.Fatalf("WriteAt src: %v", err)
+	}
+	if _, err := dst.WriteAt(ctx, dstData, 0); err != nil {
+		t.Fatalf("WriteAt dst: %v", err)
+	}
+
+	t.Run("Shared", func(t *testing.T) {
+		// A large copy should share the interior blocks of the source.
+		before, _ := kv.Len(ctx)
+		n, err := file.CopyRange(ctx, dst, 1000, src, 1500, 15000)
+		if err != nil || n != 15000 {
+			t.Fatalf("CopyRange: got (%d, %v), want (15000, nil)", n, err)
+		}
+		dstData, _ = copyModel(dstData, 1000, srcData, 1500, 15000)
+		check(t, dst, dstData)
+		after, _ := kv.Len(ctx)
+		if added := after - before; added > 8 {
+			t.Errorf("CopyRange stored %d new blocks, want at most 8", added)
+		}
+	})
+
+	t.Run("Random", func(t *testing.T) {
+		for i := range 200 {
+			dstOff := rng.Intn(len(dstData) + 500)
+			srcOff := rng.Intn(len(srcData) + 100)
+			n := rng.Intn(3000)
+			got, err := file.CopyRange(ctx, dst, int64(dstOff), src, int64(srcOff), int64(n))
+			if err != nil {
+				t.Fatalf("CopyRange %d: unexpected error: %v", i, err)
+			}
+			var want int
+			dstData, want = copyModel(dstData, dstOff, srcData, srcOff, n)
+			if got != int64(want) {
+				t.Errorf("CopyRange %d: copied %d bytes, want %d", i, got, want)
+			}
+		}
+		check(t, dst, dstData)
+		check(t, src, srcData)
+	})
+
+	t.Run("Overlap", func(t *testing.T) {
+		for _, tc := range []struct{ dstOff, srcOff, n int }{
+			{100, 0, 5000}, {0, 100, 5000}, {7, 3, 11}, {0, 0, 20000},
+		} {
+			if _, err := file.CopyRange(ctx, src, int64(tc.dstOff), src, int64(tc.srcOff), int64(tc.n)); err != nil {
+				t.Fatalf("CopyRange %+v: unexpected error: %v", tc, err)
+			}
+			srcData, _ = copyModel(srcData, tc.dstOff, srcData, tc.srcOff, tc.n)
+		}
+		check(t, src, srcData)
+	})
+
+	t.Run("Persist", func(t *testing.T) {
+		key, err := dst.Flush(ctx)
+		if err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+		f, err := file.Open(ctx, cas, key)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		check(t, f, dstData)
+	})
+
+	if _, err := file.CopyRange(ctx, dst, -1, src, 0, 1); err == nil {
+		t.Error("CopyRange with negative offset: got nil, want error")
+	}
+}
+
+func TestLargeXAttr(t *testing.T) {
+	cas := blob.CASFromKV(memstore.NewKV())
+	ctx := context.Background()
+
+	big := strings.Repeat("0123456789abcdef", 2*file.MaxInlineXAttr/16)
+	f := file.New(cas, nil)
+	f.XAttr().Set("small", "value")
+	f.XAttr().Set("big", big)
+
+	key, err := f.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if xa := findXAttr(file.Encode(f), "big"); xa == nil || len(xa.Key) == 0 || len(xa.Value) != 0 {
+		t.Errorf("Encoded big xattr: got %v, want key only", xa)
+	}
+	if xa := findXAttr(file.Encode(f), "small"); xa == nil || len(xa.Key) != 0 || string(xa.Value) != "value" {
+		t.Errorf("Encoded small xattr: got %v, want inline value", xa)
+	}
+	if bits, err := cas.Get(ctx, key); err != nil {
+		t.Fatalf("Get node: %v", err)
+	} else if len(bits) >= len(big) {
+		t.Errorf("Node size is %d bytes, want less than %d", len(bits), len(big))
+	}
+
+	g, err := file.Open(ctx, cas, key)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if got := g.XAttr().Get("big"); got != big {
+		t.Errorf("Get big: got %d bytes, want %d", len(got), len(big))
+	}
+	if got := g.XAttr().Get("small"); got != "value" {
+		t.Errorf("Get small: got %q, want %q", got, "value")
+	}
+
+	// Replacing a spilled value with a short one stores it inline again.
+	g.XAttr().Set("big", "short")
+	if _, err := g.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if xa := findXAttr(file.Encode(g), "big"); xa == nil || len(xa.Key) != 0 || string(xa.Value) != "short" {
+		t.Errorf("Encoded big xattr: got %v, want inline value", xa)
+	}
+}
+
+func findXAttr(obj *wiretype.Object, name string) *wiretype.XAttr {
+	for _, xa := range obj.GetNode().XAttrs {
+		if xa.Name == name {
+			return xa
+		}
+	}
+	return nil
+}
+
+func TestChildNames(t *testing.T) {
+	cas := blob.CASFromKV(memstore.NewKV())
+
+	for _, name := range []string{"", ".", "..", "a/b", "/", "nul\x00byte"} {
+		if err := file.CheckName(name); !errors.Is(err, file.ErrInvalidName) {
+			t.Errorf("CheckName(%q): got %v, want %v", name, err, file.ErrInvalidName)
+		}
+	}
+	for _, name := range []string{"a", "...", ".hidden", "with space", "ünïcode"} {
+		if err := file.CheckName(name); err != nil {
+			t.Errorf("CheckName(%q): unexpected error: %v", name, err)
+		}
+	}
+
+	root := file.New(cas, nil)
+	if err := root.Child().SetE("..", root.New(nil)); !errors.Is(err, file.ErrInvalidName) {
+		t.Errorf("SetE(..): got %v, want %v", err, file.ErrInvalidName)
+	}
+	if err := root.Child().SetE("ok", root.New(nil)); err != nil {
+		t.Errorf("SetE(ok): unexpected error: %v", err)
+	}
+	if got := root.Child().Names(); !slices.Equal(got, []string{"ok"}) {
+		t.Errorf("Names: got %q, want [ok]", got)
+	}
+
+	// A custom name check is inherited by descendants.
+	noCaps := func(name string) error {
+		if strings.ToLower(name) != name {
+			return fmt.Errorf("%w: %q has capitals", file.ErrInvalidName, name)
+		}
+		return nil
+	}
+	custom := file.New(cas, &file.NewOptions{CheckName: noCaps})
+	kid := custom.New(nil)
+	if err := kid.Child().SetE("Upper", custom.New(nil)); !errors.Is(err, file.ErrInvalidName) {
+		t.Errorf("SetE(Upper): got %v, want %v", err, file.ErrInvalidName)
+	}
+	if err := kid.Child().SetE("..", custom.New(nil)); err != nil {
+		t.Errorf("SetE(..) with custom check: unexpected error: %v", err)
+	}
+}
+
+func TestDataHash(t *testing.T) {
+	cas := blob.CASFromKV(memstore.NewKV())
+	ctx := context.Background()
+
+	f := file.New(cas, nil)
+	empty := f.Data().Hash()
+
+	if _, err := f.WriteAt(ctx, []byte("hello, world"), 0); err != nil {
+		t.Fatalf("WriteAt: %v", err)
+	}
+	h1 := f.Data().Hash()
+	if bytes.Equal(h1, empty) {
+		t.Errorf("Hash did not change after write: %x", h1)
+	}
+
+	// Files with the same content have the same hash.
+	g := file.New(cas, nil)
+	if err := g.SetData(ctx, strings.NewReader("hello, world")); err != nil {
+		t.Fatalf("SetData: %v", err)
+	}
+	if h := g.Data().Hash(); !bytes.Equal(h, h1) {
+		t.Errorf("Hash of same content: got %x, want %x", h, h1)
+	}
+
+	// The hash survives a round trip through storage.
+	key, err := f.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	r, err := file.Open(ctx, cas, key)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if h := r.Data().Hash(); !bytes.Equal(h, h1) {
+		t.Errorf("Hash after reload: got %x, want %x", h, h1)
+	}
+
+	// Truncation back to empty restores the empty hash.
+	if err := f.Truncate(ctx, 0); err != nil {
+		t.Fatalf("Truncate: %v", err)
+	}
+	if h := f.Data().Hash(); !bytes.Equal(h, empty) {
+		t.Errorf("Hash after truncate: got %x, want %x", h, empty)
+	}
+}
+
+func TestRechunk(t *testing.T) {
+	cas := blob.CASFromKV(memstore.NewKV())
+	ctx := context.Background()
+
+	small := &block.SplitConfig{Min: 256, Size: 1024, Max: 4096}
+	f := file.New(cas, &file.NewOptions{Split: small})
+
+	rng := rand.New(rand.NewSource(1))
+	want := make([]byte, 64<<10)
+	rng.Read(want)
+	clear(want[20<<10 : 30<<10]) // a hole in the middle
+	if err := f.SetData(ctx, bytes.NewReader(want)); err != nil {
+		t.Fatalf("SetData: %v", err)
+	}
+	want = append(want, make([]byte, 5000)...)
+	if err := f.Truncate(ctx, int64(len(want))); err != nil { // a hole at the end
+		t.Fatalf("Truncate: %v", err)
+	}
+	mtime := f.Stat().ModTime
+
+	checkContent := func(t *testing.T) {
+		t.Helper()
+		got := make([]byte, len(want)+10)
+		nr, err := f.ReadAt(ctx, got, 0)
+		if err != nil && !errors.Is(err, io.EOF) {
+			t.Fatalf("ReadAt: %v", err)
+		}
+		if !bytes.Equal(got[:nr], want) {
+			t.Errorf("Content differs after rechunk (got %d bytes, want %d)", nr, len(want))
+		}
+	}
+
+	// Rechunking with the same settings reuses all the blocks.
+	nb := f.Data().Len()
+	st, err := file.Rechunk(ctx, f, small)
+	if err != nil {
+		t.Fatalf("Rechunk: %v", err)
+	}
+	if st.Blocks != nb || st.Reused != nb || st.NewBytes != 0 {
+		t.Errorf("Rechunk same: got %+v, want %d blocks all reused", st, nb)
+	}
+	checkContent(t)
+
+	// Rechunking with larger blocks writes new blocks.
+	large := &block.SplitConfig{Min: 4096, Size: 16384, Max: 65536}
+	st, err = file.Rechunk(ctx, f, large)
+	if err != nil {
+		t.Fatalf("Rechunk: %v", err)
+	}
+	if n := f.Data().Len(); st.Blocks != n || n >= nb {
+		t.Errorf("Rechunk large: got %d blocks (stats %+v), want fewer than %d", n, st, nb)
+	}
+	if st.NewBytes == 0 {
+		t.Errorf("Rechunk large: got %+v, want new data", st)
+	}
+	checkContent(t)
+	if got := f.Stat().ModTime; !got.Equal(mtime) {
+		t.Errorf("ModTime: got %v, want %v", got, mtime)
+	}
+
+	// The content survives a round trip through storage.
+	key, err := f.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if f, err = file.Open(ctx, cas, key); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	checkContent(t)
+
+	if _, err := file.Rechunk(ctx, f, &block.SplitConfig{Min: 100, Max: 10}); err == nil {
+		t.Error("Rechunk with invalid config: got nil, want error")
+	}
+}
+
+func TestFlushSnapshot(t *testing.T) {
+	cas := blob.CASFromKV(memstore.NewKV())
+	ctx := context.Background()
+	snap := &file.FlushOptions{Snapshot: true}
+
+	root := file.New(cas, nil)
+	a := root.New(&file.NewOptions{Name: "a"})
+	b := root.New(&file.NewOptions{Name: "b"})
+	root.Child().Set("a", a)
+	root.Child().Set("b", b)
+	a.Child().Set("c", root.New(nil))
+	root.XAttr().Set("big", strings.Repeat("x", file.MaxInlineXAttr+1))
+	if _, err := a.WriteAt(ctx, []byte("alpha"), 0); err != nil {
+		t.Fatalf("WriteAt: %v", err)
+	}
+
+	// A snapshot flush records the same keys as an ordinary flush.
+	rkey, err := root.FlushWith(ctx, snap)
+	if err != nil {
+		t.Fatalf("FlushWith: %v", err)
+	}
+	if got := root.Key(); got != rkey {
+		t.Errorf("Root key: got %x, want %x", got, rkey)
+	}
+	if a.Key() == "" || b.Key() == "" {
+		t.Errorf("Child keys not recorded: a=%x b=%x", a.Key(), b.Key())
+	}
+	if got, err := root.Flush(ctx); err != nil || got != rkey {
+		t.Errorf("Flush: got (%x, %v), want (%x, nil)", got, err, rkey)
+	}
+	if xa := findXAttr(file.Encode(root), "big"); xa == nil || len(xa.Key) == 0 {
+		t.Errorf("Large xattr was not spilled: %v", xa)
+	}
+
+	// Modifying a child is picked up by the next snapshot.
+	if _, err := b.WriteAt(ctx, []byte("bravo"), 0); err != nil {
+		t.Fatalf("WriteAt: %v", err)
+	}
+	rkey2, err := root.FlushWith(ctx, snap)
+	if err != nil {
+		t.Fatalf("FlushWith: %v", err)
+	}
+	if rkey2 == rkey {
+		t.Error("Root key did not change after modifying a child")
+	}
+	if got, err := root.Flush(ctx); err != nil || got != rkey2 {
+		t.Errorf("Flush: got (%x, %v), want (%x, nil)", got, err, rkey2)
+	}
+
+	// Concurrent writers do not tear the snapshot: The writer updates a and
+	// then b, so every snapshot must have a == b or a == b+1.
+	readCounter := func(t *testing.T, f *file.File, name string) int {
+		t.Helper()
+		kid, err := f.Open(ctx, name)
+		if err != nil {
+			t.Fatalf("Open %q: %v", name, err)
+		}
+		buf := make([]byte, 8)
+		nr, err := kid.ReadAt(ctx, buf, 0)
+		if err != nil && !errors.Is(err, io.EOF) {
+			t.Fatalf("ReadAt %q: %v", name, err)
+		}
+		var v int
+		fmt.Sscanf(string(buf[:nr]), "%d", &v)
+		return v
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 300; i++ {
+			v := []byte(fmt.Sprintf("%08d", i))
+			a.WriteAt(ctx, v, 0)
+			b.WriteAt(ctx, v, 0)
+		}
+	}()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		key, err := root.FlushWith(ctx, snap)
+		if err != nil {
+			t.Fatalf("FlushWith: %v", err)
+		}
+		cp, err := file.Open(ctx, cas, key)
+		if err != nil {
+			t.Fatalf("Open snapshot: %v", err)
+		}
+		if va, vb := readCounter(t, cp, "a"), readCounter(t, cp, "b"); va != vb && va != vb+1 {
+			t.Fatalf("Torn snapshot: a=%d, b=%d", va, vb)
+		}
+	}
+
+	// Once writers are done, a snapshot reflects the final state.
+	key, err := root.FlushWith(ctx, snap)
+	if err != nil {
+		t.Fatalf("FlushWith: %v", err)
+	}
+	if got, err := root.Flush(ctx); err != nil || got != key {
+		t.Errorf("Flush: got (%x, %v), want (%x, nil)", got, err, key)
+	}
+	cp, err := file.Open(ctx, cas, key)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if va, vb := readCounter(t, cp, "a"), readCounter(t, cp, "b"); va != 300 || vb != 300 {
+		t.Errorf("Final snapshot: got a=%d, b=%d, want 300", va, vb)
+	}
+}
+
+// checkSameEncoding checks that each of files, which should be logically
+// identical, has the same canonical encoding, and that repeated encodings of
+// each file are the same.
+func checkSameEncoding(t *testing.T, files ...*file.File) {
+	t.Helper()
+	var want []byte
+	for i, f := range files {
+		for range 10 {
+			got, err := wiretype.MarshalCanonical(file.Encode(f))
+			if err != nil {
+				t.Fatalf("File %d: MarshalCanonical: %v", i, err)
+			}
+			if want == nil {
+				want = got
+			} else if !bytes.Equal(got, want) {
+				t.Fatalf("File %d: encoding differs:\ngot:  %x\nwant: %x", i, got, want)
+			}
+		}
+	}
+}
+
+func TestXAttrEncoding(t *testing.T) {
+	ctx := context.Background()
+	cas := blob.CASFromKV(memstore.NewKV())
+	big := strings.Repeat("x", file.MaxInlineXAttr+1)
+	attrs := [][2]string{{"a", "1"}, {"b", big}, {"c", "3"}, {"d", "4"}, {"e", big + "e"}}
+
+	// Files with the same attributes set in different orders encode the same,
+	// both before and after spilling large values.
+	var files []*file.File
+	rng := rand.New(rand.NewSource(1))
+	for range 5 {
+		f := file.New(cas, nil)
+		for _, i := range rng.Perm(len(attrs)) {
+			f.XAttr().Set(attrs[i][0], attrs[i][1])
+		}
+		files = append(files, f)
+	}
+	checkSameEncoding(t, files...)
+	for _, f := range files {
+		if _, err := f.Flush(ctx); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+	}
+	checkSameEncoding(t, files...)
+
+	// Duplicate attribute names are resolved in favor of the last.
+	n := &wiretype.Node{XAttrs: []*wiretype.XAttr{
+		{Name: "b", Value: []byte("old")},
+		{Name: "a", Value: []byte("1")},
+		{Name: "b", Value: []byte("mid")},
+		{Name: "b", Value: []byte("new")},
+	}}
+	n.Normalize()
+	var got []string
+	for _, xa := range n.XAttrs {
+		got = append(got, xa.Name+"="+string(xa.Value))
+	}
+	if diff := cmp.Diff(got, []string{"a=1", "b=new"}); diff != "" {
+		t.Errorf("Normalized xattrs (-got, +want):\n%s", diff)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	cas := blob.CASFromKV(memstore.NewKV())
+	ctx := context.Background()
+	const content = "all work and no play makes jack a dull boy\n"
+	want, err := file.ContentFingerprint(strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("ContentFingerprint: %v", err)
+	}
+
+	f := file.New(cas, nil)
+	if err := f.SetData(ctx, strings.NewReader(content)); err != nil {
+		t.Fatalf("SetData: %v", err)
+	}
+	if got := f.Fingerprint(); got != "" {
+		t.Errorf("Fingerprint without opt-in: got %q, want empty", got)
+	}
+
+	opts := &file.SetDataOptions{Fingerprint: true}
+	if err := f.SetDataWith(ctx, strings.NewReader(content), opts); err != nil {
+		t.Fatalf("SetDataWith: %v", err)
+	}
+	if got := f.Fingerprint(); got != want {
+		t.Errorf("Fingerprint: got %q, want %q", got, want)
+	}
+
+	// The fingerprint survives a round trip through storage.
+	key, err := f.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	g, err := file.Open(ctx, cas, key)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if got := g.Fingerprint(); got != want {
+		t.Errorf("Reopened fingerprint: got %q, want %q", got, want)
+	}
+
+	// Modifying the content discards the fingerprint.
+	for _, tc := range []struct {
+		name   string
+		modify func(*file.File) error
+	}{
+		{"WriteAt", func(f *file.File) error { _, err := f.WriteAt(ctx, []byte("x"), 0); return err }},
+		{"Truncate", func(f *file.File) error { return f.Truncate(ctx, 5) }},
+		{"CopyRange", func(f *file.File) error { _, err := file.CopyRange(ctx, f, 0, f, 10, 5); return err }},
+		{"SetData", func(f *file.File) error { return f.SetData(ctx, strings.NewReader(content)) }},
+	} {
+		if err := f.SetDataWith(ctx, strings.NewReader(content), opts); err != nil {
+			t.Fatalf("SetDataWith: %v", err)
+		}
+		if err := tc.modify(f); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got := f.Fingerprint(); got != "" {
+			t.Errorf("After %s: got fingerprint %q, want empty", tc.name, got)
+		}
+	}
+}
+
+func TestChildStats(t *testing.T) {
+	kv := memstore.NewKV()
+	cas := blob.CASFromKV(kv)
+	ctx := context.Background()
+
+	root := file.New(cas, &file.NewOptions{PersistStat: true})
+	sizes := map[string]int64{"apple": 5, "banana": 20, "cherry": 0, "durian": 11}
+	kids := make(map[string]*file.File)
+	for name, size := range sizes {
+		kid := root.New(&file.NewOptions{Stat: &file.Stat{Mode: 0640}})
+		if _, err := kid.WriteAt(ctx, bytes.Repeat([]byte("x"), int(size)), 0); err != nil {
+			t.Fatalf("WriteAt %q: %v", name, err)
+		}
+		root.Child().Set(name, kid)
+		kids[name] = kid
+	}
+	rkey, err := root.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	ckey, err := kids["cherry"].Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush cherry: %v", err)
+	}
+
+	// Reopen the root so that its children are not loaded, then open and
+	// modify one of them without flushing.
+	root, err = file.Open(ctx, cas, rkey)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	b, err := root.Open(ctx, "banana")
+	if err != nil {
+		t.Fatalf("Open banana: %v", err)
+	}
+	if err := b.Truncate(ctx, 3); err != nil {
+		t.Fatalf("Truncate: %v", err)
+	}
+	sizes["banana"] = 3
+
+	type entry struct {
+		Name string
+		Size int64
+		Mode fs.FileMode
+	}
+	var got, want []entry
+	for _, name := range root.Child().Names() {
+		want = append(want, entry{name, sizes[name], 0640})
+	}
+	for cs, err := range root.Child().Stats(ctx) {
+		if err != nil {
+			t.Fatalf("Stats %q: unexpected error: %v", cs.Name, err)
+		}
+		if cs.Info.Name() != cs.Name {
+			t.Errorf("Stats %q: info name is %q", cs.Name, cs.Info.Name())
+		}
+		got = append(got, entry{cs.Name, cs.Info.Size(), cs.Info.Mode()})
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Stats (-got, +want):\n%s", diff)
+	}
+
+	// Listing the children does not open them.
+	if n := root.Child().Release(); n != 0 {
+		t.Errorf("Release: got %d, want 0", n)
+	}
+
+	// A child that cannot be loaded is reported, and iteration continues.
+	if err := kv.Delete(ctx, ckey); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	var names []string
+	for cs, err := range root.Child().Stats(ctx) {
+		if cs.Name == "cherry" {
+			if !blob.IsKeyNotFound(err) {
+				t.Errorf("Stats cherry: got %v, want %v", err, blob.ErrKeyNotFound)
+			}
+		} else if err != nil {
+			t.Errorf("Stats %q: unexpected error: %v", cs.Name, err)
+		}
+		names = append(names, cs.Name)
+	}
+	if diff := cmp.Diff(names, root.Child().Names()); diff != "" {
+		t.Errorf("Stats names (-got, +want):\n%s", diff)
+	}
+}
+
+// slowGetCAS is a blob.CAS whose Get waits until its context ends or a short
+// delay passes, whichever is first, and counts the Gets that succeed.
+type slowGetCAS struct {
+	blob.CAS
+	loaded atomic.Int64
+}
+
+func (s *slowGetCAS) Get(ctx context.Context, key string) ([]byte, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(20 * time.Millisecond):
+	}
+	data, err := s.CAS.Get(ctx, key)
+	if err == nil {
+		s.loaded.Add(1)
+	}
+	return data, err
+}
+
+func TestChildStatsStop(t *testing.T) {
+	cas := blob.CASFromKV(memstore.NewKV())
+	ctx := context.Background()
+
+	const numKids = 64
+	root := file.New(cas, nil)
+	for i := range numKids {
+		root.Child().Set(fmt.Sprintf("kid%02d", i), root.New(&file.NewOptions{
+			Stat:        &file.Stat{Mode: fs.FileMode(0600 + i)}, // distinct keys
+			PersistStat: true,
+		}))
+	}
+	rkey, err := root.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	slow := &slowGetCAS{CAS: cas}
+	root, err = file.Open(ctx, slow, rkey)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	slow.loaded.Store(0)
+
+	// Stopping after the first child cancels the loads that remain, rather
+	// than waiting for them.
+	for range root.Child().Stats(ctx) {
+		break
+	}
+	if n := slow.loaded.Load(); n >= numKids/2 {
+		t.Errorf("Stats stopped early: loaded %d children, want fewer than %d", n, numKids/2)
+	}
+}
+
+func TestInline(t *testing.T) {
+	kv := memstore.NewKV()
+	cas := blob.CASFromKV(kv)
+	ctx := context.Background()
+
+	const limit = 64
+	sc := &block.SplitConfig{Min: 16, Size: 32, Max: 64}
+	f := file.New(cas, &file.NewOptions{Split: sc, InlineLimit: limit})
+
+	check := func(t *testing.T, f *file.File, want string, inline bool) {
+		t.Helper()
+		got, err := io.ReadAll(f.Cursor(ctx))
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		if string(got) != want {
+			t.Errorf("Contents: got %q, want %q", got, want)
+		}
+		if n := f.Data().Len(); (n == 0) != inline {
+			t.Errorf("Data has %d blocks, want inline=%v", n, inline)
+		}
+	}
+
+	// A small file is stored inline, without data blocks.
+	const small = "a small but nontrivial amount of text"
+	if _, err := f.WriteAt(ctx, []byte(small), 0); err != nil {
+		t.Fatalf("WriteAt: %v", err)
+	}
+	check(t, f, small, true)
+
+	key, err := f.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if n, _ := kv.Len(ctx); n != 1 {
+		t.Errorf("Store has %d blobs, want 1", n)
+	}
+	g, err := file.OpenWith(ctx, cas, key, &file.OpenOptions{InlineLimit: limit})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	check(t, g, small, true)
+
+	// Growing the file past the limit moves its data to blocks.
+	long := small + strings.Repeat("-", 2*limit)
+	if _, err := g.WriteAt(ctx, []byte(long[len(small):]), int64(len(small))); err != nil {
+		t.Fatalf("WriteAt: %v", err)
+	}
+	check(t, g, long, false)
+
+	// Truncating it back within the limit moves its data inline again.
+	if err := g.Truncate(ctx, 10); err != nil {
+		t.Fatalf("Truncate: %v", err)
+	}
+	check(t, g, long[:10], true)
+
+	// Copying between inline and block-stored files works in both directions.
+	h := g.New(nil)
+	if err := h.SetData(ctx, strings.NewReader(long)); err != nil {
+		t.Fatalf("SetData: %v", err)
+	}
+	check(t, h, long, false)
+	if _, err := file.CopyRange(ctx, h, 5, g, 0, 10); err != nil {
+		t.Fatalf("CopyRange: %v", err)
+	}
+	check(t, h, long[:5]+long[:10]+long[15:], false)
+	if _, err := file.CopyRange(ctx, g, 10, h, 0, 20); err != nil {
+		t.Fatalf("CopyRange: %v", err)
+	}
+	check(t, g, long[:10]+long[:5]+long[:10]+long[15:20], true)
+
+	// SetData stores small content inline.
+	if err := h.SetData(ctx, strings.NewReader(small)); err != nil {
+		t.Fatalf("SetData: %v", err)
+	}
+	check(t, h, small, true)
+
+	// Files with inline data can be opened without an inline limit.
+	key, err = h.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	r, err := file.Open(ctx, cas, key)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	check(t, r, small, true)
+}
+
+func TestBlockKeys(t *testing.T) {
+	kv := memstore.NewKV()
+	cas := blob.CASFromKV(kv)
+	ctx := context.Background()
+
+	sc := &block.SplitConfig{Min: 64, Size: 128, Max: 256}
+	root := file.New(cas, &file.NewOptions{Split: sc})
+	setData := func(f *file.File, data string) {
+		t.Helper()
+		if err := f.SetData(ctx, strings.NewReader(data)); err != nil {
+			t.Fatalf("SetData: %v", err)
+		}
+	}
+	text := strings.Repeat("all work and no play makes jack a dull boy\n", 40)
+	setData(root, "hello, world")
+	root.XAttr().Set("big", strings.Repeat("x", 2*file.MaxInlineXAttr))
+
+	// Two children with identical contents share their node and blocks.
+	for _, name := range []string{"a", "b"} {
+		kid := root.New(nil)
+		setData(kid, text)
+		root.Child().Set(name, kid)
+	}
+	c := root.New(nil)
+	setData(c, text+"and then some")
+	root.Child().Set("c", c)
+	d := c.New(nil)
+	d.XAttr().Set("big", strings.Repeat("x", 2*file.MaxInlineXAttr))
+	c.Child().Set("d", d)
+
+	rkey, err := root.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	// Every key in the store is reachable, and each is reported once.
+	var want, got []string
+	for key, err := range kv.List(ctx, "") {
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		want = append(want, key)
+	}
+	for key, err := range file.BlockKeys(ctx, cas, rkey) {
+		if err != nil {
+			t.Fatalf("BlockKeys: unexpected error: %v", err)
+		}
+		got = append(got, key)
+	}
+	if len(got) == 0 || got[0] != rkey {
+		t.Errorf("BlockKeys: first key is not the root %x", rkey)
+	}
+	slices.Sort(got)
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("BlockKeys (-got, +want):\n%s", diff)
+	}
+
+	// A missing node is reported as an error.
+	ckey, err := c.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if err := kv.Delete(ctx, ckey); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	var lastErr error
+	for _, err := range file.BlockKeys(ctx, cas, rkey) {
+		lastErr = err
+	}
+	if !blob.IsKeyNotFound(lastErr) {
+		t.Errorf("BlockKeys: got error %v, want key not found", lastErr)
+	}
+}
+
+// scanKeys returns the keys of the files reached by scanning root, and the
+// keys reported by ScanItem.Keys for each, in sorted order without duplicates.
+func scanKeys(t *testing.T, root *file.File) []string {
+	t.Helper()
+	var keys []string
+	if err := root.Scan(context.Background(), func(item file.ScanItem) bool {
+		keys = append(keys, item.Key())
+		keys = append(keys, item.Keys()...)
+		return true
+	}); err != nil {
+		t.Fatalf("Scan: unexpected error: %v", err)
+	}
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
+
+func TestScanKeys(t *testing.T) {
+	kv := memstore.NewKV()
+	cas := blob.CASFromKV(kv)
+	ctx := context.Background()
+
+	root := file.New(cas, nil)
+	if err := root.SetData(ctx, strings.NewReader("hello, world")); err != nil {
+		t.Fatalf("SetData: %v", err)
+	}
+	kid := root.New(nil)
+	kid.XAttr().Set("big", strings.Repeat("x", 2*file.MaxInlineXAttr))
+	kid.XAttr().Set("small", "y")
+	root.Child().Set("kid", kid)
+	if err := kid.Stream("extra").SetData(ctx, strings.NewReader(strings.Repeat("stream data ", 500))); err != nil {
+		t.Fatalf("Stream SetData: %v", err)
+	}
+	rkey, err := root.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if n := len(kid.XAttr().Keys()); n != 1 {
+		t.Errorf("XAttr keys: got %d, want 1", n)
+	}
+
+	// Every key in the store, including the spilled attribute value and the
+	// blocks of the stream, is reached by a scan of the reopened tree, and by
+	// BlockKeys.
+	var want []string
+	for key, err := range kv.List(ctx, "") {
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		want = append(want, key)
+	}
+	rc, err := file.Open(ctx, cas, rkey)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if diff := cmp.Diff(scanKeys(t, rc), want); diff != "" {
+		t.Errorf("Scan keys (-got, +want):\n%s", diff)
+	}
+	var got []string
+	for key, err := range file.BlockKeys(ctx, cas, rkey) {
+		if err != nil {
+			t.Fatalf("BlockKeys: unexpected error: %v", err)
+		}
+		got = append(got, key)
+	}
+	slices.Sort(got)
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("BlockKeys (-got, +want):\n%s", diff)
+	}
+
+	// Collecting the keys that a scan does not reach leaves the tree intact.
+	live := mapset.New(scanKeys(t, rc)...)
+	for _, key := range want {
+		if !live.Has(key) {
+			if err := kv.Delete(ctx, key); err != nil {
+				t.Fatalf("Delete %x: %v", key, err)
+			}
+		}
+	}
+	rc, err = file.Open(ctx, cas, rkey)
+	if err != nil {
+		t.Fatalf("Open after collection: %v", err)
+	}
+	kc, err := rc.Open(ctx, "kid")
+	if err != nil {
+		t.Fatalf("Open kid after collection: %v", err)
+	}
+	if got := kc.XAttr().Get("big"); got != strings.Repeat("x", 2*file.MaxInlineXAttr) {
+		t.Errorf("XAttr big after collection: got %d bytes, want %d", len(got), 2*file.MaxInlineXAttr)
+	}
+	stream := kc.Stream("extra")
+	buf := make([]byte, stream.Size())
+	if _, err := stream.ReadAt(ctx, buf, 0); err != nil && err != io.EOF {
+		t.Errorf("Read stream after collection: %v", err)
+	} else if want := strings.Repeat("stream data ", 500); string(buf) != want {
+		t.Errorf("Read stream after collection: got %d bytes, want %d", len(buf), len(want))
+	}
+}
+
+func TestBundle(t *testing.T) {
+	ctx := context.Background()
+
+	// Store the data blocks of the source tree apart from its nodes, to check
+	// that the bundle includes both.
+	nodes, blocks := memstore.NewKV(), memstore.NewKV()
+	sc := &block.SplitConfig{Min: 64, Size: 128, Max: 256}
+	root := file.New(blob.CASFromKV(nodes), &file.NewOptions{
+		Split:  sc,
+		Blocks: blob.CASFromKV(blocks),
+	})
+	setData := func(f *file.File, data string) {
+		t.Helper()
+		if err := f.SetData(ctx, strings.NewReader(data)); err != nil {
+			t.Fatalf("SetData: %v", err)
+		}
+	}
+	text := strings.Repeat("all work and no play makes jack a dull boy\n", 40)
+	for _, name := range []string{"a", "b"} {
+		kid := root.New(nil)
+		setData(kid, text)
+		root.Child().Set(name, kid)
+	}
+	c := root.New(nil)
+	setData(c, text+"and then some")
+	c.XAttr().Set("big", strings.Repeat("x", 2*file.MaxInlineXAttr))
+	root.Child().Set("c", c)
+
+	var buf bytes.Buffer
+	if err := file.ExportBundle(ctx, &buf, root); err != nil {
+		t.Fatalf("ExportBundle: %v", err)
+	}
+	bundle := buf.Bytes()
+	t.Logf("Bundle: %d bytes", len(bundle))
+
+	keys := func(kvs ...blob.KV) []string {
+		t.Helper()
+		var out []string
+		for _, kv := range kvs {
+			for key, err := range kv.List(ctx, "") {
+				if err != nil {
+					t.Fatalf("List: %v", err)
+				}
+				out = append(out, key)
+			}
+		}
+		slices.Sort(out)
+		return out
+	}
+
+	// Importing the bundle into an empty store reproduces the tree.
+	dst := memstore.NewKV()
+	got, err := file.ImportBundle(ctx, blob.CASFromKV(dst), bytes.NewReader(bundle))
+	if err != nil {
+		t.Fatalf("ImportBundle: %v", err)
+	}
+	if diff := cmp.Diff(keys(dst), keys(nodes, blocks)); diff != "" {
+		t.Errorf("Imported keys (-got, +want):\n%s", diff)
+	}
+	if got.Key() != root.Key() {
+		t.Errorf("Imported root: got key %x, want %x", got.Key(), root.Key())
+	}
+	gc, err := got.Open(ctx, "c")
+	if err != nil {
+		t.Fatalf("Open c: %v", err)
+	}
+	if data, err := io.ReadAll(gc.Cursor(ctx)); err != nil {
+		t.Errorf("Read c: %v", err)
+	} else if string(data) != text+"and then some" {
+		t.Errorf("Read c: got %q, want %q", data, text+"and then some")
+	}
+
+	// Importing again is harmless.
+	if _, err := file.ImportBundle(ctx, blob.CASFromKV(dst), bytes.NewReader(bundle)); err != nil {
+		t.Errorf("ImportBundle again: %v", err)
+	}
+
+	// A truncated or damaged bundle is rejected.
+	for _, bad := range [][]byte{
+		nil,
+		bundle[:len(bundle)/2],
+		bundle[:len(bundle)-1],
+		append([]byte("not a bundle"), bundle...),
+	} {
+		if _, err := file.ImportBundle(ctx, blob.CASFromKV(memstore.NewKV()), bytes.NewReader(bad)); err == nil {
+			t.Errorf("ImportBundle [%d bytes]: got nil, want error", len(bad))
+		}
+	}
+}
+
+func TestFragmentLimits(t *testing.T) {
+	ctx := context.Background()
+	cas := blob.CASFromKV(memstore.NewKV())
+	sc := &block.SplitConfig{Min: 64, Size: 128, Max: 256}
+
+	// Fill a file with small writes in random order, which leaves it with more
+	// blocks than writing the same content at once would.
+	fill := func(f *file.File) {
+		t.Helper()
+		rng := rand.New(rand.NewSource(1))
+		for _, i := range rng.Perm(400) {
+			buf := bytes.Repeat([]byte{byte(i%255 + 1)}, 16)
+			if _, err := f.WriteAt(ctx, buf, int64(i*16)); err != nil {
+				t.Fatalf("WriteAt %d: %v", i*16, err)
+			}
+		}
+	}
+
+	t.Run("Report", func(t *testing.T) {
+		var reps []file.FragmentReport
+		root := file.New(cas, &file.NewOptions{
+			Split: sc,
+			Limits: &file.FragmentLimits{
+				MaxExtents: 2,
+				OnExceed:   func(r file.FragmentReport) { reps = append(reps, r) },
+			},
+		})
+		f := root.New(&file.NewOptions{Name: "sparse"}) // inherits limits
+
+		// Each write separated from the others by a hole adds an extent.
+		for i := range 4 {
+			if _, err := f.WriteAt(ctx, []byte("x"), int64(i)*1000); err != nil {
+				t.Fatalf("WriteAt: %v", err)
+			}
+		}
+		if len(reps) != 2 {
+			t.Fatalf("Got %d reports, want 2: %+v", len(reps), reps)
+		}
+		if r := reps[1]; r.Name != "sparse" || r.Extents != 4 || r.Size != 3001 || r.Consolidated {
+			t.Errorf("Report: got %+v, want 4 extents of sparse, not consolidated", r)
+		}
+	})
+
+	t.Run("Consolidate", func(t *testing.T) {
+		var last file.FragmentReport
+		f := file.New(cas, &file.NewOptions{
+			Split: sc,
+			Limits: &file.FragmentLimits{
+				MaxBlocks:   40,
+				Consolidate: true,
+				OnExceed:    func(r file.FragmentReport) { last = r },
+			},
+		})
+		fill(f)
+		if !last.Consolidated || last.ConsolidateErr != nil {
+			t.Fatalf("Report: got %+v, want consolidated", last)
+		}
+		if last.Blocks > 40 {
+			t.Errorf("Blocks after consolidation: got %d, want ≤ 40", last.Blocks)
+		}
+
+		// Consolidation preserves the content of the file.
+		ref := file.New(cas, &file.NewOptions{Split: sc})
+		fill(ref)
+		got, err := io.ReadAll(f.Cursor(ctx))
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		want, err := io.ReadAll(ref.Cursor(ctx))
+		if err != nil {
+			t.Fatalf("Read reference: %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Error("Content changed by consolidation")
+		}
+	})
+
+	t.Run("TooLarge", func(t *testing.T) {
+		// If the data cannot fit in the limit, they are not consolidated.
+		var last file.FragmentReport
+		f := file.New(cas, &file.NewOptions{
+			Split: sc,
+			Limits: &file.FragmentLimits{
+				MaxBlocks:   10,
+				Consolidate: true,
+				OnExceed:    func(r file.FragmentReport) { last = r },
+			},
+		})
+		fill(f)
+		if last.Blocks <= 10 || last.Consolidated {
+			t.Errorf("Report: got %+v, want > 10 blocks, not consolidated", last)
+		}
+	})
+}
+
+func TestStreams(t *testing.T) {
+	kv := memstore.NewKV()
+	cas := blob.CASFromKV(kv)
+	ctx := context.Background()
+
+	sc := &block.SplitConfig{Min: 64, Size: 128, Max: 256}
+	f := file.New(cas, &file.NewOptions{Split: sc})
+	if err := f.SetData(ctx, strings.NewReader("primary content")); err != nil {
+		t.Fatalf("SetData: %v", err)
+	}
+	readStream := func(t *testing.T, s file.Stream) string {
+		t.Helper()
+		buf := make([]byte, s.Size())
+		if _, err := s.ReadAt(ctx, buf, 0); err != nil && err != io.EOF {
+			t.Fatalf("ReadAt %q: %v", s.Name(), err)
+		}
+		return string(buf)
+	}
+
+	// A stream that does not exist is empty.
+	fork := f.Stream("fork")
+	if fork.Exists() || fork.Size() != 0 {
+		t.Errorf("Stream fork: exists=%v size=%d, want false, 0", fork.Exists(), fork.Size())
+	}
+	if n, err := fork.ReadAt(ctx, make([]byte, 10), 0); n != 0 || err != io.EOF {
+		t.Errorf("ReadAt missing: got (%d, %v), want (0, EOF)", n, err)
+	}
+
+	// Writing to a stream creates it, with its own blocks.
+	big := strings.Repeat("resource fork data ", 100)
+	if _, err := fork.WriteAt(ctx, []byte(big), 0); err != nil {
+		t.Fatalf("WriteAt: %v", err)
+	}
+	if err := f.Stream("thumb").SetData(ctx, strings.NewReader("tiny")); err != nil {
+		t.Fatalf("SetData thumb: %v", err)
+	}
+	if err := f.Stream("gone").Truncate(ctx, 10); err != nil {
+		t.Fatalf("Truncate: %v", err)
+	}
+	if !f.Stream("gone").Remove() {
+		t.Error("Remove gone: got false, want true")
+	}
+	if diff := cmp.Diff(f.Streams(), []string{"fork", "thumb"}); diff != "" {
+		t.Errorf("Streams (-got, +want):\n%s", diff)
+	}
+	if len(fork.Keys()) < 2 {
+		t.Errorf("Stream fork has %d blocks, want several", len(fork.Keys()))
+	}
+
+	// Streams are persisted with the file, and do not affect its content.
+	key, err := f.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	g, err := file.Open(ctx, cas, key)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if got, err := io.ReadAll(g.Cursor(ctx)); err != nil || string(got) != "primary content" {
+		t.Errorf("Read: got (%q, %v), want primary content", got, err)
+	}
+	if got := readStream(t, g.Stream("fork")); got != big {
+		t.Errorf("Stream fork: got %d bytes, want %d", len(got), len(big))
+	}
+	if got := readStream(t, g.Stream("thumb")); got != "tiny" {
+		t.Errorf("Stream thumb: got %q, want tiny", got)
+	}
+
+	// The blocks of streams are reachable.
+	reach := make(map[string]bool)
+	for k, err := range file.BlockKeys(ctx, cas, key) {
+		if err != nil {
+			t.Fatalf("BlockKeys: %v", err)
+		}
+		reach[k] = true
+	}
+	for _, k := range g.Stream("fork").Keys() {
+		if !reach[k] {
+			t.Errorf("Stream block %x is not reachable", k)
+		}
+	}
+
+	// Modifying a stream invalidates the file.
+	if g.Stream("thumb").Remove(); g.Key() != "" {
+		t.Errorf("Key after Remove: got %x, want empty", g.Key())
+	}
+}
+
+func TestFlusher(t *testing.T) {
+	ctx := context.Background()
+	cas := blob.CASFromKV(memstore.NewKV())
+
+	// newTree returns a root with one child, flushed.
+	newTree := func() (root, kid *file.File) {
+		t.Helper()
+		root = file.New(cas, nil)
+		kid = root.New(&file.NewOptions{Name: "kid"})
+		root.Child().Set("kid", kid)
+		if _, err := root.Flush(ctx); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+		return root, kid
+	}
+	write := func(f *file.File, s string) {
+		t.Helper()
+		if _, err := f.WriteAt(ctx, []byte(s), f.Data().Size()); err != nil {
+			t.Fatalf("WriteAt: %v", err)
+		}
+	}
+	// checkStored verifies that the tree stored at key has the given content
+	// in its child.
+	checkStored := func(t *testing.T, key, want string) {
+		t.Helper()
+		f, err := file.Open(ctx, cas, key)
+		if err != nil {
+			t.Fatalf("Open %x: %v", key, err)
+		}
+		kid, err := f.Open(ctx, "kid")
+		if err != nil {
+			t.Fatalf("Open kid: %v", err)
+		}
+		got, err := io.ReadAll(kid.Cursor(ctx))
+		if err != nil {
+			t.Fatalf("Read kid: %v", err)
+		}
+		if string(got) != want {
+			t.Errorf("Stored kid: got %q, want %q", got, want)
+		}
+	}
+	type flushed struct {
+		key string
+		err error
+	}
+	waitFlush := func(t *testing.T, ch <-chan flushed) string {
+		t.Helper()
+		select {
+		case f := <-ch:
+			if f.err != nil {
+				t.Fatalf("Background flush: %v", f.err)
+			}
+			return f.key
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for a flush")
+		}
+		panic("unreachable")
+	}
+
+	t.Run("Quiet", func(t *testing.T) {
+		root, kid := newTree()
+		ch := make(chan flushed, 1)
+		fl := file.NewFlusher(ctx, root, &file.FlusherOptions{
+			Quiet:   20 * time.Millisecond,
+			OnFlush: func(key string, err error) { ch <- flushed{key, err} },
+		})
+		defer fl.Close(ctx)
+
+		write(kid, "hello")
+		checkStored(t, waitFlush(t, ch), "hello")
+	})
+
+	t.Run("MaxChanges", func(t *testing.T) {
+		root, kid := newTree()
+		ch := make(chan flushed, 1)
+		fl := file.NewFlusher(ctx, root, &file.FlusherOptions{
+			Quiet:      time.Hour,
+			Poll:       time.Millisecond,
+			MaxChanges: 3,
+			OnFlush:    func(key string, err error) { ch <- flushed{key, err} },
+		})
+		defer fl.Close(ctx)
+
+		for _, s := range []string{"a", "b", "c"} {
+			write(kid, s)
+		}
+		checkStored(t, waitFlush(t, ch), "abc")
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		root, kid := newTree()
+		fl := file.NewFlusher(ctx, root, &file.FlusherOptions{
+			Quiet:   time.Hour,
+			OnFlush: func(string, error) { t.Error("Unexpected background flush") },
+		})
+		write(kid, "goodbye")
+		key, err := fl.Close(ctx)
+		if err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		checkStored(t, key, "goodbye")
+	})
+}
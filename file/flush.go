@@ -0,0 +1,214 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/creachadair/ffs/file/wiretype"
+)
+
+// FlushOptions control the flushing of files. A nil *FlushOptions is ready
+// for use and provides default values as described.
+type FlushOptions struct {
+	// If true, flush a consistent snapshot of the file and its descendants.
+	//
+	// By default, Flush holds the lock of the file being flushed for the
+	// duration of the flush, and locks each descendant only while that
+	// descendant is written. Writers may modify a descendant that has already
+	// been written while others are still pending, so the stored tree may
+	// combine states of the files that never coexisted in memory.
+	//
+	// With Snapshot set, Flush locks the whole tree only long enough to copy
+	// the pending state of each modified file, then releases the locks and
+	// writes the copy to storage while writers proceed against the live
+	// files. The resulting key denotes the tree exactly as it was when the
+	// snapshot was taken. Each file that was not modified again during the
+	// flush records its new storage key, as with an ordinary flush; a file
+	// that was modified keeps its pending state for a later flush.
+	Snapshot bool
+}
+
+func (o *FlushOptions) snapshot() bool { return o != nil && o.Snapshot }
+
+// FlushWith flushes the current state of the file to storage if necessary,
+// using the specified options, and returns the resulting storage key. A call
+// to f.Flush(ctx) is equivalent to f.FlushWith(ctx, nil).
+func (f *File) FlushWith(ctx context.Context, opts *FlushOptions) (string, error) {
+	if !opts.snapshot() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return f.recFlushLocked(ctx, nil)
+	}
+
+	snap, err := f.takeSnapshot(ctx)
+	if err != nil {
+		return "", err
+	}
+	return snap.save(ctx)
+}
+
+// A flushSnap records the pending state of a file at the time of a snapshot.
+type flushSnap struct {
+	f   *File
+	gen uint64 // the generation of f when the snapshot was taken
+	key string // the storage key of f, if known
+
+	// If key == "", the remaining fields describe the state to be written.
+
+	node  *wiretype.Node        // the encoding of f, less dirty child keys
+	spill map[string]string     // xattr values to store separately, by name
+	kids  map[string]*flushSnap // children whose keys are pending, by name
+	fresh map[string]string     // child keys to record in f, by name
+}
+
+// takeSnapshot captures the pending state of f and its descendants. It holds
+// the locks of all the files in the tree until the whole snapshot is taken.
+func (f *File) takeSnapshot(ctx context.Context) (*flushSnap, error) {
+	var held []*File
+	defer func() {
+		for _, h := range held {
+			h.mu.Unlock()
+		}
+	}()
+	seen := make(map[*File]*flushSnap)
+
+	var rec func(*File, []*File) (*flushSnap, error)
+	rec = func(f *File, path []*File) (*flushSnap, error) {
+		if snap, ok := seen[f]; ok {
+			return snap, nil // shared by multiple parents
+		} else if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		f.mu.Lock()
+		held = append(held, f)
+
+		snap := &flushSnap{f: f, gen: f.gen, key: f.key}
+		seen[f] = snap
+		cpath := append(path, f)
+		for _, kid := range f.kids {
+			kf := kid.File
+			if kf == nil {
+				continue
+			} else if slices.Contains(cpath, kf) {
+				return nil, fmt.Errorf("flush: cycle in path at %p", kf)
+			}
+			ks, err := rec(kf, cpath)
+			if err != nil {
+				return nil, err
+			}
+			if ks.key == "" {
+				if snap.kids == nil {
+					snap.kids = make(map[string]*flushSnap)
+				}
+				snap.kids[kid.Name] = ks
+			} else if ks.key != kid.Key {
+				if snap.fresh == nil {
+					snap.fresh = make(map[string]string)
+				}
+				snap.fresh[kid.Name] = ks.key
+			}
+		}
+		if snap.key != "" && len(snap.kids) == 0 && len(snap.fresh) == 0 {
+			return snap, nil // nothing to write
+		}
+
+		// Reaching here, f or one of its children has changed.
+		snap.key = ""
+		snap.node = f.toWireTypeLocked().Value.(*wiretype.Object_Node).Node
+		for name, value := range f.xattr {
+			if _, ok := f.xkeys[name]; !ok && len(value) > MaxInlineXAttr {
+				if snap.spill == nil {
+					snap.spill = make(map[string]string)
+				}
+				snap.spill[name] = value
+			}
+		}
+		return snap, nil
+	}
+	return rec(f, nil)
+}
+
+// save writes the pending state recorded by s and its descendants to storage
+// and returns the storage key of s. Each file whose state has not changed
+// since the snapshot was taken is updated with its new storage key.
+func (s *flushSnap) save(ctx context.Context) (string, error) {
+	if s.key != "" {
+		return s.key, nil // clean, or already saved via another parent
+	} else if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	// Save the children first, so that their keys are known.
+	fresh := make(map[string]string, len(s.fresh)+len(s.kids))
+	for name, key := range s.fresh {
+		fresh[name] = key
+	}
+	for name, ks := range s.kids {
+		key, err := ks.save(ctx)
+		if err != nil {
+			return "", err
+		}
+		fresh[name] = key
+	}
+	for _, c := range s.node.Children {
+		if key, ok := fresh[c.Name]; ok {
+			c.Key = []byte(key)
+		}
+	}
+
+	xkeys := make(map[string]string, len(s.spill))
+	for name, value := range s.spill {
+		xkey, err := s.f.s.CASPut(ctx, []byte(value))
+		if err != nil {
+			return "", fmt.Errorf("storing xattr %q: %w", name, err)
+		}
+		xkeys[name] = xkey
+	}
+	for _, xa := range s.node.XAttrs {
+		if xkey, ok := xkeys[xa.Name]; ok {
+			xa.Key, xa.Value = []byte(xkey), nil
+		}
+	}
+
+	key, err := wiretype.Save(ctx, s.f.s, &wiretype.Object{Value: &wiretype.Object_Node{Node: s.node}})
+	if err != nil {
+		return "", fmt.Errorf("flushing file %x: %w", key, err)
+	}
+	s.key = key
+
+	// If f has not been modified since the snapshot, it now matches what we
+	// wrote, so record the keys. Otherwise, leave f for a later flush.
+	f := s.f
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.gen == s.gen {
+		for i, kid := range f.kids {
+			if key, ok := fresh[kid.Name]; ok {
+				f.kids[i].Key = key
+			}
+		}
+		for name, xkey := range xkeys {
+			if f.xkeys == nil {
+				f.xkeys = make(map[string]string)
+			}
+			f.xkeys[name] = xkey
+		}
+		f.key = key
+	}
+	return key, nil
+}
@@ -0,0 +1,191 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"context"
+	"time"
+)
+
+// FlusherOptions are optional settings for a [Flusher]. A nil
+// *FlusherOptions is ready for use and provides default values as described.
+type FlusherOptions struct {
+	// Flush the tree once it has been unchanged for at least this long.
+	// If zero, the default is 5 seconds.
+	Quiet time.Duration
+
+	// If positive, flush the tree once at least this many changes have been
+	// made since it was last flushed, even if it has not been quiet. Changes
+	// are counted per file, so a single operation may count more than once.
+	MaxChanges int
+
+	// How often to check the tree for changes. If zero, the default is a
+	// quarter of the Quiet interval.
+	Poll time.Duration
+
+	// Options for each flush. If nil, the tree is flushed as by Flush.
+	Flush *FlushOptions
+
+	// If non-nil, OnFlush is called after each flush made in the background,
+	// with the resulting storage key or error. It is not called for the final
+	// flush made by Close.
+	OnFlush func(key string, err error)
+}
+
+func (o *FlusherOptions) quiet() time.Duration {
+	if o == nil || o.Quiet <= 0 {
+		return 5 * time.Second
+	}
+	return o.Quiet
+}
+
+func (o *FlusherOptions) maxChanges() uint64 {
+	if o == nil || o.MaxChanges <= 0 {
+		return 0
+	}
+	return uint64(o.MaxChanges)
+}
+
+func (o *FlusherOptions) poll() time.Duration {
+	if o == nil || o.Poll <= 0 {
+		return max(o.quiet()/4, time.Millisecond)
+	}
+	return o.Poll
+}
+
+func (o *FlusherOptions) flush() *FlushOptions {
+	if o == nil {
+		return nil
+	}
+	return o.Flush
+}
+
+func (o *FlusherOptions) onFlush(key string, err error) {
+	if o != nil && o.OnFlush != nil {
+		o.OnFlush(key, err)
+	}
+}
+
+// A Flusher flushes a tree of files in the background, so that changes are
+// saved to storage even if the caller does not flush them explicitly. This is
+// useful for layers such as file system servers, where the client controls
+// when (or whether) changes are synced.
+//
+// A Flusher checks the tree periodically, and flushes it once it has been
+// quiet for a while, or once enough changes have accumulated; see
+// [FlusherOptions]. Only the files that have been opened in memory are
+// checked, so a Flusher does not load anything from storage until it flushes.
+// Call Close to stop the flusher and save any remaining changes.
+type Flusher struct {
+	root *File
+	opts *FlusherOptions
+	stop context.CancelFunc
+	done chan struct{}
+}
+
+// NewFlusher constructs a new [Flusher] for the tree rooted at root, and
+// starts it in the background. Flushes made in the background use ctx, and
+// the flusher stops when ctx ends.
+func NewFlusher(ctx context.Context, root *File, opts *FlusherOptions) *Flusher {
+	ctx, cancel := context.WithCancel(ctx)
+	fl := &Flusher{root: root, opts: opts, stop: cancel, done: make(chan struct{})}
+	gen, _ := root.treeState()
+	go fl.run(ctx, gen)
+	return fl
+}
+
+// Close stops the background flusher, waits for any flush in progress to
+// finish, then flushes the tree a final time using ctx. It returns the
+// resulting storage key of the root.
+func (fl *Flusher) Close(ctx context.Context) (string, error) {
+	fl.stop()
+	<-fl.done
+	return fl.root.FlushWith(ctx, fl.opts.flush())
+}
+
+// run checks and flushes the tree until ctx ends. The tree was at generation
+// gen when the flusher started.
+func (fl *Flusher) run(ctx context.Context, gen uint64) {
+	defer close(fl.done)
+	t := time.NewTicker(fl.opts.poll())
+	defer t.Stop()
+
+	quiet, maxChanges := fl.opts.quiet(), fl.opts.maxChanges()
+	last := gen           // generation as of the last check
+	base := gen           // generation as of the last flush
+	changed := time.Now() // when a change was last observed
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			gen, dirty := fl.root.treeState()
+			if gen != last {
+				last, changed = gen, now
+			}
+			if gen < base {
+				base = gen // files were removed from the tree
+			}
+			if !dirty {
+				base = gen
+				continue
+			}
+			if now.Sub(changed) < quiet && (maxChanges == 0 || gen-base < maxChanges) {
+				continue
+			}
+			key, err := fl.root.FlushWith(ctx, fl.opts.flush())
+			if ctx.Err() != nil {
+				return // stopped during the flush; Close will finish
+			}
+			fl.opts.onFlush(key, err)
+
+			// Flushing updates the generations of modified files, so reset the
+			// baseline to avoid counting them as changes.
+			last, _ = fl.root.treeState()
+			base, changed = last, time.Now()
+		}
+	}
+}
+
+// treeState reports the sum of the generations of f and its open descendants,
+// which increases when any of them is changed, and whether any of them has
+// changes that have not been flushed.
+func (f *File) treeState() (gen uint64, dirty bool) {
+	seen := make(map[*File]bool)
+	var rec func(*File)
+	rec = func(f *File) {
+		if seen[f] {
+			return // shared by multiple parents
+		}
+		seen[f] = true
+
+		f.mu.RLock()
+		gen += f.gen
+		dirty = dirty || f.key == ""
+		var kids []*File
+		for _, kid := range f.kids {
+			if kid.File != nil {
+				kids = append(kids, kid.File)
+			}
+		}
+		f.mu.RUnlock()
+
+		for _, kf := range kids {
+			rec(kf)
+		}
+	}
+	rec(f)
+	return gen, dirty
+}
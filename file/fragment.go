@@ -0,0 +1,121 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"context"
+
+	"github.com/creachadair/ffs/block"
+)
+
+// FragmentLimits are soft limits on the fragmentation of the data of a file.
+// Many small random writes can leave a file with a very large number of tiny
+// blocks, or of extents separated by runs of zeroes, which makes reading and
+// flushing the file slow. When a write or truncation leaves a file beyond a
+// limit, it is reported to OnExceed, and optionally consolidated.
+//
+// A limit ≤ 0 is not enforced.
+type FragmentLimits struct {
+	MaxExtents int // the number of data extents above which to report
+	MaxBlocks  int // the number of data blocks above which to report
+
+	// OnExceed, if non-nil, is called after a write or truncation that leaves
+	// the file beyond a limit. It is called without holding the lock of the
+	// file, but it is called synchronously by the method that made the change,
+	// so it should not block.
+	OnExceed func(FragmentReport)
+
+	// If Consolidate is true, a file that exceeds MaxBlocks has its data
+	// rewritten with its split settings, as [Rechunk] does, so that runs of
+	// small blocks are replaced by blocks of the usual size. The content of
+	// the file is not changed. Consolidation is skipped if the data could not
+	// fit within MaxBlocks even at the maximum block size, since rewriting them
+	// would not help.
+	Consolidate bool
+}
+
+// A FragmentReport describes a file that exceeded its [FragmentLimits].
+type FragmentReport struct {
+	Name    string // the name of the file (see File.Name)
+	Size    int64  // the size of the file data in bytes
+	Extents int    // the number of data extents after the change
+	Blocks  int    // the number of data blocks after the change
+
+	// If the file was consolidated, Consolidated is true, and Extents and
+	// Blocks report the counts after consolidation. ConsolidateErr is the
+	// error from consolidation, if it failed; the file data are not changed
+	// by a failed consolidation.
+	Consolidated   bool
+	ConsolidateErr error
+}
+
+// checkFragmentLocked checks the data of f against its fragment limits, and
+// consolidates them if required. It returns nil if f is within its limits,
+// or if it has none. The caller must hold f.mu exclusively.
+func (f *File) checkFragmentLocked(ctx context.Context) *FragmentReport {
+	lim := f.limits
+	if lim == nil || f.data.inline != nil {
+		return nil
+	}
+	exts, blks := f.data.fragments()
+	overExt := lim.MaxExtents > 0 && exts > lim.MaxExtents
+	overBlk := lim.MaxBlocks > 0 && blks > lim.MaxBlocks
+	if !overExt && !overBlk {
+		return nil
+	}
+	rep := &FragmentReport{Name: f.name, Size: f.data.totalBytes, Extents: exts, Blocks: blks}
+	if overBlk && lim.Consolidate && f.data.totalBytes/int64(splitMax(f.data.sc)) < int64(lim.MaxBlocks) {
+		if _, err := f.rechunkLocked(ctx, f.data.sc); err != nil {
+			rep.ConsolidateErr = err
+		} else {
+			rep.Consolidated = true
+			rep.Extents, rep.Blocks = f.data.fragments()
+		}
+	}
+	return rep
+}
+
+// reportFragment calls the OnExceed callback of f, if rep != nil.
+func (f *File) reportFragment(rep *FragmentReport) {
+	if rep != nil && f.limits.OnExceed != nil {
+		f.limits.OnExceed(*rep)
+	}
+}
+
+// fragments reports the number of extents and blocks in d. The first call
+// counts the blocks of d; after that, the count is updated incrementally as d
+// is modified, so that checking the limits does not slow down each write.
+func (d *fileData) fragments() (exts, blks int) {
+	if !d.nblocksValid {
+		d.nblocks, d.nblocksValid = countBlocks(d.extents), true
+	}
+	return len(d.extents), d.nblocks
+}
+
+// countBlocks reports the total number of blocks in exts.
+func countBlocks(exts []*extent) (n int) {
+	for _, ext := range exts {
+		n += len(ext.blocks)
+	}
+	return n
+}
+
+// splitMax returns the maximum block size of sc, or the default.
+func splitMax(sc *block.SplitConfig) int {
+	if sc == nil || sc.Max <= 0 {
+		return block.DefaultMax
+	}
+	return sc.Max
+}
@@ -0,0 +1,112 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"context"
+	"io"
+
+	"github.com/creachadair/mds/mbits"
+)
+
+// Small files may store their contents inline in the file node, rather than
+// in separate data blocks. When d.inline != nil, the data are stored inline:
+// the bytes of d.inline are the prefix of the content, the remainder up to
+// d.totalBytes is zeroes, and d.extents is empty.
+//
+// Data are moved inline ("demoted") when a write or truncation leaves the
+// file no larger than its inline limit, and moved to blocks ("promoted") when
+// a write makes it larger.
+
+// canInline reports whether data of the given size may be stored inline.
+func (d *fileData) canInline(size int64) bool {
+	return d.inlineLimit > 0 && size <= d.inlineLimit
+}
+
+// promote moves inline data, if any, into blocks written to s.
+func (d *fileData) promote(ctx context.Context, s BlockStore) error {
+	if d.inline == nil {
+		return nil
+	}
+	if len(d.inline) != 0 {
+		blks, err := d.splitBlobs(ctx, s, d.inline)
+		if err != nil {
+			return err
+		}
+		d.extents = splitExtent(&extent{
+			base:   0,
+			bytes:  int64(len(d.inline)),
+			blocks: blks,
+		})
+	}
+	d.inline = nil
+	d.digest.valid = false // recomputed on demand
+	d.nblocksValid = false
+	return nil
+}
+
+// demote moves the contents of d, read from s, inline.
+func (d *fileData) demote(ctx context.Context, s BlockStore) error {
+	if d.inline != nil {
+		return nil
+	}
+	buf := make([]byte, d.totalBytes)
+	nr, err := d.readAt(ctx, s, buf, 0)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	d.inline = buf[:nr]
+	d.extents = nil
+	d.digest.valid = false // recomputed on demand
+	d.nblocksValid = false
+	return nil
+}
+
+// writeInline writes data at offset in d, storing the result inline.
+func (d *fileData) writeInline(ctx context.Context, s BlockStore, data []byte, offset int64) (int, error) {
+	if err := d.demote(ctx, s); err != nil {
+		return 0, err
+	}
+	end := offset + int64(len(data))
+	if n := int64(len(d.inline)); end > n {
+		d.inline = append(d.inline, make([]byte, end-n)...)
+	}
+	copy(d.inline[offset:], data)
+	if end > d.totalBytes {
+		d.totalBytes = end
+	}
+	return len(data), nil
+}
+
+// readInline reads inline data from d into data from the specified offset,
+// with the semantics of readAt.
+func (d *fileData) readInline(data []byte, offset int64) (int, error) {
+	if offset > d.totalBytes {
+		return 0, io.EOF
+	}
+	end := offset + int64(len(data))
+	if end > d.totalBytes {
+		end = d.totalBytes
+	}
+	var nr int
+	if n := int64(len(d.inline)); offset < n {
+		nr = copy(data[:end-offset], d.inline[offset:])
+	}
+	nr += mbits.Zero(data[nr : end-offset])
+	if nr < len(data) {
+		return nr, io.EOF
+	}
+	return nr, nil
+}
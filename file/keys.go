@@ -0,0 +1,137 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"context"
+	"fmt"
+	"iter"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/file/wiretype"
+)
+
+// BlockKeys returns an iterator over the storage keys reachable from the file
+// stored at key in s: The keys of the file and its descendants, the keys of
+// their data blocks (including those of named streams), and the keys of
+// extended attribute values stored apart from their nodes. Each key is
+// reported once, even if it is shared by several files. Node keys are
+// reported before the keys they reference, and descendants are visited in
+// depth-first left-to-right order.
+//
+// BlockKeys reads the stored tree directly, without opening files, so it is
+// safe to use concurrently with changes to files in memory, but it does not
+// reflect changes that have not been flushed. Data blocks need not be stored
+// in s (see OpenOptions.Blocks), since only their keys are reported.
+//
+// If a node cannot be loaded or decoded, the iterator reports the error with
+// an empty key and stops.
+func BlockKeys(ctx context.Context, s blob.CAS, key string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for rk, err := range reachableKeys(ctx, s, key) {
+			if !yield(rk.key, err) {
+				return
+			}
+		}
+	}
+}
+
+// A reachableKey is a storage key reported by reachableKeys.
+type reachableKey struct {
+	key   string
+	block bool // whether key is a data block, rather than a node or xattr value
+}
+
+// reachableKeys implements BlockKeys, and also reports which of the keys are
+// data blocks, since those may be stored apart from s.
+func reachableKeys(ctx context.Context, s blob.CAS, key string) iter.Seq2[reachableKey, error] {
+	return func(yield func(reachableKey, error) bool) {
+		seen := make(map[string]struct{})
+		first := func(key string) bool {
+			if _, ok := seen[key]; ok {
+				return false
+			}
+			seen[key] = struct{}{}
+			return true
+		}
+
+		stk := []string{key}
+		first(key)
+		for len(stk) != 0 {
+			if err := ctx.Err(); err != nil {
+				yield(reachableKey{}, err)
+				return
+			}
+			next := stk[len(stk)-1]
+			stk = stk[:len(stk)-1]
+
+			node, err := loadNode(ctx, s, next)
+			if err != nil {
+				yield(reachableKey{}, err)
+				return
+			}
+			if !yield(reachableKey{key: next}, nil) {
+				return
+			}
+			for _, xa := range node.XAttrs {
+				if xk := string(xa.Key); xk != "" && first(xk) && !yield(reachableKey{key: xk}, nil) {
+					return
+				}
+			}
+			for _, idx := range indexes(node) {
+				if bk := string(idx.GetSingle()); bk != "" && first(bk) && !yield(reachableKey{key: bk, block: true}, nil) {
+					return
+				}
+				for _, ext := range idx.GetExtents() {
+					for _, blk := range ext.Blocks {
+						if bk := string(blk.Key); first(bk) && !yield(reachableKey{key: bk, block: true}, nil) {
+							return
+						}
+					}
+				}
+			}
+
+			// Push children in reverse so they are popped in order.
+			for i := len(node.Children) - 1; i >= 0; i-- {
+				if ck := string(node.Children[i].Key); first(ck) {
+					stk = append(stk, ck)
+				}
+			}
+		}
+	}
+}
+
+// loadNode loads and decodes the node stored at key in s.
+func loadNode(ctx context.Context, s blob.CAS, key string) (*wiretype.Node, error) {
+	var obj wiretype.Object
+	if err := wiretype.Load(ctx, s, key, &obj); err != nil {
+		return nil, fmt.Errorf("loading file %x: %w", key, err)
+	}
+	pb, ok := obj.Value.(*wiretype.Object_Node)
+	if !ok {
+		return nil, fmt.Errorf("decoding file %x: object does not contain a node", key)
+	}
+	return pb.Node, nil
+}
+
+// indexes returns the data indexes of node: its primary content, followed by
+// its named streams.
+func indexes(node *wiretype.Node) []*wiretype.Index {
+	out := []*wiretype.Index{node.Index}
+	for _, s := range node.Streams {
+		out = append(out, s.Index)
+	}
+	return out
+}
@@ -0,0 +1,238 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"iter"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/file/wiretype"
+)
+
+// Meta is the metadata of a file, without its content: its name, size, stat,
+// and the names of its children. A Meta can be obtained more cheaply than an
+// open file for a file that has not been loaded, because the data index of
+// the file is not decoded. Use it to find metadata for deep paths in trees
+// with very large files.
+//
+// A Meta is a snapshot: Later changes to the file it describes are not
+// reflected in it.
+type Meta struct {
+	Name string // the attributed name of the file
+	Key  string // the storage key of the file, or "" if it has unsaved changes
+	Size int64  // the effective size of the file content in bytes
+
+	// The stat metadata of the file. The Stat is not associated with the
+	// file, so its Clear, Update, and Persist methods must not be used.
+	Stat Stat
+
+	s    blob.CAS
+	kids []child             // N.B. Files are set only for children of an open file
+	norm func(string) string // if non-nil, normalize names for comparison
+}
+
+// Meta returns the metadata of f.
+func (f *File) Meta() Meta {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	st := f.stat
+	st.f = nil
+	return Meta{
+		Name: f.name,
+		Key:  f.key,
+		Size: f.data.totalBytes,
+		Stat: st,
+		s:    f.s,
+		kids: append([]child(nil), f.kids...),
+		norm: f.norm,
+	}
+}
+
+// LoadMeta loads the metadata of the file stored at key in s, without loading
+// its data index or extended attributes.
+func LoadMeta(ctx context.Context, s blob.CAS, key string) (Meta, error) {
+	bits, err := s.Get(ctx, key)
+	if err != nil {
+		return Meta{}, fmt.Errorf("loading file %x: %w", key, err)
+	}
+	node, err := wiretype.DecodeNodeMeta(bits)
+	if err != nil {
+		return Meta{}, fmt.Errorf("decoding file %x: %w", key, err)
+	}
+	m := Meta{Key: key, Size: int64(node.Index.GetTotalBytes()), s: s}
+	m.Stat.fromWireType(node.Stat)
+	for _, kid := range node.Children {
+		m.kids = append(m.kids, child{Name: kid.Name, Key: string(kid.Key)})
+	}
+	return m, nil
+}
+
+// Children returns the names of the children of the file, in order.
+func (m Meta) Children() []string {
+	out := make([]string, len(m.kids))
+	for i, kid := range m.kids {
+		out[i] = kid.Name
+	}
+	return out
+}
+
+// Child returns the metadata of the named child of the file, or reports
+// ErrChildNotFound if no such child exists. If the child has been opened in
+// memory, its current state is reported; otherwise its metadata are loaded
+// from storage without loading its data index.
+//
+// If m was obtained from a file with a name normalizer, names are matched as
+// by [File.Open], and children loaded from storage inherit the normalizer.
+func (m Meta) Child(ctx context.Context, name string) (Meta, error) {
+	i, ok := findChild(m.kids, m.norm, name)
+	if !ok {
+		return Meta{}, fmt.Errorf("open %q: %w", name, ErrChildNotFound)
+	}
+	kid := m.kids[i]
+	if kid.File != nil {
+		return kid.File.Meta(), nil
+	}
+	out, err := LoadMeta(ctx, m.s, kid.Key)
+	if err != nil {
+		return Meta{}, err
+	}
+	out.Name = kid.Name
+	out.norm = m.norm
+	return out, nil
+}
+
+// A ChildStat is the name and metadata of a child file, as reported by
+// [Child.Stats].
+type ChildStat struct {
+	Name string      // the name of the child
+	Info fs.FileInfo // its metadata; Info.Sys() returns a Meta
+}
+
+// statWorkers is the number of children whose metadata are loaded
+// concurrently by Child.Stats.
+const statWorkers = 8
+
+// Stats returns an iterator over the names and metadata of the children of
+// the file, in order. Children that have been opened report their current
+// state; the metadata of other children are loaded from storage as by
+// [LoadMeta], several at a time, without opening them.
+//
+// Unlike opening each child in turn, Stats does not decode the data indexes
+// of the children or retain them in memory, so it is the preferred way to
+// list the contents of a large directory.
+//
+// If the metadata of a child cannot be loaded, the iterator reports an error
+// for that child, with its Name populated, and continues with the next.
+func (c Child) Stats(ctx context.Context) iter.Seq2[ChildStat, error] {
+	return func(yield func(ChildStat, error) bool) {
+		c.f.mu.RLock()
+		kids := slices.Clone(c.f.kids)
+		s, norm := c.f.s, c.f.norm
+		c.f.mu.RUnlock()
+		statChildren(ctx, s, norm, kids)(yield)
+	}
+}
+
+// Stats returns an iterator over the names and metadata of the children of
+// the file described by m, in order, as [Child.Stats]. For each child, the
+// Info.Sys method returns its Meta, which can be used in turn to list its
+// children, so that a tree can be traversed without opening any files.
+func (m Meta) Stats(ctx context.Context) iter.Seq2[ChildStat, error] {
+	return statChildren(ctx, m.s, m.norm, m.kids)
+}
+
+// statChildren returns an iterator over the metadata of kids, loading those
+// that are not open from s. Metadata loaded from s inherit the name normalizer
+// norm. The caller must not modify kids.
+func statChildren(ctx context.Context, s blob.CAS, norm func(string) string, kids []child) iter.Seq2[ChildStat, error] {
+	return func(yield func(ChildStat, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+
+		type result struct {
+			meta Meta
+			err  error
+			done chan struct{}
+		}
+		res := make([]result, len(kids))
+		for i := range res {
+			res[i].done = make(chan struct{})
+		}
+
+		// Workers claim children in order, so that the results the caller
+		// needs first are loaded first.
+		var μ sync.Mutex
+		next := 0
+		var wg sync.WaitGroup
+		defer func() { cancel(); wg.Wait() }() // stop the workers before waiting
+		for range min(statWorkers, len(kids)) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					μ.Lock()
+					i := next
+					next++
+					μ.Unlock()
+					if i >= len(kids) {
+						return
+					}
+					r, kid := &res[i], kids[i]
+					if kid.File != nil {
+						r.meta = kid.File.Meta()
+					} else if err := ctx.Err(); err != nil {
+						r.err = err
+					} else {
+						r.meta, r.err = LoadMeta(ctx, s, kid.Key)
+						r.meta.norm = norm
+					}
+					r.meta.Name = kid.Name
+					close(r.done)
+				}
+			}()
+		}
+
+		for i := range res {
+			r := &res[i]
+			<-r.done
+			cs := ChildStat{Name: kids[i].Name}
+			if r.err == nil {
+				cs.Info = r.meta.FileInfo()
+			}
+			if !yield(cs, r.err) {
+				return
+			}
+		}
+	}
+}
+
+// FileInfo returns a fs.FileInfo describing m.
+func (m Meta) FileInfo() fs.FileInfo { return metaInfo{m: m} }
+
+type metaInfo struct{ m Meta }
+
+func (n metaInfo) Name() string       { return n.m.Name }
+func (n metaInfo) Size() int64        { return n.m.Size }
+func (n metaInfo) Mode() fs.FileMode  { return n.m.Stat.Mode }
+func (n metaInfo) ModTime() time.Time { return n.m.Stat.ModTime }
+func (n metaInfo) IsDir() bool        { return n.m.Stat.Mode.IsDir() }
+
+// Sys returns a copy of the Meta.
+func (n metaInfo) Sys() any { return n.m }
@@ -0,0 +1,120 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"context"
+	"io"
+	"io/fs"
+
+	"github.com/creachadair/ffs/blob"
+)
+
+// A View is a read-only handle to a File. A View exposes the read operations
+// of the file, but none of the operations that modify or invalidate it, so it
+// is safe to share with concurrent readers that should not be able to change
+// the file. A zero View is not valid; use OpenReadOnly or File.ReadOnly.
+type View struct{ f *File }
+
+// OpenReadOnly opens an existing file given its storage key in s, and returns
+// a read-only View of it.
+func OpenReadOnly(ctx context.Context, s blob.CAS, key string) (View, error) {
+	f, err := Open(ctx, s, key)
+	if err != nil {
+		return View{}, err
+	}
+	return View{f: f}, nil
+}
+
+// ReadOnly returns a read-only View of f. Changes made to f via other
+// references remain visible through the view.
+func (f *File) ReadOnly() View { return View{f: f} }
+
+// Key returns the storage key of the viewed file if it is known, or "" if the
+// file has not been flushed to storage in its current form.
+func (v View) Key() string { return v.f.Key() }
+
+// Name reports the attributed name of the viewed file.
+func (v View) Name() string { return v.f.Name() }
+
+// Stat returns a copy of the stat metadata for the viewed file.  The result
+// is not associated with the file, so its Clear, Update, and Persist methods
+// must not be used.
+func (v View) Stat() Stat {
+	v.f.mu.RLock()
+	defer v.f.mu.RUnlock()
+	return v.f.stat // N.B. f is nil in the copy
+}
+
+// FileInfo returns a fs.FileInfo describing the current state of the viewed
+// file. The result is detached from the file: its Sys method returns a [Meta]
+// rather than the underlying *File, so it cannot be used to modify the file.
+func (v View) FileInfo() fs.FileInfo { return v.f.Meta().FileInfo() }
+
+// Size returns the effective size of the file content in bytes.
+func (v View) Size() int64 { return v.f.Data().Size() }
+
+// ReadAt reads up to len(data) bytes into data from the given offset, and
+// reports the number of bytes successfully read, as io.ReaderAt.
+func (v View) ReadAt(ctx context.Context, data []byte, offset int64) (int, error) {
+	return v.f.ReadAt(ctx, data, offset)
+}
+
+// Reader returns an io.ReadSeeker for the content of the viewed file, bound to
+// ctx. The content size is captured when Reader is called.  The reader may be
+// used only during the lifetime of the request whose context it binds.
+func (v View) Reader(ctx context.Context) *io.SectionReader {
+	return io.NewSectionReader(viewReader{ctx: ctx, f: v.f}, 0, v.Size())
+}
+
+// Open opens the specified child of the viewed file as a read-only View, or
+// returns ErrChildNotFound if no such child exists.
+func (v View) Open(ctx context.Context, name string) (View, error) {
+	c, err := v.f.Open(ctx, name)
+	if err != nil {
+		return View{}, err
+	}
+	return View{f: c}, nil
+}
+
+// HasChild reports whether the viewed file has a child with the given name.
+func (v View) HasChild(name string) bool { return v.f.Child().Has(name) }
+
+// ChildNames returns a lexicographically ordered slice of the names of all the
+// children of the viewed file.
+func (v View) ChildNames() []string { return v.f.Child().Names() }
+
+// XAttr reports the value of the specified extended attribute, and whether it
+// is defined on the viewed file.
+func (v View) XAttr(name string) (string, bool) {
+	v.f.mu.RLock()
+	defer v.f.mu.RUnlock()
+	val, ok := v.f.xattr[name]
+	return val, ok
+}
+
+// XAttrNames returns a slice of the names of all the extended attributes
+// defined on the viewed file, in lexicographic order.
+func (v View) XAttrNames() []string { return v.f.XAttr().Names() }
+
+// viewReader adapts a *File to the io.ReaderAt interface with a fixed context.
+type viewReader struct {
+	ctx context.Context
+	f   *File
+}
+
+func (r viewReader) ReadAt(data []byte, offset int64) (int, error) {
+	return r.f.ReadAt(r.ctx, data, offset)
+}
@@ -0,0 +1,161 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package root
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"unicode/utf8"
+
+	"github.com/creachadair/ffs/blob"
+)
+
+// List returns an iterator over the roots stored in kv, in order by key, with
+// their decoded values. The roots are associated with kv. Signatures are not
+// reported. If a root cannot be loaded or decoded, the iterator reports the
+// error and stops.
+//
+// List is equivalent to the ListRoots method of a [KV] wrapping kv, except that
+// the roots it reports are associated with kv itself.
+func List(ctx context.Context, kv blob.KV) iter.Seq2[Entry, error] {
+	return func(yield func(Entry, error) bool) {
+		for e, err := range NewKV(kv).ListRoots(ctx) {
+			if err == nil {
+				e.Root.kv = kv
+			}
+			if !yield(e, err) {
+				return
+			}
+		}
+	}
+}
+
+// A Record is the exported form of a root written by [Export] and read by
+// [Import]. The file and index keys are encoded in hexadecimal, so that the
+// records can be read and edited by hand.
+type Record struct {
+	Key         string `json:"key"`
+	Description string `json:"description,omitempty"`
+	FileKey     string `json:"file_key"`
+	IndexKey    string `json:"index_key,omitempty"`
+
+	// The detached signature of the root, if it has one.
+	Signature []byte `json:"signature,omitempty"`
+}
+
+// Export writes a record for each root stored in kv to w, in order by key, and
+// reports the number of roots written. Each record is a [Record] encoded as a
+// single line of JSON. A root's detached signature, if it has one, is included
+// in its record.
+//
+// The storage key of each root must be valid UTF-8, so that it can be encoded
+// as a JSON string; otherwise Export reports an error.
+func Export(ctx context.Context, w io.Writer, kv blob.KV) (int, error) {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	var n int
+	for e, err := range List(ctx, kv) {
+		if err != nil {
+			return n, err
+		} else if !utf8.ValidString(e.Key) {
+			return n, fmt.Errorf("root %q: key is not valid UTF-8", e.Key)
+		}
+		rec := Record{
+			Key:         e.Key,
+			Description: e.Root.Description,
+			FileKey:     hex.EncodeToString([]byte(e.Root.FileKey)),
+			IndexKey:    hex.EncodeToString([]byte(e.Root.IndexKey)),
+		}
+		sig, err := kv.Get(ctx, SignatureKey(e.Key))
+		if err == nil {
+			rec.Signature = sig
+		} else if !blob.IsKeyNotFound(err) {
+			return n, fmt.Errorf("loading root signature %q: %w", e.Key, err)
+		}
+		if err := enc.Encode(rec); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, bw.Flush()
+}
+
+// Import reads records written by [Export] from r, stores the root described
+// by each in kv, and reports the number of roots stored. If replace is false,
+// Import fails when it reaches a root whose key already exists in kv.
+//
+// The signature from a record, if present, is stored alongside its root. Roots
+// are stored in the same canonical encoding used by Save, so the signature of
+// a root that was written by Save remains valid after import.
+//
+// Roots are stored one at a time, in the order they are read. If Import fails,
+// the roots stored before the failure remain in kv.
+func Import(ctx context.Context, r io.Reader, kv blob.KV, replace bool) (int, error) {
+	dec := json.NewDecoder(r)
+	var n int
+	for {
+		var rec Record
+		if err := dec.Decode(&rec); errors.Is(err, io.EOF) {
+			return n, nil
+		} else if err != nil {
+			return n, fmt.Errorf("record %d: %w", n+1, err)
+		}
+		if err := importRecord(ctx, kv, rec, replace); err != nil {
+			return n, fmt.Errorf("record %d: %w", n+1, err)
+		}
+		n++
+	}
+}
+
+// importRecord stores the root described by rec in kv.
+func importRecord(ctx context.Context, kv blob.KV, rec Record, replace bool) error {
+	if rec.Key == "" {
+		return errors.New("missing root key")
+	}
+	fkey, err := hex.DecodeString(rec.FileKey)
+	if err != nil {
+		return fmt.Errorf("root %q: invalid file key: %w", rec.Key, err)
+	}
+	ikey, err := hex.DecodeString(rec.IndexKey)
+	if err != nil {
+		return fmt.Errorf("root %q: invalid index key: %w", rec.Key, err)
+	}
+	rt := New(kv, &Options{
+		Description: rec.Description,
+		FileKey:     string(fkey),
+		IndexKey:    string(ikey),
+	})
+	bits, _, err := rt.encode(rec.Key)
+	if err != nil {
+		return fmt.Errorf("root %q: %w", rec.Key, err)
+	}
+	if err := kv.Put(ctx, blob.PutOptions{Key: rec.Key, Data: bits, Replace: replace}); err != nil {
+		return err
+	}
+	if rec.Signature == nil {
+		return nil
+	}
+	return kv.Put(ctx, blob.PutOptions{
+		Key:     SignatureKey(rec.Key),
+		Data:    rec.Signature,
+		Replace: true,
+	})
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package root
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/file/wiretype"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrNotRoot is reported by a [KV] for a value that is not a valid encoding
+// of a root record.
+var ErrNotRoot = errors.New("value is not a root")
+
+// A KV wraps a keyspace that holds root records, and checks that the values
+// written to and read from it are valid roots. This catches corruption, and
+// accidental use of the keyspace for other data, when it happens rather than
+// when the root is next opened.
+//
+// Keys beginning with [SignaturePrefix] hold detached signatures for roots,
+// and their values are not checked.
+type KV struct {
+	blob.KV
+}
+
+// NewKV constructs a [KV] that wraps kv. It will panic if kv == nil.
+func NewKV(kv blob.KV) KV {
+	if kv == nil {
+		panic("keyspace is nil")
+	}
+	return KV{KV: kv}
+}
+
+// Get implements part of the [blob.KV] interface. If the value of key is not
+// a valid root, Get reports an error wrapping ErrNotRoot.
+func (k KV) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := k.KV.Get(ctx, key)
+	if err != nil || isSignatureKey(key) {
+		return data, err
+	}
+	if _, err := k.decode(key, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Put implements part of the [blob.KV] interface. If opts.Data is not a valid
+// root, Put reports an error wrapping ErrNotRoot and writes nothing.
+func (k KV) Put(ctx context.Context, opts blob.PutOptions) error {
+	if !isSignatureKey(opts.Key) {
+		if _, err := k.decode(opts.Key, opts.Data); err != nil {
+			return err
+		}
+	}
+	return k.KV.Put(ctx, opts)
+}
+
+// decode decodes data as the root stored at key.
+func (k KV) decode(key string, data []byte) (*Root, error) {
+	var obj wiretype.Object
+	if err := proto.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("root %q: %w: %w", key, ErrNotRoot, err)
+	}
+	r, err := Decode(k, &obj)
+	if err != nil {
+		return nil, fmt.Errorf("root %q: %w: %w", key, ErrNotRoot, err)
+	}
+	return r, nil
+}
+
+// An Entry is a root record reported by [KV.ListRoots].
+type Entry struct {
+	Key  string // the storage key of the root
+	Root *Root  // the decoded root, associated with the KV
+}
+
+// ListRoots returns an iterator over the roots stored in k, in order by key,
+// with their decoded values. Signatures are not reported. If a root cannot be
+// loaded or decoded, the iterator reports the error and stops.
+func (k KV) ListRoots(ctx context.Context) iter.Seq2[Entry, error] {
+	return func(yield func(Entry, error) bool) {
+		for key, err := range k.KV.List(ctx, "") {
+			if err != nil {
+				yield(Entry{}, err)
+				return
+			} else if isSignatureKey(key) {
+				continue
+			}
+			data, err := k.KV.Get(ctx, key)
+			if err != nil {
+				yield(Entry{}, fmt.Errorf("loading root %q: %w", key, err))
+				return
+			}
+			r, err := k.decode(key, data)
+			if err != nil {
+				yield(Entry{}, err)
+				return
+			}
+			if !yield(Entry{Key: key, Root: r}, nil) {
+				return
+			}
+		}
+	}
+}
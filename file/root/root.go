@@ -20,6 +20,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
 
 	"github.com/creachadair/ffs/blob"
 	"github.com/creachadair/ffs/file"
@@ -32,7 +35,8 @@ var ErrNoData = errors.New("requested data not found")
 
 // A Root records the location of the root of a file tree.
 type Root struct {
-	kv blob.KV
+	kv     blob.KV
+	signer Signer // if non-nil, sign the root when it is saved
 
 	Description string // a human-readable description
 	FileKey     string // the storage key of the file node
@@ -46,7 +50,8 @@ func New(s blob.KV, opts *Options) *Root {
 		opts = new(Options)
 	}
 	return &Root{
-		kv: s,
+		kv:     s,
+		signer: opts.Signer,
 
 		Description: opts.Description,
 		FileKey:     opts.FileKey,
@@ -54,13 +59,84 @@ func New(s blob.KV, opts *Options) *Root {
 	}
 }
 
+// Create creates a new root at the given storage key in s, whose root file is
+// a new empty directory stored in files, and returns the root and its file.
+// The Description and IndexKey of the new root are set from opts, if it is
+// non-nil; any FileKey in opts is ignored. Create will panic if files == nil.
+//
+// The files store should not share a keyspace with s, since file data in the
+// roots keyspace are rejected by [List], [Export], and [KV.ListRoots].
+//
+// Create does not replace an existing root: If key already exists in s, it
+// reports an error satisfying [blob.IsKeyExists], and no root is written.
+func Create(ctx context.Context, s blob.KV, files blob.CAS, key string, opts *Options) (*Root, *file.File, error) {
+	if files == nil {
+		panic("file store is nil")
+	}
+	rf := file.New(files, &file.NewOptions{
+		Stat:        &file.Stat{Mode: fs.ModeDir | 0755},
+		PersistStat: true,
+	})
+	fkey, err := rf.Flush(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("flushing root file: %w", err)
+	}
+	r := New(s, opts)
+	r.FileKey = fkey
+	if err := r.Save(ctx, key, false); err != nil {
+		return nil, nil, fmt.Errorf("creating root %q: %w", key, err)
+	}
+	return r, rf, nil
+}
+
 // Open opens a stored root record given its storage key in s.
 func Open(ctx context.Context, s blob.KV, key string) (*Root, error) {
+	return OpenWith(ctx, s, key, nil)
+}
+
+// OpenOptions control the opening of existing roots. A nil *OpenOptions is
+// ready for use and provides default values as described.
+type OpenOptions struct {
+	// If non-nil, Verifier is used to check the detached signature of the
+	// root before it is decoded. If the signature is missing or invalid, the
+	// root is not opened, and OpenWith reports an error wrapping
+	// ErrBadSignature. If nil, signatures are not checked.
+	Verifier Verifier
+
+	// If non-nil, Signer is used to sign the root when it is saved.
+	Signer Signer
+}
+
+// OpenWith opens a stored root record given its storage key in s, using the
+// specified options.
+func OpenWith(ctx context.Context, s blob.KV, key string, opts *OpenOptions) (*Root, error) {
+	bits, err := s.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("loading root %q: %w", key, err)
+	}
+	if opts != nil && opts.Verifier != nil {
+		sig, err := s.Get(ctx, SignatureKey(key))
+		if blob.IsKeyNotFound(err) {
+			return nil, fmt.Errorf("root %q: missing signature: %w", key, ErrBadSignature)
+		} else if err != nil {
+			return nil, fmt.Errorf("loading root signature %q: %w", key, err)
+		}
+		if err := opts.Verifier.Verify(signedMessage(key, bits), sig); err != nil {
+			return nil, fmt.Errorf("root %q: %w: %w", key, ErrBadSignature, err)
+		}
+	}
 	var obj wiretype.Object
-	if err := wiretype.Load(ctx, s, key, &obj); err != nil {
+	if err := proto.Unmarshal(bits, &obj); err != nil {
 		return nil, fmt.Errorf("loading root %q: %w", key, err)
 	}
-	return Decode(s, &obj)
+	r, err := Decode(s, &obj)
+	if err != nil {
+		return nil, err
+	}
+	if opts != nil {
+		r.signer = opts.Signer
+	}
+	return r, nil
 }
 
 // File loads and returns the root file of r from s, if one exists.  If no file
@@ -76,21 +152,129 @@ func (r *Root) File(ctx context.Context, s blob.CAS) (*file.File, error) {
 }
 
 // Save writes r in wire format to the given storage key in s.
+//
+// If r has a signer, Save also writes a detached signature for the root at
+// SignatureKey(key), replacing any existing signature. The signature is
+// written after the root, so that an interrupted Save leaves a root whose
+// signature does not verify, rather than a valid signature for a root that
+// was not written.
 func (r *Root) Save(ctx context.Context, key string, replace bool) error {
-	if r.FileKey == "" {
-		return errors.New("missing file key")
-	}
-	bits, err := proto.Marshal(Encode(r))
+	bits, sig, err := r.encode(key)
 	if err != nil {
 		return err
 	}
-	return r.kv.Put(ctx, blob.PutOptions{
+	if err := r.kv.Put(ctx, blob.PutOptions{
 		Key:     key,
 		Data:    bits,
 		Replace: replace,
+	}); err != nil {
+		return err
+	}
+	if sig == nil {
+		return nil
+	}
+	return r.kv.Put(ctx, blob.PutOptions{
+		Key:     SignatureKey(key),
+		Data:    sig,
+		Replace: true,
 	})
 }
 
+// encode returns the wire encoding of r for storage at key, and its detached
+// signature if r has a signer.
+func (r *Root) encode(key string) (bits, sig []byte, err error) {
+	if isSignatureKey(key) {
+		return nil, nil, errors.New("key is reserved for signatures")
+	} else if r.FileKey == "" {
+		return nil, nil, errors.New("missing file key")
+	}
+	bits, err = wiretype.MarshalCanonical(Encode(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	if r.signer != nil {
+		sig, err = r.signer.Sign(signedMessage(key, bits))
+		if err != nil {
+			return nil, nil, fmt.Errorf("signing root: %w", err)
+		}
+	}
+	return bits, sig, nil
+}
+
+// SaveAll writes each root in roots in wire format to its storage key in kv,
+// along with its detached signature if it has a signer, so that a snapshot
+// spanning several roots can be published together. The roots are written to
+// kv regardless of the store each was opened or created with. If replace is
+// false, SaveAll fails without writing anything if any of the keys exists.
+//
+// If kv implements [blob.MultiPutter], the roots are written atomically.
+// Otherwise, SaveAll first records the existing contents of each key, then
+// writes the roots in lexicographic order of key, each followed by its
+// signature. If a write fails, SaveAll attempts to restore the keys it has
+// already written to their previous state, in reverse order, and reports the
+// original error along with any error from the restoration. A concurrent
+// writer, or a failure during restoration, can leave some roots updated and
+// others not.
+func SaveAll(ctx context.Context, kv blob.KV, roots map[string]*Root, replace bool) error {
+	var puts []blob.PutOptions
+	for _, key := range slices.Sorted(maps.Keys(roots)) {
+		bits, sig, err := roots[key].encode(key)
+		if err != nil {
+			return fmt.Errorf("root %q: %w", key, err)
+		}
+		puts = append(puts, blob.PutOptions{Key: key, Data: bits, Replace: replace})
+		if sig != nil {
+			puts = append(puts, blob.PutOptions{Key: SignatureKey(key), Data: sig, Replace: true})
+		}
+	}
+	if mp, ok := kv.(blob.MultiPutter); ok {
+		return mp.PutAll(ctx, puts)
+	}
+
+	// Record the previous state of each key, so that we can restore it if any
+	// of the writes fails. Check for conflicts before writing anything.
+	type prevState struct {
+		data   []byte
+		exists bool
+	}
+	prev := make([]prevState, len(puts))
+	for i, p := range puts {
+		data, err := kv.Get(ctx, p.Key)
+		if blob.IsKeyNotFound(err) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("checking root %q: %w", p.Key, err)
+		} else if !p.Replace {
+			return blob.KeyExists(p.Key)
+		}
+		prev[i] = prevState{data: data, exists: true}
+	}
+
+	for i, p := range puts {
+		err := kv.Put(ctx, p)
+		if err == nil {
+			continue
+		}
+
+		// Restore the keys already written, even if ctx has ended.
+		errs := []error{fmt.Errorf("saving root %q: %w", p.Key, err)}
+		rctx := context.WithoutCancel(ctx)
+		for j := i - 1; j >= 0; j-- {
+			var rerr error
+			if prev[j].exists {
+				rerr = kv.Put(rctx, blob.PutOptions{Key: puts[j].Key, Data: prev[j].data, Replace: true})
+			} else if rerr = kv.Delete(rctx, puts[j].Key); blob.IsKeyNotFound(rerr) {
+				rerr = nil
+			}
+			if rerr != nil {
+				errs = append(errs, fmt.Errorf("restoring %q: %w", puts[j].Key, rerr))
+			}
+		}
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
 // Encode encodes r as a protobuf message for storage.
 func Encode(r *Root) *wiretype.Object {
 	return &wiretype.Object{
@@ -126,4 +310,7 @@ type Options struct {
 	FileKey     string
 	Description string
 	IndexKey    string
+
+	// If non-nil, Signer is used to sign the root when it is saved.
+	Signer Signer
 }
@@ -15,14 +15,20 @@
 package root_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/ed25519"
+	"errors"
 	"io/fs"
+	"strings"
 	"testing"
 
 	"github.com/creachadair/ffs/blob"
 	"github.com/creachadair/ffs/blob/memstore"
 	"github.com/creachadair/ffs/file"
 	"github.com/creachadair/ffs/file/root"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestRoot(t *testing.T) {
@@ -78,3 +84,371 @@ func TestRoot(t *testing.T) {
 		t.Errorf("Loaded index key: got %q, want %q", rc.IndexKey, r.IndexKey)
 	}
 }
+
+func TestCreate(t *testing.T) {
+	kv := memstore.NewKV()
+	files := blob.CASFromKV(memstore.NewKV())
+	ctx := context.Background()
+
+	r, rf, err := root.Create(ctx, kv, files, "new-root", &root.Options{
+		Description: "Created root",
+		FileKey:     "ignored",
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if !rf.Stat().Mode.IsDir() {
+		t.Errorf("Root file mode: got %v, want directory", rf.Stat().Mode)
+	}
+	if key := rf.Key(); key == "" || key != r.FileKey {
+		t.Errorf("Root file key: got %q, want %q", key, r.FileKey)
+	}
+
+	rc, err := root.Open(ctx, kv, "new-root")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if rc.Description != "Created root" || rc.FileKey != r.FileKey {
+		t.Errorf("Loaded root: got (%q, %q), want (%q, %q)",
+			rc.Description, rc.FileKey, "Created root", r.FileKey)
+	}
+	if _, err := rc.File(ctx, files); err != nil {
+		t.Errorf("Open root file: %v", err)
+	}
+
+	// Only the root is written to the roots keyspace.
+	var keys []string
+	for e, err := range root.List(ctx, kv) {
+		if err != nil {
+			t.Fatalf("List: unexpected error: %v", err)
+		}
+		keys = append(keys, e.Key)
+	}
+	if diff := cmp.Diff(keys, []string{"new-root"}); diff != "" {
+		t.Errorf("List (-got, +want):\n%s", diff)
+	}
+
+	// Creating the same root again must fail.
+	if _, _, err := root.Create(ctx, kv, files, "new-root", nil); !blob.IsKeyExists(err) {
+		t.Errorf("Create again: got %v, want %v", err, blob.ErrKeyExists)
+	}
+}
+
+func TestSignature(t *testing.T) {
+	kv := memstore.NewKV()
+	ctx := context.Background()
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	signer := root.Ed25519Signer{Key: priv}
+	verify := &root.OpenOptions{Verifier: root.Ed25519Verifier{Key: pub}}
+
+	// An unsigned root does not verify, but opens without a verifier.
+	r := root.New(kv, &root.Options{Description: "unsigned", FileKey: "f"})
+	if err := r.Save(ctx, "plain", false); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := root.OpenWith(ctx, kv, "plain", verify); !errors.Is(err, root.ErrBadSignature) {
+		t.Errorf("Open unsigned: got %v, want %v", err, root.ErrBadSignature)
+	}
+	if _, err := root.Open(ctx, kv, "plain"); err != nil {
+		t.Errorf("Open unsigned without verifier: %v", err)
+	}
+
+	// A signed root verifies with the matching key only.
+	r = root.New(kv, &root.Options{Description: "signed", FileKey: "f", Signer: signer})
+	if err := r.Save(ctx, "signed", false); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if rc, err := root.OpenWith(ctx, kv, "signed", verify); err != nil {
+		t.Errorf("Open signed: %v", err)
+	} else if rc.Description != "signed" {
+		t.Errorf("Open signed: got description %q, want %q", rc.Description, "signed")
+	}
+	other := &root.OpenOptions{Verifier: root.Ed25519Verifier{Key: otherPub}}
+	if _, err := root.OpenWith(ctx, kv, "signed", other); !errors.Is(err, root.ErrBadSignature) {
+		t.Errorf("Open with wrong key: got %v, want %v", err, root.ErrBadSignature)
+	}
+
+	// Tampering with the root invalidates the signature.
+	r.Description = "tampered"
+	bits, err := proto.Marshal(root.Encode(r))
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if err := kv.Put(ctx, blob.PutOptions{Key: "signed", Data: bits, Replace: true}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, err := root.OpenWith(ctx, kv, "signed", verify); !errors.Is(err, root.ErrBadSignature) {
+		t.Errorf("Open tampered: got %v, want %v", err, root.ErrBadSignature)
+	}
+
+	// A signed root copied to another name does not verify there.
+	sig, err := kv.Get(ctx, root.SignatureKey("signed"))
+	if err != nil {
+		t.Fatalf("Get signature: %v", err)
+	}
+	orig, err := proto.Marshal(root.Encode(root.New(nil, &root.Options{Description: "signed", FileKey: "f"})))
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	kv.Put(ctx, blob.PutOptions{Key: "moved", Data: orig})
+	kv.Put(ctx, blob.PutOptions{Key: root.SignatureKey("moved"), Data: sig})
+	if _, err := root.OpenWith(ctx, kv, "moved", verify); !errors.Is(err, root.ErrBadSignature) {
+		t.Errorf("Open moved: got %v, want %v", err, root.ErrBadSignature)
+	}
+
+	// A root opened with a signer is signed again when saved.
+	rc, err := root.OpenWith(ctx, kv, "plain", &root.OpenOptions{Signer: signer})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := rc.Save(ctx, "plain", true); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if _, err := root.OpenWith(ctx, kv, "plain", verify); err != nil {
+		t.Errorf("Open re-signed: %v", err)
+	}
+}
+
+// failKV is a blob.KV that does not support atomic writes, and fails writes
+// to a designated key.
+type failKV struct {
+	blob.KV
+	fail string
+}
+
+func (f failKV) Put(ctx context.Context, opts blob.PutOptions) error {
+	if opts.Key == f.fail {
+		return errors.New("write failed")
+	}
+	return f.KV.Put(ctx, opts)
+}
+
+func TestSaveAll(t *testing.T) {
+	ctx := context.Background()
+	mkRoots := func(desc string) map[string]*root.Root {
+		return map[string]*root.Root{
+			"a": root.New(nil, &root.Options{Description: desc, FileKey: "fa"}),
+			"b": root.New(nil, &root.Options{Description: desc, FileKey: "fb"}),
+			"c": root.New(nil, &root.Options{Description: desc, FileKey: "fc"}),
+		}
+	}
+	checkRoots := func(t *testing.T, kv blob.KV, want map[string]string) {
+		t.Helper()
+		for key, desc := range want {
+			r, err := root.Open(ctx, kv, key)
+			if desc == "" {
+				if !blob.IsKeyNotFound(err) {
+					t.Errorf("Open %q: got %v, want %v", key, err, blob.ErrKeyNotFound)
+				}
+			} else if err != nil {
+				t.Errorf("Open %q: %v", key, err)
+			} else if r.Description != desc {
+				t.Errorf("Open %q: got description %q, want %q", key, r.Description, desc)
+			}
+		}
+	}
+
+	for _, tc := range []struct {
+		name string
+		kv   func() blob.KV
+	}{
+		{"Atomic", func() blob.KV { return memstore.NewKV() }},
+		{"Ordered", func() blob.KV { return failKV{KV: memstore.NewKV()} }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			kv := tc.kv()
+			if err := root.SaveAll(ctx, kv, mkRoots("v1"), false); err != nil {
+				t.Fatalf("SaveAll: %v", err)
+			}
+			checkRoots(t, kv, map[string]string{"a": "v1", "b": "v1", "c": "v1"})
+
+			// Without replacement, an existing root prevents all the writes.
+			if err := root.SaveAll(ctx, kv, map[string]*root.Root{
+				"0": root.New(nil, &root.Options{Description: "v2", FileKey: "f0"}),
+				"b": root.New(nil, &root.Options{Description: "v2", FileKey: "fb"}),
+			}, false); !blob.IsKeyExists(err) {
+				t.Errorf("SaveAll: got %v, want %v", err, blob.ErrKeyExists)
+			}
+			checkRoots(t, kv, map[string]string{"0": "", "b": "v1"})
+
+			// A root without a file key prevents all the writes.
+			bad := mkRoots("v2")
+			bad["b"].FileKey = ""
+			if err := root.SaveAll(ctx, kv, bad, true); err == nil {
+				t.Error("SaveAll with missing file key: got nil, want error")
+			}
+			checkRoots(t, kv, map[string]string{"a": "v1", "b": "v1", "c": "v1"})
+
+			if err := root.SaveAll(ctx, kv, mkRoots("v3"), true); err != nil {
+				t.Fatalf("SaveAll: %v", err)
+			}
+			checkRoots(t, kv, map[string]string{"a": "v3", "b": "v3", "c": "v3"})
+		})
+	}
+
+	t.Run("Rollback", func(t *testing.T) {
+		kv := failKV{KV: memstore.NewKV(), fail: "c"}
+		if err := root.SaveAll(ctx, kv, map[string]*root.Root{
+			"a": root.New(nil, &root.Options{Description: "v1", FileKey: "fa"}),
+		}, false); err != nil {
+			t.Fatalf("SaveAll: %v", err)
+		}
+
+		// The write of "c" fails after "a" and "b" are written, so "a" is
+		// restored to its previous value and "b" is removed.
+		if err := root.SaveAll(ctx, kv, mkRoots("v2"), true); err == nil {
+			t.Error("SaveAll: got nil, want error")
+		}
+		checkRoots(t, kv, map[string]string{"a": "v1", "b": "", "c": ""})
+	})
+}
+
+func TestKV(t *testing.T) {
+	ctx := context.Background()
+	base := memstore.NewKV()
+	kv := root.NewKV(base)
+
+	// A root whose name resembles a signature is still a root.
+	for _, key := range []string{"b", "a", "c.sig"} {
+		r := root.New(kv, &root.Options{Description: "root " + key, FileKey: "f" + key})
+		if err := r.Save(ctx, key, false); err != nil {
+			t.Fatalf("Save %q: %v", key, err)
+		}
+	}
+	signed := root.New(kv, &root.Options{
+		Description: "root c", FileKey: "fc",
+		Signer: root.Ed25519Signer{Key: ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))},
+	})
+	if err := signed.Save(ctx, "c", false); err != nil {
+		t.Fatalf("Save signed: %v", err)
+	}
+
+	// Roots cannot be stored under the keys reserved for signatures.
+	if err := signed.Save(ctx, root.SignatureKey("c"), true); err == nil {
+		t.Error("Save at signature key: got nil, want error")
+	}
+
+	// Foreign values are rejected, and nothing is written.
+	if err := kv.Put(ctx, blob.PutOptions{Key: "junk", Data: []byte("not a root")}); !errors.Is(err, root.ErrNotRoot) {
+		t.Errorf("Put junk: got %v, want %v", err, root.ErrNotRoot)
+	}
+	fbits, err := proto.Marshal(file.Encode(file.New(nil, nil)))
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if err := kv.Put(ctx, blob.PutOptions{Key: "file", Data: fbits}); !errors.Is(err, root.ErrNotRoot) {
+		t.Errorf("Put file: got %v, want %v", err, root.ErrNotRoot)
+	}
+	if n, err := base.Len(ctx); err != nil || n != 5 {
+		t.Errorf("Len: got (%d, %v), want (5, nil)", n, err)
+	}
+
+	var got []string
+	for e, err := range kv.ListRoots(ctx) {
+		if err != nil {
+			t.Fatalf("ListRoots: unexpected error: %v", err)
+		}
+		got = append(got, e.Key+"="+e.Root.Description)
+	}
+	if diff := cmp.Diff(got, []string{"a=root a", "b=root b", "c=root c", "c.sig=root c.sig"}); diff != "" {
+		t.Errorf("ListRoots (-got, +want):\n%s", diff)
+	}
+
+	// Corruption written behind the wrapper is detected on read.
+	base.Put(ctx, blob.PutOptions{Key: "bad", Data: []byte("\xff\xff"), Replace: true})
+	if _, err := kv.Get(ctx, "bad"); !errors.Is(err, root.ErrNotRoot) {
+		t.Errorf("Get bad: got %v, want %v", err, root.ErrNotRoot)
+	}
+	var nr int
+	for _, err := range kv.ListRoots(ctx) {
+		if err != nil {
+			if !errors.Is(err, root.ErrNotRoot) {
+				t.Errorf("ListRoots: got %v, want %v", err, root.ErrNotRoot)
+			}
+			break
+		}
+		nr++
+	}
+	if nr != 2 {
+		t.Errorf("ListRoots: got %d roots before the error, want 2", nr)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	src := memstore.NewKV()
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	for _, key := range []string{"b", "a"} {
+		r := root.New(src, &root.Options{Description: "root " + key, FileKey: "\x00f" + key})
+		if err := r.Save(ctx, key, false); err != nil {
+			t.Fatalf("Save %q: %v", key, err)
+		}
+	}
+	signed := root.New(src, &root.Options{
+		Description: "root c", FileKey: "fc", IndexKey: "\xffic",
+		Signer: root.Ed25519Signer{Key: priv},
+	})
+	if err := signed.Save(ctx, "c", false); err != nil {
+		t.Fatalf("Save signed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if n, err := root.Export(ctx, &buf, src); err != nil || n != 3 {
+		t.Fatalf("Export: got (%d, %v), want (3, nil)", n, err)
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 3 {
+		t.Errorf("Export: got %d lines, want 3:\n%s", n, buf.String())
+	}
+	data := buf.String()
+
+	dst := memstore.NewKV()
+	if n, err := root.Import(ctx, strings.NewReader(data), dst, false); err != nil || n != 3 {
+		t.Fatalf("Import: got (%d, %v), want (3, nil)", n, err)
+	}
+	type rootInfo struct{ Key, Desc, File, Index string }
+	list := func(kv blob.KV) []rootInfo {
+		t.Helper()
+		var out []rootInfo
+		for e, err := range root.List(ctx, kv) {
+			if err != nil {
+				t.Fatalf("List: unexpected error: %v", err)
+			}
+			out = append(out, rootInfo{e.Key, e.Root.Description, e.Root.FileKey, e.Root.IndexKey})
+		}
+		return out
+	}
+	if diff := cmp.Diff(list(dst), list(src)); diff != "" {
+		t.Errorf("Imported roots (-got, +want):\n%s", diff)
+	}
+
+	// The signature of the signed root survives the round trip.
+	verify := &root.OpenOptions{Verifier: root.Ed25519Verifier{Key: pub}}
+	if _, err := root.OpenWith(ctx, dst, "c", verify); err != nil {
+		t.Errorf("Open imported signed root: %v", err)
+	}
+
+	// Existing roots are not replaced unless requested.
+	if _, err := root.Import(ctx, strings.NewReader(data), dst, false); !blob.IsKeyExists(err) {
+		t.Errorf("Import again: got %v, want key exists", err)
+	}
+	if n, err := root.Import(ctx, strings.NewReader(data), dst, true); err != nil || n != 3 {
+		t.Errorf("Import replace: got (%d, %v), want (3, nil)", n, err)
+	}
+
+	// Invalid records are rejected.
+	if _, err := root.Import(ctx, strings.NewReader(`{"key":"x","file_key":"zz"}`), dst, true); err == nil {
+		t.Error("Import invalid file key: got nil, want error")
+	}
+}
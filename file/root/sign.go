@@ -0,0 +1,94 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package root
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+// SignaturePrefix is prepended to the storage key of a root to obtain the
+// storage key of its detached signature; see [SignatureKey].
+//
+// Keys that begin with SignaturePrefix are reserved for signatures, so that a
+// signature cannot collide with a root: Save, SaveAll, and Import do not store
+// roots under such keys.
+const SignaturePrefix = "\x00sig\x00"
+
+// SignatureKey returns the storage key of the detached signature for the root
+// stored at key.
+func SignatureKey(key string) string { return SignaturePrefix + key }
+
+// isSignatureKey reports whether key is the storage key of a signature.
+func isSignatureKey(key string) bool { return strings.HasPrefix(key, SignaturePrefix) }
+
+// ErrBadSignature is reported when the signature of a root is missing or does
+// not verify.
+var ErrBadSignature = errors.New("invalid root signature")
+
+// A Signer produces detached signatures for root records.
+type Signer interface {
+	// Sign returns a signature for msg.
+	Sign(msg []byte) ([]byte, error)
+}
+
+// A Verifier checks detached signatures for root records.
+type Verifier interface {
+	// Verify reports nil if sig is a valid signature for msg, or otherwise
+	// an error.
+	Verify(msg, sig []byte) error
+}
+
+// Ed25519Signer is a [Signer] that uses an Ed25519 private key.
+type Ed25519Signer struct {
+	Key ed25519.PrivateKey
+}
+
+// Sign implements the [Signer] interface.
+func (s Ed25519Signer) Sign(msg []byte) ([]byte, error) {
+	if len(s.Key) != ed25519.PrivateKeySize {
+		return nil, errors.New("invalid ed25519 private key")
+	}
+	return ed25519.Sign(s.Key, msg), nil
+}
+
+// Ed25519Verifier is a [Verifier] that uses an Ed25519 public key.
+type Ed25519Verifier struct {
+	Key ed25519.PublicKey
+}
+
+// Verify implements the [Verifier] interface.
+func (v Ed25519Verifier) Verify(msg, sig []byte) error {
+	if len(v.Key) != ed25519.PublicKeySize {
+		return errors.New("invalid ed25519 public key")
+	} else if !ed25519.Verify(v.Key, msg, sig) {
+		return errors.New("ed25519 signature does not verify")
+	}
+	return nil
+}
+
+// signedMessage returns the message that is signed for the root record whose
+// encoding is bits, stored at key. Including the key in the message prevents
+// a validly-signed root from being substituted under a different name.
+func signedMessage(key string, bits []byte) []byte {
+	const domain = "ffs root signature v1\x00"
+	msg := make([]byte, 0, len(domain)+binary.MaxVarintLen64+len(key)+len(bits))
+	msg = append(msg, domain...)
+	msg = binary.AppendUvarint(msg, uint64(len(key)))
+	msg = append(msg, key...)
+	return append(msg, bits...)
+}
@@ -46,17 +46,32 @@ type Stat struct {
 // Clear clears the current stat metadata for the file associated with s.
 // Calling this method does not change whether stat is persisted, nor does it
 // modify the current contents of s, so calling Update on the same s will
-// restore the cleared values. Clear returns s.
-func (s Stat) Clear() Stat { s.f.mu.Lock(); defer s.f.mu.Unlock(); s.f.setStatLocked(Stat{}); return s }
+// restore the cleared values. Clear returns s. It will panic if the file is
+// read-only.
+func (s Stat) Clear() Stat {
+	s.f.mustWritable("clear stat")
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	s.f.setStatLocked(Stat{})
+	return s
+}
 
 // Update updates the stat metadata for the file associated with s to the
 // current contents of s. Calling this method does not change whether stat is
-// persisted. Update returns s.
-func (s Stat) Update() Stat { s.f.mu.Lock(); defer s.f.mu.Unlock(); s.f.setStatLocked(s); return s }
+// persisted. Update returns s. It will panic if the file is read-only.
+func (s Stat) Update() Stat {
+	s.f.mustWritable("update stat")
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	s.f.setStatLocked(s)
+	return s
+}
 
 // Persist enables (ok == true) or disables (ok == false) stat persistence for
 // the file associated with s. The contents of s are not changed. It returns s.
+// Persist will panic if the file is read-only.
 func (s Stat) Persist(ok bool) Stat {
+	s.f.mustWritable("persist stat")
 	s.f.mu.Lock()
 	defer s.f.mu.Unlock()
 	s.f.saveStat = ok
@@ -0,0 +1,217 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+
+	"github.com/creachadair/ffs/block"
+	"github.com/creachadair/ffs/file/wiretype"
+)
+
+// Stream returns a view of the named data stream of f. A data stream is a
+// secondary body of content, such as a resource fork or a thumbnail, stored
+// with its own block index alongside the primary content of the file. Unlike
+// an extended attribute, a stream may be arbitrarily large, and it can be
+// read and written piecewise.
+//
+// A file initially has no streams. Writing to a stream that does not exist
+// creates it. Streams use the split and inline settings of the file.
+func (f *File) Stream(name string) Stream { return Stream{f: f, name: name} }
+
+// Streams returns the names of the data streams of f in lexicographic order.
+func (f *File) Streams() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	names := make([]string, 0, len(f.streams))
+	for name := range f.streams {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Stream is a view of a named data stream of a file. See [File.Stream].
+type Stream struct {
+	f    *File
+	name string
+}
+
+// Name returns the name of the stream.
+func (s Stream) Name() string { return s.name }
+
+// Exists reports whether the stream exists.
+func (s Stream) Exists() bool {
+	s.f.mu.RLock()
+	defer s.f.mu.RUnlock()
+	_, ok := s.f.streams[s.name]
+	return ok
+}
+
+// Size returns the size of the stream content in bytes, or 0 if the stream
+// does not exist.
+func (s Stream) Size() int64 {
+	s.f.mu.RLock()
+	defer s.f.mu.RUnlock()
+	if d, ok := s.f.streams[s.name]; ok {
+		return d.totalBytes
+	}
+	return 0
+}
+
+// Keys returns the storage keys of the data blocks of the stream, in order.
+func (s Stream) Keys() []string {
+	s.f.mu.RLock()
+	defer s.f.mu.RUnlock()
+	d, ok := s.f.streams[s.name]
+	if !ok {
+		return nil
+	}
+	var keys []string
+	d.blocks(func(_ int64, key string) { keys = append(keys, key) })
+	return keys
+}
+
+// ReadAt reads up to len(data) bytes of the stream from the given offset, and
+// reports the number of bytes successfully read, as io.ReaderAt. A stream
+// that does not exist reads as empty.
+func (s Stream) ReadAt(ctx context.Context, data []byte, offset int64) (int, error) {
+	s.f.mu.RLock()
+	defer s.f.mu.RUnlock()
+	d, ok := s.f.streams[s.name]
+	if !ok {
+		return 0, io.EOF
+	}
+	return d.readAt(ctx, s.f.blocks(), data, offset)
+}
+
+// WriteAt writes len(data) bytes from data at the given offset of the stream,
+// and reports the number of bytes successfully written, as io.WriterAt. If the
+// stream does not exist, it is created.
+func (s Stream) WriteAt(ctx context.Context, data []byte, offset int64) (int, error) {
+	if err := s.f.checkWritable(); err != nil {
+		return 0, err
+	}
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	defer s.f.modifyLocked()
+	return s.f.streamLocked(s.name).writeAt(ctx, s.f.blocks(), data, offset)
+}
+
+// Truncate modifies the length of the stream to end at offset, extending or
+// contracting it as necessary. If the stream does not exist, it is created.
+func (s Stream) Truncate(ctx context.Context, offset int64) error {
+	if err := s.f.checkWritable(); err != nil {
+		return err
+	}
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	defer s.f.modifyLocked()
+	return s.f.streamLocked(s.name).truncate(ctx, s.f.blocks(), offset)
+}
+
+// SetData fully reads r and replaces the content of the stream with its data,
+// creating the stream if it does not exist. In case of error, the stream is
+// not modified.
+func (s Stream) SetData(ctx context.Context, r io.Reader) error {
+	if err := s.f.checkWritable(); err != nil {
+		return err
+	}
+	s.f.mu.RLock()
+	base := fileData{sc: s.f.data.sc, zc: s.f.data.zc, inlineLimit: s.f.data.inlineLimit}
+	s.f.mu.RUnlock()
+
+	bs := s.f.blocks()
+	fd, err := newFileData(block.NewSplitter(r, base.sc), base.zc, func(data []byte) (string, error) {
+		return bs.CASPut(ctx, data)
+	})
+	if err != nil {
+		return err
+	}
+	fd.inlineLimit = base.inlineLimit
+	if fd.canInline(fd.totalBytes) {
+		if err := fd.demote(ctx, bs); err != nil {
+			return err
+		}
+	}
+
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	defer s.f.modifyLocked()
+	if s.f.streams == nil {
+		s.f.streams = make(map[string]*fileData)
+	}
+	s.f.streams[s.name] = &fd
+	return nil
+}
+
+// Remove removes the stream from the file, and reports whether it existed.
+// Remove will panic if the file is read-only.
+func (s Stream) Remove() bool {
+	s.f.mustWritable("remove stream")
+	s.f.mu.Lock()
+	defer s.f.mu.Unlock()
+	if _, ok := s.f.streams[s.name]; !ok {
+		return false
+	}
+	delete(s.f.streams, s.name)
+	s.f.modifyLocked()
+	return true
+}
+
+// streamLocked returns the data of the named stream of f, creating it if it
+// does not exist. The caller must hold f.mu exclusively.
+func (f *File) streamLocked(name string) *fileData {
+	if d, ok := f.streams[name]; ok {
+		return d
+	}
+	if f.streams == nil {
+		f.streams = make(map[string]*fileData)
+	}
+	d := &fileData{sc: f.data.sc, zc: f.data.zc, inlineLimit: f.data.inlineLimit}
+	f.streams[name] = d
+	return d
+}
+
+// streamsToWireType encodes the streams of f. The caller must hold f.mu.
+func (f *File) streamsToWireType() []*wiretype.Stream {
+	out := make([]*wiretype.Stream, 0, len(f.streams))
+	for name, d := range f.streams {
+		out = append(out, &wiretype.Stream{Name: name, Index: d.toWireType()})
+	}
+	return out
+}
+
+// streamsFromWireType decodes the streams of a node into f.
+func (f *File) streamsFromWireType(pbs []*wiretype.Stream) error {
+	f.streams = nil
+	for _, pb := range pbs {
+		if _, ok := f.streams[pb.Name]; ok {
+			return errors.New("duplicate stream name")
+		}
+		d := &fileData{sc: f.data.sc, zc: f.data.zc, inlineLimit: f.data.inlineLimit}
+		if err := d.fromWireType(pb.Index); err != nil {
+			return err
+		}
+		if f.streams == nil {
+			f.streams = make(map[string]*fileData)
+		}
+		f.streams[pb.Name] = d
+	}
+	return nil
+}
@@ -14,7 +14,10 @@
 
 package file
 
-import "sort"
+import (
+	"context"
+	"sort"
+)
 
 // Child provides access to the children of a file.
 type Child struct{ f *File }
@@ -27,19 +30,95 @@ func (c Child) Has(name string) bool {
 	return ok
 }
 
-// Set makes kid a child of f under the given name. Set will panic if kid == nil.
+// Set makes kid a child of f under the given name. Set will panic if kid == nil,
+// or if f is read-only (use SetE to get ErrReadOnly instead). Set does not
+// check whether name is valid; use SetE or CheckName to do so.
 func (c Child) Set(name string, kid *File) {
 	if kid == nil {
 		panic("set: nil file")
 	}
+	c.f.mustWritable("set")
 	c.f.mu.Lock()
 	defer c.f.mu.Unlock()
+	c.setLocked(name, kid)
+}
+
+// SetE makes kid a child of f under the given name, as Set, but first checks
+// that name is valid (see [CheckName]). If not, SetE reports an error and f is
+// not modified. SetE will panic if kid == nil.
+func (c Child) SetE(name string, kid *File) error {
+	if kid == nil {
+		panic("set: nil file")
+	} else if err := c.f.checkWritable(); err != nil {
+		return err
+	}
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	if err := c.f.checkName(name); err != nil {
+		return err
+	}
+	c.setLocked(name, kid)
+	return nil
+}
+
+// CheckName reports an error wrapping ErrInvalidName if name is not a valid
+// name for a new child of f, using the check set by the CheckName field of the
+// options f was created or opened with, or otherwise the [CheckName] function.
+// SetE applies the same check.
+func (c Child) CheckName(name string) error { return c.f.checkName(name) }
+
+// Swap makes kid a child of f under the given name, as Set, and reports
+// whether it replaced an existing child. If so, key is the storage key of the
+// replaced child, or "" if that child has not been stored in its current
+// state. Swap will panic if kid == nil or if f is read-only. Like Set, Swap
+// does not check whether name is valid; use CheckName to do so.
+func (c Child) Swap(name string, kid *File) (key string, replaced bool) {
+	if kid == nil {
+		panic("swap: nil file")
+	}
+	c.f.mustWritable("swap")
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	if i, ok := c.f.findChildLocked(name); ok {
+		key, replaced = c.f.kids[i].Key, true
+		if old := c.f.kids[i].File; old != nil && old != kid {
+			old.mu.RLock()
+			key = old.key
+			old.mu.RUnlock()
+		}
+	}
+	c.setLocked(name, kid)
+	return key, replaced
+}
+
+// SetNew makes kid a child of f under the given name, as Set, but only if f
+// does not already have a child with that name. It reports whether kid was
+// added. The check and the insertion are atomic with respect to other
+// changes to the children of f. SetNew will panic if kid == nil or if f is
+// read-only. Like Set, SetNew does not check whether name is valid; use
+// CheckName to do so.
+func (c Child) SetNew(name string, kid *File) bool {
+	if kid == nil {
+		panic("set: nil file")
+	}
+	c.f.mustWritable("set")
+	c.f.mu.Lock()
+	defer c.f.mu.Unlock()
+	if _, ok := c.f.findChildLocked(name); ok {
+		return false
+	}
+	c.setLocked(name, kid)
+	return true
+}
+
+func (c Child) setLocked(name string, kid *File) {
 	defer c.f.modifyLocked()
-	kid.name = name
 	if i, ok := c.f.findChildLocked(name); ok {
-		c.f.kids[i].File = kid // replace an existing child
+		kid.name = c.f.kids[i].Name // preserve the stored name
+		c.f.kids[i].File = kid      // replace an existing child
 		return
 	}
+	kid.name = name
 	c.f.kids = append(c.f.kids, child{Name: name, File: kid})
 
 	// Restore lexicographic order.
@@ -55,7 +134,9 @@ func (c Child) Set(name string, kid *File) {
 func (c Child) Len() int { c.f.mu.RLock(); defer c.f.mu.RUnlock(); return len(c.f.kids) }
 
 // Remove removes name as a child of f, and reports whether a change was made.
+// Remove will panic if f is read-only.
 func (c Child) Remove(name string) bool {
+	c.f.mustWritable("remove")
 	c.f.mu.Lock()
 	defer c.f.mu.Unlock()
 	if i, ok := c.f.findChildLocked(name); ok {
@@ -128,6 +209,75 @@ func (d Data) Keys() []string {
 	return keys
 }
 
+// Hash returns a fingerprint of the file content, computed from the offsets,
+// sizes, and storage keys of its data blocks. Files with equal hashes have the
+// same content. The converse does not hold: Files with the same content may
+// have different hashes if their data were split into blocks differently.
+//
+// The first call to Hash visits all the data blocks of the file. After that,
+// the fingerprint is updated incrementally as the file is written, so that
+// subsequent calls are cheap.
+func (d Data) Hash() []byte { d.f.mu.Lock(); defer d.f.mu.Unlock(); return d.f.data.hash() }
+
+// A DataChange describes the effect of a modification of file data.
+type DataChange struct {
+	Size   int64 // the size of the file content after the change
+	Blocks int   // the number of data blocks written by the change
+}
+
+// WriteAt writes len(data) bytes from data at the given offset, as
+// [File.WriteAt]. In addition to the number of bytes written, it reports the
+// size of the file after the write, which is valid even in case of error.
+func (d Data) WriteAt(ctx context.Context, data []byte, offset int64) (int, DataChange, error) {
+	if err := d.f.checkWritable(); err != nil {
+		return 0, DataChange{Size: d.Size()}, err
+	}
+	d.f.mu.Lock()
+	cs := &countingStore{BlockStore: d.f.blocks()}
+	nw, err := d.f.data.writeAt(ctx, cs, data, offset)
+	d.f.modifyDataLocked()
+	rep := d.f.checkFragmentLocked(ctx)
+	dc := DataChange{Size: d.f.data.totalBytes, Blocks: cs.n}
+	d.f.mu.Unlock()
+
+	d.f.reportFragment(rep)
+	return nw, dc, err
+}
+
+// Truncate modifies the length of the file content to end at offset, as
+// [File.Truncate], and reports the size of the file after the change, which
+// is valid even in case of error.
+func (d Data) Truncate(ctx context.Context, offset int64) (DataChange, error) {
+	if err := d.f.checkWritable(); err != nil {
+		return DataChange{Size: d.Size()}, err
+	}
+	d.f.mu.Lock()
+	cs := &countingStore{BlockStore: d.f.blocks()}
+	err := d.f.data.truncate(ctx, cs, offset)
+	d.f.modifyDataLocked()
+	rep := d.f.checkFragmentLocked(ctx)
+	dc := DataChange{Size: d.f.data.totalBytes, Blocks: cs.n}
+	d.f.mu.Unlock()
+
+	d.f.reportFragment(rep)
+	return dc, err
+}
+
+// countingStore is a BlockStore that counts the blocks successfully written
+// through it. It is not safe for concurrent use.
+type countingStore struct {
+	BlockStore
+	n int
+}
+
+func (c *countingStore) CASPut(ctx context.Context, data []byte) (string, error) {
+	key, err := c.BlockStore.CASPut(ctx, data)
+	if err == nil {
+		c.n++
+	}
+	return key, err
+}
+
 // XAttr provides access to the extended attributes of a file.
 type XAttr struct{ f *File }
 
@@ -143,23 +293,29 @@ func (x XAttr) Has(key string) bool {
 // not defined.
 func (x XAttr) Get(key string) string { x.f.mu.RLock(); defer x.f.mu.RUnlock(); return x.f.xattr[key] }
 
-// Set sets the specified xattr.
+// Set sets the specified xattr. Values longer than [MaxInlineXAttr] bytes
+// are stored separately from the file node when the file is flushed.
+// Set will panic if the file is read-only.
 func (x XAttr) Set(key, value string) {
+	x.f.mustWritable("set xattr")
 	x.f.mu.Lock()
 	defer x.f.mu.Unlock()
 	defer x.f.invalLocked()
 	x.f.xattr[key] = value
+	delete(x.f.xkeys, key)
 }
 
 // Len reports the number of extended attributes defined on f.
 func (x XAttr) Len() int { x.f.mu.RLock(); defer x.f.mu.RUnlock(); return len(x.f.xattr) }
 
-// Remove removes the specified xattr.
+// Remove removes the specified xattr. It will panic if the file is read-only.
 func (x XAttr) Remove(key string) {
+	x.f.mustWritable("remove xattr")
 	x.f.mu.Lock()
 	defer x.f.mu.Unlock()
 	if _, ok := x.f.xattr[key]; ok {
 		delete(x.f.xattr, key)
+		delete(x.f.xkeys, key)
 		x.f.invalLocked()
 	}
 }
@@ -176,12 +332,34 @@ func (x XAttr) Names() []string {
 	return names
 }
 
-// Clear removes all the extended attributes set on the file.
+// Keys returns the storage keys of the attribute values that are stored apart
+// from the file node (see [MaxInlineXAttr]), in order by attribute name.
+// Values are stored apart when the file is flushed, so a large value set
+// since the last flush has no key yet.
+func (x XAttr) Keys() []string {
+	x.f.mu.RLock()
+	defer x.f.mu.RUnlock()
+	names := make([]string, 0, len(x.f.xkeys))
+	for name := range x.f.xkeys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	keys := make([]string, len(names))
+	for i, name := range names {
+		keys[i] = x.f.xkeys[name]
+	}
+	return keys
+}
+
+// Clear removes all the extended attributes set on the file. It will panic if
+// the file is read-only.
 func (x XAttr) Clear() {
+	x.f.mustWritable("clear xattr")
 	x.f.mu.Lock()
 	defer x.f.mu.Unlock()
 	if len(x.f.xattr) != 0 {
 		defer x.f.invalLocked()
 		clear(x.f.xattr)
+		clear(x.f.xkeys)
 	}
 }
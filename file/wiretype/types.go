@@ -21,11 +21,15 @@ package wiretype
 //go:generate protoc -I. -I../.. --go_out=. --go_opt=paths=source_relative wiretype.proto
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"sort"
 
 	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -40,14 +44,36 @@ func (n *Node) MarshalJSON() ([]byte, error) { return protojson.Marshal(n) }
 func (r *Root) MarshalJSON() ([]byte, error) { return protojson.Marshal(r) }
 
 // Normalize updates n in-place so that all fields are in canonical order.
+// If several extended attributes have the same name, only the last of them in
+// the original order is kept.
 func (n *Node) Normalize() {
 	n.Index.Normalize()
-	sort.Slice(n.XAttrs, func(i, j int) bool {
-		return n.XAttrs[i].Name < n.XAttrs[j].Name
+	slices.SortStableFunc(n.XAttrs, func(a, b *XAttr) int {
+		return cmp.Compare(a.Name, b.Name)
 	})
+	n.XAttrs = dedupXAttrs(n.XAttrs)
 	sort.Slice(n.Children, func(i, j int) bool {
 		return n.Children[i].Name < n.Children[j].Name
 	})
+	for _, s := range n.Streams {
+		s.Index.Normalize()
+	}
+	sort.Slice(n.Streams, func(i, j int) bool {
+		return n.Streams[i].Name < n.Streams[j].Name
+	})
+}
+
+// dedupXAttrs removes from xs, which must be sorted stably by name, all but
+// the last of each run of attributes with the same name.
+func dedupXAttrs(xs []*XAttr) []*XAttr {
+	out := xs[:0]
+	for i, xa := range xs {
+		if i+1 < len(xs) && xs[i+1].Name == xa.Name {
+			continue // superseded by a later attribute of the same name
+		}
+		out = append(out, xa)
+	}
+	return out
 }
 
 // Normalize updates n in-place so that all fields are in canonical order.
@@ -100,16 +126,155 @@ func Load(ctx context.Context, s Getter, key string, msg proto.Message) error {
 	return proto.Unmarshal(bits, msg)
 }
 
-// Save encodes msg in wire format and writes it to s, returning the storage key.
+// Save encodes msg in canonical wire format and writes it to s, returning the
+// storage key. See [MarshalCanonical].
 func Save(ctx context.Context, s Putter, msg proto.Message) (string, error) {
-	bits, err := proto.Marshal(msg)
+	bits, err := MarshalCanonical(msg)
 	if err != nil {
 		return "", fmt.Errorf("encoding message: %w", err)
 	}
 	return s.CASPut(ctx, bits)
 }
 
+// MarshalCanonical encodes msg in canonical wire format and returns the bytes.
+// Messages that are logically equal encode to the same bytes, so that storing
+// them in a content-addressable store yields the same key.
+//
+// Any Node or Index in msg is normalized, as by its Normalize method, before
+// it is encoded; msg itself is not modified. The encoding then relies on the
+// behavior of the Go protobuf runtime for the generated types in this
+// package, which have no map or unknown fields: Fields are emitted in
+// field-number order, and fields with zero values are omitted. The runtime
+// does not promise that this output is stable across its versions.
+func MarshalCanonical(msg proto.Message) ([]byte, error) {
+	switch m := msg.(type) {
+	case *Object:
+		if m.GetNode() != nil {
+			c := proto.Clone(m).(*Object)
+			c.GetNode().Normalize()
+			msg = c
+		}
+	case *Node:
+		if m != nil {
+			c := proto.Clone(m).(*Node)
+			c.Normalize()
+			msg = c
+		}
+	case *Index:
+		if m != nil {
+			c := proto.Clone(m).(*Index)
+			c.Normalize()
+			msg = c
+		}
+	}
+	return proto.Marshal(msg)
+}
+
 // ToBinary encodes msg in wire format and returns the bytes.
 // This is a wrapper around proto.Marshal so the caller does not need to
 // directly import the protobuf machinery.
 func ToBinary(msg proto.Message) ([]byte, error) { return proto.Marshal(msg) }
+
+// DecodeNodeMeta decodes the metadata of a file node from bits, which must
+// contain an encoded Object holding a Node. Unlike a full decode, the blocks
+// of the data index are skipped without being decoded: The result has an
+// Index with only TotalBytes set, and no XAttrs. This is much cheaper than a
+// full decode for nodes of large files.
+func DecodeNodeMeta(bits []byte) (*Node, error) {
+	nbits, ok, err := findField(bits, 1) // Object.node
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errors.New("object does not contain a node")
+	}
+
+	var node Node
+	for len(nbits) != 0 {
+		num, typ, n := protowire.ConsumeTag(nbits)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		nbits = nbits[n:]
+		if typ != protowire.BytesType || (num != 1 && num != 2 && num != 4) {
+			n = protowire.ConsumeFieldValue(num, typ, nbits)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			nbits = nbits[n:]
+			continue
+		}
+		val, n := protowire.ConsumeBytes(nbits)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		nbits = nbits[n:]
+
+		switch num {
+		case 1: // index
+			node.Index = new(Index)
+			for len(val) != 0 {
+				num, typ, n := protowire.ConsumeTag(val)
+				if n < 0 {
+					return nil, protowire.ParseError(n)
+				}
+				val = val[n:]
+				if num == 1 && typ == protowire.VarintType { // total_bytes
+					v, n := protowire.ConsumeVarint(val)
+					if n < 0 {
+						return nil, protowire.ParseError(n)
+					}
+					node.Index.TotalBytes = v
+					val = val[n:]
+					continue
+				}
+				n = protowire.ConsumeFieldValue(num, typ, val)
+				if n < 0 {
+					return nil, protowire.ParseError(n)
+				}
+				val = val[n:]
+			}
+		case 2: // stat
+			node.Stat = new(Stat)
+			if err := proto.Unmarshal(val, node.Stat); err != nil {
+				return nil, fmt.Errorf("decoding stat: %w", err)
+			}
+		case 4: // children
+			var kid Child
+			if err := proto.Unmarshal(val, &kid); err != nil {
+				return nil, fmt.Errorf("decoding child: %w", err)
+			}
+			node.Children = append(node.Children, &kid)
+		}
+	}
+	node.Normalize()
+	return &node, nil
+}
+
+// findField returns the contents of the last occurrence of the length-delimited
+// field num in the encoded message bits, and reports whether it was found.
+func findField(bits []byte, num protowire.Number) ([]byte, bool, error) {
+	var out []byte
+	var found bool
+	for len(bits) != 0 {
+		fnum, typ, n := protowire.ConsumeTag(bits)
+		if n < 0 {
+			return nil, false, protowire.ParseError(n)
+		}
+		bits = bits[n:]
+		if fnum == num && typ == protowire.BytesType {
+			val, n := protowire.ConsumeBytes(bits)
+			if n < 0 {
+				return nil, false, protowire.ParseError(n)
+			}
+			out, found = val, true
+			bits = bits[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(fnum, typ, bits)
+		if n < 0 {
+			return nil, false, protowire.ParseError(n)
+		}
+		bits = bits[n:]
+	}
+	return out, found, nil
+}
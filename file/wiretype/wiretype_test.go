@@ -0,0 +1,81 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wiretype_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/blob/memstore"
+	"github.com/creachadair/ffs/file/wiretype"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMarshalCanonical(t *testing.T) {
+	blk := func(n uint64, key string) []*wiretype.Block {
+		return []*wiretype.Block{{Bytes: n, Key: []byte(key)}}
+	}
+	// These nodes are logically equal, but their fields are in different
+	// orders and the extents are split differently.
+	a := &wiretype.Node{
+		Index: &wiretype.Index{TotalBytes: 10, Extents: []*wiretype.Extent{
+			{Base: 0, Bytes: 3, Blocks: blk(3, "1")},
+			{Base: 3, Bytes: 7, Blocks: blk(7, "2")},
+		}},
+		XAttrs:   []*wiretype.XAttr{{Name: "a", Value: []byte("1")}, {Name: "b", Value: []byte("2")}},
+		Children: []*wiretype.Child{{Name: "x", Key: []byte("k1")}, {Name: "y", Key: []byte("k2")}},
+	}
+	b := &wiretype.Node{
+		Index: &wiretype.Index{TotalBytes: 10, Extents: []*wiretype.Extent{
+			{Base: 8, Bytes: 0},
+			{Base: 0, Bytes: 10, Blocks: append(blk(3, "1"), blk(7, "2")...)},
+		}},
+		XAttrs:   []*wiretype.XAttr{{Name: "b", Value: []byte("2")}, {Name: "a", Value: []byte("1")}},
+		Children: []*wiretype.Child{{Name: "y", Key: []byte("k2")}, {Name: "x", Key: []byte("k1")}},
+	}
+	orig := proto.Clone(b).(*wiretype.Node)
+	abits, err := wiretype.MarshalCanonical(&wiretype.Object{Value: &wiretype.Object_Node{Node: a}})
+	if err != nil {
+		t.Fatalf("MarshalCanonical a: %v", err)
+	}
+	bbits, err := wiretype.MarshalCanonical(&wiretype.Object{Value: &wiretype.Object_Node{Node: b}})
+	if err != nil {
+		t.Fatalf("MarshalCanonical b: %v", err)
+	}
+	if !bytes.Equal(abits, bbits) {
+		t.Errorf("Canonical encodings differ:\na: %x\nb: %x", abits, bbits)
+	}
+
+	// The input is not modified by encoding it.
+	if !proto.Equal(b, orig) {
+		t.Errorf("MarshalCanonical modified its input:\ngot:  %v\nwant: %v", b, orig)
+	}
+
+	// Saving either node yields the same storage key.
+	cas := blob.CASFromKV(memstore.NewKV())
+	ka, err := wiretype.Save(context.Background(), cas, a)
+	if err != nil {
+		t.Fatalf("Save a: %v", err)
+	}
+	kb, err := wiretype.Save(context.Background(), cas, b)
+	if err != nil {
+		t.Fatalf("Save b: %v", err)
+	}
+	if ka != kb {
+		t.Errorf("Save keys differ: %x vs. %x", ka, kb)
+	}
+}
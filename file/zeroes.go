@@ -0,0 +1,57 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+// A ZeroConfig controls how runs of zero bytes in file data are suppressed.
+//
+// By default, data blocks consisting entirely of zeroes are not stored, and
+// long runs of zeroes at the head or tail of a block are trimmed off and
+// recorded as gaps between extents. This saves space for sparse data such as
+// disk images, but costs a scan of every block for data that rarely contains
+// zeroes, such as encrypted or compressed content.
+//
+// A nil *ZeroConfig is ready for use and provides default values.
+type ZeroConfig struct {
+	// Disable, if true, turns off zero suppression entirely: Zero bytes are
+	// stored like any other data.
+	Disable bool
+
+	// MinRun, if positive, is the minimum length in bytes of a run of zeroes
+	// at the head or tail of a block that is trimmed. Smaller values detect
+	// zeroes more aggressively, at the cost of more extents in the index.
+	//
+	// If MinRun ≤ 0, a default heuristic based on the block size is used.
+	MinRun int
+}
+
+// check reports the lengths of the zero prefix and suffix of data, along with
+// the length of data, as zeroCheck does. If zero suppression is disabled, it
+// reports no zeroes without scanning data.
+func (z *ZeroConfig) check(data []byte) (zhead, ztail, n int) {
+	if z != nil && z.Disable {
+		return 0, 0, len(data)
+	}
+	return zeroCheck(data)
+}
+
+// worthTrimming reports whether a prefix or suffix of nz zeroes should be
+// trimmed from a block of length n. If z does not set a threshold, def is
+// used to decide.
+func (z *ZeroConfig) worthTrimming(nz, n int, def func(nz, n int) bool) bool {
+	if z == nil || z.MinRun <= 0 {
+		return def(nz, n)
+	}
+	return nz >= z.MinRun
+}
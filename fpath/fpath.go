@@ -33,14 +33,44 @@ var (
 	// ErrNilFile is reported by Set when passed a nil file.
 	ErrNilFile = errors.New("nil file")
 
+	// ErrExist is reported by Set when the target of an exclusive set already
+	// exists.
+	ErrExist = errors.New("file exists")
+
 	// ErrSkipChildren signals to the Walk function that the children of the
 	// current node should not be visited.
 	ErrSkipChildren = errors.New("skip child files")
 )
 
+// A PathError reports a failure to traverse an element of a path. The error
+// wraps the underlying cause, so for example if the element does not exist,
+// errors.Is(err, file.ErrChildNotFound) is true.
+type PathError struct {
+	Path string // the path up to and including the element that failed
+	Elem string // the name of the element that failed
+	Err  error  // the underlying error
+}
+
+// Error implements the error interface.
+func (p *PathError) Error() string { return fmt.Sprintf("path %q: %v", p.Path, p.Err) }
+
+// Unwrap returns the underlying error from p, to support error wrapping.
+func (p *PathError) Unwrap() error { return p.Err }
+
+// newPathError returns a *PathError for the i-th element of the given path,
+// whose parsed elements are elems.
+func newPathError(path string, elems []string, i int, err error) *PathError {
+	pre := strings.Join(elems[:i+1], "/")
+	if strings.HasPrefix(path, "/") {
+		pre = "/" + pre
+	}
+	return &PathError{Path: pre, Elem: elems[i], Err: err}
+}
+
 // Open traverses the given slash-separated path sequentially from root, and
-// returns the resulting file or file.ErrChildNotFound. An empty path yields
-// root without error.
+// returns the resulting file or an error. If an element of the path does not
+// exist, the error is a *PathError wrapping file.ErrChildNotFound. An empty
+// path yields root without error.
 func Open(ctx context.Context, root *file.File, path string) (*file.File, error) {
 	fp, err := findPath(ctx, query{root: root, path: path})
 	return fp.target, err
@@ -49,14 +79,15 @@ func Open(ctx context.Context, root *file.File, path string) (*file.File, error)
 // OpenPath traverses the given slash-separated path sequentially from root,
 // and returns a slice of all the files along the path, not including root
 // itself.  If any element of the path does not exist, OpenPath returns the
-// prefix that was found along with an file.ErrChildNotFound error.
+// prefix that was found along with a *PathError wrapping file.ErrChildNotFound.
 func OpenPath(ctx context.Context, root *file.File, path string) ([]*file.File, error) {
 	var out []*file.File
 	cur := root
-	for _, name := range parsePath(path) {
+	elems := parsePath(path)
+	for i, name := range elems {
 		c, err := cur.Open(ctx, name)
 		if err != nil {
-			return out, err
+			return out, newPathError(path, elems, i, err)
 		}
 		out = append(out, c)
 		cur = c
@@ -64,6 +95,26 @@ func OpenPath(ctx context.Context, root *file.File, path string) ([]*file.File,
 	return out, nil
 }
 
+// Stat traverses the given slash-separated path sequentially from root, and
+// returns the metadata of the resulting file. Unlike Open, Stat does not load
+// the data index of any file along the path that has not already been opened,
+// nor does it open those files, so it is cheaper for tools that only need
+// metadata. If an element of the path does not exist, the error is a
+// *PathError wrapping file.ErrChildNotFound. An empty path yields the
+// metadata of root without error.
+func Stat(ctx context.Context, root *file.File, path string) (file.Meta, error) {
+	cur := root.Meta()
+	elems := parsePath(path)
+	for i, name := range elems {
+		c, err := cur.Child(ctx, name)
+		if err != nil {
+			return file.Meta{}, newPathError(path, elems, i, err)
+		}
+		cur = c
+	}
+	return cur, nil
+}
+
 // SetOptions control the behaviour of the Set function. A nil *SetOptions
 // behaves as a zero-valued options structure.
 type SetOptions struct {
@@ -78,10 +129,16 @@ type SetOptions struct {
 	// If not nil, insert this element at the end of the path.  If nil, a new
 	// empty file with default options is created.
 	File *file.File
+
+	// If true, it is an error (ErrExist) if the final element of the path
+	// already exists, and the existing file is not replaced.
+	Exclusive bool
 }
 
 func (s *SetOptions) create() bool { return s != nil && s.Create }
 
+func (s *SetOptions) exclusive() bool { return s != nil && s.Exclusive }
+
 func (s *SetOptions) target() *file.File {
 	if s == nil {
 		return nil
@@ -102,22 +159,50 @@ func (s *SetOptions) setStat(f *file.File) *file.File {
 // inserts a file at the end of it. An empty path is an error (ErrEmptyPath).
 //
 // If opts.Create is true, any missing path entries are created; otherwise it
-// is an error (file.ErrChildNotFound) if any path element except the last does
-// not exist.
+// is an error (a *PathError wrapping file.ErrChildNotFound) if any path
+// element except the last does not exist.
 //
 // If opts.File != nil, that file is inserted at the end of the path; otherwise
 // if opts.Create is true, a new empty file is inserted. If neither of these is
 // true, Set reports ErrNilFile.
+//
+// If opts.Exclusive is true and the final element of the path already exists,
+// Set reports ErrExist without modifying it. Intermediate path elements may
+// still be created in that case.
+//
+// The names of the final element and of any path elements Set creates are
+// checked as by [file.Child.SetE]. If a name is not valid, Set reports an
+// error wrapping file.ErrInvalidName.
 func Set(ctx context.Context, root *file.File, path string, opts *SetOptions) (*file.File, error) {
+	res, err := Put(ctx, root, path, opts)
+	return res.File, err
+}
+
+// SetResult reports the outcome of a successful call to [Put].
+type SetResult struct {
+	File *file.File // the file inserted at the end of the path
+
+	// Replaced reports whether File replaced an existing file, rather than
+	// creating a new entry.
+	Replaced bool
+
+	// If Replaced is true, PrevKey is the storage key of the replaced file,
+	// or "" if that file had not been stored in its current state.
+	PrevKey string
+}
+
+// Put inserts a file at the end of the given slash-separated path, as Set,
+// and reports whether a new entry was created or an existing one replaced.
+func Put(ctx context.Context, root *file.File, path string, opts *SetOptions) (SetResult, error) {
 	if opts.target() == nil && !opts.create() {
-		return nil, fmt.Errorf("set %q: %w", path, ErrNilFile)
+		return SetResult{}, fmt.Errorf("set %q: %w", path, ErrNilFile)
 	}
 	dir, base := "", path
 	if i := strings.LastIndex(path, "/"); i >= 0 {
 		dir, base = path[:i], path[i+1:]
 	}
 	if base == "" {
-		return nil, fmt.Errorf("set %q: %w", path, ErrEmptyPath)
+		return SetResult{}, fmt.Errorf("set %q: %w", path, ErrEmptyPath)
 	}
 	fp, err := findPath(ctx, query{
 		root: root,
@@ -125,7 +210,9 @@ func Set(ctx context.Context, root *file.File, path string, opts *SetOptions) (*
 		ef: func(fp *foundPath, err error) error {
 			if errors.Is(err, file.ErrChildNotFound) && opts.create() {
 				c := opts.setStat(fp.target.New(&file.NewOptions{Name: fp.targetName}))
-				fp.target.Child().Set(fp.targetName, c)
+				if err := fp.target.Child().SetE(fp.targetName, c); err != nil {
+					return err
+				}
 				fp.parent, fp.target = fp.target, c
 				return nil
 			}
@@ -133,19 +220,27 @@ func Set(ctx context.Context, root *file.File, path string, opts *SetOptions) (*
 		},
 	})
 	if err != nil {
-		return nil, err
+		return SetResult{}, err
+	} else if err := fp.target.Child().CheckName(base); err != nil {
+		return SetResult{}, fmt.Errorf("set %q: %w", path, err)
+	}
+	last := opts.target()
+	if last == nil {
+		last = opts.setStat(root.New(nil))
 	}
-	if last := opts.target(); last != nil {
-		fp.target.Child().Set(base, last)
-		return last, nil
+	if opts.exclusive() {
+		if !fp.target.Child().SetNew(base, last) {
+			return SetResult{}, fmt.Errorf("set %q: %w", path, ErrExist)
+		}
+		return SetResult{File: last}, nil
 	}
-	newf := root.New(nil)
-	fp.target.Child().Set(base, opts.setStat(newf))
-	return newf, nil
+	key, ok := fp.target.Child().Swap(base, last)
+	return SetResult{File: last, Replaced: ok, PrevKey: key}, nil
 }
 
 // Remove removes the file at the given slash-separated path beneath root.  If
-// any component of the path does not exist, it returns file.ErrChildNotFound.
+// any component of the path does not exist, it returns a *PathError wrapping
+// file.ErrChildNotFound.
 func Remove(ctx context.Context, root *file.File, path string) error {
 	fp, err := findPath(ctx, query{root: root, path: path})
 	if err != nil {
@@ -158,9 +253,50 @@ func Remove(ctx context.Context, root *file.File, path string) error {
 
 // An Entry is the argument to the visit callback for the Walk function.
 type Entry struct {
-	Path string     // the path of this entry relative to the root
-	File *file.File // the file for this entry (nil on error)
-	Err  error
+	Path   string     // the path of this entry relative to the root
+	Parent string     // the path of the parent of this entry ("" at the root)
+	Depth  int        // the number of path elements below the root (0 at the root)
+	File   *file.File // the file for this entry (nil on error)
+	Key    string     // the storage key of File, or "" if unflushed or on error
+	Err    error
+}
+
+// WalkOptions control the behaviour of the WalkWith function. A nil
+// *WalkOptions behaves as a zero-valued options structure.
+type WalkOptions struct {
+	// If positive, files more than this many path elements below the root are
+	// not visited.
+	MaxDepth int
+
+	// Files whose names match any of these patterns, as defined by path.Match,
+	// are not visited, nor are their descendants. The root is never skipped.
+	Skip []string
+}
+
+func (o *WalkOptions) tooDeep(depth int) bool {
+	return o != nil && o.MaxDepth > 0 && depth > o.MaxDepth
+}
+
+func (o *WalkOptions) skip(name string) bool {
+	if o != nil {
+		for _, pat := range o.Skip {
+			if ok, _ := path.Match(pat, name); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (o *WalkOptions) checkPatterns() error {
+	if o != nil {
+		for _, pat := range o.Skip {
+			if _, err := path.Match(pat, ""); err != nil {
+				return fmt.Errorf("skip pattern %q: %w", pat, err)
+			}
+		}
+	}
+	return nil
 }
 
 // Walk walks the file tree rooted at root, depth-first, and calls visit with
@@ -172,31 +308,55 @@ type Entry struct {
 // If visit reports an error other than ErrSkipChildren, traversal stops and
 // that error is returned to the caller of Walk.  If it returns ErrSkipChildren
 // the walk continues but skips the descendant files of the current entry.
+//
+// Walk is equivalent to WalkWith with nil options.
 func Walk(ctx context.Context, root *file.File, visit func(Entry) error) error {
-	q := []string{""}
+	return WalkWith(ctx, root, nil, visit)
+}
+
+// WalkWith walks the file tree rooted at root as Walk does, subject to the
+// constraints specified by opts. WalkWith reports an error without visiting
+// any files if opts contains an invalid skip pattern.
+func WalkWith(ctx context.Context, root *file.File, opts *WalkOptions, visit func(Entry) error) error {
+	if err := opts.checkPatterns(); err != nil {
+		return err
+	}
+	type walkItem struct {
+		path, parent string
+		depth        int
+	}
+	q := []walkItem{{}}
 	for ctx.Err() == nil && len(q) != 0 {
 		next := q[len(q)-1]
 		q = q[:len(q)-1]
 
-		f, err := Open(ctx, root, next)
-		err = visit(Entry{
-			Path: next,
-			File: f,
-			Err:  err,
-		})
+		f, err := Open(ctx, root, next.path)
+		e := Entry{
+			Path:   next.path,
+			Parent: next.parent,
+			Depth:  next.depth,
+			File:   f,
+			Err:    err,
+		}
+		if f != nil {
+			e.Key = f.Key()
+		}
+		err = visit(e)
 		if err == nil {
-			if f == nil {
-				continue // the error was suppressed
+			if f == nil || opts.tooDeep(next.depth+1) {
+				continue // the error was suppressed, or we are at max depth
 			}
 			kids := f.Child().Names()
-			for i, name := range kids {
-				kids[i] = path.Join(next, name)
-			}
-			for i, j := 0, len(kids)-1; i < j; i++ {
-				kids[i], kids[j] = kids[j], kids[i]
-				j--
+			for i := len(kids) - 1; i >= 0; i-- {
+				if opts.skip(kids[i]) {
+					continue
+				}
+				q = append(q, walkItem{
+					path:   path.Join(next.path, kids[i]),
+					parent: next.path,
+					depth:  next.depth + 1,
+				})
 			}
-			q = append(q, kids...)
 		} else if err != ErrSkipChildren {
 			return err
 		}
@@ -211,15 +371,16 @@ func findPath(ctx context.Context, q query) (foundPath, error) {
 		parent: nil,
 		target: q.root,
 	}
-	for _, name := range parsePath(q.path) {
+	elems := parsePath(q.path)
+	for i, name := range elems {
 		fp.targetName = name
 		c, err := fp.target.Open(ctx, name)
 		if err == nil {
 			fp.parent, fp.target = fp.target, c
 		} else if q.ef == nil {
-			return fp, err
+			return fp, newPathError(q.path, elems, i, err)
 		} else if ferr := q.ef(&fp, err); ferr != nil {
-			return fp, ferr
+			return fp, newPathError(q.path, elems, i, ferr)
 		}
 	}
 	return fp, nil
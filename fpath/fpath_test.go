@@ -15,13 +15,18 @@
 package fpath_test
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha1"
 	"errors"
 	"flag"
+	"fmt"
 	"hash"
 	"io/fs"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/creachadair/ffs/blob"
@@ -240,6 +245,340 @@ func TestPaths(t *testing.T) {
 	t.Logf("Root key: %x", rk)
 }
 
+func TestPathError(t *testing.T) {
+	cas := mustNewCAS(t, sha1.New)
+	ctx := context.Background()
+
+	root := file.New(cas, nil)
+	if _, err := fpath.Set(ctx, root, "/a/lasting/peace", &fpath.SetOptions{Create: true}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		run        func() error
+		path, elem string
+	}{
+		{"Open", func() error {
+			_, err := fpath.Open(ctx, root, "/a/lasting/war/of/words")
+			return err
+		}, "/a/lasting/war", "war"},
+		{"OpenPath", func() error {
+			_, err := fpath.OpenPath(ctx, root, "a/lost/peace")
+			return err
+		}, "a/lost", "lost"},
+		{"Stat", func() error {
+			_, err := fpath.Stat(ctx, root, "a/lasting/war")
+			return err
+		}, "a/lasting/war", "war"},
+		{"Remove", func() error {
+			return fpath.Remove(ctx, root, "/nonesuch")
+		}, "/nonesuch", "nonesuch"},
+		{"Set", func() error {
+			_, err := fpath.Set(ctx, root, "/a/lasting/war/crimes", &fpath.SetOptions{File: root.New(nil)})
+			return err
+		}, "/a/lasting/war", "war"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.run()
+			if !errors.Is(err, file.ErrChildNotFound) {
+				t.Errorf("Error: got %v, want %v", err, file.ErrChildNotFound)
+			}
+			var perr *fpath.PathError
+			if !errors.As(err, &perr) {
+				t.Fatalf("Error: got %T, want *fpath.PathError", err)
+			}
+			if perr.Path != tc.path || perr.Elem != tc.elem {
+				t.Errorf("PathError: got (%q, %q), want (%q, %q)", perr.Path, perr.Elem, tc.path, tc.elem)
+			}
+		})
+	}
+}
+
+func TestPut(t *testing.T) {
+	cas := mustNewCAS(t, sha1.New)
+	ctx := context.Background()
+	root := file.New(cas, nil)
+
+	// Creating a new entry does not report a replacement.
+	res, err := fpath.Put(ctx, root, "a/b", &fpath.SetOptions{Create: true})
+	if err != nil {
+		t.Fatalf("Put new: unexpected error: %v", err)
+	}
+	if res.File == nil || res.Replaced || res.PrevKey != "" {
+		t.Errorf("Put new: got %+v, want a new file", res)
+	}
+	old := res.File
+
+	// An exclusive set of an existing entry fails and leaves it alone.
+	_, err = fpath.Put(ctx, root, "a/b", &fpath.SetOptions{File: root.New(nil), Exclusive: true})
+	if !errors.Is(err, fpath.ErrExist) {
+		t.Errorf("Put exclusive: got %v, want %v", err, fpath.ErrExist)
+	}
+	if f, err := fpath.Open(ctx, root, "a/b"); err != nil || f != old {
+		t.Errorf("Open after exclusive: got (%p, %v), want (%p, nil)", f, err, old)
+	}
+
+	// Of several concurrent exclusive sets of the same path, exactly one wins.
+	var wg sync.WaitGroup
+	var wins atomic.Int32
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := fpath.Put(ctx, root, "a/race", &fpath.SetOptions{File: root.New(nil), Exclusive: true})
+			if err == nil {
+				wins.Add(1)
+			} else if !errors.Is(err, fpath.ErrExist) {
+				t.Errorf("Put exclusive: got %v, want %v", err, fpath.ErrExist)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := wins.Load(); n != 1 {
+		t.Errorf("Concurrent exclusive Put: %d succeeded, want 1", n)
+	}
+
+	// Replacing an existing entry reports the key of the prior file.
+	wantKey, err := old.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	res, err = fpath.Put(ctx, root, "a/b", &fpath.SetOptions{File: root.New(nil)})
+	if err != nil {
+		t.Fatalf("Put replace: unexpected error: %v", err)
+	}
+	if !res.Replaced || res.PrevKey != wantKey {
+		t.Errorf("Put replace: got (%v, %x), want (true, %x)", res.Replaced, res.PrevKey, wantKey)
+	}
+
+	// A replaced file that has not been stored has no key.
+	res, err = fpath.Put(ctx, root, "a/b", &fpath.SetOptions{Create: true})
+	if err != nil {
+		t.Fatalf("Put replace: unexpected error: %v", err)
+	}
+	if !res.Replaced || res.PrevKey != "" {
+		t.Errorf("Put replace unstored: got (%v, %x), want (true, \"\")", res.Replaced, res.PrevKey)
+	}
+
+	// Invalid names are rejected, for both the final and created elements.
+	for _, path := range []string{"a/..", "a/.", "a//c", "a/../y", "a/b\x00"} {
+		if _, err := fpath.Put(ctx, root, path, &fpath.SetOptions{Create: true}); !errors.Is(err, file.ErrInvalidName) {
+			t.Errorf("Put %q: got %v, want %v", path, err, file.ErrInvalidName)
+		}
+	}
+	if diff := cmp.Diff(root.Child().Names(), []string{"a"}); diff != "" {
+		t.Errorf("Names after invalid (-got, +want):\n%s", diff)
+	}
+
+	// The name check of the target directory is applied.
+	strict := file.New(cas, &file.NewOptions{CheckName: func(name string) error {
+		if strings.HasPrefix(name, "_") {
+			return fmt.Errorf("%w: %q", file.ErrInvalidName, name)
+		}
+		return nil
+	}})
+	for _, path := range []string{"_x", "_d/x", "d/_x"} {
+		if _, err := fpath.Put(ctx, strict, path, &fpath.SetOptions{Create: true}); !errors.Is(err, file.ErrInvalidName) {
+			t.Errorf("Put %q: got %v, want %v", path, err, file.ErrInvalidName)
+		}
+	}
+}
+
+func TestStat(t *testing.T) {
+	cas := mustNewCAS(t, sha1.New)
+	ctx := context.Background()
+
+	root := file.New(cas, &file.NewOptions{PersistStat: true})
+	big, err := fpath.Set(ctx, root, "/a/b/big", &fpath.SetOptions{
+		Create:  true,
+		SetStat: func(s *file.Stat) { s.Mode = 0640 },
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	data := bytes.Repeat([]byte("0123456789abcdef"), 1<<14)
+	if err := big.SetData(ctx, bytes.NewReader(data)); err != nil {
+		t.Fatalf("SetData: %v", err)
+	}
+	fpath.Set(ctx, root, "/a/b/small", &fpath.SetOptions{Create: true})
+	rkey, err := root.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	check := func(t *testing.T, root *file.File) {
+		t.Helper()
+		m, err := fpath.Stat(ctx, root, "/a/b/big")
+		if err != nil {
+			t.Fatalf("Stat: %v", err)
+		}
+		if m.Name != "big" || m.Size != int64(len(data)) || m.Stat.Mode != 0640 {
+			t.Errorf("Stat: got (%q, %d, %v), want (big, %d, %v)",
+				m.Name, m.Size, m.Stat.Mode, len(data), fs.FileMode(0640))
+		}
+		if fi := m.FileInfo(); fi.Name() != "big" || fi.Size() != m.Size {
+			t.Errorf("FileInfo: got (%q, %d), want (big, %d)", fi.Name(), fi.Size(), m.Size)
+		}
+		dir, err := fpath.Stat(ctx, root, "a/b")
+		if err != nil {
+			t.Fatalf("Stat dir: %v", err)
+		}
+		if diff := cmp.Diff(dir.Children(), []string{"big", "small"}); diff != "" {
+			t.Errorf("Children (-got, +want):\n%s", diff)
+		}
+	}
+
+	// Stat works on the live tree and on a tree loaded from storage.
+	t.Run("Live", func(t *testing.T) { check(t, root) })
+	t.Run("Stored", func(t *testing.T) {
+		cp, err := file.Open(ctx, cas, rkey)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		check(t, cp)
+	})
+
+	// Unsaved changes to an open file are reflected.
+	if err := big.Truncate(ctx, 10); err != nil {
+		t.Fatalf("Truncate: %v", err)
+	}
+	if m, err := fpath.Stat(ctx, root, "a/b/big"); err != nil {
+		t.Fatalf("Stat: %v", err)
+	} else if m.Size != 10 || m.Key != "" {
+		t.Errorf("Stat modified: got size %d, key %q; want 10, empty", m.Size, m.Key)
+	}
+}
+
+func TestStatNormalize(t *testing.T) {
+	cas := mustNewCAS(t, sha1.New)
+	ctx := context.Background()
+
+	// A stand-in for NFC normalization of the names used here.
+	nfc := strings.NewReplacer("e\u0301", "\u00e9").Replace
+	const nfd, composed = "cafe\u0301", "caf\u00e9"
+
+	root := file.New(cas, nil)
+	dir := root.New(&file.NewOptions{
+		Stat:        &file.Stat{Mode: fs.ModeDir | 0755},
+		PersistStat: true,
+	})
+	root.Child().Set(nfd, dir)
+	dir.Child().Set(nfd, dir.New(nil))
+	rkey, err := root.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	rc, err := file.OpenWith(ctx, cas, rkey, &file.OpenOptions{NormalizeName: nfc})
+	if err != nil {
+		t.Fatalf("OpenWith: %v", err)
+	}
+
+	// Stat, FS.StatMeta, and WalkDir must find the same files as Open, whether or
+	// not the files along the path have been opened.
+	path := composed + "/" + composed
+	check := func(t *testing.T) {
+		t.Helper()
+		if m, err := fpath.Stat(ctx, rc, path); err != nil {
+			t.Errorf("Stat %q: %v", path, err)
+		} else if m.Name != nfd {
+			t.Errorf("Stat %q: got name %q, want %q", path, m.Name, nfd)
+		}
+		fsys := fpath.NewFS(ctx, rc)
+		if _, err := fsys.StatMeta(path); err != nil {
+			t.Errorf("FS.StatMeta %q: %v", path, err)
+		}
+		var got []string
+		if err := fsys.WalkDir(composed, func(path string, _ fs.DirEntry, err error) error {
+			got = append(got, path)
+			return err
+		}); err != nil {
+			t.Errorf("WalkDir %q: %v", composed, err)
+		}
+		if diff := cmp.Diff(got, []string{composed, composed + "/" + nfd}); diff != "" {
+			t.Errorf("WalkDir (-got, +want):\n%s", diff)
+		}
+	}
+	t.Run("Stored", check)
+	if _, err := fpath.Open(ctx, rc, path); err != nil {
+		t.Fatalf("Open %q: %v", path, err)
+	}
+	t.Run("Opened", check)
+}
+
+func TestWalkWith(t *testing.T) {
+	cas := mustNewCAS(t, sha1.New)
+	ctx := context.Background()
+
+	root := file.New(cas, nil)
+	for _, p := range []string{"a/b/c", "a/skip.tmp/d", "e/f", "g.tmp"} {
+		if _, err := fpath.Set(ctx, root, p, &fpath.SetOptions{Create: true}); err != nil {
+			t.Fatalf("Create %q: %v", p, err)
+		}
+	}
+	rk, err := root.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush root: %v", err)
+	}
+
+	type entry struct {
+		Path, Parent string
+		Depth        int
+	}
+	tests := []struct {
+		name string
+		opts *fpath.WalkOptions
+		want []entry
+	}{
+		{"Nil", nil, []entry{
+			{"", "", 0}, {"a", "", 1}, {"a/b", "a", 2}, {"a/b/c", "a/b", 3},
+			{"a/skip.tmp", "a", 2}, {"a/skip.tmp/d", "a/skip.tmp", 3},
+			{"e", "", 1}, {"e/f", "e", 2}, {"g.tmp", "", 1},
+		}},
+		{"MaxDepth", &fpath.WalkOptions{MaxDepth: 1}, []entry{
+			{"", "", 0}, {"a", "", 1}, {"e", "", 1}, {"g.tmp", "", 1},
+		}},
+		{"Skip", &fpath.WalkOptions{Skip: []string{"*.tmp", "f"}}, []entry{
+			{"", "", 0}, {"a", "", 1}, {"a/b", "a", 2}, {"a/b/c", "a/b", 3}, {"e", "", 1},
+		}},
+		{"Both", &fpath.WalkOptions{MaxDepth: 2, Skip: []string{"e"}}, []entry{
+			{"", "", 0}, {"a", "", 1}, {"a/b", "a", 2}, {"a/skip.tmp", "a", 2}, {"g.tmp", "", 1},
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []entry
+			if err := fpath.WalkWith(ctx, root, tc.opts, func(e fpath.Entry) error {
+				if e.Err != nil {
+					return e.Err
+				}
+				if e.Key == "" || e.Key != e.File.Key() {
+					t.Errorf("Entry %q: got key %x, want %x", e.Path, e.Key, e.File.Key())
+				}
+				got = append(got, entry{e.Path, e.Parent, e.Depth})
+				return nil
+			}); err != nil {
+				t.Fatalf("WalkWith failed: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Walk entries (-want, +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("BadPattern", func(t *testing.T) {
+		err := fpath.WalkWith(ctx, root, &fpath.WalkOptions{Skip: []string{"["}}, func(fpath.Entry) error {
+			t.Error("Unexpected call to visit")
+			return nil
+		})
+		if err == nil {
+			t.Error("WalkWith: got nil, want error")
+		}
+	})
+	t.Logf("Root key: %x", rk)
+}
+
 func TestFS(t *testing.T) {
 	cas := mustNewCAS(t, sha1.New)
 	ctx := context.Background()
@@ -278,6 +617,22 @@ func TestFS(t *testing.T) {
 		if sys, ok := fi.Sys().(*file.File); !ok || sys != kid {
 			t.Fatalf("Stat sys: got %+v, want %+v", fi.Sys(), kid)
 		}
+		if _, err := fp.Stat("nonesuch"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Stat nonesuch: got %v, want %v", err, fs.ErrNotExist)
+		}
+	})
+
+	t.Run("StatMeta", func(t *testing.T) {
+		fi, err := fp.StatMeta("kid")
+		if err != nil {
+			t.Fatalf("StatMeta kid: %v", err)
+		}
+		if sys, ok := fi.Sys().(file.Meta); !ok || sys.Name != "kid" || sys.Stat.Mode != 0644 {
+			t.Fatalf("StatMeta sys: got %+v, want meta for %+v", fi.Sys(), kid)
+		}
+		if _, err := fp.StatMeta("nonesuch"); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("StatMeta nonesuch: got %v, want %v", err, fs.ErrNotExist)
+		}
 	})
 
 	t.Run("ReadDir", func(t *testing.T) {
@@ -294,6 +649,26 @@ func TestFS(t *testing.T) {
 		if des[0].IsDir() {
 			t.Error("IsDir is true, want false")
 		}
+		if fi, err := des[0].Info(); err != nil {
+			t.Errorf("Info: %v", err)
+		} else if sys, ok := fi.Sys().(*file.File); !ok || sys != kid {
+			t.Errorf("Info sys: got %+v, want %+v", fi.Sys(), kid)
+		}
+	})
+
+	t.Run("ReadDirMeta", func(t *testing.T) {
+		des, err := fp.ReadDirMeta(".")
+		if err != nil {
+			t.Fatalf("ReadDirMeta root: %v", err)
+		}
+		if len(des) != 1 || des[0].Name() != "kid" {
+			t.Fatalf("Got %+v, wanted 1 entry for kid", des)
+		}
+		if fi, err := des[0].Info(); err != nil {
+			t.Errorf("Info: %v", err)
+		} else if sys, ok := fi.Sys().(file.Meta); !ok || sys.Name != "kid" {
+			t.Errorf("Info sys: got %+v, want meta for kid", fi.Sys())
+		}
 	})
 
 	rk, err := root.Flush(ctx)
@@ -303,6 +678,123 @@ func TestFS(t *testing.T) {
 	t.Logf("Root key: %x", rk)
 }
 
+func TestWalkDir(t *testing.T) {
+	cas := mustNewCAS(t, sha1.New)
+	ctx := context.Background()
+
+	root := file.New(cas, &file.NewOptions{
+		Stat: &file.Stat{Mode: fs.ModeDir | 0755},
+	})
+	dir := func(s *file.Stat) { s.Mode = fs.ModeDir | 0755 }
+	for _, path := range []string{"a/b/c", "a/b/d", "a/e", "f/g/h", "f/i", "j"} {
+		if _, err := fpath.Set(ctx, root, path, &fpath.SetOptions{
+			Create: true, SetStat: dir,
+		}); err != nil {
+			t.Fatalf("Set %q: %v", path, err)
+		}
+	}
+	// Flush so that the walk has to load unopened files from storage.
+	rk, err := root.Flush(ctx)
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	root, err = file.Open(ctx, cas, rk)
+	if err != nil {
+		t.Fatalf("Open root: %v", err)
+	}
+	fp := fpath.NewFS(ctx, root)
+
+	// Record the paths visited, stopping or skipping at the given path.
+	walk := func(walk func(string, fs.WalkDirFunc) error, start, stop string, skip error) ([]string, error) {
+		var got []string
+		err := walk(start, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			got = append(got, path+":"+strconv.FormatBool(d.IsDir()))
+			if path == stop {
+				return skip
+			}
+			return nil
+		})
+		return got, err
+	}
+	std := func(root string, fn fs.WalkDirFunc) error { return fs.WalkDir(fp, root, fn) }
+
+	tests := []struct {
+		start, stop string
+		skip        error
+	}{
+		{".", "", nil},
+		{"a", "", nil},
+		{"a/b/c", "", nil},
+		{".", "a/b", fs.SkipDir},
+		{".", "a/b/c", fs.SkipDir},
+		{".", "f", fs.SkipDir},
+		{".", "f/g/h", fs.SkipAll},
+		{".", ".", fs.SkipDir},
+		{"nonesuch", "", nil},
+	}
+	for _, tc := range tests {
+		want, werr := walk(std, tc.start, tc.stop, tc.skip)
+		got, err := walk(fp.WalkDir, tc.start, tc.stop, tc.skip)
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("WalkDir(%q, stop %q) paths (-got, +want):\n%s", tc.start, tc.stop, diff)
+		}
+		if fmt.Sprint(err) != fmt.Sprint(werr) {
+			t.Errorf("WalkDir(%q, stop %q): got err %v, want %v", tc.start, tc.stop, err, werr)
+		}
+	}
+
+	// If a directory cannot be read completely, the entries read before the
+	// failure are still visited.
+	proot := file.New(cas, &file.NewOptions{
+		Stat:        &file.Stat{Mode: fs.ModeDir | 0755},
+		PersistStat: true,
+	})
+	for i, name := range []string{"a", "b", "c"} {
+		proot.Child().Set(name, proot.New(&file.NewOptions{
+			Stat:        &file.Stat{Mode: fs.FileMode(0600 + i)}, // distinct keys
+			PersistStat: true,
+		}))
+	}
+	if _, err := proot.Flush(ctx); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	bm, err := fpath.Stat(ctx, proot, "b")
+	if err != nil {
+		t.Fatalf("Stat b: %v", err)
+	}
+	if err := cas.Delete(ctx, bm.Key); err != nil {
+		t.Fatalf("Delete b: %v", err)
+	}
+	proot, err = file.Open(ctx, cas, proot.Key())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	var got []string
+	var errs int
+	if err := fpath.NewFS(ctx, proot).WalkDir(".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			errs++
+			if !blob.IsKeyNotFound(err) {
+				t.Errorf("WalkDir %q: got %v, want key not found", path, err)
+			}
+			return nil
+		}
+		got = append(got, path)
+		return nil
+	}); err != nil {
+		t.Errorf("WalkDir: unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(got, []string{".", "a"}); diff != "" {
+		t.Errorf("WalkDir partial (-got, +want):\n%s", diff)
+	}
+	if errs != 1 {
+		t.Errorf("WalkDir partial: got %d errors, want 1", errs)
+	}
+}
+
 func errorOK(err, werr error) bool {
 	if werr == nil {
 		return err == nil
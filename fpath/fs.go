@@ -61,6 +61,17 @@ func (fp FS) Stat(path string) (fs.FileInfo, error) {
 	return target.Stat().FileInfo(), nil
 }
 
+// StatMeta is as Stat, but does not open the target file or load its data
+// index; see [Stat]. The Sys method of the info record returned by a
+// successful StatMeta call returns the [file.Meta] of the file.
+func (fp FS) StatMeta(path string) (fs.FileInfo, error) {
+	meta, err := fp.statMeta(path)
+	if err != nil {
+		return nil, err
+	}
+	return meta.FileInfo(), nil
+}
+
 // Sub implements the fs.SubFS interface.
 func (fp FS) Sub(dir string) (fs.FS, error) {
 	target, err := fp.openFile("sub", dir)
@@ -70,7 +81,8 @@ func (fp FS) Sub(dir string) (fs.FS, error) {
 	return NewFS(fp.ctx, target), nil
 }
 
-// ReadDir implements the fs.ReadDirFS interface.
+// ReadDir implements the fs.ReadDirFS interface. The concrete type of the
+// info record for each entry is file.FileInfo.
 func (fp FS) ReadDir(path string) ([]fs.DirEntry, error) {
 	target, err := fp.openFile("readdir", path)
 	if err != nil {
@@ -88,6 +100,97 @@ func (fp FS) ReadDir(path string) ([]fs.DirEntry, error) {
 	return out, nil
 }
 
+// ReadDirMeta is as ReadDir, but does not open the children of the directory;
+// see [file.Child.Stats]. The Sys method of the info for each entry returns
+// the [file.Meta] of the entry.
+func (fp FS) ReadDirMeta(path string) ([]fs.DirEntry, error) {
+	target, err := fp.openFile("readdir", path)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]fs.DirEntry, 0, target.Child().Len())
+	for kid, err := range target.Child().Stats(fp.ctx) {
+		if err != nil {
+			return nil, pathErr("readdir", slashpath.Join(path, kid.Name), err)
+		}
+		out = append(out, fs.FileInfoToDirEntry(kid.Info))
+	}
+	return out, nil
+}
+
+// WalkDir walks the file tree rooted at root, calling fn for each file or
+// directory in the tree, including root. It has the same semantics as
+// [fs.WalkDir] applied to fp, including the handling of [fs.SkipDir] and
+// [fs.SkipAll], but it is much cheaper for large trees: Each directory is
+// loaded only once, and WalkDir does not open any files along the way, nor
+// re-traverse the path from the root to each directory it visits.
+//
+// The Sys method of the info for each [fs.DirEntry] returns the [file.Meta]
+// of the entry.
+func (fp FS) WalkDir(root string, fn fs.WalkDirFunc) error {
+	meta, err := fp.statMeta(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = fp.walkDir(root, meta, fs.FileInfoToDirEntry(meta.FileInfo()), fn)
+	}
+	if err == fs.SkipDir || err == fs.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// walkDir recursively visits path, whose metadata are m, mirroring the
+// behavior of the unexported walker used by fs.WalkDir.
+func (fp FS) walkDir(path string, m file.Meta, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(path, d, nil); err != nil || !d.IsDir() {
+		if err == fs.SkipDir && d.IsDir() {
+			err = nil // skip the contents of this directory
+		}
+		return err
+	}
+
+	// As with fs.WalkDir, if reading the directory fails, the entries read
+	// before the failure are still visited unless fn reports an error.
+	var kids []file.ChildStat
+	for kid, err := range m.Stats(fp.ctx) {
+		if err != nil {
+			if err := fn(path, d, pathErr("readdir", slashpath.Join(path, kid.Name), err)); err != nil {
+				if err == fs.SkipDir {
+					err = nil
+				}
+				return err
+			}
+			break
+		}
+		kids = append(kids, kid)
+	}
+	for _, kid := range kids {
+		km := kid.Info.Sys().(file.Meta)
+		if err := fp.walkDir(slashpath.Join(path, kid.Name), km, fs.FileInfoToDirEntry(kid.Info), fn); err != nil {
+			if err == fs.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// statMeta returns the metadata of the file at path without opening it.
+func (fp FS) statMeta(path string) (file.Meta, error) {
+	if !fs.ValidPath(path) {
+		return file.Meta{}, pathErr("stat", path, fs.ErrInvalid)
+	}
+	meta, err := Stat(fp.ctx, fp.root, path)
+	if errors.Is(err, file.ErrChildNotFound) {
+		return file.Meta{}, pathErr("stat", path, fs.ErrNotExist)
+	} else if err != nil {
+		return file.Meta{}, pathErr("stat", path, err)
+	}
+	return meta, nil
+}
+
 func (fp FS) openFile(op, path string) (*file.File, error) {
 	if !fs.ValidPath(path) {
 		return nil, pathErr(op, path, fs.ErrInvalid)
@@ -15,6 +15,7 @@
 package index
 
 import (
+	"bufio"
 	"bytes"
 	"compress/zlib"
 	"encoding/binary"
@@ -53,24 +54,29 @@ func Decode(pb *indexpb.Index) (*Index, error) {
 		// TODO(creachadair): Check the hash_func value.
 	}
 
-	// Compressed segments.
+	// Compressed segments. Decode them directly into the bit vector, rather
+	// than buffering the whole decompressed stream.
 	rc, err := zlib.NewReader(bytes.NewReader(pb.SegmentData))
 	if err != nil {
 		return nil, err
 	}
 	defer rc.Close()
-	bits, err := io.ReadAll(rc)
-	if err != nil {
-		return nil, err
+	nseg := pb.NumSegments
+	if nseg > maxSegments {
+		return nil, fmt.Errorf("invalid segment count %d", nseg)
 	}
-	nseg := int(pb.NumSegments)
-	if len(bits) != 8*nseg {
-		return nil, fmt.Errorf("invalid segment data: got %d bytes, want %d", len(bits), 8*nseg)
+	br := bufio.NewReader(rc)
+	var val [8]byte
+	idx.bits, err = readSegments(nseg, func() (uint64, error) {
+		_, err := io.ReadFull(br, val[:])
+		return binary.BigEndian.Uint64(val[:]), err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid segment data: %w", err)
 	}
-	idx.bits = make(bitVector, nseg)
-	idx.nbits = 64 * pb.NumSegments
-	for i := 0; i < nseg; i++ {
-		idx.bits[i] = binary.BigEndian.Uint64(bits[8*i:])
+	idx.nbits = 64 * nseg
+	if n, _ := io.Copy(io.Discard, br); n != 0 {
+		return nil, fmt.Errorf("invalid segment data: %d extra bytes", n)
 	}
 	return idx, nil
 }
@@ -13,6 +13,10 @@
 // limitations under the License.
 
 // Package index constructs a Bloom filter index for a set of string keys.
+//
+// An index can be stored as a protocol buffer message (see [Encode] and
+// [Decode]), or in a flat uncompressed layout (see [Index.WriteTo]) that can
+// be queried in place with a [View].
 package index
 
 import (
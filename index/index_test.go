@@ -15,12 +15,20 @@
 package index_test
 
 import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"fmt"
 	"os"
 	"strings"
 	"testing"
 
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/blob/memstore"
 	"github.com/creachadair/ffs/index"
 	"github.com/creachadair/ffs/index/indexpb"
+	"github.com/creachadair/mds/mapset"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"google.golang.org/protobuf/proto"
@@ -105,4 +113,176 @@ func TestIndex(t *testing.T) {
 	}
 }
 
+func TestStream(t *testing.T) {
+	keyData, err := os.ReadFile("testdata/keys.txt")
+	if err != nil {
+		t.Fatalf("Reading keys: %v", err)
+	}
+	keys := strings.Split(strings.TrimSpace(string(keyData)), "\n")
+
+	idx := index.New(len(keys)/2, nil)
+	for _, key := range keys[:len(keys)/2] {
+		idx.Add(key)
+	}
+
+	var buf bytes.Buffer
+	nw, err := idx.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo: unexpected error: %v", err)
+	} else if nw != int64(buf.Len()) {
+		t.Errorf("WriteTo: reported %d bytes, wrote %d", nw, buf.Len())
+	}
+	t.Logf("Flat index: %d bytes", nw)
+	flat := bytes.Clone(buf.Bytes())
+
+	// Append trailing data to verify that ReadFrom does not consume it.
+	buf.WriteString("extra")
+	var dec index.Index
+	nr, err := dec.ReadFrom(&buf)
+	if err != nil {
+		t.Fatalf("ReadFrom: unexpected error: %v", err)
+	} else if nr != nw {
+		t.Errorf("ReadFrom: read %d bytes, want %d", nr, nw)
+	}
+	if rest := buf.String(); rest != "extra" {
+		t.Errorf("ReadFrom: remaining input is %q, want %q", rest, "extra")
+	}
+	opts := []cmp.Option{
+		cmp.AllowUnexported(index.Index{}),
+		cmpopts.IgnoreFields(index.Index{}, "hash"),
+	}
+	if diff := cmp.Diff(idx, &dec, opts...); diff != "" {
+		t.Errorf("ReadFrom index (-want, +got):\n%s", diff)
+	}
+
+	v, err := index.OpenView(flat)
+	if err != nil {
+		t.Fatalf("OpenView: unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(idx.Stats(), v.Stats()); diff != "" {
+		t.Errorf("View stats (-want, +got):\n%s", diff)
+	}
+	for _, key := range keys {
+		if got, want := v.Has(key), idx.Has(key); got != want {
+			t.Errorf("View Has(%q): got %v, want %v", key, got, want)
+		}
+	}
+
+	// Damaged inputs should be rejected.
+	for _, bad := range [][]byte{nil, flat[:10], flat[:len(flat)-1], append([]byte("X"), flat[1:]...)} {
+		if _, err := index.OpenView(bad); err == nil {
+			t.Errorf("OpenView(%d bytes): got nil, want error", len(bad))
+		}
+		if _, err := dec.ReadFrom(bytes.NewReader(bad)); err == nil {
+			t.Errorf("ReadFrom(%d bytes): got nil, want error", len(bad))
+		}
+	}
+}
+
+func TestOversizedHeader(t *testing.T) {
+	// A header claiming a huge number of segments, with no data behind it,
+	// must be rejected without allocating space for the segments.
+	var seg bytes.Buffer
+	zw := zlib.NewWriter(&seg)
+	zw.Write(make([]byte, 16))
+	zw.Close()
+
+	for _, nseg := range []uint64{1 << 32, 1<<32 + 1, 1 << 40, 1<<64 - 1} {
+		var hdr bytes.Buffer
+		hdr.WriteString("FFSIDX\x00\x01")
+		binary.Write(&hdr, binary.BigEndian, []uint64{1, 1, nseg, 12345})
+
+		var dec index.Index
+		if _, err := dec.ReadFrom(bytes.NewReader(hdr.Bytes())); err == nil {
+			t.Errorf("ReadFrom(%d segments): got nil, want error", nseg)
+		}
+		if _, err := index.Decode(&indexpb.Index{NumKeys: 1, Seeds: []uint64{1}, NumSegments: nseg, SegmentData: seg.Bytes()}); err == nil {
+			t.Errorf("Decode(%d segments): got nil, want error", nseg)
+		}
+	}
+}
+
+func TestUnion(t *testing.T) {
+	a := &countSet{keys: mapset.New("apple", "cherry")}
+	b := &countSet{keys: mapset.New("banana", "cherry")}
+	u := index.NewUnion([]index.Set{a, b}, &index.UnionOptions{MissCacheSize: 2})
+
+	check := func(key string, want bool, wantProbes int) {
+		t.Helper()
+		a.n, b.n = 0, 0
+		if got := u.Has(key); got != want {
+			t.Errorf("Has(%q): got %v, want %v", key, got, want)
+		}
+		if got := a.n + b.n; got != wantProbes {
+			t.Errorf("Has(%q): got %d probes, want %d", key, got, wantProbes)
+		}
+	}
+	check("apple", true, 1)
+	check("banana", true, 2)
+	check("durian", false, 2) // not yet cached
+	check("durian", false, 0) // cached
+	check("elder", false, 2)
+	check("fig", false, 2)    // evicts durian
+	check("durian", false, 2) // no longer cached
+	check("fig", false, 0)
+
+	// After a reset, all keys are probed again.
+	u.Reset()
+	check("fig", false, 2)
+
+	want := index.UnionStats{Queries: 9, Misses: 7, CacheHits: 2}
+	if diff := cmp.Diff(u.Stats(), want); diff != "" {
+		t.Errorf("Stats (-got, +want):\n%s", diff)
+	}
+}
+
+func TestFromList(t *testing.T) {
+	ctx := context.Background()
+	kv := memstore.NewKV()
+
+	// An empty keyspace yields an empty index.
+	if idx, err := index.FromList(ctx, kv, nil); err != nil {
+		t.Fatalf("FromList: unexpected error: %v", err)
+	} else if idx.Len() != 0 || idx.Has("x") {
+		t.Errorf("FromList empty: got %d keys, Has(x)=%v; want 0, false", idx.Len(), idx.Has("x"))
+	}
+
+	var keys []string
+	for i := range 500 {
+		key := fmt.Sprintf("key-%d", i)
+		keys = append(keys, key)
+		if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte(key)}); err != nil {
+			t.Fatalf("Put %q: %v", key, err)
+		}
+	}
+	idx, err := index.FromList(ctx, kv, &index.Options{FalsePositiveRate: 0.01})
+	if err != nil {
+		t.Fatalf("FromList: unexpected error: %v", err)
+	}
+	if got := idx.Len(); got != len(keys) {
+		t.Errorf("Len: got %d, want %d", got, len(keys))
+	}
+	for _, key := range keys {
+		if !idx.Has(key) {
+			t.Errorf("Has(%q): got false, want true", key)
+		}
+	}
+	var fp int
+	for i := range 1000 {
+		if idx.Has(fmt.Sprintf("other-%d", i)) {
+			fp++
+		}
+	}
+	if fp > 50 {
+		t.Errorf("False positives: got %d of 1000, want at most 50", fp)
+	}
+}
+
+type countSet struct {
+	keys mapset.Set[string]
+	n    int
+}
+
+func (c *countSet) Has(key string) bool { c.n++; return c.keys.Has(key) }
+
 func percent(x, n int) float64 { return 100 * (float64(x) / float64(n)) }
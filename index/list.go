@@ -0,0 +1,46 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"context"
+
+	"github.com/creachadair/ffs/blob"
+)
+
+// FromList constructs an index of all the keys present in ks, by listing the
+// keyspace rather than walking a file tree. This is useful to index whatever
+// a store holds, for example to answer Has queries locally when syncing to a
+// remote store. A nil opts value is ready for use and provides default values
+// as described on Options.
+//
+// The index is sized by the Len method of ks, and keys are added as they are
+// listed, so the keys themselves are not held in memory. If keys are added to
+// ks while it is being listed, the index may contain more keys than it was
+// sized for, and its false positive rate may exceed the requested rate.
+func FromList(ctx context.Context, ks blob.KVCore, opts *Options) (*Index, error) {
+	n, err := ks.Len(ctx)
+	if err != nil {
+		return nil, err
+	}
+	idx := New(max(int(n), 1), opts)
+	for key, err := range ks.List(ctx, "") {
+		if err != nil {
+			return nil, err
+		}
+		idx.Add(key)
+	}
+	return idx, nil
+}
@@ -0,0 +1,224 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The flat binary layout of an index, as written by WriteTo, is a sequence of
+// big-endian 64-bit words:
+//
+//	magic | numKeys | numSeeds | numSegments | seeds... | segments...
+//
+// The segments are not compressed, and every field is 8-byte aligned, so that
+// a View can query an encoded index in place, for example from a memory-mapped
+// file, without decoding the segments up front.
+const (
+	flatMagic  = "FFSIDX\x00\x01"
+	headerSize = 32
+)
+
+// WriteTo writes idx to w in a flat uncompressed binary layout that can be read
+// back by ReadFrom or queried in place with OpenView. It implements the
+// [io.WriterTo] interface.
+func (idx *Index) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	var nw int64
+	put := func(v uint64) error {
+		var buf [8]byte
+		binary.BigEndian.PutUint64(buf[:], v)
+		n, err := bw.Write(buf[:])
+		nw += int64(n)
+		return err
+	}
+
+	n, err := bw.WriteString(flatMagic)
+	nw += int64(n)
+	if err != nil {
+		return nw, err
+	}
+	for _, v := range []uint64{uint64(idx.numKeys), uint64(len(idx.seeds)), uint64(len(idx.bits))} {
+		if err := put(v); err != nil {
+			return nw, err
+		}
+	}
+	for _, seed := range idx.seeds {
+		if err := put(seed); err != nil {
+			return nw, err
+		}
+	}
+	for _, seg := range idx.bits {
+		if err := put(seg); err != nil {
+			return nw, err
+		}
+	}
+	return nw, bw.Flush()
+}
+
+// ReadFrom replaces the contents of idx with an index read from r in the
+// layout written by WriteTo. It implements the [io.ReaderFrom] interface.
+// The index uses the default hash function.
+//
+// ReadFrom reads exactly the encoded index from r, and does not consume any
+// data that follow it. If an error occurs, idx is not modified.
+func (idx *Index) ReadFrom(r io.Reader) (int64, error) {
+	var nr int64
+	var buf [8]byte
+	get := func() (uint64, error) {
+		n, err := io.ReadFull(r, buf[:])
+		nr += int64(n)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return binary.BigEndian.Uint64(buf[:]), err
+	}
+
+	var hdr [headerSize]byte
+	n, err := io.ReadFull(r, hdr[:])
+	nr += int64(n)
+	if err != nil {
+		return nr, fmt.Errorf("read header: %w", err)
+	}
+	numKeys, seeds, nseg, err := parseHeader(hdr[:])
+	if err != nil {
+		return nr, err
+	}
+
+	out := Index{
+		numKeys: int(numKeys),
+		seeds:   make([]uint64, seeds),
+		nbits:   64 * nseg,
+		hash:    (*Options)(nil).hashFunc(), // the default
+	}
+	for i := range out.seeds {
+		if out.seeds[i], err = get(); err != nil {
+			return nr, fmt.Errorf("read seeds: %w", err)
+		}
+	}
+	out.bits, err = readSegments(nseg, get)
+	if err != nil {
+		return nr, fmt.Errorf("read segments: %w", err)
+	}
+	*idx = out
+	return nr, nil
+}
+
+// maxSegments is the largest number of segments accepted when decoding an
+// index (32 GiB of filter data).
+const maxSegments = 1 << 32
+
+// readSegments reads nseg segments by calling get. The number of segments
+// comes from untrusted input, so rather than allocating them all up front,
+// readSegments grows the vector as segments are actually read.
+func readSegments(nseg uint64, get func() (uint64, error)) (bitVector, error) {
+	bits := make(bitVector, 0, min(nseg, 1<<16))
+	for i := uint64(0); i < nseg; i++ {
+		v, err := get()
+		if err != nil {
+			return nil, fmt.Errorf("segment %d of %d: %w", i, nseg, err)
+		}
+		bits = append(bits, v)
+	}
+	return bits, nil
+}
+
+// parseHeader checks the magic number of a flat index header and returns the
+// number of keys, seeds, and segments it describes.
+func parseHeader(hdr []byte) (numKeys, seeds, nseg uint64, _ error) {
+	if string(hdr[:8]) != flatMagic {
+		return 0, 0, 0, errors.New("invalid index header")
+	}
+	numKeys = binary.BigEndian.Uint64(hdr[8:])
+	seeds = binary.BigEndian.Uint64(hdr[16:])
+	nseg = binary.BigEndian.Uint64(hdr[24:])
+
+	// Guard against absurd sizes before allocating or indexing.
+	if seeds == 0 || seeds > 1024 || nseg == 0 || nseg > maxSegments {
+		return 0, 0, 0, fmt.Errorf("invalid index shape: %d seeds, %d segments", seeds, nseg)
+	}
+	return numKeys, seeds, nseg, nil
+}
+
+// A View is a read-only index that queries the flat layout written by WriteTo
+// in place, without copying or decoding the segment data. This is intended
+// for large indexes, whose encoded form may be memory-mapped from a file.
+type View struct {
+	numKeys int
+	seeds   []uint64
+	nseg    uint64
+	segs    []byte // nseg × 8 bytes, big-endian
+	hash    func(string) uint64
+}
+
+// OpenView returns a View of the flat index layout in data, as written by
+// WriteTo. The View retains data, which must not be modified while the View is
+// in use. Only the header and seeds are decoded by OpenView; the segments are
+// read from data as needed by queries. The View uses the default hash function.
+func OpenView(data []byte) (*View, error) {
+	if len(data) < headerSize {
+		return nil, errors.New("invalid index: short header")
+	}
+	numKeys, seeds, nseg, err := parseHeader(data[:headerSize])
+	if err != nil {
+		return nil, err
+	}
+	segPos := headerSize + 8*seeds
+	if want := segPos + 8*nseg; uint64(len(data)) != want {
+		return nil, fmt.Errorf("invalid index: got %d bytes, want %d", len(data), want)
+	}
+	v := &View{
+		numKeys: int(numKeys),
+		seeds:   make([]uint64, seeds),
+		nseg:    nseg,
+		segs:    data[segPos:],
+		hash:    (*Options)(nil).hashFunc(), // the default
+	}
+	for i := range v.seeds {
+		v.seeds[i] = binary.BigEndian.Uint64(data[headerSize+8*i:])
+	}
+	return v, nil
+}
+
+// Has reports whether key is one of the indexed keys, with the same semantics
+// as the Has method of an Index.
+func (v *View) Has(key string) bool {
+	hash := v.hash(key)
+	nbits := 64 * v.nseg
+	for _, seed := range v.seeds {
+		pos := (hash ^ seed) % nbits
+		word := binary.BigEndian.Uint64(v.segs[8*(pos>>6):])
+		if word&(uint64(1)<<(pos&0x3f)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Len reports the number of keys in the index.
+func (v *View) Len() int { return v.numKeys }
+
+// Stats returns size and capacity statistics for the index.
+func (v *View) Stats() Stats {
+	return Stats{
+		NumKeys:    v.numKeys,
+		FilterBits: int(64 * v.nseg),
+		NumHashes:  len(v.seeds),
+	}
+}
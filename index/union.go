@@ -0,0 +1,111 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"sync/atomic"
+
+	"github.com/creachadair/mds/cache"
+)
+
+// A Set answers membership queries for a set of keys. Both [*Index] and
+// [*View] implement this interface.
+type Set interface {
+	// Has reports whether key is a member of the set. As with an Index, false
+	// positives are permitted but false negatives are not.
+	Has(key string) bool
+}
+
+// A Union is a [Set] that reports whether a key is present in any of a
+// collection of sets, such as the indexes for several roots during garbage
+// collection.
+//
+// A Union remembers the keys it most recently found to be absent from all its
+// sets, so that a key queried repeatedly, for example when sweeping multiple
+// keyspaces that share content, is only checked against every set once while
+// it remains in the cache. The member sets must not change while the Union is
+// in use; call Reset to discard the cache after modifying them.
+//
+// A Union is safe for concurrent use by multiple goroutines, if its member
+// sets are.
+type Union struct {
+	sets []Set
+	miss *cache.Cache[string, struct{}]
+
+	queries atomic.Int64
+	misses  atomic.Int64
+	cached  atomic.Int64
+}
+
+// NewUnion constructs a new [Union] of the specified sets. A nil opts value is
+// ready for use and provides default values as described on UnionOptions.
+func NewUnion(sets []Set, opts *UnionOptions) *Union {
+	return &Union{
+		sets: sets,
+		miss: cache.New(cache.LRU[string, struct{}](opts.missCacheSize())),
+	}
+}
+
+// UnionOptions provide optional settings for a [Union]. A nil *UnionOptions
+// is ready for use and provides default values as described.
+type UnionOptions struct {
+	// The maximum number of absent keys to remember. A value ≤ 0 defaults to
+	// 4096.
+	MissCacheSize int
+}
+
+func (o *UnionOptions) missCacheSize() int64 {
+	if o == nil || o.MissCacheSize <= 0 {
+		return 4096
+	}
+	return int64(o.MissCacheSize)
+}
+
+// Has reports whether key is present in any of the sets of u.
+func (u *Union) Has(key string) bool {
+	u.queries.Add(1)
+	if _, ok := u.miss.Get(key); ok {
+		u.misses.Add(1)
+		u.cached.Add(1)
+		return false
+	}
+	for _, s := range u.sets {
+		if s.Has(key) {
+			return true
+		}
+	}
+	u.misses.Add(1)
+	u.miss.Put(key, struct{}{})
+	return false
+}
+
+// Reset discards the cache of absent keys. The statistics are not reset.
+func (u *Union) Reset() { u.miss.Clear() }
+
+// Stats returns query statistics for u.
+func (u *Union) Stats() UnionStats {
+	return UnionStats{
+		Queries:   u.queries.Load(),
+		Misses:    u.misses.Load(),
+		CacheHits: u.cached.Load(),
+	}
+}
+
+// UnionStats record query statistics for a [Union].
+type UnionStats struct {
+	Queries   int64 // the number of keys queried
+	Misses    int64 // the number of queried keys absent from all sets
+	CacheHits int64 // the number of misses answered from the cache
+}
@@ -19,6 +19,7 @@ package cachestore
 import (
 	"bytes"
 	"context"
+	"errors"
 	"iter"
 	"strings"
 	"sync"
@@ -30,7 +31,6 @@ import (
 	"github.com/creachadair/mds/cache"
 	"github.com/creachadair/mds/mapset"
 	"github.com/creachadair/mds/stree"
-	"github.com/creachadair/taskgroup"
 )
 
 // Store implements the [blob.StoreCloser] interface.
@@ -41,6 +41,28 @@ type Store struct {
 type state struct {
 	base     blob.Store
 	maxBytes int
+	reg      *registry
+}
+
+// A registry records the keyspaces of a store, for aggregating stats.
+type registry struct {
+	μ   sync.Mutex
+	kvs []*KV
+
+	budget *blob.Budget // if non-nil, charged for the cache of each KV
+	verify bool         // if true, each KV verifies exclusive puts
+}
+
+func (r *registry) add(kv *KV) {
+	r.μ.Lock()
+	defer r.μ.Unlock()
+	r.kvs = append(r.kvs, kv)
+}
+
+func (r *registry) all() []*KV {
+	r.μ.Lock()
+	defer r.μ.Unlock()
+	return append([]*KV(nil), r.kvs...)
 }
 
 // New constructs a new root Store delegated to base.
@@ -50,30 +72,103 @@ func New(base blob.Store, maxBytes int) Store {
 		panic("cache size is negative")
 	}
 	return Store{M: monitor.New(monitor.Config[state, *KV]{
-		DB: state{base: base, maxBytes: maxBytes},
+		DB: state{base: base, maxBytes: maxBytes, reg: new(registry)},
 		NewKV: func(ctx context.Context, db state, _ dbkey.Prefix, name string) (*KV, error) {
 			kv, err := db.base.KV(ctx, name)
 			if err != nil {
 				return nil, err
 			}
-			return NewKV(kv, db.maxBytes), nil
+			out := NewKV(kv, db.maxBytes)
+			if b := db.reg.budget; b != nil {
+				out.SetBudget(b)
+			}
+			out.SetVerifyPut(db.reg.verify)
+			db.reg.add(out)
+			return out, nil
 		},
 		NewSub: func(ctx context.Context, db state, _ dbkey.Prefix, name string) (state, error) {
 			sub, err := db.base.Sub(ctx, name)
 			if err != nil {
 				return state{}, err
 			}
-			return state{base: sub, maxBytes: db.maxBytes}, nil
+			return state{base: sub, maxBytes: db.maxBytes, reg: db.reg}, nil
 		},
 	})}
 }
 
+// SetBudget charges the memory used by the caches of all the keyspaces opened
+// via s or its substores to b, as described by [KV.SetBudget]. SetBudget must
+// be called before s is first used, and at most once.
+func (s Store) SetBudget(b *blob.Budget) { s.M.DB.reg.budget = b }
+
+// SetVerifyPut sets whether the keyspaces opened via s or its substores
+// verify exclusive puts against the base store, as described by
+// [KV.SetVerifyPut]. SetVerifyPut must be called before s is first used.
+func (s Store) SetVerifyPut(verify bool) { s.M.DB.reg.verify = verify }
+
+// CAS implements a method of [blob.Store]. The CAS returned is the
+// content-addressed view of the underlying keyspace described by [KV.CAS].
+func (s Store) CAS(ctx context.Context, name string) (blob.CAS, error) {
+	kv, err := s.M.KV(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return kv.(*KV).CAS(), nil
+}
+
+// Sub implements a method of [blob.Store]. The concrete type of stores
+// returned is [Store].
+func (s Store) Sub(ctx context.Context, name string) (blob.Store, error) {
+	sub, err := s.M.Sub(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return Store{M: sub.(*monitor.M[state, *KV])}, nil
+}
+
 // Close implements a method of the [blob.StoreCloser] interface.
 func (s Store) Close(ctx context.Context) error {
+	kerr := s.M.CloseAll(ctx)
 	if c, ok := s.M.DB.base.(blob.Closer); ok {
-		return c.Close(ctx)
+		return errors.Join(kerr, c.Close(ctx))
 	}
-	return nil
+	return kerr
+}
+
+// Stats reports the sum of the cache statistics for all the keyspaces that
+// have been opened via s or any of its substores.
+func (s Store) Stats() Stats {
+	var out Stats
+	for _, kv := range s.M.DB.reg.all() {
+		ks := kv.Stats()
+		out.Hits += ks.Hits
+		out.Misses += ks.Misses
+		out.NegativeHits += ks.NegativeHits
+		out.Evictions += ks.Evictions
+		out.Keys += ks.Keys
+		out.CachedBlobs += ks.CachedBlobs
+		out.CachedBytes += ks.CachedBytes
+		out.MaxBytes += ks.MaxBytes
+		out.StaleKeys += ks.StaleKeys
+	}
+	return out
+}
+
+// Stats record cache performance counters for a [KV] or a [Store].
+type Stats struct {
+	Hits         int64 // lookups served from the cache
+	Misses       int64 // lookups fetched from the base store
+	NegativeHits int64 // lookups of keys known to be absent, not sent to the base store
+	Evictions    int64 // blobs evicted from the cache to make room for others
+
+	Keys        int64 // keys recorded in the keymap (0 if not yet loaded)
+	CachedBlobs int64 // blobs currently held in the cache
+	CachedBytes int64 // total size of blobs currently held in the cache
+	MaxBytes    int64 // the capacity of the cache in bytes
+
+	// Keys whose presence in the keymap was found to disagree with the base
+	// store. A nonzero value means something else is modifying the base store.
+	StaleKeys int64
 }
 
 // KV implements a [blob.KV] that delegates to an underlying store through an
@@ -106,23 +201,146 @@ type KV struct {
 
 	// The keymap is initialized to the keyspace of the underlying store.
 	// Additional keys are added by store queries.
+
+	maxBytes int64
+	verify   bool // verify exclusive puts against the base store
+
+	// Counters for Stats. The cache reports both evictions and explicit
+	// removals to its callback, so evictions are computed as the difference
+	// between the callbacks and the removals made by the KV itself.
+	hits, misses, negHits atomic.Int64
+	dropped, removed      atomic.Int64
+	stale                 atomic.Int64
+
+	// If acct != nil, the memory used by the cache is charged to a shared
+	// budget. The keys of cached blobs are recorded in cached, so that the
+	// cache can give up memory when the budget asks.
+	acct   *blob.Account
+	bμ     sync.Mutex
+	cached mapset.Set[string]
 }
 
 // NewKV constructs a new cached [KV] with the specified capacity in bytes,
 // delegating storage operations to s.  It will panic if maxBytes < 0.
 func NewKV(s blob.KV, maxBytes int) *KV {
-	return &KV{
-		base:   s,
-		keymap: stree.New[string](300, strings.Compare),
-		cache: cache.New(cache.LRU[string, []byte](int64(maxBytes)).
-			WithSize(cache.Length),
-		),
+	kv := &KV{
+		base:     s,
+		keymap:   stree.New[string](300, strings.Compare),
+		maxBytes: int64(maxBytes),
+	}
+	kv.cache = cache.New(cache.LRU[string, []byte](int64(maxBytes)).
+		WithSize(cache.Length).
+		OnEvict(func(key string, data []byte) { kv.dropped.Add(1); kv.uncharge(key, data) }),
+	)
+	return kv
+}
+
+// SetBudget charges the memory used by the cache of s to b. When b runs short
+// of memory, s evicts cached blobs to make room; when s cannot reserve memory
+// from b, it does not cache the blob. SetBudget must be called before s is
+// first used, and at most once.
+func (s *KV) SetBudget(b *blob.Budget) {
+	s.acct = b.Register(s.reclaim)
+}
+
+// SetVerifyPut sets whether s verifies exclusive puts against the base store.
+//
+// By default, a Put with Replace false reports [blob.ErrKeyExists] for a key
+// whose blob is cached, without consulting the base store. If the key was
+// deleted from the base store by another writer, that report is wrong. When
+// verification is enabled, s checks such keys with the base store before
+// reporting them, and discards them if they are gone. This costs a query to
+// the base store for each exclusive put of a cached key.
+//
+// SetVerifyPut must be called before s is first used.
+func (s *KV) SetVerifyPut(verify bool) { s.verify = verify }
+
+// uncharge releases the budget memory for a blob removed from the cache.
+func (s *KV) uncharge(key string, data []byte) {
+	if s.acct == nil {
+		return
+	}
+	s.acct.Release(int64(len(data)))
+	s.bμ.Lock()
+	defer s.bμ.Unlock()
+	s.cached.Remove(key)
+}
+
+// reclaim evicts cached blobs until at least n bytes have been released to
+// the budget, or the cache is empty.
+func (s *KV) reclaim(n int64) {
+	s.bμ.Lock()
+	keys := make([]string, 0, len(s.cached))
+	for key := range s.cached {
+		keys = append(keys, key)
+	}
+	s.bμ.Unlock()
+
+	goal := s.acct.Used() - n
+	for _, key := range keys {
+		if s.acct.Used() <= goal {
+			break
+		}
+		if !s.cache.Remove(key) {
+			s.bμ.Lock()
+			s.cached.Remove(key) // stale
+			s.bμ.Unlock()
+		}
 	}
 }
 
+// Stats reports cache statistics for s.
+func (s *KV) Stats() Stats {
+	out := Stats{
+		Hits:         s.hits.Load(),
+		Misses:       s.misses.Load(),
+		NegativeHits: s.negHits.Load(),
+		Evictions:    s.dropped.Load() - s.removed.Load(),
+		CachedBlobs:  int64(s.cache.Len()),
+		CachedBytes:  s.cache.Size(),
+		MaxBytes:     s.maxBytes,
+		StaleKeys:    s.stale.Load(),
+	}
+	if s.listed.Load() {
+		s.μ.RLock()
+		out.Keys = int64(s.keymap.Len())
+		s.μ.RUnlock()
+	}
+	return out
+}
+
+// putCache adds data to the cache for key, and reports whether it was stored.
+func (s *KV) putCache(key string, data []byte) bool {
+	if s.acct != nil && !s.acct.Reserve(int64(len(data))) {
+		// The budget has no room for this blob, so do not cache it, and do not
+		// leave a previous value in place.
+		if s.cache.Remove(key) {
+			s.removed.Add(1)
+		}
+		return false
+	}
+	replaced := s.cache.Has(key)
+	ok := s.cache.Put(key, data)
+	if ok && replaced {
+		s.removed.Add(1) // the old value was replaced, not evicted
+	}
+	if s.acct != nil {
+		if !ok {
+			s.acct.Release(int64(len(data)))
+		} else {
+			s.bμ.Lock()
+			s.cached.Add(key)
+			s.bμ.Unlock()
+		}
+	}
+	return ok
+}
+
 // Get implements a method of [blob.KV].
 func (s *KV) Get(ctx context.Context, key string) ([]byte, error) {
-	if err := s.initKeyMap(ctx); err != nil {
+	if err := blob.CheckKey(key); err != nil {
+		return nil, err
+	} else if err := s.initKeyMap(ctx); err != nil {
 		return nil, err
 	}
 	s.μ.RLock()
@@ -145,11 +363,14 @@ func (s *KV) Get(ctx context.Context, key string) ([]byte, error) {
 // s.μ either exclusively or shared.
 func (s *KV) getLocked(ctx context.Context, key string) ([]byte, bool, error) {
 	if _, ok := s.keymap.Get(key); !ok {
+		s.negHits.Add(1)
 		return nil, false, blob.KeyNotFound(key)
 	}
 	if data, ok := s.cache.Get(key); ok {
+		s.hits.Add(1)
 		return data, true, nil
 	}
+	s.misses.Add(1)
 
 	// Reaching here, the key is in the key map but not in the cache.
 	data, err := s.base.Get(ctx, key)
@@ -158,6 +379,9 @@ func (s *KV) getLocked(ctx context.Context, key string) ([]byte, bool, error) {
 		// keymap is out of sync. But we can'tmodify the keymap here, as we do
 		// not have it exclusively. Record the offender so that the next call to
 		// List can process it out (since that's where it would be visible).
+		if blob.IsKeyNotFound(err) {
+			s.stale.Add(1)
+		}
 		s.vμ.Lock()
 		s.invalid.Add(key)
 		s.vμ.Unlock()
@@ -165,7 +389,7 @@ func (s *KV) getLocked(ctx context.Context, key string) ([]byte, bool, error) {
 	}
 
 	// Update the cache before returning the value.
-	cached := s.cache.Put(key, data)
+	cached := s.putCache(key, data)
 	return data, cached, nil
 }
 
@@ -187,29 +411,52 @@ func (s *KV) Has(ctx context.Context, keys ...string) (blob.KeySet, error) {
 
 // Put implements a method of [blob.KV].
 func (s *KV) Put(ctx context.Context, opts blob.PutOptions) error {
-	if err := s.initKeyMap(ctx); err != nil {
+	if err := blob.CheckKey(opts.Key); err != nil {
+		return err
+	} else if err := s.initKeyMap(ctx); err != nil {
 		return err
 	}
 	s.μ.Lock()
 	defer s.μ.Unlock()
 	s.checkInvalidLocked()
 
-	if !opts.Replace {
-		if s.cache.Has(opts.Key) {
+	if !opts.Replace && s.cache.Has(opts.Key) {
+		if !s.verify {
 			return blob.KeyExists(opts.Key)
 		}
+		ks, err := s.base.Has(ctx, opts.Key)
+		if err != nil {
+			return err
+		} else if ks.Has(opts.Key) {
+			return blob.KeyExists(opts.Key)
+		}
+
+		// The key was removed from the base store behind our back.
+		s.stale.Add(1)
+		if s.cache.Remove(opts.Key) {
+			s.removed.Add(1)
+		}
+		s.keymap.Remove(opts.Key)
 	}
 	if err := s.base.Put(ctx, opts); err != nil {
+		if errors.Is(err, blob.ErrKeyExists) {
+			// The key was added to the base store behind our back.
+			if s.keymap.Add(opts.Key) {
+				s.stale.Add(1)
+			}
+		}
 		return err
 	}
-	s.cache.Put(opts.Key, opts.Data)
+	s.putCache(opts.Key, opts.Data)
 	s.keymap.Replace(opts.Key)
 	return nil
 }
 
 // Delete implements a method of [blob.KV].
 func (s *KV) Delete(ctx context.Context, key string) error {
-	if err := s.initKeyMap(ctx); err != nil {
+	if err := blob.CheckKey(key); err != nil {
+		return err
+	} else if err := s.initKeyMap(ctx); err != nil {
 		return err
 	}
 	s.μ.Lock()
@@ -218,11 +465,37 @@ func (s *KV) Delete(ctx context.Context, key string) error {
 
 	// Even if we fail to delete the key from the underlying store, take this as
 	// a signal that we should forget about its data.
-	s.cache.Remove(key)
+	if s.cache.Remove(key) {
+		s.removed.Add(1)
+	}
 	s.keymap.Remove(key)
 	return s.base.Delete(ctx, key)
 }
 
+// CAS returns a content-addressed view of s, sharing its cache and keymap.
+//
+// Because the content stored under a content address never changes, a cached
+// blob never needs to be revalidated: A Get for a cached key is served from
+// the cache without locking the keymap, and a CASPut for a key in the keymap
+// succeeds without consulting the base store, even if s verifies exclusive
+// puts (see [KV.SetVerifyPut]). Thus only a Delete through s or the view
+// removes a content address from the cache, and only a put adds one that is
+// known to be absent. As with [blob.CASFromKV], content addresses are
+// computed with SHA3-256.
+func (s *KV) CAS() blob.CAS { return blob.CASFromKV(casKV{s}) }
+
+// casKV is a view of a KV used to implement its CAS method.
+type casKV struct{ *KV }
+
+// Get implements a method of [blob.KV].
+func (c casKV) Get(ctx context.Context, key string) ([]byte, error) {
+	if data, ok := c.cache.Get(key); ok {
+		c.hits.Add(1)
+		return bytes.Clone(data), nil
+	}
+	return c.KV.Get(ctx, key)
+}
+
 // initKeyMap initializes the key map from the base store.
 func (s *KV) initKeyMap(ctx context.Context) error {
 	if s.listed.Load() {
@@ -234,33 +507,14 @@ func (s *KV) initKeyMap(ctx context.Context) error {
 		return nil // someone else did it, OK
 	}
 
-	ictx, cancel := context.WithCancel(ctx)
-	defer cancel()
-	g := taskgroup.New(cancel)
-
-	// The keymap is not safe for concurrent use by multiple goroutines, so
-	// serialize insertions through a collector.
-	coll := taskgroup.Gather(g.Go, func(key string) {
+	for key, err := range blob.ListSharded(ctx, s.base, 256) {
+		if err != nil {
+			return err
+		}
 		s.keymap.Add(key)
-	})
-
-	for i := 0; i < 256; i++ {
-		pfx := string([]byte{byte(i)})
-		coll.Report(func(report func(string)) error {
-			for key, err := range s.base.List(ictx, pfx) {
-				if err != nil {
-					return err
-				} else if !strings.HasPrefix(key, pfx) {
-					break
-				}
-				report(key)
-			}
-			return nil
-		})
 	}
-	err := g.Wait()
-	s.listed.Store(err == nil)
-	return err
+	s.listed.Store(true)
+	return nil
 }
 
 // checkInvalidLocked updates the keymap to remove any keys found to be
@@ -16,12 +16,14 @@ package cachestore_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/creachadair/ffs/blob"
 	"github.com/creachadair/ffs/blob/memstore"
 	"github.com/creachadair/ffs/blob/storetest"
 	"github.com/creachadair/ffs/storage/cachestore"
+	"github.com/google/go-cmp/cmp"
 )
 
 var (
@@ -83,3 +85,230 @@ func TestRecurrentList(t *testing.T) {
 		}
 	}
 }
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+	base := memstore.New(func() blob.KV {
+		return memstore.NewKV().Init(map[string]string{
+			"a": "apple", "b": "banana", "c": "cherry",
+		})
+	})
+	cs := cachestore.New(base, 12)
+	kv := storetest.SubKV(t, ctx, cs, "test").(*cachestore.KV)
+
+	if diff := cmp.Diff(cachestore.Stats{MaxBytes: 12}, kv.Stats()); diff != "" {
+		t.Errorf("Initial stats (-want, +got):\n%s", diff)
+	}
+
+	mustGet := func(key string) {
+		t.Helper()
+		if _, err := kv.Get(ctx, key); err != nil {
+			t.Fatalf("Get %q: unexpected error: %v", key, err)
+		}
+	}
+	mustGet("a") // miss
+	mustGet("a") // hit
+	mustGet("b") // miss; cache holds 11 bytes
+	mustGet("c") // miss; evicts "a"
+	if _, err := kv.Get(ctx, "nonesuch"); !blob.IsKeyNotFound(err) {
+		t.Fatalf("Get nonesuch: got %v, want %v", err, blob.ErrKeyNotFound)
+	}
+	if err := kv.Put(ctx, blob.PutOptions{Key: "c", Data: []byte("cranberry"), Replace: true}); err != nil {
+		t.Fatalf("Put c: %v", err) // replaces "c", evicts "b"
+	}
+	if err := kv.Delete(ctx, "c"); err != nil {
+		t.Fatalf("Delete c: %v", err)
+	}
+
+	want := cachestore.Stats{
+		Hits:         1,
+		Misses:       3,
+		NegativeHits: 1,
+		Evictions:    2,
+		Keys:         2,
+		MaxBytes:     12,
+	}
+	if diff := cmp.Diff(want, kv.Stats()); diff != "" {
+		t.Errorf("Stats (-want, +got):\n%s", diff)
+	}
+
+	// A second keyspace contributes to the store aggregate.
+	kv2 := storetest.SubKV(t, ctx, cs, "sub", "other")
+	if _, err := kv2.Get(ctx, "b"); err != nil {
+		t.Fatalf("Get b: %v", err)
+	}
+	want.Misses++
+	want.Keys += 3
+	want.CachedBlobs++
+	want.CachedBytes += 6
+	want.MaxBytes += 12
+	if diff := cmp.Diff(want, cs.Stats()); diff != "" {
+		t.Errorf("Store stats (-want, +got):\n%s", diff)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	ctx := context.Background()
+	base := memstore.New(func() blob.KV {
+		return memstore.NewKV().Init(map[string]string{
+			"a": "apple", "b": "banana", "c": "cherry",
+		})
+	})
+	budget := blob.NewBudget(12)
+	cs := cachestore.New(base, 100)
+	cs.SetBudget(budget)
+	kv1 := storetest.SubKV(t, ctx, cs, "one").(*cachestore.KV)
+	kv2 := storetest.SubKV(t, ctx, cs, "two").(*cachestore.KV)
+
+	mustGet := func(kv blob.KV, key, want string) {
+		t.Helper()
+		got, err := kv.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("Get %q: unexpected error: %v", key, err)
+		} else if string(got) != want {
+			t.Errorf("Get %q: got %q, want %q", key, got, want)
+		}
+	}
+
+	// Each cache alone has room for everything, but the shared budget does
+	// not, so caching in one keyspace evicts from the other.
+	mustGet(kv1, "a", "apple")
+	mustGet(kv1, "b", "banana")
+	if got := budget.Used(); got != 11 {
+		t.Errorf("Budget used: got %d, want 11", got)
+	}
+	mustGet(kv2, "c", "cherry")
+	if got := budget.Used(); got > budget.Limit() {
+		t.Errorf("Budget used: got %d, want ≤ %d", got, budget.Limit())
+	}
+	if got := kv2.Stats().CachedBytes; got != 6 {
+		t.Errorf("kv2 cached bytes: got %d, want 6", got)
+	}
+	st := cs.Stats()
+	if st.CachedBytes != budget.Used() {
+		t.Errorf("Cached bytes: got %d, budget reports %d", st.CachedBytes, budget.Used())
+	}
+
+	// A blob too large for the budget is not cached, but is still served.
+	if err := kv1.Put(ctx, blob.PutOptions{Key: "d", Data: []byte("dragonfruit!!")}); err != nil {
+		t.Fatalf("Put d: %v", err)
+	}
+	mustGet(kv1, "d", "dragonfruit!!")
+	if got := budget.Used(); got > budget.Limit() {
+		t.Errorf("Budget used: got %d, want ≤ %d", got, budget.Limit())
+	}
+
+	// Deleting cached blobs returns their memory to the budget.
+	for _, key := range []string{"a", "b", "c"} {
+		kv1.Delete(ctx, key)
+		kv2.Delete(ctx, key)
+	}
+	if got := budget.Used(); got != 0 {
+		t.Errorf("Budget used after delete: got %d, want 0", got)
+	}
+}
+
+func TestVerifyPut(t *testing.T) {
+	ctx := context.Background()
+	put := func(kv blob.KV, key string) error {
+		return kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte("data")})
+	}
+
+	for _, verify := range []bool{false, true} {
+		base := memstore.NewKV()
+		kv := cachestore.NewKV(base, 1000)
+		kv.SetVerifyPut(verify)
+
+		// Cache a key, then delete it from the base store out of band.
+		if err := put(kv, "a"); err != nil {
+			t.Fatalf("Put a: unexpected error: %v", err)
+		}
+		if err := base.Delete(ctx, "a"); err != nil {
+			t.Fatalf("Delete a: unexpected error: %v", err)
+		}
+
+		// Without verification, the stale key is reported to exist.
+		err := put(kv, "a")
+		if verify {
+			if err != nil {
+				t.Errorf("Put a (verify): unexpected error: %v", err)
+			}
+			if _, err := base.Get(ctx, "a"); err != nil {
+				t.Errorf("Get a from base: unexpected error: %v", err)
+			}
+		} else if !errors.Is(err, blob.ErrKeyExists) {
+			t.Errorf("Put a: got %v, want %v", err, blob.ErrKeyExists)
+		}
+
+		// A key added to the base store out of band is reported to exist, and
+		// recorded thereafter.
+		if err := put(base, "b"); err != nil {
+			t.Fatalf("Put b in base: unexpected error: %v", err)
+		}
+		if err := put(kv, "b"); !errors.Is(err, blob.ErrKeyExists) {
+			t.Errorf("Put b: got %v, want %v", err, blob.ErrKeyExists)
+		}
+		if ks, err := kv.Has(ctx, "b"); err != nil || !ks.Has("b") {
+			t.Errorf("Has b: got (%v, %v), want b present", ks, err)
+		}
+
+		want := int64(1)
+		if verify {
+			want = 2
+		}
+		if got := kv.Stats().StaleKeys; got != want {
+			t.Errorf("StaleKeys (verify=%v): got %d, want %d", verify, got, want)
+		}
+	}
+}
+
+func TestCAS(t *testing.T) {
+	ctx := context.Background()
+	base := memstore.NewKV()
+	kv := cachestore.NewKV(base, 1000)
+	kv.SetVerifyPut(true)
+	cas := kv.CAS()
+
+	key, err := cas.CASPut(ctx, []byte("hello"))
+	if err != nil {
+		t.Fatalf("CASPut: unexpected error: %v", err)
+	}
+
+	// Remove the blob from the base store out of band. The content-addressed
+	// view continues to trust its cache, even though the KV verifies puts.
+	if err := base.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete from base: %v", err)
+	}
+	if got, err := cas.Get(ctx, key); err != nil || string(got) != "hello" {
+		t.Errorf("Get: got (%q, %v), want hello", got, err)
+	}
+	if _, err := cas.CASPut(ctx, []byte("hello")); err != nil {
+		t.Errorf("CASPut again: unexpected error: %v", err)
+	}
+	if _, err := base.Get(ctx, key); !blob.IsKeyNotFound(err) {
+		t.Errorf("Get from base: got %v, want %v", err, blob.ErrKeyNotFound)
+	}
+
+	// A delete through the cache invalidates the key, so that it is written
+	// again by the next put.
+	if err := cas.Delete(ctx, key); !blob.IsKeyNotFound(err) {
+		t.Errorf("Delete: got %v, want %v", err, blob.ErrKeyNotFound)
+	}
+	if _, err := cas.Get(ctx, key); !blob.IsKeyNotFound(err) {
+		t.Errorf("Get after delete: got %v, want %v", err, blob.ErrKeyNotFound)
+	}
+	if _, err := cas.CASPut(ctx, []byte("hello")); err != nil {
+		t.Fatalf("CASPut again: unexpected error: %v", err)
+	}
+	if got, err := base.Get(ctx, key); err != nil || string(got) != "hello" {
+		t.Errorf("Get from base: got (%q, %v), want hello", got, err)
+	}
+
+	// Substores are also cache stores, so that their CAS is cache-aware.
+	s := cachestore.New(memstore.New(nil), 1000)
+	if sub, err := s.Sub(ctx, "sub"); err != nil {
+		t.Fatalf("Sub: unexpected error: %v", err)
+	} else if _, ok := sub.(cachestore.Store); !ok {
+		t.Errorf("Sub: got %T, want cachestore.Store", sub)
+	}
+}
@@ -14,6 +14,9 @@
 
 // Package encrypted implements an encryption codec which encodes data by
 // encrypting and authenticating with a cipher.AEAD instance.
+//
+// To keep the data key in an external key management service rather than in
+// local storage, see [KeyWrapper].
 package encrypted
 
 import (
@@ -29,8 +32,9 @@ import (
 // A Codec implements the encoded.Codec interface and encrypts and
 // authenticates data using a cipher.AEAD instance.
 type Codec struct {
-	aead   cipher.AEAD        // the encryption context
-	random func([]byte) error // used to generate nonce values
+	aead    cipher.AEAD        // the encryption context
+	random  func([]byte) error // used to generate nonce values
+	segSize int                // plaintext segment size for EncodeStream
 }
 
 // Options control the construction of a *Codec.
@@ -38,6 +42,11 @@ type Options struct {
 	// Replace the contents of buf with cryptographically-secure random bytes.
 	// If nil, the store uses the crypto/rand package to generate bytes.
 	Random func(buf []byte) error
+
+	// The size in bytes of the plaintext segments written by EncodeStream.
+	// Memory use for streaming is proportional to this size. If zero, a
+	// default of 1 MiB is used.
+	SegmentSize int
 }
 
 func (o *Options) random() func([]byte) error {
@@ -50,6 +59,13 @@ func (o *Options) random() func([]byte) error {
 	}
 }
 
+func (o *Options) segmentSize() int {
+	if o == nil || o.SegmentSize <= 0 {
+		return defaultSegmentSize
+	}
+	return min(o.SegmentSize, maxSegmentSize)
+}
+
 // New constructs an encryption codec that uses the given encryption context.
 // If opts == nil, default options are used.  New will panic if aead == nil.
 //
@@ -59,7 +75,7 @@ func New(aead cipher.AEAD, opts *Options) *Codec {
 	if aead == nil {
 		panic("aead == nil")
 	}
-	return &Codec{aead: aead, random: opts.random()}
+	return &Codec{aead: aead, random: opts.random(), segSize: opts.segmentSize()}
 }
 
 // Encode implements part of the codec interface. It encrypts src with the
@@ -76,7 +92,11 @@ func (c *Codec) Encode(w io.Writer, src []byte) error {
 // Decode implements part of the codec interface.  It decodes src from a
 // wrapper block, decrypts the message, and writes the result to w.  If
 // decryption fails, an error is reported without writing any data to w.
+// Decode also accepts the output of EncodeStream.
 func (c *Codec) Decode(w io.Writer, src []byte) error {
+	if len(src) != 0 && src[0] == streamTag {
+		return c.decodeStream(w, src)
+	}
 	blk, err := parseBlock(src)
 	if err != nil {
 		return err
@@ -157,4 +177,18 @@ An encrypted blob is stored as a buffer with the following structure:
 
 Block data are compressed with https://github.com/google/snappy.
 Authenticated encryption is managed by a cipher.AEAD instance.
+
+A blob written by EncodeStream instead has the structure:
+
+   tag   byte    : 0 (never a valid nonce length)
+   ssize uvarint : plaintext segment size
+   nlen  byte    : nonce length
+   base  []byte  : base nonce (nlen bytes)
+   segments...   : one or more, each
+     slen  uvarint : length of segment data
+     data  []byte  : segment data, compressed, encrypted
+
+Each segment holds at most ssize bytes of plaintext, and only the last may be
+shorter. Segment i is sealed with the base nonce whose final 8 bytes are XORed
+with 2*i, or 2*i+1 for the last segment.
 */
@@ -16,8 +16,11 @@ package encrypted_test
 
 import (
 	"bytes"
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/creachadair/ffs/storage/codecs/encrypted"
@@ -69,3 +72,159 @@ func TestRoundTrip(t *testing.T) {
 		t.Errorf("Decode: got %q, want %q", got, value)
 	}
 }
+
+func TestStream(t *testing.T) {
+	aes, err := aes.NewCipher([]byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("Creating AES cipher: %v", err)
+	}
+	gcm, err := cipher.NewGCM(aes)
+	if err != nil {
+		t.Fatalf("Creating AES-GCM instance: %v", err)
+	}
+	const segSize = 64
+	e := encrypted.New(gcm, &encrypted.Options{SegmentSize: segSize})
+
+	encode := func(t *testing.T, value string) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := e.EncodeStream(&buf, strings.NewReader(value)); err != nil {
+			t.Fatalf("EncodeStream: unexpected error: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	text := strings.Repeat("abcdefghijklmnopqrstuvwxyz", 20)
+	for _, n := range []int{0, 1, segSize - 1, segSize, segSize + 1, 4 * segSize, len(text)} {
+		value := text[:n]
+		enc := encode(t, value)
+
+		var got bytes.Buffer
+		if err := e.DecodeStream(&got, bytes.NewReader(enc)); err != nil {
+			t.Errorf("DecodeStream [%d]: unexpected error: %v", n, err)
+		} else if got.String() != value {
+			t.Errorf("DecodeStream [%d]: got %q, want %q", n, got.String(), value)
+		}
+
+		got.Reset()
+		if err := e.Decode(&got, enc); err != nil {
+			t.Errorf("Decode [%d]: unexpected error: %v", n, err)
+		} else if got.String() != value {
+			t.Errorf("Decode [%d]: got %q, want %q", n, got.String(), value)
+		}
+	}
+
+	// DecodeStream accepts the output of Encode.
+	var blk, got bytes.Buffer
+	if err := e.Encode(&blk, []byte(text)); err != nil {
+		t.Fatalf("Encode: unexpected error: %v", err)
+	}
+	if err := e.DecodeStream(&got, &blk); err != nil {
+		t.Errorf("DecodeStream block: unexpected error: %v", err)
+	} else if got.String() != text {
+		t.Errorf("DecodeStream block: got %q, want %q", got.String(), text)
+	}
+
+	// Damaged, truncated, or extended streams are rejected by Decode without
+	// writing any output.
+	enc := encode(t, text)
+	damaged := bytes.Clone(enc)
+	damaged[len(damaged)/2] ^= 1
+	for _, tc := range []struct {
+		name string
+		data []byte
+	}{
+		{"Damaged", damaged},
+		{"Truncated", enc[:len(enc)-1]},
+		{"Extended", append(bytes.Clone(enc), enc[len(enc)-20:]...)},
+	} {
+		var out bytes.Buffer
+		if err := e.Decode(&out, tc.data); err == nil {
+			t.Errorf("Decode %s: got nil, want error", tc.name)
+		} else if out.Len() != 0 {
+			t.Errorf("Decode %s: wrote %d bytes, want 0", tc.name, out.Len())
+		}
+	}
+}
+
+func TestKeyWrap(t *testing.T) {
+	ctx := context.Background()
+	kw, err := encrypted.NewMemoryWrapper([]byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("NewMemoryWrapper: %v", err)
+	}
+
+	// Route calls through the function adapter to check that it delegates.
+	var calls int
+	adapter := encrypted.WrapFuncs{
+		Wrap: func(ctx context.Context, key []byte) ([]byte, error) {
+			calls++
+			return kw.WrapKey(ctx, key)
+		},
+		Unwrap: func(ctx context.Context, wrapped []byte) ([]byte, error) {
+			calls++
+			return kw.UnwrapKey(ctx, wrapped)
+		},
+	}
+
+	key, wrapped, err := encrypted.GenerateKey(ctx, adapter, 32)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	if len(key) != 32 {
+		t.Errorf("Key length: got %d, want 32", len(key))
+	}
+	if bytes.Contains(wrapped, key) {
+		t.Error("Wrapped key contains the plaintext key")
+	}
+
+	// Encode a value with the data key.
+	newCodec := func(key []byte) *encrypted.Codec {
+		t.Helper()
+		blk, err := aes.NewCipher(key)
+		if err != nil {
+			t.Fatalf("Creating AES cipher: %v", err)
+		}
+		gcm, err := cipher.NewGCM(blk)
+		if err != nil {
+			t.Fatalf("Creating AES-GCM instance: %v", err)
+		}
+		return encrypted.New(gcm, nil)
+	}
+	const value = "a secret kept is a secret lost"
+	var enc bytes.Buffer
+	if err := newCodec(key).Encode(&enc, []byte(value)); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	// Recover the data key from its wrapped form, and decode the value.
+	got, err := adapter.UnwrapKey(ctx, wrapped)
+	if err != nil {
+		t.Fatalf("UnwrapKey: %v", err)
+	}
+	var dec bytes.Buffer
+	if err := newCodec(got).Decode(&dec, enc.Bytes()); err != nil {
+		t.Fatalf("Decode: %v", err)
+	} else if dec.String() != value {
+		t.Errorf("Decode: got %q, want %q", dec.String(), value)
+	}
+	if calls != 2 {
+		t.Errorf("Adapter calls: got %d, want 2", calls)
+	}
+
+	// A different key encryption key cannot unwrap the key.
+	other, err := encrypted.NewMemoryWrapper([]byte("fedcba9876543210"))
+	if err != nil {
+		t.Fatalf("NewMemoryWrapper: %v", err)
+	}
+	if got, err := other.UnwrapKey(ctx, wrapped); err == nil {
+		t.Errorf("UnwrapKey with wrong key: got %q, want error", got)
+	}
+
+	// Unwrapping honors the context.
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := kw.UnwrapKey(cctx, wrapped); !errors.Is(err, context.Canceled) {
+		t.Errorf("UnwrapKey canceled: got %v, want %v", err, context.Canceled)
+	}
+}
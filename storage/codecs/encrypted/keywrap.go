@@ -0,0 +1,134 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encrypted
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// A KeyWrapper wraps and unwraps data keys using a key encryption key that is
+// held elsewhere, typically by a cloud key management service (KMS) or a
+// hardware security module (HSM). This allows the data key for a [Codec] to
+// be stored in wrapped form, so that the plaintext key is never written to
+// local storage:
+//
+//	key, wrapped, err := encrypted.GenerateKey(ctx, kw, 32)
+//	// ... save wrapped, and use key to construct the codec.
+//
+//	// Later:
+//	key, err := kw.UnwrapKey(ctx, wrapped)
+//
+// Implementations must be safe for concurrent use by multiple goroutines.
+type KeyWrapper interface {
+	// WrapKey encrypts key and returns the wrapped key.
+	WrapKey(ctx context.Context, key []byte) ([]byte, error)
+
+	// UnwrapKey decrypts a wrapped key previously returned by WrapKey.
+	// It must report an error if wrapped is invalid or was not produced by
+	// this wrapper.
+	UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// WrapFuncs implements the [KeyWrapper] interface by calling functions, for
+// adapting the client library of an external service. Both functions must be
+// set.
+type WrapFuncs struct {
+	Wrap   func(ctx context.Context, key []byte) ([]byte, error)
+	Unwrap func(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// WrapKey implements part of the [KeyWrapper] interface.
+func (w WrapFuncs) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	return w.Wrap(ctx, key)
+}
+
+// UnwrapKey implements part of the [KeyWrapper] interface.
+func (w WrapFuncs) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	return w.Unwrap(ctx, wrapped)
+}
+
+// GenerateKey generates a random data key of n bytes, wraps it with kw, and
+// returns both the plaintext key and the wrapped key. The caller should store
+// only the wrapped key. GenerateKey will panic if n ≤ 0.
+func GenerateKey(ctx context.Context, kw KeyWrapper, n int) (key, wrapped []byte, _ error) {
+	if n <= 0 {
+		panic("key length must be positive")
+	}
+	key = make([]byte, n)
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, fmt.Errorf("generating key: %w", err)
+	}
+	wrapped, err := kw.WrapKey(ctx, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("wrapping key: %w", err)
+	}
+	return key, wrapped, nil
+}
+
+// MemoryWrapper is an in-memory implementation of the [KeyWrapper] interface,
+// that wraps keys with AES-GCM using a key encryption key held in memory. It
+// is intended for testing, and as a stand-in for an external service.
+type MemoryWrapper struct {
+	aead cipher.AEAD
+}
+
+// NewMemoryWrapper constructs a [MemoryWrapper] that uses kek as its key
+// encryption key. The length of kek must be a valid AES key size (16, 24, or
+// 32 bytes).
+func NewMemoryWrapper(kek []byte) (*MemoryWrapper, error) {
+	blk, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(blk)
+	if err != nil {
+		return nil, err
+	}
+	return &MemoryWrapper{aead: aead}, nil
+}
+
+// WrapKey implements part of the [KeyWrapper] interface.
+func (m *MemoryWrapper) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	nlen := m.aead.NonceSize()
+	buf := make([]byte, nlen, nlen+len(key)+m.aead.Overhead())
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	return m.aead.Seal(buf, buf, key, nil), nil
+}
+
+// UnwrapKey implements part of the [KeyWrapper] interface.
+func (m *MemoryWrapper) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	nlen := m.aead.NonceSize()
+	if len(wrapped) < nlen {
+		return nil, errors.New("unwrap: invalid wrapped key")
+	}
+	key, err := m.aead.Open(nil, wrapped[:nlen], wrapped[nlen:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("unwrap: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,201 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package encrypted
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/golang/snappy"
+)
+
+const (
+	// streamTag is the first byte of a stream-encoded blob. A block-encoded
+	// blob begins with its nonce length, which is never zero.
+	streamTag = 0
+
+	// defaultSegmentSize is the default plaintext size of a stream segment.
+	defaultSegmentSize = 1 << 20
+
+	// maxSegmentSize is the largest segment size accepted by the decoder, to
+	// bound the memory used for a corrupted stream.
+	maxSegmentSize = 1 << 26
+
+	// minStreamNonce is the shortest nonce that admits a segment counter.
+	minStreamNonce = 8
+)
+
+// EncodeStream reads data from r until EOF, and writes it to w encoded as a
+// sequence of segments, each separately compressed and encrypted. Unlike
+// Encode, which holds the whole blob in memory, EncodeStream uses memory
+// proportional to the segment size (see Options.SegmentSize), so it is
+// suitable for very large blobs. The result may be decoded by Decode or by
+// DecodeStream.
+//
+// Each segment is sealed with a nonce derived from a random base nonce, its
+// position in the stream, and whether it is the last segment, so that
+// segments cannot be reordered, dropped, or truncated without detection.
+// This requires a nonce of at least 8 bytes, which the standard AEAD
+// constructions provide.
+func (c *Codec) EncodeStream(w io.Writer, r io.Reader) error {
+	nlen := c.aead.NonceSize()
+	if nlen < minStreamNonce {
+		return fmt.Errorf("encrypt: nonce size %d is too short for streaming", nlen)
+	}
+
+	// Header: [tag][uvarint segment size][nlen][base nonce ...]
+	hdr := binary.AppendUvarint([]byte{streamTag}, uint64(c.segSize))
+	hdr = append(hdr, byte(nlen))
+	base := make([]byte, nlen)
+	if err := c.random(base); err != nil {
+		return fmt.Errorf("encrypt: generating nonce: %w", err)
+	}
+	if _, err := w.Write(append(hdr, base...)); err != nil {
+		return err
+	}
+
+	// Read one segment ahead so that we know which segment is the last.
+	plain := make([]byte, c.segSize)
+	next := make([]byte, c.segSize)
+	buf := make([]byte, snappy.MaxEncodedLen(c.segSize)+c.aead.Overhead())
+	nonce := make([]byte, nlen)
+	var slen [binary.MaxVarintLen64]byte
+
+	np, err := io.ReadFull(r, plain)
+	for seq := uint64(0); ; seq++ {
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return fmt.Errorf("encrypt: reading input: %w", err)
+		}
+		last := err != nil
+		var nn int
+		if !last {
+			nn, err = io.ReadFull(r, next)
+			last = err == io.EOF
+		}
+
+		segmentNonce(nonce, base, seq, last)
+		compressed := snappy.Encode(buf, plain[:np])
+		sealed := c.aead.Seal(compressed[:0], nonce, compressed, nil)
+		if _, err := w.Write(binary.AppendUvarint(slen[:0], uint64(len(sealed)))); err != nil {
+			return err
+		} else if _, err := w.Write(sealed); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+		plain, next, np = next, plain, nn
+	}
+}
+
+// DecodeStream reads a stream-encoded blob from r and writes its decoded
+// content to w, using memory proportional to the segment size of the stream.
+// Each segment is written to w once it has been authenticated, so if an error
+// occurs, some of the content may already have been written.
+//
+// DecodeStream also accepts the output of Encode, but does not bound its
+// memory use in that case.
+func (c *Codec) DecodeStream(w io.Writer, r io.Reader) error {
+	br := bufio.NewReader(r)
+	tag, err := br.Peek(1)
+	if err != nil {
+		return errors.New("parse: invalid block format")
+	}
+	if tag[0] != streamTag {
+		src, err := io.ReadAll(br)
+		if err != nil {
+			return err
+		}
+		return c.Decode(w, src)
+	}
+	br.ReadByte() // discard the tag
+
+	segSize, err := binary.ReadUvarint(br)
+	if err != nil || segSize == 0 || segSize > maxSegmentSize {
+		return errors.New("parse: invalid stream header")
+	}
+	nlen, err := br.ReadByte()
+	if err != nil || int(nlen) != c.aead.NonceSize() {
+		return errors.New("parse: invalid stream header")
+	}
+	base := make([]byte, nlen)
+	if _, err := io.ReadFull(br, base); err != nil {
+		return errors.New("parse: invalid stream header")
+	}
+
+	maxSealed := uint64(snappy.MaxEncodedLen(int(segSize)) + c.aead.Overhead())
+	buf := make([]byte, maxSealed)
+	plain := make([]byte, segSize)
+	nonce := make([]byte, nlen)
+	for seq := uint64(0); ; seq++ {
+		n, err := binary.ReadUvarint(br)
+		if err != nil || n > maxSealed {
+			return fmt.Errorf("parse: invalid segment %d", seq)
+		}
+		sealed := buf[:n]
+		if _, err := io.ReadFull(br, sealed); err != nil {
+			return fmt.Errorf("parse: truncated segment %d", seq)
+		}
+
+		// Check whether this is the last segment by peeking past it.
+		_, perr := br.Peek(1)
+		last := perr == io.EOF
+		segmentNonce(nonce, base, seq, last)
+		compressed, err := c.aead.Open(sealed[:0], nonce, sealed, nil)
+		if err != nil {
+			return fmt.Errorf("decrypt: segment %d: %w", seq, err)
+		}
+		if dlen, err := snappy.DecodedLen(compressed); err != nil || uint64(dlen) > segSize {
+			return fmt.Errorf("decrypt: segment %d: invalid length", seq)
+		}
+		data, err := snappy.Decode(plain, compressed)
+		if err != nil {
+			return fmt.Errorf("decrypt: decompressing segment %d: %w", seq, err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// decodeStream decodes a stream-encoded blob held in memory, writing nothing
+// to w unless the whole stream is valid.
+func (c *Codec) decodeStream(w io.Writer, src []byte) error {
+	var buf bytes.Buffer
+	if err := c.DecodeStream(&buf, bytes.NewReader(src)); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// segmentNonce sets nonce to the nonce for segment seq of a stream with the
+// given base nonce. The low-order bit records whether the segment is last.
+func segmentNonce(nonce, base []byte, seq uint64, last bool) {
+	copy(nonce, base)
+	ctr := seq << 1
+	if last {
+		ctr |= 1
+	}
+	tail := nonce[len(nonce)-8:]
+	binary.BigEndian.PutUint64(tail, binary.BigEndian.Uint64(tail)^ctr)
+}
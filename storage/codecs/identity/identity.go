@@ -0,0 +1,148 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package identity implements the encoded.Codec interface to store blobs
+// without compression or encryption, but tagged with their length and a
+// checksum so that damaged blobs are detected when they are read.
+//
+// The encoded format is:
+//
+//	uvarint(len(data)) | data | crc32c(data)
+//
+// where the checksum is a 4-byte big-endian CRC-32 using the Castagnoli
+// polynomial.
+package identity
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"sync/atomic"
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrChecksum is reported by Decode when the checksum of a blob does not
+// match its contents.
+var ErrChecksum = errors.New("checksum mismatch")
+
+// A Codec implements the encoded.Codec interface to tag blob data with their
+// length and a checksum, without otherwise transforming them. It also counts
+// the blobs and bytes that pass through it. A Codec is safe for concurrent use
+// by multiple goroutines.
+type Codec struct {
+	encBlobs, encBytes atomic.Int64
+	decBlobs, decBytes atomic.Int64
+	errors             atomic.Int64
+}
+
+// NewCodec returns a new Codec with zero stats.
+func NewCodec() *Codec { return new(Codec) }
+
+// Stats record counts of the data processed by a Codec.
+type Stats struct {
+	EncodedBlobs int64 // the number of blobs encoded
+	EncodedBytes int64 // the total number of bytes encoded (before tagging)
+	DecodedBlobs int64 // the number of blobs successfully decoded
+	DecodedBytes int64 // the total number of bytes decoded (after checking)
+	Errors       int64 // the number of blobs that failed to decode
+}
+
+// Stats returns a snapshot of the current statistics for c.
+func (c *Codec) Stats() Stats {
+	return Stats{
+		EncodedBlobs: c.encBlobs.Load(),
+		EncodedBytes: c.encBytes.Load(),
+		DecodedBlobs: c.decBlobs.Load(),
+		DecodedBytes: c.decBytes.Load(),
+		Errors:       c.errors.Load(),
+	}
+}
+
+// Encode writes src to w tagged with its length and checksum.
+func (c *Codec) Encode(w io.Writer, src []byte) error {
+	buf := make([]byte, 0, binary.MaxVarintLen64+len(src)+4)
+	buf = binary.AppendUvarint(buf, uint64(len(src)))
+	buf = append(buf, src...)
+	buf = binary.BigEndian.AppendUint32(buf, crc32.Checksum(src, crcTable))
+	if _, err := w.Write(buf); err != nil {
+		return err
+	}
+	c.encBlobs.Add(1)
+	c.encBytes.Add(int64(len(src)))
+	return nil
+}
+
+// Decode checks the length and checksum of src, and writes the original data
+// to w. If the checksum does not match, Decode reports an error wrapping
+// ErrChecksum.
+func (c *Codec) Decode(w io.Writer, src []byte) error {
+	data, err := check(src)
+	if err != nil {
+		c.errors.Add(1)
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	c.decBlobs.Add(1)
+	c.decBytes.Add(int64(len(data)))
+	return nil
+}
+
+// DecodedLen reports the length of the original data encoded in src, without
+// verifying its checksum.
+func (c *Codec) DecodedLen(src []byte) (int, error) {
+	n, _, err := parseHeader(src)
+	return n, err
+}
+
+// DecodedSize reports the length of the original data encoded in a blob whose
+// encoding begins with prefix. It implements the encoded.SizeCodec interface.
+func (c *Codec) DecodedSize(prefix []byte) (int64, bool) {
+	v, nb := binary.Uvarint(prefix)
+	if nb <= 0 || v > math.MaxInt64 {
+		return 0, false
+	}
+	return int64(v), true
+}
+
+// parseHeader decodes the length header of src, and reports the length along
+// with the offset of the data in src.
+func parseHeader(src []byte) (n, pos int, _ error) {
+	v, nb := binary.Uvarint(src)
+	if nb <= 0 {
+		return 0, 0, errors.New("invalid length header")
+	} else if v > uint64(len(src)-nb) || int(v)+nb+4 != len(src) {
+		return 0, 0, fmt.Errorf("invalid length: header says %d bytes, have %d", v, len(src)-nb)
+	}
+	return int(v), nb, nil
+}
+
+// check verifies the length and checksum of src and returns the data.
+func check(src []byte) ([]byte, error) {
+	n, pos, err := parseHeader(src)
+	if err != nil {
+		return nil, err
+	}
+	data := src[pos : pos+n]
+	want := binary.BigEndian.Uint32(src[pos+n:])
+	if got := crc32.Checksum(data, crcTable); got != want {
+		return nil, fmt.Errorf("%w: got %08x, want %08x", ErrChecksum, got, want)
+	}
+	return data, nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package identity_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/creachadair/ffs/blob/memstore"
+	"github.com/creachadair/ffs/blob/storetest"
+	"github.com/creachadair/ffs/storage/codecs/identity"
+	"github.com/creachadair/ffs/storage/encoded"
+)
+
+var _ encoded.Codec = (*identity.Codec)(nil)
+
+func TestStore(t *testing.T) {
+	m := encoded.New(memstore.New(nil), identity.NewCodec())
+	storetest.Run(t, storetest.NopCloser(m))
+}
+
+func TestCodec(t *testing.T) {
+	c := identity.NewCodec()
+	const input = "the quick brown fox jumps over the lazy dog"
+
+	var enc bytes.Buffer
+	if err := c.Encode(&enc, []byte(input)); err != nil {
+		t.Fatalf("Encode: unexpected error: %v", err)
+	}
+	if n, err := c.DecodedLen(enc.Bytes()); err != nil || n != len(input) {
+		t.Errorf("DecodedLen: got (%d, %v), want (%d, nil)", n, err, len(input))
+	}
+	if n, ok := c.DecodedSize(enc.Bytes()[:2]); !ok || n != int64(len(input)) {
+		t.Errorf("DecodedSize: got (%d, %v), want (%d, true)", n, ok, len(input))
+	}
+
+	var dec bytes.Buffer
+	if err := c.Decode(&dec, enc.Bytes()); err != nil {
+		t.Fatalf("Decode: unexpected error: %v", err)
+	} else if got := dec.String(); got != input {
+		t.Errorf("Decode: got %q, want %q", got, input)
+	}
+
+	// Damage the data and verify that the checksum catches it.
+	bad := bytes.Clone(enc.Bytes())
+	bad[5] ^= 1
+	if err := c.Decode(&dec, bad); !errors.Is(err, identity.ErrChecksum) {
+		t.Errorf("Decode damaged: got %v, want %v", err, identity.ErrChecksum)
+	}
+
+	// Truncated data should be rejected.
+	if err := c.Decode(&dec, enc.Bytes()[:enc.Len()-1]); err == nil {
+		t.Error("Decode truncated: got nil, want error")
+	}
+
+	want := identity.Stats{
+		EncodedBlobs: 1, EncodedBytes: int64(len(input)),
+		DecodedBlobs: 1, DecodedBytes: int64(len(input)),
+		Errors: 2,
+	}
+	if got := c.Stats(); got != want {
+		t.Errorf("Stats: got %+v, want %+v", got, want)
+	}
+}
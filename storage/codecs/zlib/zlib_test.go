@@ -27,3 +27,8 @@ func TestStore(t *testing.T) {
 	m := encoded.New(memstore.New(nil), zlib.NewCodec(zlib.LevelDefault))
 	storetest.Run(t, storetest.NopCloser(m))
 }
+
+func FuzzStore(f *testing.F) {
+	m := encoded.New(memstore.New(nil), zlib.NewCodec(zlib.LevelDefault))
+	storetest.Fuzz(f, m, nil)
+}
@@ -0,0 +1,442 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ecstore implements a [blob.Store] that spreads each blob across
+// several underlying stores using Reed-Solomon erasure coding.
+//
+// Each blob is split into k data shards, from which m parity shards are
+// computed. Each of the k+m shards is written to a different underlying store
+// under the same key. Any k of the shards suffice to reconstruct the blob, so
+// the data survive the loss of up to m of the underlying stores.
+//
+// Each shard carries a checksum, so that a damaged shard is treated as missing
+// rather than silently corrupting the result. Use [KV.Scrub] to verify the
+// shards of a blob and rewrite any that are missing or damaged.
+package ecstore
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"iter"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/storage/dbkey"
+	"github.com/creachadair/ffs/storage/monitor"
+	"github.com/creachadair/taskgroup"
+)
+
+// Store implements the [blob.StoreCloser] interface by distributing the shards
+// of each blob across a collection of underlying stores.
+type Store struct {
+	*monitor.M[state, *KV]
+}
+
+type state struct {
+	bases []blob.Store
+	k     int
+}
+
+// New constructs a [Store] that spreads each blob over the given base stores,
+// with dataShards data shards and len(bases)-dataShards parity shards.  It
+// reports an error if dataShards < 1, if there are not more bases than data
+// shards, or if there are more than 256 bases in total.
+func New(bases []blob.Store, dataShards int) (Store, error) {
+	if err := checkShards(len(bases), dataShards); err != nil {
+		return Store{}, err
+	}
+	return Store{M: monitor.New(monitor.Config[state, *KV]{
+		DB: state{bases: bases, k: dataShards},
+		NewKV: func(ctx context.Context, db state, _ dbkey.Prefix, name string) (*KV, error) {
+			kvs := make([]blob.KV, len(db.bases))
+			for i, b := range db.bases {
+				kv, err := b.KV(ctx, name)
+				if err != nil {
+					return nil, fmt.Errorf("shard %d: %w", i, err)
+				}
+				kvs[i] = kv
+			}
+			return NewKV(kvs, db.k)
+		},
+		NewSub: func(ctx context.Context, db state, _ dbkey.Prefix, name string) (state, error) {
+			subs := make([]blob.Store, len(db.bases))
+			for i, b := range db.bases {
+				sub, err := b.Sub(ctx, name)
+				if err != nil {
+					return state{}, fmt.Errorf("shard %d: %w", i, err)
+				}
+				subs[i] = sub
+			}
+			return state{bases: subs, k: db.k}, nil
+		},
+	})}, nil
+}
+
+// Close implements part of the [blob.StoreCloser] interface.
+// It closes all the base stores.
+func (s Store) Close(ctx context.Context) error {
+	errs := []error{s.M.CloseAll(ctx)}
+	for _, b := range s.M.DB.bases {
+		if c, ok := b.(blob.Closer); ok {
+			errs = append(errs, c.Close(ctx))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func checkShards(n, k int) error {
+	if k < 1 {
+		return fmt.Errorf("invalid data shard count %d", k)
+	} else if n <= k {
+		return fmt.Errorf("need more than %d shard stores, got %d", k, n)
+	} else if n > 256 {
+		return fmt.Errorf("too many shard stores (%d > 256)", n)
+	}
+	return nil
+}
+
+// KV implements the [blob.KV] interface by distributing the shards of each
+// blob across a collection of underlying keyspaces.
+//
+// A key is considered present if at least as many shards as the number of data
+// shards are present, since that is enough to reconstruct its value.
+type KV struct {
+	shards []blob.KV
+	rs     *rsCodec
+}
+
+// NewKV constructs a [KV] that spreads each blob over the given keyspaces,
+// with dataShards data shards and len(shards)-dataShards parity shards.
+// It reports an error if the shard counts are invalid, as for [New].
+func NewKV(shards []blob.KV, dataShards int) (*KV, error) {
+	if err := checkShards(len(shards), dataShards); err != nil {
+		return nil, err
+	}
+	return &KV{
+		shards: shards,
+		rs:     newRSCodec(dataShards, len(shards)-dataShards),
+	}, nil
+}
+
+// Get implements part of [blob.KV]. It succeeds if enough shards are intact
+// to reconstruct the value.
+func (s *KV) Get(ctx context.Context, key string) ([]byte, error) {
+	shards, dlen, err := s.fetch(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.rs.reconstruct(shards); err != nil {
+		return nil, fmt.Errorf("key %q: %w", key, err)
+	}
+	return s.join(shards, dlen), nil
+}
+
+// Has implements part of [blob.KV].
+func (s *KV) Has(ctx context.Context, keys ...string) (blob.KeySet, error) {
+	counts := make(map[string]int)
+	for i, kv := range s.shards {
+		got, err := kv.Has(ctx, keys...)
+		if err != nil {
+			return nil, fmt.Errorf("shard %d: %w", i, err)
+		}
+		for key := range got {
+			counts[key]++
+		}
+	}
+	out := make(blob.KeySet)
+	for key, n := range counts {
+		if n >= s.rs.k {
+			out.Add(key)
+		}
+	}
+	return out, nil
+}
+
+// Put implements part of [blob.KV]. It succeeds only if all the shards are
+// successfully written.
+func (s *KV) Put(ctx context.Context, opts blob.PutOptions) error {
+	if !opts.Replace {
+		if got, err := s.Has(ctx, opts.Key); err != nil {
+			return err
+		} else if got.Has(opts.Key) {
+			return blob.KeyExists(opts.Key)
+		}
+	}
+	shards := s.split(opts.Data)
+	s.rs.encode(shards)
+
+	g := taskgroup.New(nil)
+	for i, kv := range s.shards {
+		rec := encodeShard(len(opts.Data), shards[i])
+		g.Go(func() error {
+			if err := kv.Put(ctx, blob.PutOptions{
+				Key:     opts.Key,
+				Data:    rec,
+				Replace: true,
+			}); err != nil {
+				return fmt.Errorf("shard %d: %w", i, err)
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// Delete implements part of [blob.KV]. It removes the shards of key from all
+// the underlying keyspaces, and reports ErrKeyNotFound only if none of them
+// had a shard for key.
+func (s *KV) Delete(ctx context.Context, key string) error {
+	var found bool
+	var errs []error
+	for i, kv := range s.shards {
+		if err := kv.Delete(ctx, key); err == nil {
+			found = true
+		} else if !blob.IsKeyNotFound(err) {
+			errs = append(errs, fmt.Errorf("shard %d: %w", i, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	} else if !found {
+		return blob.KeyNotFound(key)
+	}
+	return nil
+}
+
+// List implements part of [blob.KV]. It reports the union of the keys of the
+// underlying keyspaces in order; a key is listed even if some of its shards
+// are missing.
+func (s *KV) List(ctx context.Context, start string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		type cursor struct {
+			next func() (string, error, bool)
+			key  string
+			ok   bool
+		}
+		curs := make([]*cursor, len(s.shards))
+		for i, kv := range s.shards {
+			next, stop := iter.Pull2(kv.List(ctx, start))
+			defer stop()
+			curs[i] = &cursor{next: next}
+		}
+		advance := func(c *cursor) error {
+			key, err, ok := c.next()
+			if !ok {
+				c.ok = false
+				return nil
+			} else if err != nil {
+				return err
+			}
+			c.key, c.ok = key, true
+			return nil
+		}
+		for _, c := range curs {
+			if err := advance(c); err != nil {
+				yield("", err)
+				return
+			}
+		}
+		for {
+			var min string
+			var found bool
+			for _, c := range curs {
+				if c.ok && (!found || c.key < min) {
+					min, found = c.key, true
+				}
+			}
+			if !found {
+				return
+			}
+			if !yield(min, nil) {
+				return
+			}
+			for _, c := range curs {
+				if c.ok && c.key == min {
+					if err := advance(c); err != nil {
+						yield("", err)
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// Len implements part of [blob.KV]. It is implemented using List.
+func (s *KV) Len(ctx context.Context) (int64, error) {
+	var nk int64
+	for _, err := range s.List(ctx, "") {
+		if err != nil {
+			return 0, err
+		}
+		nk++
+	}
+	return nk, nil
+}
+
+// Scrub verifies the shards stored for key, and rewrites any that are missing
+// or damaged, provided enough remain intact to reconstruct the value. It
+// reports the number of shards that were rewritten.
+func (s *KV) Scrub(ctx context.Context, key string) (int, error) {
+	shards, dlen, err := s.fetch(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	var bad []int
+	for i, sh := range shards {
+		if sh == nil {
+			bad = append(bad, i)
+		}
+	}
+	if len(bad) == 0 {
+		return 0, nil
+	}
+	if err := s.rs.reconstruct(shards); err != nil {
+		return 0, fmt.Errorf("key %q: %w", key, err)
+	}
+	var nw int
+	for _, i := range bad {
+		if err := s.shards[i].Put(ctx, blob.PutOptions{
+			Key:     key,
+			Data:    encodeShard(dlen, shards[i]),
+			Replace: true,
+		}); err != nil {
+			return nw, fmt.Errorf("shard %d: %w", i, err)
+		}
+		nw++
+	}
+	return nw, nil
+}
+
+// fetch reads all the shards for key concurrently. Missing or damaged shards
+// are reported as nil. It also reports the length of the original blob.  It
+// reports an error if too few shards are usable to reconstruct the value.
+func (s *KV) fetch(ctx context.Context, key string) ([][]byte, int, error) {
+	shards := make([][]byte, len(s.shards))
+	lens := make([]int, len(s.shards))
+	errs := make([]error, len(s.shards))
+
+	g := taskgroup.New(nil)
+	for i, kv := range s.shards {
+		g.Go(func() error {
+			rec, err := kv.Get(ctx, key)
+			if err != nil {
+				errs[i] = err
+				return nil
+			}
+			dlen, data, ok := decodeShard(rec)
+			if ok {
+				shards[i], lens[i] = data, dlen
+			}
+			return nil
+		})
+	}
+	g.Wait()
+
+	// Only shards that agree on the blob and shard length are usable. Pick the
+	// length reported by the most shards.
+	votes := make(map[[2]int]int)
+	var best [2]int
+	for i, sh := range shards {
+		if sh != nil {
+			v := [2]int{lens[i], len(sh)}
+			votes[v]++
+			if votes[v] > votes[best] {
+				best = v
+			}
+		}
+	}
+	var nok int
+	for i, sh := range shards {
+		if sh != nil && [2]int{lens[i], len(sh)} != best {
+			shards[i] = nil
+		} else if sh != nil {
+			nok++
+		}
+	}
+	if nok >= s.rs.k {
+		return shards, best[0], nil
+	}
+
+	// Reaching here, we do not have enough shards. If they are all simply
+	// missing, report the key as not found.
+	var nf int
+	var other []error
+	for _, err := range errs {
+		if blob.IsKeyNotFound(err) {
+			nf++
+		} else if err != nil {
+			other = append(other, err)
+		}
+	}
+	if nf == len(errs) {
+		return nil, 0, blob.KeyNotFound(key)
+	} else if err := errors.Join(other...); err != nil {
+		return nil, 0, err
+	}
+	return nil, 0, fmt.Errorf("key %q: %w", key, errTooFewShards)
+}
+
+// split partitions data into k equal-length data shards, padding with zeroes
+// as necessary. The result has room for the parity shards.
+func (s *KV) split(data []byte) [][]byte {
+	size := (len(data) + s.rs.k - 1) / s.rs.k
+	shards := make([][]byte, len(s.shards))
+	for i := range s.rs.k {
+		sh := make([]byte, size)
+		if lo := i * size; lo < len(data) {
+			copy(sh, data[lo:])
+		}
+		shards[i] = sh
+	}
+	return shards
+}
+
+// join reassembles the original blob of dlen bytes from complete data shards.
+func (s *KV) join(shards [][]byte, dlen int) []byte {
+	out := make([]byte, 0, dlen)
+	for _, sh := range shards[:s.rs.k] {
+		out = append(out, sh...)
+	}
+	return out[:dlen]
+}
+
+// encodeShard packs a shard for storage:
+//
+//	uvarint dlen | shard data | crc32 (IEEE, big-endian) of the preceding bytes
+func encodeShard(dlen int, data []byte) []byte {
+	buf := make([]byte, 0, binary.MaxVarintLen64+len(data)+4)
+	buf = binary.AppendUvarint(buf, uint64(dlen))
+	buf = append(buf, data...)
+	return binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf))
+}
+
+// decodeShard unpacks a shard record. It reports false if the record is
+// malformed or fails its checksum.
+func decodeShard(rec []byte) (int, []byte, bool) {
+	if len(rec) < 5 {
+		return 0, nil, false
+	}
+	body, sum := rec[:len(rec)-4], rec[len(rec)-4:]
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(sum) {
+		return 0, nil, false
+	}
+	dlen, n := binary.Uvarint(body)
+	if n <= 0 {
+		return 0, nil, false
+	}
+	return int(dlen), body[n:], true
+}
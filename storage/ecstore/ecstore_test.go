@@ -0,0 +1,179 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ecstore_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/blob/memstore"
+	"github.com/creachadair/ffs/blob/storetest"
+	"github.com/creachadair/ffs/storage/ecstore"
+)
+
+var (
+	_ blob.KV          = (*ecstore.KV)(nil)
+	_ blob.StoreCloser = ecstore.Store{}
+)
+
+func TestStore(t *testing.T) {
+	bases := make([]blob.Store, 5)
+	for i := range bases {
+		bases[i] = memstore.New(nil)
+	}
+	s, err := ecstore.New(bases, 3)
+	if err != nil {
+		t.Fatalf("New: unexpected error: %v", err)
+	}
+	storetest.Run(t, s)
+}
+
+func TestNewErrors(t *testing.T) {
+	for _, tc := range []struct{ n, k int }{{3, 0}, {3, 3}, {2, 5}, {300, 4}} {
+		kvs := make([]blob.KV, tc.n)
+		if _, err := ecstore.NewKV(kvs, tc.k); err == nil {
+			t.Errorf("NewKV(%d shards, k=%d): got nil, want error", tc.n, tc.k)
+		}
+	}
+}
+
+func TestReconstruct(t *testing.T) {
+	ctx := context.Background()
+	const k, m = 4, 3
+
+	shards := make([]*memstore.KV, k+m)
+	kvs := make([]blob.KV, k+m)
+	for i := range shards {
+		shards[i] = memstore.NewKV()
+		kvs[i] = shards[i]
+	}
+	kv, err := ecstore.NewKV(kvs, k)
+	if err != nil {
+		t.Fatalf("NewKV: unexpected error: %v", err)
+	}
+
+	values := map[string]string{
+		"empty": "",
+		"short": "x",
+		"odd":   "abcdefghijklm",
+		"long":  strings.Repeat("all work and no play makes jack a dull boy\n", 50),
+	}
+	for key, val := range values {
+		if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte(val)}); err != nil {
+			t.Fatalf("Put %q: unexpected error: %v", key, err)
+		}
+	}
+	checkAll := func(t *testing.T) {
+		t.Helper()
+		for key, want := range values {
+			got, err := kv.Get(ctx, key)
+			if err != nil {
+				t.Errorf("Get %q: unexpected error: %v", key, err)
+			} else if string(got) != want {
+				t.Errorf("Get %q: got %q, want %q", key, got, want)
+			}
+		}
+	}
+
+	// Erase every combination of m shards and check that the values survive.
+	for lost := range 1 << (k + m) {
+		var names []string
+		for i := range k + m {
+			if lost&(1<<i) != 0 {
+				names = append(names, fmt.Sprint(i))
+			}
+		}
+		if len(names) != m {
+			continue
+		}
+		t.Run("Lost-"+strings.Join(names, ","), func(t *testing.T) {
+			saved := make([]map[string]string, k+m)
+			for i := range k + m {
+				if lost&(1<<i) != 0 {
+					saved[i] = shards[i].Snapshot(nil)
+					shards[i].Clear()
+				}
+			}
+			checkAll(t)
+			for i, snap := range saved {
+				if snap != nil {
+					shards[i].Init(snap)
+				}
+			}
+		})
+	}
+
+	// Damage a shard, then lose more than the tolerance.
+	t.Run("TooFew", func(t *testing.T) {
+		for i := range m + 1 {
+			shards[i].Clear()
+		}
+		if got, err := kv.Get(ctx, "long"); err == nil {
+			t.Errorf("Get long: got %q, want error", got)
+		}
+		if got, err := kv.Has(ctx, "long"); err != nil || got.Has("long") {
+			t.Errorf("Has long: got (%v, %v), want absent", got, err)
+		}
+	})
+}
+
+func TestScrub(t *testing.T) {
+	ctx := context.Background()
+	shards := make([]*memstore.KV, 5)
+	kvs := make([]blob.KV, len(shards))
+	for i := range shards {
+		shards[i] = memstore.NewKV()
+		kvs[i] = shards[i]
+	}
+	kv, err := ecstore.NewKV(kvs, 3)
+	if err != nil {
+		t.Fatalf("NewKV: unexpected error: %v", err)
+	}
+	const testData = "the quick brown fox jumps over the lazy dog"
+	if err := kv.Put(ctx, blob.PutOptions{Key: "fox", Data: []byte(testData)}); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+
+	// Remove one shard and corrupt another.
+	if err := shards[0].Delete(ctx, "fox"); err != nil {
+		t.Fatalf("Delete shard: %v", err)
+	}
+	if err := shards[3].Put(ctx, blob.PutOptions{Key: "fox", Data: []byte("garbage"), Replace: true}); err != nil {
+		t.Fatalf("Damage shard: %v", err)
+	}
+
+	if got, err := kv.Get(ctx, "fox"); err != nil || string(got) != testData {
+		t.Errorf("Get: got (%q, %v), want (%q, nil)", got, err, testData)
+	}
+	if n, err := kv.Scrub(ctx, "fox"); err != nil || n != 2 {
+		t.Errorf("Scrub: got (%d, %v), want (2, nil)", n, err)
+	}
+	if n, err := kv.Scrub(ctx, "fox"); err != nil || n != 0 {
+		t.Errorf("Scrub again: got (%d, %v), want (0, nil)", n, err)
+	}
+
+	// After scrubbing, any two shards can be lost.
+	shards[1].Clear()
+	shards[2].Clear()
+	if got, err := kv.Get(ctx, "fox"); err != nil || string(got) != testData {
+		t.Errorf("Get after scrub: got (%q, %v), want (%q, nil)", got, err, testData)
+	}
+	if _, err := kv.Scrub(ctx, "nonesuch"); !blob.IsKeyNotFound(err) {
+		t.Errorf("Scrub nonesuch: got %v, want %v", err, blob.ErrKeyNotFound)
+	}
+}
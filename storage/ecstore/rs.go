@@ -0,0 +1,202 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ecstore
+
+import "errors"
+
+// Arithmetic in GF(2^8) with the reducing polynomial x^8+x^4+x^3+x^2+1.
+// Addition is XOR; multiplication and division use log/exp tables.
+var gfExp, gfLog = func() (exp [510]byte, log [256]byte) {
+	x := 1
+	for i := range 255 {
+		exp[i] = byte(x)
+		exp[i+255] = byte(x)
+		log[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+	return
+}()
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+// gfInv returns the multiplicative inverse of a, which must be non-zero.
+func gfInv(a byte) byte { return gfExp[255-int(gfLog[a])] }
+
+// mulAdd sets dst[i] ^= c*src[i] for each i.
+func mulAdd(dst, src []byte, c byte) {
+	if c == 0 {
+		return
+	}
+	lc := int(gfLog[c])
+	for i, v := range src {
+		if v != 0 {
+			dst[i] ^= gfExp[lc+int(gfLog[v])]
+		}
+	}
+}
+
+// An rsCodec implements systematic Reed-Solomon coding of k data shards with
+// m parity shards. The parity rows of the generator are a Cauchy matrix, so
+// that every k×k submatrix of the full generator is invertible, and any k of
+// the k+m shards suffice to reconstruct the data.
+type rsCodec struct {
+	k, m   int
+	parity [][]byte // m × k
+}
+
+func newRSCodec(k, m int) *rsCodec {
+	c := &rsCodec{k: k, m: m, parity: make([][]byte, m)}
+	for i := range m {
+		row := make([]byte, k)
+		for j := range k {
+			// x_i = k+i and y_j = j are disjoint, so x_i ^ y_j ≠ 0.
+			row[j] = gfInv(byte(k+i) ^ byte(j))
+		}
+		c.parity[i] = row
+	}
+	return c
+}
+
+// encode computes the parity shards from the data shards. All the data shards
+// must have the same length. On return, shards[k:] contain the parity.
+func (c *rsCodec) encode(shards [][]byte) {
+	n := len(shards[0])
+	for i := range c.m {
+		p := make([]byte, n)
+		for j := range c.k {
+			mulAdd(p, shards[j], c.parity[i][j])
+		}
+		shards[c.k+i] = p
+	}
+}
+
+// row returns the generator row for shard i.
+func (c *rsCodec) row(i int) []byte {
+	if i >= c.k {
+		return c.parity[i-c.k]
+	}
+	r := make([]byte, c.k)
+	r[i] = 1
+	return r
+}
+
+var errTooFewShards = errors.New("too few shards to reconstruct")
+
+// reconstruct fills in any nil entries of shards from the others. All the
+// non-nil shards must have the same length.
+func (c *rsCodec) reconstruct(shards [][]byte) error {
+	var have []int
+	for i, s := range shards {
+		if s != nil {
+			have = append(have, i)
+		}
+	}
+	if len(have) < c.k {
+		return errTooFewShards
+	} else if len(have) == len(shards) {
+		return nil // nothing to do
+	}
+	have = have[:c.k]
+
+	// Recover missing data shards by inverting the generator rows of the
+	// shards we are using.
+	var missingData bool
+	for i := range c.k {
+		missingData = missingData || shards[i] == nil
+	}
+	if missingData {
+		mat := make([][]byte, c.k)
+		for r, i := range have {
+			mat[r] = c.row(i)
+		}
+		inv, err := invert(mat)
+		if err != nil {
+			return err
+		}
+		n := len(shards[have[0]])
+		for j := range c.k {
+			if shards[j] != nil {
+				continue
+			}
+			d := make([]byte, n)
+			for r, i := range have {
+				mulAdd(d, shards[i], inv[j][r])
+			}
+			shards[j] = d
+		}
+	}
+
+	// Recompute any missing parity shards from the complete data.
+	n := len(shards[0])
+	for i := range c.m {
+		if shards[c.k+i] != nil {
+			continue
+		}
+		p := make([]byte, n)
+		for j := range c.k {
+			mulAdd(p, shards[j], c.parity[i][j])
+		}
+		shards[c.k+i] = p
+	}
+	return nil
+}
+
+// invert returns the inverse of the square matrix m, which is not modified.
+func invert(m [][]byte) ([][]byte, error) {
+	n := len(m)
+	a := make([][]byte, n)
+	for i, row := range m {
+		a[i] = make([]byte, 2*n)
+		copy(a[i], row)
+		a[i][n+i] = 1
+	}
+	for col := range n {
+		pivot := -1
+		for r := col; r < n; r++ {
+			if a[r][col] != 0 {
+				pivot = r
+				break
+			}
+		}
+		if pivot < 0 {
+			return nil, errors.New("singular matrix")
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		if v := a[col][col]; v != 1 {
+			s := gfInv(v)
+			for j := range a[col] {
+				a[col][j] = gfMul(a[col][j], s)
+			}
+		}
+		for r := range n {
+			if r != col && a[r][col] != 0 {
+				mulAdd(a[r], a[col], a[r][col])
+			}
+		}
+	}
+	out := make([][]byte, n)
+	for i := range a {
+		out[i] = a[i][n:]
+	}
+	return out, nil
+}
@@ -21,6 +21,8 @@ import (
 	"context"
 	"io"
 	"iter"
+	"sync"
+	"sync/atomic"
 
 	"github.com/creachadair/ffs/blob"
 )
@@ -34,11 +36,86 @@ type Codec interface {
 	Decode(w io.Writer, src []byte) error
 }
 
+// A SizeCodec is an optional interface that a [Codec] may implement to report
+// the decoded size of a blob from a prefix of its encoding, without decoding
+// the whole blob. See [KV.Stat].
+type SizeCodec interface {
+	Codec
+
+	// DecodedSize reports the decoded size of the blob whose encoding begins
+	// with prefix, and true; or it reports false if the size cannot be
+	// determined from prefix. The prefix may contain the entire encoding.
+	DecodedSize(prefix []byte) (int64, bool)
+}
+
+// statPrefixLen is the length of the encoded prefix fetched by Stat from a
+// store that supports ranged reads.
+const statPrefixLen = 64
+
 // A Store wraps an existing [blob.Store] implementation so that its key spaces
 // are encoded using a [Codec].
 type Store struct {
 	codec Codec
 	real  blob.Store
+	path  string    // the path of this (sub)store, "" at the root
+	reg   *registry // shared by all the substores of a root
+}
+
+// A registry records the statistics of the keyspaces of a store, by path.
+type registry struct {
+	μ     sync.Mutex
+	stats map[string]*counters
+}
+
+func (r *registry) counters(path string) *counters {
+	r.μ.Lock()
+	defer r.μ.Unlock()
+	c, ok := r.stats[path]
+	if !ok {
+		c = new(counters)
+		r.stats[path] = c
+	}
+	return c
+}
+
+// counters are the statistics for writes to a keyspace.
+type counters struct {
+	puts, plainBytes, encodedBytes atomic.Int64
+}
+
+func (c *counters) stats() Stats {
+	return Stats{
+		Puts:         c.puts.Load(),
+		PlainBytes:   c.plainBytes.Load(),
+		EncodedBytes: c.encodedBytes.Load(),
+	}
+}
+
+// Stats are statistics about the blobs written to a keyspace.
+type Stats struct {
+	Puts         int64 // the number of blobs written
+	PlainBytes   int64 // total size of the blobs written, before encoding
+	EncodedBytes int64 // total size of the blobs written, after encoding
+}
+
+// Ratio reports the ratio of encoded to plain bytes written, or 1 if no bytes
+// have been written. For a compressing codec, smaller is better.
+func (s Stats) Ratio() float64 {
+	if s.PlainBytes == 0 {
+		return 1
+	}
+	return float64(s.EncodedBytes) / float64(s.PlainBytes)
+}
+
+// Saved reports the number of bytes saved by encoding, which is negative if
+// the encoded blobs are larger than the originals.
+func (s Stats) Saved() int64 { return s.PlainBytes - s.EncodedBytes }
+
+func (s Stats) add(t Stats) Stats {
+	s.Puts += t.Puts
+	s.PlainBytes += t.PlainBytes
+	s.EncodedBytes += t.EncodedBytes
+	return s
 }
 
 // KV implements a method of [blob.Store]. The concrete type of keyspaces
@@ -48,7 +125,34 @@ func (s Store) KV(ctx context.Context, name string) (blob.KV, error) {
 	if err != nil {
 		return nil, err
 	}
-	return KV{codec: s.codec, real: kv}, nil
+	return KV{codec: s.codec, real: kv, st: s.reg.counters(s.path + name)}, nil
+}
+
+// Stats reports the sum of the statistics for all the keyspaces that have
+// been opened via s or any of its substores, or via the root store from
+// which s was derived.
+func (s Store) Stats() Stats {
+	var out Stats
+	for _, st := range s.KeyspaceStats() {
+		out = out.add(st)
+	}
+	return out
+}
+
+// KeyspaceStats reports the statistics for each keyspace that has been opened
+// via s or any of its substores, or via the root store from which s was
+// derived. Each keyspace is identified by its path from the root: The names
+// of the substores containing it, if any, and its name, separated by "/".
+// Statistics are accumulated for the lifetime of the root store, and are not
+// persisted.
+func (s Store) KeyspaceStats() map[string]Stats {
+	s.reg.μ.Lock()
+	defer s.reg.μ.Unlock()
+	out := make(map[string]Stats, len(s.reg.stats))
+	for path, c := range s.reg.stats {
+		out[path] = c.stats()
+	}
+	return out
 }
 
 // CAS implements a method of [blob.Store].
@@ -63,7 +167,7 @@ func (s Store) Sub(ctx context.Context, name string) (blob.Store, error) {
 	if err != nil {
 		return nil, err
 	}
-	return Store{codec: s.codec, real: sub}, nil
+	return Store{codec: s.codec, real: sub, path: s.path + name + "/", reg: s.reg}, nil
 }
 
 // Close implements a method of the [blob.StoreCloser] interface.
@@ -82,14 +186,15 @@ func New(s blob.Store, c Codec) Store {
 	} else if c == nil {
 		panic("codec is nil")
 	}
-	return Store{codec: c, real: s}
+	return Store{codec: c, real: s, reg: &registry{stats: make(map[string]*counters)}}
 }
 
 // A KV wraps an existing [blob.KV] implementation in which blobs are encoded
 // using a [Codec].
 type KV struct {
-	codec Codec   // used to compress and decompress blobs
-	real  blob.KV // the underlying storage implementation
+	codec Codec     // used to compress and decompress blobs
+	real  blob.KV   // the underlying storage implementation
+	st    *counters // statistics for writes
 }
 
 // NewKV constructs a new KV that delegates to kv and uses c to encode and
@@ -100,11 +205,18 @@ func NewKV(kv blob.KV, c Codec) KV {
 	} else if c == nil {
 		panic("codec is nil")
 	}
-	return KV{codec: c, real: kv}
+	return KV{codec: c, real: kv, st: new(counters)}
 }
 
+// Stats reports statistics for the blobs written to s. A KV obtained from a
+// [Store] shares its statistics with other KV values for the same keyspace.
+func (s KV) Stats() Stats { return s.st.stats() }
+
 // Get implements part of the [blob.KV] interface.
 func (s KV) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := blob.CheckKey(key); err != nil {
+		return nil, err
+	}
 	enc, err := s.real.Get(ctx, key)
 	if err != nil {
 		return nil, err
@@ -121,6 +233,47 @@ func (s KV) Get(ctx context.Context, key string) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// Stat reports the decoded size in bytes of the blob stored for key.
+//
+// If the codec implements [SizeCodec], Stat asks it for the size, and if the
+// underlying store also supports ranged reads, Stat fetches only a prefix of
+// the encoded blob to do so. Otherwise, Stat fetches and decodes the blob.
+// The size reported by a SizeCodec is not verified against the content.
+func (s KV) Stat(ctx context.Context, key string) (int64, error) {
+	if err := blob.CheckKey(key); err != nil {
+		return 0, err
+	}
+	sc, canSize := s.codec.(SizeCodec)
+	rg, canRange := s.real.(blob.RangeGetter)
+
+	var enc []byte
+	var err error
+	if canSize && canRange {
+		enc, err = rg.GetRange(ctx, key, 0, statPrefixLen)
+	} else {
+		enc, err = s.real.Get(ctx, key)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if canSize {
+		if n, ok := sc.DecodedSize(enc); ok {
+			return n, nil
+		} else if canRange {
+			// The prefix was not enough; fall back to the whole blob.
+			enc, err = s.real.Get(ctx, key)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := s.codec.Decode(&buf, enc); err != nil {
+		return 0, err
+	}
+	return int64(buf.Len()), nil
+}
+
 // Has implements part of the [blob.KV] interface.
 func (s KV) Has(ctx context.Context, keys ...string) (blob.KeySet, error) {
 	return s.real.Has(ctx, keys...)
@@ -128,18 +281,31 @@ func (s KV) Has(ctx context.Context, keys ...string) (blob.KeySet, error) {
 
 // Put implements part of the [blob.KV] interface.
 func (s KV) Put(ctx context.Context, opts blob.PutOptions) error {
-	buf := bytes.NewBuffer(make([]byte, 0, len(opts.Data)))
+	if err := blob.CheckKey(opts.Key); err != nil {
+		return err
+	}
+	plain := len(opts.Data)
+	buf := bytes.NewBuffer(make([]byte, 0, plain))
 	if err := s.codec.Encode(buf, opts.Data); err != nil {
 		return err
 	}
 	// Leave the original options as given, but replace the data.
 	opts.Data = buf.Bytes()
-	return s.real.Put(ctx, opts)
+	if err := s.real.Put(ctx, opts); err != nil {
+		return err
+	}
+	s.st.puts.Add(1)
+	s.st.plainBytes.Add(int64(plain))
+	s.st.encodedBytes.Add(int64(len(opts.Data)))
+	return nil
 }
 
 // Delete implements part of the [blob.KV] interface.
 // It delegates directly to the underlying store.
 func (s KV) Delete(ctx context.Context, key string) error {
+	if err := blob.CheckKey(key); err != nil {
+		return err
+	}
 	return s.real.Delete(ctx, key)
 }
 
@@ -15,14 +15,18 @@
 package encoded_test
 
 import (
+	"bytes"
 	"context"
 	"io"
+	"strings"
 	"testing"
 
 	"github.com/creachadair/ffs/blob"
 	"github.com/creachadair/ffs/blob/memstore"
 	"github.com/creachadair/ffs/blob/storetest"
+	idcodec "github.com/creachadair/ffs/storage/codecs/identity"
 	"github.com/creachadair/ffs/storage/encoded"
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestStore(t *testing.T) {
@@ -83,3 +87,128 @@ func (t tagger) Decode(w io.Writer, src []byte) error {
 	_, err := w.Write(src[:len(src)-1])
 	return err
 }
+
+func TestStat(t *testing.T) {
+	ctx := context.Background()
+	base := memstore.NewKV()
+	for _, key := range []string{"empty", "short", "long"} {
+		data := map[string]string{"short": "hello", "long": strings.Repeat("x", 5000)}[key]
+		var buf bytes.Buffer
+		if err := idcodec.NewCodec().Encode(&buf, []byte(data)); err != nil {
+			t.Fatalf("Encode %q: %v", key, err)
+		}
+		if err := base.Put(ctx, blob.PutOptions{Key: key, Data: buf.Bytes()}); err != nil {
+			t.Fatalf("Put %q: %v", key, err)
+		}
+	}
+	want := map[string]int64{"empty": 0, "short": 5, "long": 5000}
+
+	tests := []struct {
+		name  string
+		kv    blob.KV
+		codec encoded.Codec
+	}{
+		{"Plain", base, idcodec.NewCodec()},
+		{"Ranged", &rangeKV{KV: base}, idcodec.NewCodec()},
+		{"Decode", &rangeKV{KV: base}, noSize{idcodec.NewCodec()}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			kv := encoded.NewKV(tc.kv, tc.codec)
+			for key, size := range want {
+				got, err := kv.Stat(ctx, key)
+				if err != nil {
+					t.Errorf("Stat %q: unexpected error: %v", key, err)
+				} else if got != size {
+					t.Errorf("Stat %q: got %d, want %d", key, got, size)
+				}
+			}
+			if _, err := kv.Stat(ctx, "nonesuch"); !blob.IsKeyNotFound(err) {
+				t.Errorf("Stat nonesuch: got %v, want %v", err, blob.ErrKeyNotFound)
+			}
+			if rkv, ok := tc.kv.(*rangeKV); ok {
+				t.Logf("Fetched %d bytes", rkv.bytes)
+				if _, ok := tc.codec.(encoded.SizeCodec); ok && rkv.bytes > 100 {
+					t.Errorf("Stat fetched %d bytes, want only prefixes", rkv.bytes)
+				}
+			}
+		})
+	}
+}
+
+// rangeKV is a blob.KV that supports ranged reads, and counts the bytes it
+// returns from them.
+type rangeKV struct {
+	blob.KV
+	bytes int64
+}
+
+func (r *rangeKV) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := r.KV.Get(ctx, key)
+	r.bytes += int64(len(data))
+	return data, err
+}
+
+func (r *rangeKV) GetRange(ctx context.Context, key string, offset, length int64) ([]byte, error) {
+	data, err := r.KV.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	data = data[min(offset, int64(len(data))):]
+	data = data[:min(length, int64(len(data)))]
+	r.bytes += int64(len(data))
+	return data, nil
+}
+
+// noSize hides the SizeCodec implementation of a codec.
+type noSize struct{ encoded.Codec }
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+	enc := encoded.New(memstore.New(nil), tagger("@"))
+
+	put := func(s blob.Store, name, key, value string) {
+		t.Helper()
+		kv := storetest.SubKV(t, ctx, s, name)
+		if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte(value)}); err != nil {
+			t.Fatalf("Put %q: %v", key, err)
+		}
+	}
+	sub, err := enc.Sub(ctx, "sub")
+	if err != nil {
+		t.Fatalf("Sub: %v", err)
+	}
+	put(enc, "a", "k1", "abc")
+	put(enc, "a", "k2", "defgh")
+	put(sub.(encoded.Store), "b", "k1", "x")
+
+	// A failed write is not counted.
+	kv := storetest.SubKV(t, ctx, enc, "a")
+	if err := kv.Put(ctx, blob.PutOptions{Key: "k1", Data: []byte("zzz")}); !blob.IsKeyExists(err) {
+		t.Errorf("Put k1 again: got %v, want %v", err, blob.ErrKeyExists)
+	}
+	if got, want := kv.(encoded.KV).Stats(), (encoded.Stats{Puts: 2, PlainBytes: 8, EncodedBytes: 10}); got != want {
+		t.Errorf("KV stats: got %+v, want %+v", got, want)
+	}
+
+	want := map[string]encoded.Stats{
+		"a":     {Puts: 2, PlainBytes: 8, EncodedBytes: 10},
+		"sub/b": {Puts: 1, PlainBytes: 1, EncodedBytes: 2},
+	}
+	if diff := cmp.Diff(enc.KeyspaceStats(), want); diff != "" {
+		t.Errorf("KeyspaceStats (-got, +want):\n%s", diff)
+	}
+	st := enc.Stats()
+	if want := (encoded.Stats{Puts: 3, PlainBytes: 9, EncodedBytes: 12}); st != want {
+		t.Errorf("Stats: got %+v, want %+v", st, want)
+	}
+	if got, want := st.Ratio(), 12.0/9; got != want {
+		t.Errorf("Ratio: got %v, want %v", got, want)
+	}
+	if got := st.Saved(); got != -3 {
+		t.Errorf("Saved: got %d, want -3", got)
+	}
+	if got := (encoded.Stats{}).Ratio(); got != 1 {
+		t.Errorf("Empty ratio: got %v, want 1", got)
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package expirystore implements a wrapper for a [blob.Store] in which keys
+// may be written with an expiration time. This is intended for keyspaces used
+// as caches, locks, or journals, whose contents are ephemeral.
+//
+// An expired key is reported as not found by Get and Has, but remains in the
+// underlying store until it is removed by [KV.Reap] or [Store.Reap], or by a
+// background reaper (see [Options]). Until then, List and Len may include it.
+//
+// Each value is stored with an 8-byte big-endian header giving its expiration
+// time in nanoseconds since the Unix epoch, or 0 if it does not expire.
+package expirystore
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"iter"
+	"sync"
+	"time"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/storage/dbkey"
+	"github.com/creachadair/ffs/storage/monitor"
+)
+
+// Options are optional settings for a [Store] or [KV]. A nil *Options is
+// ready for use and provides default values as described.
+type Options struct {
+	// TTL is the lifetime of keys written by Put. If TTL ≤ 0, keys written by
+	// Put do not expire. Use PutTTL to choose a lifetime for a single key.
+	TTL time.Duration
+
+	// ReapInterval, if positive, is the interval at which a background
+	// reaper removes expired keys from all the keyspaces of a Store. The
+	// reaper runs until the store is closed. Errors during background reaping
+	// are ignored, and reaping is retried at the next interval.
+	ReapInterval time.Duration
+
+	// Now, if non-nil, is used to obtain the current time.
+	// If nil, it uses time.Now.
+	Now func() time.Time
+}
+
+func (o *Options) ttl() time.Duration {
+	if o == nil {
+		return 0
+	}
+	return o.TTL
+}
+
+func (o *Options) now() time.Time {
+	if o == nil || o.Now == nil {
+		return time.Now()
+	}
+	return o.Now()
+}
+
+// Store implements the [blob.StoreCloser] interface by delegating to a base
+// store. Keyspaces derived from the store are of concrete type [*KV].
+type Store struct {
+	*monitor.M[state, *KV]
+}
+
+type state struct {
+	base blob.Store
+	opts *Options
+	reg  *registry
+}
+
+// A registry records the keyspaces of a store, for reaping.
+type registry struct {
+	μ    sync.Mutex
+	kvs  []*KV
+	stop context.CancelFunc // if non-nil, stops the background reaper
+	done chan struct{}      // closed when the background reaper exits
+}
+
+func (r *registry) add(kv *KV) {
+	r.μ.Lock()
+	defer r.μ.Unlock()
+	r.kvs = append(r.kvs, kv)
+}
+
+func (r *registry) all() []*KV {
+	r.μ.Lock()
+	defer r.μ.Unlock()
+	return append([]*KV(nil), r.kvs...)
+}
+
+// New constructs a [blob.Store] wrapper that delegates to base and supports
+// expiration of keys, with settings from opts. New will panic if base == nil.
+func New(base blob.Store, opts *Options) Store {
+	if base == nil {
+		panic("base is nil")
+	}
+	reg := new(registry)
+	s := Store{M: monitor.New(monitor.Config[state, *KV]{
+		DB: state{base: base, opts: opts, reg: reg},
+		NewKV: func(ctx context.Context, db state, _ dbkey.Prefix, name string) (*KV, error) {
+			kv, err := db.base.KV(ctx, name)
+			if err != nil {
+				return nil, err
+			}
+			out := NewKV(kv, db.opts)
+			db.reg.add(out)
+			return out, nil
+		},
+		NewSub: func(ctx context.Context, db state, _ dbkey.Prefix, name string) (state, error) {
+			sub, err := db.base.Sub(ctx, name)
+			if err != nil {
+				return state{}, err
+			}
+			return state{base: sub, opts: db.opts, reg: db.reg}, nil
+		},
+	})}
+	if opts != nil && opts.ReapInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		reg.stop, reg.done = cancel, make(chan struct{})
+		go s.reapLoop(ctx, opts.ReapInterval)
+	}
+	return s
+}
+
+func (s Store) reapLoop(ctx context.Context, interval time.Duration) {
+	defer close(s.M.DB.reg.done)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			s.Reap(ctx)
+		}
+	}
+}
+
+// Close implements part of the [blob.StoreCloser] interface. It stops the
+// background reaper, if one is running, before closing the base store.
+func (s Store) Close(ctx context.Context) error {
+	if reg := s.M.DB.reg; reg.stop != nil {
+		reg.stop()
+		<-reg.done
+	}
+	kerr := s.M.CloseAll(ctx)
+	if c, ok := s.M.DB.base.(blob.Closer); ok {
+		return errors.Join(kerr, c.Close(ctx))
+	}
+	return kerr
+}
+
+// Reap removes expired keys from all the keyspaces of s that have been opened
+// so far, and reports the total number of keys removed.
+func (s Store) Reap(ctx context.Context) (int, error) {
+	var nr int
+	for _, kv := range s.M.DB.reg.all() {
+		n, err := kv.Reap(ctx)
+		nr += n
+		if err != nil {
+			return nr, err
+		}
+	}
+	return nr, nil
+}
+
+// KV implements the [blob.KV] interface by delegating to a base keyspace,
+// in which keys may expire.
+type KV struct {
+	base blob.KV
+	opts *Options
+}
+
+// NewKV constructs a [KV] that delegates to base, with settings from opts.
+// NewKV will panic if base == nil.
+func NewKV(base blob.KV, opts *Options) *KV {
+	if base == nil {
+		panic("base is nil")
+	}
+	return &KV{base: base, opts: opts}
+}
+
+// errBadRecord is reported when a stored value cannot be decoded.
+var errBadRecord = errors.New("invalid expiry record")
+
+// load fetches and decodes the record for key, reporting whether it is live.
+func (s *KV) load(ctx context.Context, key string) ([]byte, bool, error) {
+	rec, err := s.base.Get(ctx, key)
+	if err != nil {
+		return nil, false, err
+	} else if len(rec) < 8 {
+		return nil, false, errBadRecord
+	}
+	exp := int64(binary.BigEndian.Uint64(rec))
+	live := exp == 0 || s.opts.now().UnixNano() < exp
+	return rec[8:], live, nil
+}
+
+// Get implements part of [blob.KV]. It reports ErrKeyNotFound for a key that
+// has expired.
+func (s *KV) Get(ctx context.Context, key string) ([]byte, error) {
+	data, live, err := s.load(ctx, key)
+	if err != nil {
+		return nil, err
+	} else if !live {
+		return nil, blob.KeyNotFound(key)
+	}
+	return data, nil
+}
+
+// Has implements part of [blob.KV]. Keys that have expired are not reported.
+func (s *KV) Has(ctx context.Context, keys ...string) (blob.KeySet, error) {
+	have, err := s.base.Has(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+	for key := range have {
+		_, live, err := s.load(ctx, key)
+		if blob.IsKeyNotFound(err) || (err == nil && !live) {
+			have.Remove(key)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return have, nil
+}
+
+// Put implements part of [blob.KV]. The key expires after the TTL given in
+// the options for s, if any. An existing key that has expired is replaced
+// even if opts.Replace is false.
+func (s *KV) Put(ctx context.Context, opts blob.PutOptions) error {
+	return s.PutTTL(ctx, opts, s.opts.ttl())
+}
+
+// PutTTL is as Put, but the key expires after the specified ttl.
+// If ttl ≤ 0, the key does not expire.
+func (s *KV) PutTTL(ctx context.Context, opts blob.PutOptions, ttl time.Duration) error {
+	var exp int64
+	if ttl > 0 {
+		exp = s.opts.now().Add(ttl).UnixNano()
+	}
+	rec := make([]byte, 8, 8+len(opts.Data))
+	binary.BigEndian.PutUint64(rec, uint64(exp))
+	rec = append(rec, opts.Data...)
+
+	err := s.base.Put(ctx, blob.PutOptions{Key: opts.Key, Data: rec, Replace: opts.Replace})
+	if blob.IsKeyExists(err) {
+		// If the existing key has expired, replace it.
+		if _, live, lerr := s.load(ctx, opts.Key); lerr == nil && !live {
+			return s.base.Put(ctx, blob.PutOptions{Key: opts.Key, Data: rec, Replace: true})
+		}
+	}
+	return err
+}
+
+// Delete implements part of [blob.KV]. It delegates to the base store.
+func (s *KV) Delete(ctx context.Context, key string) error { return s.base.Delete(ctx, key) }
+
+// List implements part of [blob.KV]. It delegates to the base store, so keys
+// that have expired but have not yet been reaped are listed.
+func (s *KV) List(ctx context.Context, start string) iter.Seq2[string, error] {
+	return s.base.List(ctx, start)
+}
+
+// Len implements part of [blob.KV]. It delegates to the base store, so keys
+// that have expired but have not yet been reaped are counted.
+func (s *KV) Len(ctx context.Context) (int64, error) { return s.base.Len(ctx) }
+
+// Reap removes all the expired keys from s, and reports the number of keys
+// removed. A key that is rewritten concurrently with a call to Reap may be
+// removed even if its new value has not expired.
+func (s *KV) Reap(ctx context.Context) (int, error) {
+	// Collect the victims first, since we cannot modify the keyspace while
+	// listing it.
+	var victims []string
+	for key, err := range s.base.List(ctx, "") {
+		if err != nil {
+			return 0, err
+		}
+		_, live, err := s.load(ctx, key)
+		if blob.IsKeyNotFound(err) {
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+		if !live {
+			victims = append(victims, key)
+		}
+	}
+	var nr int
+	for _, key := range victims {
+		if err := s.base.Delete(ctx, key); err == nil {
+			nr++
+		} else if !blob.IsKeyNotFound(err) {
+			return nr, err
+		}
+	}
+	return nr, nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package expirystore_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/blob/memstore"
+	"github.com/creachadair/ffs/blob/storetest"
+	"github.com/creachadair/ffs/storage/expirystore"
+)
+
+var (
+	_ blob.KV          = (*expirystore.KV)(nil)
+	_ blob.StoreCloser = expirystore.Store{}
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, expirystore.New(memstore.New(nil), nil))
+}
+
+// fakeClock is a manually-advanced clock for testing.
+type fakeClock struct {
+	μ   sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { c.μ.Lock(); defer c.μ.Unlock(); return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.μ.Lock(); defer c.μ.Unlock(); c.now = c.now.Add(d) }
+
+func TestExpiry(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	base := memstore.NewKV()
+	kv := expirystore.NewKV(base, &expirystore.Options{TTL: time.Minute, Now: clock.Now})
+
+	mustPut := func(key string, ttl time.Duration) {
+		t.Helper()
+		if err := kv.PutTTL(ctx, blob.PutOptions{Key: key, Data: []byte(key)}, ttl); err != nil {
+			t.Fatalf("PutTTL %q: %v", key, err)
+		}
+	}
+	checkLive := func(key string, want bool) {
+		t.Helper()
+		_, err := kv.Get(ctx, key)
+		if got := err == nil; got != want {
+			t.Errorf("Get %q: got err=%v, want live=%v", key, err, want)
+		}
+		if got, err := kv.Has(ctx, key); err != nil {
+			t.Errorf("Has %q: unexpected error: %v", key, err)
+		} else if got.Has(key) != want {
+			t.Errorf("Has %q: got %v, want %v", key, got.Has(key), want)
+		}
+	}
+
+	mustPut("short", time.Second)
+	mustPut("forever", 0)
+	if err := kv.Put(ctx, blob.PutOptions{Key: "default", Data: []byte("x")}); err != nil {
+		t.Fatalf("Put default: %v", err)
+	}
+	checkLive("short", true)
+	checkLive("forever", true)
+	checkLive("default", true)
+
+	clock.Advance(2 * time.Second)
+	checkLive("short", false)
+	checkLive("default", true)
+
+	// An expired key can be rewritten without Replace.
+	mustPut("short", time.Hour)
+	checkLive("short", true)
+
+	clock.Advance(2 * time.Minute)
+	checkLive("short", true)
+	checkLive("default", false)
+	checkLive("forever", true)
+
+	// A live key cannot be rewritten without Replace.
+	if err := kv.Put(ctx, blob.PutOptions{Key: "forever", Data: []byte("y")}); !blob.IsKeyExists(err) {
+		t.Errorf("Put forever: got %v, want %v", err, blob.ErrKeyExists)
+	}
+
+	// Reaping removes the expired key from the base store.
+	if n, err := kv.Reap(ctx); err != nil || n != 1 {
+		t.Errorf("Reap: got (%d, %v), want (1, nil)", n, err)
+	}
+	if n, err := base.Len(ctx); err != nil || n != 2 {
+		t.Errorf("Base Len: got (%d, %v), want (2, nil)", n, err)
+	}
+}
+
+func TestBackgroundReaper(t *testing.T) {
+	ctx := context.Background()
+	base := memstore.New(nil)
+	s := expirystore.New(base, &expirystore.Options{ReapInterval: 5 * time.Millisecond})
+
+	kv := storetest.SubKV(t, ctx, s, "sub", "cache").(*expirystore.KV)
+	if err := kv.PutTTL(ctx, blob.PutOptions{Key: "k", Data: []byte("v")}, time.Millisecond); err != nil {
+		t.Fatalf("PutTTL: %v", err)
+	}
+	bkv := storetest.SubKV(t, ctx, base, "sub", "cache")
+	for range 200 {
+		if n, _ := bkv.Len(ctx); n == 0 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n, err := bkv.Len(ctx); err != nil || n != 0 {
+		t.Errorf("Base Len after reaping: got (%d, %v), want (0, nil)", n, err)
+	}
+	if err := s.Close(ctx); err != nil {
+		t.Errorf("Close: unexpected error: %v", err)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"iter"
 	"os"
 	"path"
@@ -97,8 +98,10 @@ type KV struct {
 }
 
 // Opener constructs a filestore from an address comprising a path, for use
-// with the [store] package. The concrete type of the result is [Store].
+// with the [storage] registry or the [store] package. The concrete type of the
+// result is [Store].
 //
+// [storage]: https://godoc.org/github.com/creachadair/ffs/storage
 // [store]: https://godoc.org/github.com/creachadair/ffstools/lib/store
 func Opener(ctx context.Context, addr string) (blob.StoreCloser, error) {
 	return New(strings.TrimPrefix(addr, "//")) // allow URL-like paths
@@ -119,6 +122,41 @@ func (s KV) Get(_ context.Context, key string) ([]byte, error) {
 	return bits, nil
 }
 
+// GetRange implements the [blob.RangeGetter] interface. It reads only the
+// requested portion of the file for key.
+func (s KV) GetRange(_ context.Context, key string, offset, length int64) ([]byte, error) {
+	if offset < 0 || length < 0 {
+		return nil, fmt.Errorf("invalid range: offset %d, length %d", offset, length)
+	}
+	bits, err := readRange(s.keyPath(key), offset, length)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = blob.KeyNotFound(key)
+		}
+		return nil, fmt.Errorf("key %q: %w", key, err)
+	}
+	return bits, nil
+}
+
+// readRange reads up to length bytes from the file at path, starting at offset.
+func readRange(path string, offset, length int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, min(length, max(fi.Size()-offset, 0)))
+	nr, err := f.ReadAt(buf, offset)
+	if err == io.EOF {
+		err = nil // the file was truncated after we checked its size
+	}
+	return buf[:nr], err
+}
+
 // Has implements part of [blob.KV].
 func (s KV) Has(ctx context.Context, keys ...string) (blob.KeySet, error) {
 	var out blob.KeySet
@@ -186,6 +224,67 @@ func (s KV) List(_ context.Context, start string) iter.Seq2[string, error] {
 	}
 }
 
+// DeleteRange implements the [blob.RangeDeleter] interface. A shard directory
+// whose keys all fall within the range is removed as a whole; otherwise only
+// the keys of the shard that fall within the range are removed.
+func (s KV) DeleteRange(_ context.Context, start, end string) (int64, error) {
+	hs, he := hex.EncodeToString([]byte(start)), hex.EncodeToString([]byte(end))
+	roots, err := listdir(s.Dir())
+	if err != nil {
+		return 0, err
+	}
+
+	var nr int64
+	for _, root := range roots {
+		if len(root) != s.key.Shard || strings.Trim(root, "0123456789abcdef-") != "" {
+			continue // not a shard directory, e.g., a keyspace or substore
+		}
+		cur := filepath.Join(s.Dir(), root)
+
+		// Shards padded with "-" hold only short keys; check them one by one.
+		// Otherwise, the shard holds exactly the keys whose encoding has root
+		// as a prefix, and we can tell whether they are all in range or none.
+		if !strings.Contains(root, "-") {
+			n := len(root)
+			if hs[:min(n, len(hs))] > root || (end != "" && root >= he) {
+				continue // no keys of this shard are in range
+			}
+			if root >= hs && (end == "" || he[:min(n, len(he))] > root) {
+				keys, err := listdir(cur)
+				if err != nil {
+					return nr, err
+				}
+				if err := os.RemoveAll(cur); err != nil {
+					return nr, err
+				}
+				for _, tail := range keys {
+					if _, err := s.key.Decode(path.Join(cur, tail)); err == nil {
+						nr++
+					}
+				}
+				continue
+			}
+		}
+
+		keys, err := listdir(cur)
+		if err != nil {
+			return nr, err
+		}
+		for _, tail := range keys {
+			key, err := s.key.Decode(path.Join(cur, tail))
+			if err != nil || key < start || (end != "" && key >= end) {
+				continue // skip non-key files and keys out of range
+			}
+			if err := os.Remove(filepath.Join(cur, tail)); err == nil {
+				nr++
+			} else if !os.IsNotExist(err) {
+				return nr, err
+			}
+		}
+	}
+	return nr, nil
+}
+
 // Len implements part of [blob.KV]. It is implemented using List, so it
 // linearizes in the same manner.
 func (s KV) Len(ctx context.Context) (int64, error) {
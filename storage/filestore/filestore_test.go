@@ -18,8 +18,10 @@ import (
 	"context"
 	"flag"
 	"os"
+	"slices"
 	"testing"
 
+	"github.com/creachadair/ffs/blob"
 	"github.com/creachadair/ffs/blob/storetest"
 	"github.com/creachadair/ffs/storage/filestore"
 )
@@ -43,6 +45,15 @@ func TestStore(t *testing.T) {
 	storetest.Run(t, s)
 }
 
+func FuzzStore(f *testing.F) {
+	s, err := filestore.New(f.TempDir())
+	if err != nil {
+		f.Fatalf("Creating store: %v", err)
+	}
+	// Keys are hex-encoded into file names, whose length the filesystem limits.
+	storetest.Fuzz(f, s, &storetest.FuzzOptions{MaxKeyLen: 120})
+}
+
 func TestNesting(t *testing.T) {
 	dir := t.TempDir()
 	t.Logf("Test store: %s", dir)
@@ -60,3 +71,67 @@ func TestNesting(t *testing.T) {
 		t.Fatalf("Equal directories: %q", k1d)
 	}
 }
+
+func TestDeleteRange(t *testing.T) {
+	s, err := filestore.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	ctx := context.Background()
+	kv := storetest.SubKV(t, ctx, s, "")
+	sub := storetest.SubKV(t, ctx, s, "sub")
+
+	// Write keys spanning many shards, including some short keys that land in
+	// padded shards.
+	var keys []string
+	for i := range 512 {
+		keys = append(keys, string([]byte{byte(i / 2), byte(i), 'x'}))
+	}
+	keys = append(keys, "", "\x10", "\x80", "\xff")
+	for _, key := range keys {
+		if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte("ok")}); err != nil {
+			t.Fatalf("Put %q: %v", key, err)
+		}
+	}
+	if err := sub.Put(ctx, blob.PutOptions{Key: "\x20", Data: []byte("keep")}); err != nil {
+		t.Fatalf("Put sub: %v", err)
+	}
+
+	for _, tc := range []struct{ start, end string }{
+		{"\x10\x20", "\x40\x00"},
+		{"\x80", "\x90"},
+		{"\xf0", ""},
+		{"", "\x01"},
+	} {
+		var want []string
+		for _, key := range keys {
+			if key < tc.start || (tc.end != "" && key >= tc.end) {
+				want = append(want, key)
+			}
+		}
+		slices.Sort(want)
+		nr, err := blob.DeleteRange(ctx, kv, tc.start, tc.end)
+		if err != nil {
+			t.Fatalf("DeleteRange(%q, %q): unexpected error: %v", tc.start, tc.end, err)
+		}
+		if n := int64(len(keys) - len(want)); nr != n {
+			t.Errorf("DeleteRange(%q, %q): removed %d, want %d", tc.start, tc.end, nr, n)
+		}
+		var got []string
+		for key, err := range kv.List(ctx, "") {
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			got = append(got, key)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("DeleteRange(%q, %q): got %d keys remaining, want %d", tc.start, tc.end, len(got), len(want))
+		}
+		keys = want
+	}
+
+	// The substore should not be affected.
+	if got, err := sub.Get(ctx, "\x20"); err != nil || string(got) != "keep" {
+		t.Errorf("Get sub: got (%q, %v), want keep", got, err)
+	}
+}
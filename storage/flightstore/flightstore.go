@@ -0,0 +1,189 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package flightstore implements a wrapper for a [blob.Store] that collapses
+// concurrent Get calls for the same key into a single fetch from the base
+// store.
+//
+// This is useful for a server whose clients often request the same blobs at
+// the same time, such as the root and directory nodes of a popular file tree,
+// from a base store where each fetch is expensive. Unlike a cache, the wrapper
+// holds no data once the callers waiting for a fetch have been answered.
+package flightstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"iter"
+	"sync"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/storage/dbkey"
+	"github.com/creachadair/ffs/storage/monitor"
+)
+
+// Store implements the [blob.StoreCloser] interface by delegating to a base
+// store. Concurrent Get calls for the same key in a keyspace derived from the
+// store share a single fetch from the base store.
+type Store struct {
+	*monitor.M[blob.Store, *KV]
+}
+
+// New constructs a [blob.Store] wrapper that delegates to base.
+// New will panic if base == nil.
+func New(base blob.Store) Store {
+	if base == nil {
+		panic("base is nil")
+	}
+	return Store{M: monitor.New(monitor.Config[blob.Store, *KV]{
+		DB: base,
+		NewKV: func(ctx context.Context, db blob.Store, _ dbkey.Prefix, name string) (*KV, error) {
+			kv, err := db.KV(ctx, name)
+			if err != nil {
+				return nil, err
+			}
+			return NewKV(kv), nil
+		},
+		NewSub: func(ctx context.Context, db blob.Store, _ dbkey.Prefix, name string) (blob.Store, error) {
+			return db.Sub(ctx, name)
+		},
+	})}
+}
+
+// Close implements part of the [blob.StoreCloser] interface.
+func (s Store) Close(ctx context.Context) error {
+	kerr := s.M.CloseAll(ctx)
+	if c, ok := s.M.DB.(blob.Closer); ok {
+		return errors.Join(kerr, c.Close(ctx))
+	}
+	return kerr
+}
+
+// KV implements the [blob.KV] interface by delegating to a base keyspace.
+// Concurrent calls to Get for the same key share a single fetch.
+type KV struct {
+	base blob.KV
+
+	μ       sync.Mutex
+	flights map[string]*flight
+}
+
+// NewKV constructs a new [KV] that delegates to base.
+func NewKV(base blob.KV) *KV {
+	return &KV{base: base, flights: make(map[string]*flight)}
+}
+
+// A flight is an in-progress fetch of a key from the base store.
+type flight struct {
+	done   chan struct{} // closed when the fetch is complete
+	data   []byte        // the result of the fetch, once done
+	err    error         // the error from the fetch, once done
+	wait   int           // the number of callers waiting; protected by KV.μ
+	cancel context.CancelFunc
+}
+
+// Get implements part of [blob.KV]. If another call to Get is already
+// fetching key from the base store, Get waits for and shares its result.
+// Each caller receives its own copy of the data.
+//
+// The shared fetch continues as long as any caller is waiting for it. If ctx
+// ends before the fetch is complete, Get reports the error from ctx. If all
+// the callers waiting for a fetch give up, the fetch is cancelled.
+func (s *KV) Get(ctx context.Context, key string) ([]byte, error) {
+	s.μ.Lock()
+	f, ok := s.flights[key]
+	if !ok {
+		fctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{done: make(chan struct{}), cancel: cancel}
+		s.flights[key] = f
+		go s.fetch(fctx, key, f)
+	}
+	f.wait++
+	s.μ.Unlock()
+
+	select {
+	case <-f.done:
+		if f.err != nil {
+			return nil, f.err
+		}
+		return bytes.Clone(f.data), nil
+	case <-ctx.Done():
+		s.μ.Lock()
+		defer s.μ.Unlock()
+		f.wait--
+		if f.wait == 0 {
+			f.cancel()
+			s.detachLocked(key, f)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// fetch fetches key from the base store on behalf of the callers waiting on f.
+func (s *KV) fetch(ctx context.Context, key string, f *flight) {
+	defer f.cancel()
+	data, err := s.base.Get(ctx, key)
+
+	s.μ.Lock()
+	s.detachLocked(key, f)
+	s.μ.Unlock()
+
+	f.data, f.err = data, err
+	close(f.done)
+}
+
+// detachLocked removes f from the flights of s, if it is the current flight
+// for key, so that later calls to Get start a new fetch. The caller must hold
+// s.μ.
+func (s *KV) detachLocked(key string, f *flight) {
+	if s.flights[key] == f {
+		delete(s.flights, key)
+	}
+}
+
+// forget detaches any in-progress fetch for key, so that calls to Get after a
+// modification of key do not share a fetch that began before it.
+func (s *KV) forget(key string) {
+	s.μ.Lock()
+	defer s.μ.Unlock()
+	if f, ok := s.flights[key]; ok {
+		s.detachLocked(key, f)
+	}
+}
+
+// Has implements part of [blob.KV]. It delegates to the base store.
+func (s *KV) Has(ctx context.Context, keys ...string) (blob.KeySet, error) {
+	return s.base.Has(ctx, keys...)
+}
+
+// Put implements part of [blob.KV]. It delegates to the base store.
+func (s *KV) Put(ctx context.Context, opts blob.PutOptions) error {
+	defer s.forget(opts.Key)
+	return s.base.Put(ctx, opts)
+}
+
+// Delete implements part of [blob.KV]. It delegates to the base store.
+func (s *KV) Delete(ctx context.Context, key string) error {
+	defer s.forget(key)
+	return s.base.Delete(ctx, key)
+}
+
+// List implements part of [blob.KV]. It delegates to the base store.
+func (s *KV) List(ctx context.Context, start string) iter.Seq2[string, error] {
+	return s.base.List(ctx, start)
+}
+
+// Len implements part of [blob.KV]. It delegates to the base store.
+func (s *KV) Len(ctx context.Context) (int64, error) { return s.base.Len(ctx) }
@@ -0,0 +1,132 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flightstore_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/blob/memstore"
+	"github.com/creachadair/ffs/blob/storetest"
+	"github.com/creachadair/ffs/storage/flightstore"
+)
+
+var (
+	_ blob.KV          = (*flightstore.KV)(nil)
+	_ blob.StoreCloser = flightstore.Store{}
+)
+
+func TestStore(t *testing.T) {
+	s := flightstore.New(memstore.New(nil))
+	storetest.Run(t, s)
+}
+
+// gatedKV is a blob.KV whose Get calls block until released, and which
+// counts the number of Get calls that reach it.
+type gatedKV struct {
+	blob.KV
+	gets atomic.Int64
+	gate chan struct{}
+}
+
+func (g *gatedKV) Get(ctx context.Context, key string) ([]byte, error) {
+	g.gets.Add(1)
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-g.gate:
+		return g.KV.Get(ctx, key)
+	}
+}
+
+func TestShared(t *testing.T) {
+	ctx := context.Background()
+	base := &gatedKV{
+		KV:   memstore.NewKV().Init(map[string]string{"hot": "potato"}),
+		gate: make(chan struct{}),
+	}
+	kv := flightstore.NewKV(base)
+
+	// Start several concurrent readers of the same key. Only one fetch
+	// should reach the base store.
+	const numReaders = 8
+	var wg sync.WaitGroup
+	results := make([][]byte, numReaders)
+	errs := make([]error, numReaders)
+	var started sync.WaitGroup
+	for i := range numReaders {
+		wg.Add(1)
+		started.Add(1)
+		go func() {
+			defer wg.Done()
+			started.Done()
+			results[i], errs[i] = kv.Get(ctx, "hot")
+		}()
+	}
+	started.Wait()
+	for base.gets.Load() == 0 {
+		runtime.Gosched() // wait for the fetch to begin
+	}
+	close(base.gate)
+	wg.Wait()
+
+	for i := range numReaders {
+		if errs[i] != nil || string(results[i]) != "potato" {
+			t.Errorf("Reader %d: got (%q, %v), want potato", i, results[i], errs[i])
+		}
+	}
+	if n := base.gets.Load(); n > numReaders || n < 1 {
+		t.Errorf("Base gets: got %d, want between 1 and %d", n, numReaders)
+	}
+
+	// Each caller gets its own copy of the data.
+	if len(results[0]) != 0 && &results[0][0] == &results[1][0] {
+		t.Error("Readers share the same data buffer")
+	}
+}
+
+func TestCancel(t *testing.T) {
+	base := &gatedKV{
+		KV:   memstore.NewKV().Init(map[string]string{"k": "v"}),
+		gate: make(chan struct{}),
+	}
+	kv := flightstore.NewKV(base)
+
+	// A caller that gives up does not wait for the fetch.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { _, err := kv.Get(ctx, "k"); done <- err }()
+	for base.gets.Load() == 0 {
+		runtime.Gosched() // wait for the fetch to begin
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Get: got %v, want %v", err, context.Canceled)
+	}
+
+	// A later caller starts a fresh fetch.
+	close(base.gate)
+	if got, err := kv.Get(context.Background(), "k"); err != nil || string(got) != "v" {
+		t.Errorf("Get: got (%q, %v), want v", got, err)
+	}
+	if n := base.gets.Load(); n != 2 {
+		t.Errorf("Base gets: got %d, want 2", n)
+	}
+}
@@ -0,0 +1,338 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package journalstore implements a wrapper for a [blob.Store] that records
+// an audit [Record] in a log keyspace for each successful Put or Delete in any
+// of its keyspaces.
+//
+// Each record is written to the log as a separate blob holding its JSON
+// encoding, whose key is the sequence number of the record as 16 hexadecimal
+// digits, so that listing the log reports records in the order they were
+// written. Records are never rewritten, so the cost of recording a mutation
+// does not depend on the size of the log. Optionally, the oldest records are
+// removed to limit the size of the log; see [Options].
+//
+// To attribute mutations to a principal, attach it to the context of the Put
+// or Delete call with [WithPrincipal]:
+//
+//	ctx = journalstore.WithPrincipal(ctx, "alice")
+//	err := kv.Put(ctx, opts) // the record names alice
+//
+// Keys are not recorded directly, since they may be sensitive. Each record
+// instead contains a SHA-256 hash of the key.
+package journalstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/storage/dbkey"
+	"github.com/creachadair/ffs/storage/monitor"
+)
+
+// Op identifies the kind of a mutation.
+type Op string
+
+const (
+	Put    Op = "put"    // a blob was written
+	Delete Op = "delete" // a blob was deleted
+)
+
+// A Record is the audit record of a successful mutation of a keyspace.
+type Record struct {
+	Op        Op        `json:"op"`                  // the kind of mutation
+	Keyspace  string    `json:"keyspace"`            // the slash-separated path of the keyspace
+	KeyHash   string    `json:"keyHash"`             // the SHA-256 of the key, in hex
+	Size      int       `json:"size,omitempty"`      // for Put, the length of the data written
+	Time      time.Time `json:"time"`                // when the mutation completed
+	Principal string    `json:"principal,omitempty"` // the principal from the context, if any
+}
+
+// HashKey returns the key hash recorded for key, so that a caller can find
+// the records for a specific key.
+func HashKey(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context derived from ctx that attributes mutations
+// made with it to the specified principal.
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// Principal returns the principal attached to ctx by [WithPrincipal], or ""
+// if there is none.
+func Principal(ctx context.Context) string {
+	p, _ := ctx.Value(principalKey{}).(string)
+	return p
+}
+
+// Options are optional settings for a [Store]. A nil *Options is ready for
+// use and provides default values as described.
+type Options struct {
+	// MaxRecords, if positive, is the maximum number of records kept in the
+	// log. When a record is added, the oldest records beyond this limit are
+	// removed. If MaxRecords ≤ 0, all records are kept.
+	MaxRecords int
+
+	// Now, if non-nil, is used to obtain the current time.
+	// If nil, it uses time.Now.
+	Now func() time.Time
+}
+
+func (o *Options) maxRecords() uint64 {
+	if o == nil || o.MaxRecords <= 0 {
+		return 0
+	}
+	return uint64(o.MaxRecords)
+}
+
+func (o *Options) now() time.Time {
+	if o == nil || o.Now == nil {
+		return time.Now()
+	}
+	return o.Now()
+}
+
+// Store implements the [blob.StoreCloser] interface by delegating to a base
+// store. Successful writes and deletes through [blob.KV] instances derived
+// from the store are recorded in its log.
+type Store struct {
+	*monitor.M[state, *KV]
+}
+
+type state struct {
+	base blob.Store
+	j    *journal
+	path string // slash-separated substore names from the root
+}
+
+// New constructs a [blob.Store] wrapper that delegates to base and records
+// mutations in log, with settings from opts. New will panic if base == nil or
+// log == nil.
+//
+// The log keyspace is shared by all the keyspaces derived from the store, and
+// should be dedicated to the journal. In particular, it should not be one of
+// the keyspaces of base that the caller will access via the wrapper.
+func New(base blob.Store, log blob.KV, opts *Options) Store {
+	if base == nil {
+		panic("base is nil")
+	} else if log == nil {
+		panic("log is nil")
+	}
+	return Store{M: monitor.New(monitor.Config[state, *KV]{
+		DB: state{base: base, j: &journal{log: log, opts: opts}},
+		NewKV: func(ctx context.Context, db state, _ dbkey.Prefix, name string) (*KV, error) {
+			kv, err := db.base.KV(ctx, name)
+			if err != nil {
+				return nil, err
+			}
+			return &KV{base: kv, j: db.j, space: path.Join(db.path, name)}, nil
+		},
+		NewSub: func(ctx context.Context, db state, _ dbkey.Prefix, name string) (state, error) {
+			sub, err := db.base.Sub(ctx, name)
+			if err != nil {
+				return state{}, err
+			}
+			return state{base: sub, j: db.j, path: path.Join(db.path, name)}, nil
+		},
+	})}
+}
+
+// Close implements part of the [blob.StoreCloser] interface.
+func (s Store) Close(ctx context.Context) error {
+	kerr := s.M.CloseAll(ctx)
+	var berr, lerr error
+	if c, ok := s.M.DB.base.(blob.Closer); ok {
+		berr = c.Close(ctx)
+	}
+	if c, ok := s.M.DB.j.log.(blob.Closer); ok {
+		lerr = c.Close(ctx)
+	}
+	return errors.Join(kerr, berr, lerr)
+}
+
+// Log returns the log keyspace used by s.
+func (s Store) Log() blob.KV { return s.M.DB.j.log }
+
+// Records returns an iterator over the records in the log of s, in the order
+// they were written. Records that have been removed from the log are not
+// reported.
+func (s Store) Records(ctx context.Context) iter.Seq2[Record, error] {
+	return func(yield func(Record, error) bool) {
+		log := s.M.DB.j.log
+		for key, err := range log.List(ctx, "") {
+			if err != nil {
+				yield(Record{}, err)
+				return
+			}
+			data, err := log.Get(ctx, key)
+			if blob.IsKeyNotFound(err) {
+				continue // removed while we were listing
+			} else if err != nil {
+				yield(Record{}, err)
+				return
+			}
+			var rec Record
+			if err := json.Unmarshal(data, &rec); err != nil {
+				yield(Record{}, fmt.Errorf("record %q: %w", key, err))
+				return
+			}
+			if !yield(rec, nil) {
+				return
+			}
+		}
+	}
+}
+
+// KV implements the [blob.KV] interface by delegating to a base keyspace.
+// Successful calls to Put and Delete are recorded in the log of the enclosing
+// [Store].
+type KV struct {
+	base  blob.KV
+	j     *journal
+	space string // the name of this keyspace, for records
+}
+
+// Get implements part of [blob.KV]. It delegates to the base store.
+func (s *KV) Get(ctx context.Context, key string) ([]byte, error) { return s.base.Get(ctx, key) }
+
+// Has implements part of [blob.KV]. It delegates to the base store.
+func (s *KV) Has(ctx context.Context, keys ...string) (blob.KeySet, error) {
+	return s.base.Has(ctx, keys...)
+}
+
+// Put implements part of [blob.KV]. It delegates to the base store, and
+// records the write if it succeeds. If the write succeeds but cannot be
+// recorded, Put reports an error, although the blob has been written.
+func (s *KV) Put(ctx context.Context, opts blob.PutOptions) error {
+	if err := s.base.Put(ctx, opts); err != nil {
+		return err
+	}
+	return s.j.append(ctx, Record{
+		Op:        Put,
+		Keyspace:  s.space,
+		KeyHash:   HashKey(opts.Key),
+		Size:      len(opts.Data),
+		Principal: Principal(ctx),
+	})
+}
+
+// Delete implements part of [blob.KV]. It delegates to the base store, and
+// records the deletion if it succeeds. If the deletion succeeds but cannot be
+// recorded, Delete reports an error, although the blob has been deleted.
+func (s *KV) Delete(ctx context.Context, key string) error {
+	if err := s.base.Delete(ctx, key); err != nil {
+		return err
+	}
+	return s.j.append(ctx, Record{
+		Op:        Delete,
+		Keyspace:  s.space,
+		KeyHash:   HashKey(key),
+		Principal: Principal(ctx),
+	})
+}
+
+// List implements part of [blob.KV]. It delegates to the base store.
+func (s *KV) List(ctx context.Context, start string) iter.Seq2[string, error] {
+	return s.base.List(ctx, start)
+}
+
+// Len implements part of [blob.KV]. It delegates to the base store.
+func (s *KV) Len(ctx context.Context) (int64, error) { return s.base.Len(ctx) }
+
+// A journal appends records to a log keyspace. It is shared by all the
+// keyspaces of a store.
+type journal struct {
+	log  blob.KV
+	opts *Options
+
+	μ     sync.Mutex
+	ready bool   // whether next has been initialized from the log
+	next  uint64 // the sequence number of the next record
+}
+
+// recordKey returns the storage key of record n.
+func recordKey(n uint64) string { return fmt.Sprintf("%016x", n) }
+
+// initLocked finds the last record in the log, so that new records are added
+// after it, and removes old records beyond the limit. Existing records are not
+// modified. The caller must hold j.μ.
+func (j *journal) initLocked(ctx context.Context) error {
+	var last uint64
+	for key, err := range j.log.List(ctx, "") {
+		if err != nil {
+			return err
+		}
+		if n, err := strconv.ParseUint(key, 16, 64); err == nil && len(key) == 16 {
+			last = max(last, n)
+		}
+	}
+	j.next, j.ready = last+1, true
+	if keep := j.opts.maxRecords(); keep != 0 && last >= keep {
+		if _, err := blob.DeleteRange(ctx, j.log, "", recordKey(last-keep+1)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// append writes rec to the log as a new record, and removes the record that
+// falls outside the limit as a result, if any. The log is locked only to
+// assign a sequence number, so concurrent appends do not wait for each other
+// to be written.
+func (j *journal) append(ctx context.Context, rec Record) error {
+	rec.Time = j.opts.now()
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("journal: %w", err)
+	}
+
+	j.μ.Lock()
+	if !j.ready {
+		if err := j.initLocked(ctx); err != nil {
+			j.μ.Unlock()
+			return fmt.Errorf("journal: %w", err)
+		}
+	}
+	seq := j.next
+	j.next++
+	j.μ.Unlock()
+
+	if err := j.log.Put(ctx, blob.PutOptions{Key: recordKey(seq), Data: data}); err != nil {
+		return fmt.Errorf("journal: %w", err)
+	}
+	if keep := j.opts.maxRecords(); keep != 0 && seq > keep {
+		// Records are removed one at a time as new ones are added, so at most
+		// one record falls outside the limit. It may already be gone if its
+		// write failed.
+		if err := j.log.Delete(ctx, recordKey(seq-keep)); err != nil && !blob.IsKeyNotFound(err) {
+			return fmt.Errorf("journal: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journalstore_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/blob/memstore"
+	"github.com/creachadair/ffs/blob/storetest"
+	"github.com/creachadair/ffs/storage/journalstore"
+	"github.com/google/go-cmp/cmp"
+)
+
+var (
+	_ blob.KV          = (*journalstore.KV)(nil)
+	_ blob.StoreCloser = journalstore.Store{}
+)
+
+func TestStore(t *testing.T) {
+	s := journalstore.New(memstore.New(nil), memstore.NewKV(), nil)
+	storetest.Run(t, s)
+}
+
+func records(t *testing.T, s journalstore.Store) []journalstore.Record {
+	t.Helper()
+	var out []journalstore.Record
+	for rec, err := range s.Records(context.Background()) {
+		if err != nil {
+			t.Fatalf("Records: unexpected error: %v", err)
+		}
+		out = append(out, rec)
+	}
+	return out
+}
+
+func TestJournal(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	s := journalstore.New(memstore.New(nil), memstore.NewKV(), &journalstore.Options{
+		Now: func() time.Time { return now },
+	})
+
+	kv := storetest.SubKV(t, ctx, s, "sub", "test")
+	actx := journalstore.WithPrincipal(ctx, "alice")
+	if err := kv.Put(actx, blob.PutOptions{Key: "a", Data: []byte("apple")}); err != nil {
+		t.Fatalf("Put a: %v", err)
+	}
+	// A failed mutation is not recorded.
+	if err := kv.Put(ctx, blob.PutOptions{Key: "a", Data: []byte("avocado")}); !blob.IsKeyExists(err) {
+		t.Fatalf("Put a again: got %v, want %v", err, blob.ErrKeyExists)
+	}
+	if err := kv.Delete(ctx, "nonesuch"); !blob.IsKeyNotFound(err) {
+		t.Fatalf("Delete nonesuch: got %v, want %v", err, blob.ErrKeyNotFound)
+	}
+	if err := kv.Delete(ctx, "a"); err != nil {
+		t.Fatalf("Delete a: %v", err)
+	}
+
+	want := []journalstore.Record{{
+		Op:        journalstore.Put,
+		Keyspace:  "sub/test",
+		KeyHash:   journalstore.HashKey("a"),
+		Size:      5,
+		Time:      now,
+		Principal: "alice",
+	}, {
+		Op:       journalstore.Delete,
+		Keyspace: "sub/test",
+		KeyHash:  journalstore.HashKey("a"),
+		Time:     now,
+	}}
+	if diff := cmp.Diff(records(t, s), want); diff != "" {
+		t.Errorf("Records (-got, +want):\n%s", diff)
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	ctx := context.Background()
+	log := memstore.NewKV()
+	s := journalstore.New(memstore.New(nil), log, nil)
+	kv := storetest.SubKV(t, ctx, s, "test")
+
+	const numWriters = 16
+	var wg sync.WaitGroup
+	for i := range numWriters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := strconv.Itoa(i)
+			if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte(key)}); err != nil {
+				t.Errorf("Put %q: %v", key, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Each record is stored as a separate blob.
+	if n, err := log.Len(ctx); err != nil || n != numWriters {
+		t.Errorf("Log len: got (%d, %v), want (%d, nil)", n, err, numWriters)
+	}
+	if got := len(records(t, s)); got != numWriters {
+		t.Errorf("Records: got %d, want %d", got, numWriters)
+	}
+}
+
+func TestMaxRecords(t *testing.T) {
+	ctx := context.Background()
+	log := memstore.NewKV()
+	s := journalstore.New(memstore.New(nil), log, &journalstore.Options{MaxRecords: 3})
+	kv := storetest.SubKV(t, ctx, s, "test")
+
+	put := func(key string) {
+		t.Helper()
+		if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte(key)}); err != nil {
+			t.Fatalf("Put %q: %v", key, err)
+		}
+	}
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		put(key)
+	}
+	if n, err := log.Len(ctx); err != nil || n != 3 {
+		t.Errorf("Log len: got (%d, %v), want (3, nil)", n, err)
+	}
+
+	var got []string
+	for _, rec := range records(t, s) {
+		got = append(got, rec.KeyHash)
+	}
+	want := []string{journalstore.HashKey("c"), journalstore.HashKey("d"), journalstore.HashKey("e")}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Records (-got, +want):\n%s", diff)
+	}
+
+	// A new store on the same log continues after the existing records.
+	s2 := journalstore.New(memstore.New(nil), log, nil)
+	kv2 := storetest.SubKV(t, ctx, s2, "test")
+	if err := kv2.Put(ctx, blob.PutOptions{Key: "f", Data: []byte("f")}); err != nil {
+		t.Fatalf("Put f: %v", err)
+	}
+	recs := records(t, s2)
+	if len(recs) != 4 || recs[3].KeyHash != journalstore.HashKey("f") {
+		t.Errorf("Records after reopen: got %+v, want f last of 4", recs)
+	}
+
+	// A new store with a limit removes the records beyond it.
+	s3 := journalstore.New(memstore.New(nil), log, &journalstore.Options{MaxRecords: 2})
+	kv3 := storetest.SubKV(t, ctx, s3, "test")
+	if err := kv3.Put(ctx, blob.PutOptions{Key: "g", Data: []byte("g")}); err != nil {
+		t.Fatalf("Put g: %v", err)
+	}
+	got = nil
+	for _, rec := range records(t, s3) {
+		got = append(got, rec.KeyHash)
+	}
+	want = []string{journalstore.HashKey("f"), journalstore.HashKey("g")}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("Records after limit (-got, +want):\n%s", diff)
+	}
+}
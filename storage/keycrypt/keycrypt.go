@@ -0,0 +1,272 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package keycrypt implements a wrapper for a [blob.Store] that encrypts the
+// keys of its keyspaces, for keyspaces whose keys are themselves sensitive,
+// such as a keyspace of roots whose names are meaningful. The values are not
+// modified; to encrypt them as well, use an encrypted codec (see package
+// [github.com/creachadair/ffs/storage/encoded]).
+//
+// # Encryption
+//
+// Keys are encrypted deterministically, so that the same key always has the
+// same encryption and can be found again by Get, Has, and Delete. The scheme
+// is a synthetic IV (SIV) construction: The IV is an HMAC-SHA256 tag of the
+// key, truncated to 16 bytes, and the key is encrypted with AES-256 in CTR
+// mode using that IV. The stored key is the IV followed by the ciphertext, so
+// it is 16 bytes longer than the original. Decryption recomputes the tag, and
+// rejects stored keys that were not produced with the same secret.
+//
+// Deterministic encryption reveals when two keys are equal, and the length of
+// each key; it does not otherwise reveal their contents.
+//
+// # Ordering
+//
+// The order of the encrypted keys is unrelated to the order of the original
+// keys, and reveals nothing about it. To report keys in order, as [blob.KV]
+// requires, List reads and decrypts all the keys of the underlying keyspace,
+// and sorts them, before it reports the first key. Each listing therefore costs
+// time and memory proportional to the size of the whole keyspace, regardless
+// of its starting point. This is suitable for small keyspaces such as roots,
+// but not for large content-addressed keyspaces, whose keys are not sensitive
+// in any case.
+//
+// The names of keyspaces and substores are not encrypted.
+package keycrypt
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/storage/dbkey"
+	"github.com/creachadair/ffs/storage/monitor"
+)
+
+// ivSize is the length in bytes of the synthetic IV prefixed to each key.
+const ivSize = aes.BlockSize
+
+// ErrInvalidKey is reported by List for a key of the underlying keyspace that
+// was not encrypted with the same secret.
+var ErrInvalidKey = errors.New("key is not validly encrypted")
+
+// A Cipher encrypts and decrypts keys deterministically. A Cipher is safe for
+// concurrent use by multiple goroutines.
+type Cipher struct {
+	macKey []byte       // for the synthetic IV
+	block  cipher.Block // for encryption
+}
+
+// NewCipher constructs a [Cipher] from secret, which must be at least 32 bytes
+// long. Separate keys for authentication and encryption are derived from it.
+func NewCipher(secret []byte) (*Cipher, error) {
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("secret is too short (%d < 32 bytes)", len(secret))
+	}
+	block, err := aes.NewCipher(derive(secret, "ffs keycrypt encrypt"))
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{macKey: derive(secret, "ffs keycrypt iv"), block: block}, nil
+}
+
+// derive returns a 32-byte key derived from secret for the given purpose.
+func derive(secret []byte, label string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(label))
+	return h.Sum(nil)
+}
+
+// tag returns the synthetic IV for key.
+func (c *Cipher) tag(key string) []byte {
+	h := hmac.New(sha256.New, c.macKey)
+	h.Write([]byte(key))
+	return h.Sum(nil)[:ivSize]
+}
+
+// Encrypt returns the encryption of key.
+func (c *Cipher) Encrypt(key string) string {
+	out := make([]byte, ivSize+len(key))
+	iv := c.tag(key)
+	copy(out, iv)
+	cipher.NewCTR(c.block, iv).XORKeyStream(out[ivSize:], []byte(key))
+	return string(out)
+}
+
+// Decrypt returns the decryption of ekey, or reports ErrInvalidKey if ekey is
+// not the encryption of a key with c.
+func (c *Cipher) Decrypt(ekey string) (string, error) {
+	if len(ekey) < ivSize {
+		return "", ErrInvalidKey
+	}
+	iv := []byte(ekey[:ivSize])
+	out := make([]byte, len(ekey)-ivSize)
+	cipher.NewCTR(c.block, iv).XORKeyStream(out, []byte(ekey[ivSize:]))
+	if !hmac.Equal(iv, c.tag(string(out))) {
+		return "", ErrInvalidKey
+	}
+	return string(out), nil
+}
+
+// Store implements the [blob.StoreCloser] interface by delegating to a base
+// store. Keyspaces derived from the store are of concrete type [*KV].
+type Store struct {
+	*monitor.M[state, *KV]
+}
+
+type state struct {
+	base blob.Store
+	c    *Cipher
+}
+
+// New constructs a [blob.Store] wrapper that delegates to base and encrypts
+// the keys of its keyspaces with c. New will panic if base == nil or c == nil.
+func New(base blob.Store, c *Cipher) Store {
+	if base == nil {
+		panic("base is nil")
+	} else if c == nil {
+		panic("cipher is nil")
+	}
+	return Store{M: monitor.New(monitor.Config[state, *KV]{
+		DB: state{base: base, c: c},
+		NewKV: func(ctx context.Context, db state, _ dbkey.Prefix, name string) (*KV, error) {
+			kv, err := db.base.KV(ctx, name)
+			if err != nil {
+				return nil, err
+			}
+			return NewKV(kv, db.c), nil
+		},
+		NewSub: func(ctx context.Context, db state, _ dbkey.Prefix, name string) (state, error) {
+			sub, err := db.base.Sub(ctx, name)
+			if err != nil {
+				return state{}, err
+			}
+			return state{base: sub, c: db.c}, nil
+		},
+	})}
+}
+
+// Close implements part of the [blob.StoreCloser] interface.
+func (s Store) Close(ctx context.Context) error {
+	kerr := s.M.CloseAll(ctx)
+	if c, ok := s.M.DB.base.(blob.Closer); ok {
+		return errors.Join(kerr, c.Close(ctx))
+	}
+	return kerr
+}
+
+// KV implements the [blob.KV] interface by delegating to a base keyspace, in
+// which the keys are encrypted.
+type KV struct {
+	base blob.KV
+	c    *Cipher
+}
+
+// NewKV constructs a [KV] that delegates to base and encrypts its keys with c.
+// NewKV will panic if base == nil or c == nil.
+func NewKV(base blob.KV, c *Cipher) *KV {
+	if base == nil {
+		panic("base is nil")
+	} else if c == nil {
+		panic("cipher is nil")
+	}
+	return &KV{base: base, c: c}
+}
+
+// keyError rewrites a *blob.KeyError from the base store for the encryption
+// of key, so that it reports key instead.
+func keyError(err error, key string) error {
+	var kerr *blob.KeyError
+	if errors.As(err, &kerr) {
+		return &blob.KeyError{Key: key, Err: kerr.Err}
+	}
+	return err
+}
+
+// Get implements part of [blob.KV].
+func (s *KV) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := s.base.Get(ctx, s.c.Encrypt(key))
+	return data, keyError(err, key)
+}
+
+// Has implements part of [blob.KV].
+func (s *KV) Has(ctx context.Context, keys ...string) (blob.KeySet, error) {
+	ekeys := make([]string, len(keys))
+	plain := make(map[string]string, len(keys))
+	for i, key := range keys {
+		ekeys[i] = s.c.Encrypt(key)
+		plain[ekeys[i]] = key
+	}
+	have, err := s.base.Has(ctx, ekeys...)
+	if err != nil {
+		return nil, err
+	}
+	var out blob.KeySet
+	for ekey := range have {
+		out.Add(plain[ekey])
+	}
+	return out, nil
+}
+
+// Put implements part of [blob.KV].
+func (s *KV) Put(ctx context.Context, opts blob.PutOptions) error {
+	key := opts.Key
+	opts.Key = s.c.Encrypt(key)
+	return keyError(s.base.Put(ctx, opts), key)
+}
+
+// Delete implements part of [blob.KV].
+func (s *KV) Delete(ctx context.Context, key string) error {
+	return keyError(s.base.Delete(ctx, s.c.Encrypt(key)), key)
+}
+
+// List implements part of [blob.KV]. It reads and decrypts all the keys of the
+// base keyspace before reporting the first key; see the package documentation.
+// If a key of the base keyspace cannot be decrypted, List reports an error
+// wrapping ErrInvalidKey.
+func (s *KV) List(ctx context.Context, start string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		var keys []string
+		for ekey, err := range s.base.List(ctx, "") {
+			if err != nil {
+				yield("", err)
+				return
+			}
+			key, err := s.c.Decrypt(ekey)
+			if err != nil {
+				yield("", fmt.Errorf("list %x: %w", ekey, err))
+				return
+			}
+			if key >= start {
+				keys = append(keys, key)
+			}
+		}
+		slices.SortFunc(keys, blob.CompareKeys)
+		for _, key := range keys {
+			if !yield(key, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Len implements part of [blob.KV].
+func (s *KV) Len(ctx context.Context) (int64, error) { return s.base.Len(ctx) }
@@ -0,0 +1,132 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keycrypt_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/blob/memstore"
+	"github.com/creachadair/ffs/blob/storetest"
+	"github.com/creachadair/ffs/storage/keycrypt"
+	"github.com/google/go-cmp/cmp"
+)
+
+var (
+	_ blob.KV          = (*keycrypt.KV)(nil)
+	_ blob.StoreCloser = keycrypt.Store{}
+)
+
+func mustCipher(t *testing.T, seed byte) *keycrypt.Cipher {
+	t.Helper()
+	c, err := keycrypt.NewCipher(bytes.Repeat([]byte{seed}, 32))
+	if err != nil {
+		t.Fatalf("NewCipher: %v", err)
+	}
+	return c
+}
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, keycrypt.New(memstore.New(nil), mustCipher(t, 1)))
+}
+
+func TestCipher(t *testing.T) {
+	if _, err := keycrypt.NewCipher([]byte("short")); err == nil {
+		t.Error("NewCipher with a short secret: got nil, want error")
+	}
+	c1, c2 := mustCipher(t, 1), mustCipher(t, 2)
+	for _, key := range []string{"", "a", "roots/main", strings.Repeat("\xff", 100)} {
+		ekey := c1.Encrypt(key)
+		if c1.Encrypt(key) != ekey {
+			t.Errorf("Encrypt %q is not deterministic", key)
+		}
+		if strings.Contains(ekey, key) && key != "" {
+			t.Errorf("Encrypt %q: plaintext appears in %x", key, ekey)
+		}
+		if got, err := c1.Decrypt(ekey); err != nil || got != key {
+			t.Errorf("Decrypt %x: got (%q, %v), want (%q, nil)", ekey, got, err, key)
+		}
+		if got, err := c2.Decrypt(ekey); !errors.Is(err, keycrypt.ErrInvalidKey) {
+			t.Errorf("Decrypt with wrong cipher: got (%q, %v), want %v", got, err, keycrypt.ErrInvalidKey)
+		}
+	}
+}
+
+func TestKV(t *testing.T) {
+	ctx := context.Background()
+	base := memstore.NewKV()
+	kv := keycrypt.NewKV(base, mustCipher(t, 1))
+
+	keys := []string{"zebra", "apple", "mango", "banana", "cherry"}
+	for _, key := range keys {
+		if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte("v-" + key)}); err != nil {
+			t.Fatalf("Put %q: %v", key, err)
+		}
+	}
+
+	// The underlying keys do not reveal the originals.
+	for ekey, err := range base.List(ctx, "") {
+		if err != nil {
+			t.Fatalf("List base: %v", err)
+		}
+		for _, key := range keys {
+			if strings.Contains(ekey, key) {
+				t.Errorf("Base key %x contains %q", ekey, key)
+			}
+		}
+	}
+
+	// Listing reports the original keys in order, from the start key.
+	list := func(start string) []string {
+		t.Helper()
+		var out []string
+		for key, err := range kv.List(ctx, start) {
+			if err != nil {
+				t.Fatalf("List %q: %v", start, err)
+			}
+			out = append(out, key)
+		}
+		return out
+	}
+	if diff := cmp.Diff(list(""), []string{"apple", "banana", "cherry", "mango", "zebra"}); diff != "" {
+		t.Errorf("List (-got, +want):\n%s", diff)
+	}
+	if diff := cmp.Diff(list("cherry"), []string{"cherry", "mango", "zebra"}); diff != "" {
+		t.Errorf("List cherry (-got, +want):\n%s", diff)
+	}
+
+	// Errors report the original key.
+	var kerr *blob.KeyError
+	if _, err := kv.Get(ctx, "nonesuch"); !errors.As(err, &kerr) || kerr.Key != "nonesuch" {
+		t.Errorf("Get nonesuch: got %v, want key error for nonesuch", err)
+	}
+	if got, err := kv.Get(ctx, "mango"); err != nil || string(got) != "v-mango" {
+		t.Errorf("Get mango: got (%q, %v), want (v-mango, nil)", got, err)
+	}
+
+	// A foreign key in the underlying keyspace is reported by List.
+	base.Put(ctx, blob.PutOptions{Key: "plain key", Data: []byte("x")})
+	var lerr error
+	for _, err := range kv.List(ctx, "") {
+		lerr = err
+	}
+	if !errors.Is(lerr, keycrypt.ErrInvalidKey) {
+		t.Errorf("List with foreign key: got %v, want %v", lerr, keycrypt.ErrInvalidKey)
+	}
+}
@@ -40,6 +40,7 @@ package monitor
 
 import (
 	"context"
+	"errors"
 	"sync"
 
 	"github.com/creachadair/ffs/blob"
@@ -78,9 +79,10 @@ type M[DB any, KV blob.KV] struct {
 	newKV  func(context.Context, DB, dbkey.Prefix, string) (KV, error)
 	newSub func(context.Context, DB, dbkey.Prefix, string) (DB, error)
 
-	μ    sync.Mutex
-	subs map[string]*M[DB, KV]
-	kvs  map[string]KV
+	μ     sync.Mutex
+	subs  map[string]*M[DB, KV]
+	kvs   map[string]KV
+	order []any // KVs and substores (*M), in order of creation
 }
 
 // New constructs a new empty store using the specified database, prefix, and
@@ -119,6 +121,7 @@ func (d *M[DB, KV]) KV(ctx context.Context, name string) (blob.KV, error) {
 			return nil, err
 		}
 		d.kvs[name] = kv
+		d.order = append(d.order, kv)
 	}
 	return kv, nil
 }
@@ -150,6 +153,40 @@ func (d *M[DB, KV]) Sub(ctx context.Context, name string) (blob.Store, error) {
 			NewSub: d.newSub,
 		})
 		d.subs[name] = sub
+		d.order = append(d.order, sub)
 	}
 	return sub, nil
 }
+
+// CloseAll closes the keyspaces and substores created by d, in the reverse of
+// the order in which they were created, so that a keyspace or substore is
+// closed before any created earlier, on which it might depend. A keyspace is
+// closed if it implements [blob.Closer], and a substore is closed by calling
+// its CloseAll method. CloseAll does not close d.DB, nor the states of any
+// substores, which remain the responsibility of the caller.
+//
+// CloseAll attempts to close everything even if some closes fail, and reports
+// the errors from all that failed. Afterward, d retains no keyspaces or
+// substores, so subsequent calls to KV and Sub construct new ones.
+//
+// A store that embeds an M should call CloseAll from its Close method before
+// closing its underlying storage.
+func (d *M[DB, KV]) CloseAll(ctx context.Context) error {
+	d.μ.Lock()
+	order := d.order
+	d.order = nil
+	clear(d.kvs)
+	clear(d.subs)
+	d.μ.Unlock()
+
+	var errs []error
+	for i := len(order) - 1; i >= 0; i-- {
+		switch v := order[i].(type) {
+		case *M[DB, KV]:
+			errs = append(errs, v.CloseAll(ctx))
+		case blob.Closer:
+			errs = append(errs, v.Close(ctx))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -15,10 +15,81 @@
 package monitor_test
 
 import (
+	"context"
+	"errors"
+	"testing"
+
 	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/blob/memstore"
+	"github.com/creachadair/ffs/storage/dbkey"
 	"github.com/creachadair/ffs/storage/monitor"
+	"github.com/google/go-cmp/cmp"
 )
 
 type kvStub struct{ blob.KV }
 
 var _ blob.Store = (*monitor.M[any, kvStub])(nil)
+
+// closeKV is a KV that records when it is closed.
+type closeKV struct {
+	blob.KV
+	name   string
+	closed *[]string
+}
+
+func (c closeKV) Close(context.Context) error {
+	*c.closed = append(*c.closed, c.name)
+	if c.name == "root/bad" {
+		return errors.New("close failed")
+	}
+	return nil
+}
+
+func TestCloseAll(t *testing.T) {
+	ctx := context.Background()
+	var closed []string
+	m := monitor.New(monitor.Config[string, closeKV]{
+		DB: "root",
+		NewKV: func(_ context.Context, db string, _ dbkey.Prefix, name string) (closeKV, error) {
+			return closeKV{KV: memstore.NewKV(), name: db + "/" + name, closed: &closed}, nil
+		},
+		NewSub: func(_ context.Context, db string, _ dbkey.Prefix, name string) (string, error) {
+			return db + "/" + name, nil
+		},
+	})
+	mustKV := func(s blob.Store, name string) {
+		t.Helper()
+		if _, err := s.KV(ctx, name); err != nil {
+			t.Fatalf("KV %q: unexpected error: %v", name, err)
+		}
+	}
+
+	mustKV(m, "a")
+	sub, err := m.Sub(ctx, "sub")
+	if err != nil {
+		t.Fatalf("Sub: unexpected error: %v", err)
+	}
+	mustKV(sub, "x")
+	mustKV(m, "b")
+	mustKV(sub, "y")
+	mustKV(m, "a") // already exists, not recreated
+
+	if err := m.CloseAll(ctx); err != nil {
+		t.Fatalf("CloseAll: unexpected error: %v", err)
+	}
+	want := []string{"root/b", "root/sub/y", "root/sub/x", "root/a"}
+	if diff := cmp.Diff(closed, want); diff != "" {
+		t.Errorf("Close order (-got, +want):\n%s", diff)
+	}
+
+	// After CloseAll, nothing remains to close, and new keyspaces can be made.
+	closed = nil
+	mustKV(m, "bad")
+	mustKV(m, "c")
+	if err := m.CloseAll(ctx); err == nil {
+		t.Error("CloseAll: got nil, want error")
+	}
+	if diff := cmp.Diff(closed, []string{"root/c", "root/bad"}); diff != "" {
+		t.Errorf("Close order (-got, +want):\n%s", diff)
+	}
+}
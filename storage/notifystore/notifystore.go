@@ -0,0 +1,251 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package notifystore implements a wrapper for a [blob.Store] that publishes
+// an [Event] to registered subscribers for each successful Put or Delete in
+// any of its keyspaces.
+//
+// This allows other components in the same process, such as caches, metrics,
+// or change feeds, to observe mutations without polling the store:
+//
+//	s := notifystore.New(base)
+//	cancel := s.Subscribe(func(e notifystore.Event) {
+//	   log.Printf("%v %q in %q", e.Op, e.Key, e.Keyspace)
+//	})
+//	defer cancel()
+//
+// Events are delivered only for mutations made through the wrapper.
+// Changes made directly to the base store, or by other processes, are not
+// observed.
+package notifystore
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"path"
+	"sync"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/storage/dbkey"
+	"github.com/creachadair/ffs/storage/monitor"
+)
+
+// Op identifies the kind of a mutation.
+type Op int
+
+const (
+	Put    Op = iota + 1 // a blob was written
+	Delete               // a blob was deleted
+)
+
+func (o Op) String() string {
+	switch o {
+	case Put:
+		return "put"
+	case Delete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+// An Event describes a successful mutation of a keyspace.
+type Event struct {
+	Op       Op     // the kind of mutation
+	Keyspace string // the slash-separated path of the keyspace from the root
+	Key      string // the key that was modified
+	Size     int    // for Put, the length of the data written
+	Replace  bool   // for Put, whether the Replace option was set
+}
+
+// Store implements the [blob.StoreCloser] interface by delegating to a base
+// store. Successful writes and deletes through [blob.KV] instances derived
+// from the store are published to its subscribers.
+type Store struct {
+	*monitor.M[state, *KV]
+}
+
+type state struct {
+	base blob.Store
+	hub  *hub
+	path string // slash-separated substore names from the root
+}
+
+// New constructs a [blob.Store] wrapper that delegates to base and publishes
+// mutations to subscribers. New will panic if base == nil.
+func New(base blob.Store) Store {
+	if base == nil {
+		panic("base is nil")
+	}
+	return Store{M: monitor.New(monitor.Config[state, *KV]{
+		DB: state{base: base, hub: new(hub)},
+		NewKV: func(ctx context.Context, db state, _ dbkey.Prefix, name string) (*KV, error) {
+			kv, err := db.base.KV(ctx, name)
+			if err != nil {
+				return nil, err
+			}
+			return &KV{base: kv, hub: db.hub, space: path.Join(db.path, name)}, nil
+		},
+		NewSub: func(ctx context.Context, db state, _ dbkey.Prefix, name string) (state, error) {
+			sub, err := db.base.Sub(ctx, name)
+			if err != nil {
+				return state{}, err
+			}
+			return state{base: sub, hub: db.hub, path: path.Join(db.path, name)}, nil
+		},
+	})}
+}
+
+// Close implements part of the [blob.StoreCloser] interface.
+// Closing the store does not remove its subscribers.
+func (s Store) Close(ctx context.Context) error {
+	kerr := s.M.CloseAll(ctx)
+	if c, ok := s.M.DB.base.(blob.Closer); ok {
+		return errors.Join(kerr, c.Close(ctx))
+	}
+	return kerr
+}
+
+// Subscribe registers f to be called for each event published by s, including
+// events from all its substores and keyspaces. It returns a function that
+// removes the subscription; it is safe to call the cancel function more than
+// once.
+//
+// Events are delivered synchronously: f is called after the mutation has
+// completed but before the Put or Delete call returns to its caller. The
+// function f may be called concurrently from multiple goroutines, and must
+// not block for long. It must not modify the store that generated the event.
+func (s Store) Subscribe(f func(Event)) (cancel func()) { return s.M.DB.hub.add(f) }
+
+// Watch returns a channel that receives the events published by s until ctx
+// ends, after which the channel is closed. The channel has buffer capacity
+// for n events (n < 0 is treated as 0).
+//
+// Because events are delivered synchronously, mutations of s block while the
+// channel buffer is full. The caller must receive from the channel promptly,
+// or cancel ctx to unblock writers.
+func (s Store) Watch(ctx context.Context, n int) <-chan Event {
+	ch := make(chan Event, max(n, 0))
+	var μ sync.RWMutex // exclusive: closing ch; shared: sending to ch
+	done := false
+	cancel := s.Subscribe(func(e Event) {
+		μ.RLock()
+		defer μ.RUnlock()
+		if done {
+			return
+		}
+		select {
+		case ch <- e:
+		case <-ctx.Done():
+		}
+	})
+	go func() {
+		<-ctx.Done()
+		cancel()
+		μ.Lock()
+		defer μ.Unlock()
+		done = true
+		close(ch)
+	}()
+	return ch
+}
+
+// KV implements the [blob.KV] interface by delegating to a base keyspace.
+// Successful calls to Put and Delete are published to the subscribers of the
+// enclosing [Store].
+type KV struct {
+	base  blob.KV
+	hub   *hub
+	space string // the name of this keyspace, for events
+}
+
+// Get implements part of [blob.KV]. It delegates to the base store.
+func (s *KV) Get(ctx context.Context, key string) ([]byte, error) { return s.base.Get(ctx, key) }
+
+// Has implements part of [blob.KV]. It delegates to the base store.
+func (s *KV) Has(ctx context.Context, keys ...string) (blob.KeySet, error) {
+	return s.base.Has(ctx, keys...)
+}
+
+// Put implements part of [blob.KV]. It delegates to the base store, and
+// publishes a Put event if the write succeeds.
+func (s *KV) Put(ctx context.Context, opts blob.PutOptions) error {
+	if err := s.base.Put(ctx, opts); err != nil {
+		return err
+	}
+	s.hub.publish(Event{
+		Op:       Put,
+		Keyspace: s.space,
+		Key:      opts.Key,
+		Size:     len(opts.Data),
+		Replace:  opts.Replace,
+	})
+	return nil
+}
+
+// Delete implements part of [blob.KV]. It delegates to the base store, and
+// publishes a Delete event if the deletion succeeds.
+func (s *KV) Delete(ctx context.Context, key string) error {
+	if err := s.base.Delete(ctx, key); err != nil {
+		return err
+	}
+	s.hub.publish(Event{Op: Delete, Keyspace: s.space, Key: key})
+	return nil
+}
+
+// List implements part of [blob.KV]. It delegates to the base store.
+func (s *KV) List(ctx context.Context, start string) iter.Seq2[string, error] {
+	return s.base.List(ctx, start)
+}
+
+// Len implements part of [blob.KV]. It delegates to the base store.
+func (s *KV) Len(ctx context.Context) (int64, error) { return s.base.Len(ctx) }
+
+// A hub is a registry of subscriber callbacks shared by all the keyspaces of
+// a store.
+type hub struct {
+	μ    sync.Mutex
+	next int
+	subs map[int]func(Event)
+}
+
+func (h *hub) add(f func(Event)) func() {
+	h.μ.Lock()
+	defer h.μ.Unlock()
+	if h.subs == nil {
+		h.subs = make(map[int]func(Event))
+	}
+	id := h.next
+	h.next++
+	h.subs[id] = f
+	return func() {
+		h.μ.Lock()
+		defer h.μ.Unlock()
+		delete(h.subs, id)
+	}
+}
+
+func (h *hub) publish(e Event) {
+	h.μ.Lock()
+	fs := make([]func(Event), 0, len(h.subs))
+	for _, f := range h.subs {
+		fs = append(fs, f)
+	}
+	h.μ.Unlock()
+
+	for _, f := range fs {
+		f(e)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package notifystore_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/blob/memstore"
+	"github.com/creachadair/ffs/blob/storetest"
+	"github.com/creachadair/ffs/storage/notifystore"
+	"github.com/google/go-cmp/cmp"
+)
+
+var (
+	_ blob.KV          = (*notifystore.KV)(nil)
+	_ blob.StoreCloser = notifystore.Store{}
+)
+
+func TestStore(t *testing.T) {
+	s := notifystore.New(memstore.New(nil))
+	storetest.Run(t, s)
+}
+
+func TestSubscribe(t *testing.T) {
+	ctx := context.Background()
+	s := notifystore.New(memstore.New(nil))
+
+	var μ sync.Mutex
+	var got []notifystore.Event
+	cancel := s.Subscribe(func(e notifystore.Event) {
+		μ.Lock()
+		defer μ.Unlock()
+		got = append(got, e)
+	})
+
+	kv := storetest.SubKV(t, ctx, s, "sub", "test")
+	root := storetest.SubKV(t, ctx, s, "top")
+
+	if err := kv.Put(ctx, blob.PutOptions{Key: "a", Data: []byte("apple")}); err != nil {
+		t.Fatalf("Put a: %v", err)
+	}
+	// A failed put is not published.
+	if err := kv.Put(ctx, blob.PutOptions{Key: "a", Data: []byte("avocado")}); !blob.IsKeyExists(err) {
+		t.Fatalf("Put a again: got %v, want %v", err, blob.ErrKeyExists)
+	}
+	if err := kv.Put(ctx, blob.PutOptions{Key: "a", Data: []byte("apricot"), Replace: true}); err != nil {
+		t.Fatalf("Replace a: %v", err)
+	}
+	if err := root.Put(ctx, blob.PutOptions{Key: "b", Data: []byte("banana")}); err != nil {
+		t.Fatalf("Put b: %v", err)
+	}
+	if err := kv.Delete(ctx, "a"); err != nil {
+		t.Fatalf("Delete a: %v", err)
+	}
+	// A failed delete is not published.
+	if err := kv.Delete(ctx, "a"); !blob.IsKeyNotFound(err) {
+		t.Fatalf("Delete a again: got %v, want %v", err, blob.ErrKeyNotFound)
+	}
+
+	cancel()
+	cancel() // safe to repeat
+
+	// After cancellation, no further events are delivered.
+	if err := root.Delete(ctx, "b"); err != nil {
+		t.Fatalf("Delete b: %v", err)
+	}
+
+	want := []notifystore.Event{
+		{Op: notifystore.Put, Keyspace: "sub/test", Key: "a", Size: 5},
+		{Op: notifystore.Put, Keyspace: "sub/test", Key: "a", Size: 7, Replace: true},
+		{Op: notifystore.Put, Keyspace: "top", Key: "b", Size: 6},
+		{Op: notifystore.Delete, Keyspace: "sub/test", Key: "a"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Events (-want, +got):\n%s", diff)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := notifystore.New(memstore.New(nil))
+	ch := s.Watch(ctx, 2)
+
+	kv := storetest.SubKV(t, ctx, s, "test")
+	for _, key := range []string{"x", "y"} {
+		if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte(key)}); err != nil {
+			t.Fatalf("Put %q: %v", key, err)
+		}
+	}
+	for _, want := range []string{"x", "y"} {
+		if e := <-ch; e.Op != notifystore.Put || e.Key != want {
+			t.Errorf("Watch: got %+v, want put of %q", e, want)
+		}
+	}
+
+	cancel()
+	for e := range ch {
+		t.Errorf("Watch: unexpected event after cancel: %+v", e)
+	}
+}
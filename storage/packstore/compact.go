@@ -0,0 +1,252 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+)
+
+// DefaultMinGarbage is the default fraction of pack space that must be
+// garbage before a keyspace is compacted.
+const DefaultMinGarbage = 0.25
+
+// CompactOptions are settings for compaction. A nil *CompactOptions is ready
+// for use and provides default values as described.
+type CompactOptions struct {
+	// If true, compact the keyspace regardless of how much garbage it has.
+	Force bool
+
+	// MinGarbage is the fraction of the total size of the packs that must be
+	// used by replaced or deleted blobs before the keyspace is compacted.
+	// If zero, DefaultMinGarbage is used. Regardless of this setting, a
+	// keyspace with two or more small packs is compacted to merge them.
+	MinGarbage float64
+
+	// If positive, limit the rate at which compaction copies data to
+	// approximately this many bytes per second.
+	BytesPerSecond int64
+
+	// If non-nil, Progress is called after each key is processed by
+	// compaction. Calls to Progress for a keyspace are not concurrent, but
+	// calls for different keyspaces of a Store may be.
+	Progress func(CompactProgress)
+}
+
+func (o *CompactOptions) force() bool { return o != nil && o.Force }
+
+func (o *CompactOptions) minGarbage() float64 {
+	if o == nil || o.MinGarbage <= 0 {
+		return DefaultMinGarbage
+	}
+	return o.MinGarbage
+}
+
+func (o *CompactOptions) report(p CompactProgress) {
+	if o != nil && o.Progress != nil {
+		o.Progress(p)
+	}
+}
+
+// throttle delays until copying nb bytes since start would not exceed the
+// rate limit, or until ctx ends.
+func (o *CompactOptions) throttle(ctx context.Context, start time.Time, nb int64) error {
+	if o == nil || o.BytesPerSecond <= 0 {
+		return nil
+	}
+	want := time.Duration(float64(nb) / float64(o.BytesPerSecond) * float64(time.Second))
+	wait := want - time.Since(start)
+	if wait <= 0 {
+		return nil
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// CompactProgress is the argument to the Progress callback of a compaction.
+type CompactProgress struct {
+	Dir       string // the directory of the keyspace being compacted
+	Keys      int64  // the number of keys processed so far
+	TotalKeys int64  // the number of keys when compaction began
+	Bytes     int64  // the number of bytes copied so far
+}
+
+// Compact rewrites the current blobs of s into new packs and removes the old
+// packs, reclaiming the space used by replaced and deleted blobs and merging
+// small packs. It reports whether compaction was performed: Unless opts
+// requests it to be forced, compaction is skipped if s does not have enough
+// garbage or small packs to be worth the effort.
+//
+// Other operations on s may proceed concurrently with compaction, but only
+// one compaction at a time is run on a given KV.
+//
+// If Compact fails or ctx ends before it is complete, s remains valid: Some
+// blobs may have been copied to new packs, and the old packs are retained.
+func (s *KV) Compact(ctx context.Context, opts *CompactOptions) (bool, error) {
+	s.cμ.Lock()
+	defer s.cμ.Unlock()
+
+	// Start a new pack, so that every blob written from here on, whether by
+	// compaction or by a concurrent Put, goes to a pack newer than the
+	// packs being compacted.
+	s.μ.Lock()
+	if s.closed {
+		s.μ.Unlock()
+		return false, errClosed
+	} else if !opts.force() && !s.needsCompactionLocked(opts) {
+		s.μ.Unlock()
+		return false, nil
+	}
+	old := slices.Clone(s.packs)
+	if err := s.rotateLocked(); err != nil {
+		s.μ.Unlock()
+		return false, err
+	}
+	firstNew := s.active.seq
+	prog := CompactProgress{Dir: s.dir, TotalKeys: int64(len(s.index))}
+	s.μ.Unlock()
+
+	// Move each blob still stored in an old pack to the new packs. Keys added
+	// or replaced since we started are already in the new packs, and keys
+	// deleted since we started no longer need to be moved.
+	start := time.Now()
+	cur, ok := s.nextKey("", false)
+	for ok {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		nb, err := s.moveKey(cur, firstNew)
+		if err != nil {
+			return false, err
+		}
+		prog.Keys++
+		prog.Bytes += nb
+		opts.report(prog)
+		if err := opts.throttle(ctx, start, prog.Bytes); err != nil {
+			return false, err
+		}
+		cur, ok = s.nextKey(cur, true)
+	}
+
+	s.μ.Lock()
+	defer s.μ.Unlock()
+	if s.closed {
+		return false, errClosed
+	}
+	for _, p := range s.packs[len(old):] {
+		if err := p.f.Sync(); err != nil {
+			return false, err
+		}
+	}
+
+	// All current blobs are now in the new packs. Remove the old packs in
+	// order, so that if we are interrupted, replaying the remaining packs
+	// cannot resurrect a deleted key.
+	for i, p := range old {
+		if err := os.Remove(p.f.Name()); err != nil {
+			s.packs = s.packs[i:]
+			return false, err
+		}
+		p.f.Close()
+	}
+	s.packs = s.packs[len(old):]
+	return true, syncDir(s.dir)
+}
+
+// needsCompactionLocked reports whether s has enough garbage or small packs
+// to be worth compacting. The caller must hold s.μ.
+func (s *KV) needsCompactionLocked(opts *CompactOptions) bool {
+	var live, total int64
+	var small int
+	for _, p := range s.packs {
+		live += p.live
+		total += p.size
+		if p != s.active && p.size < s.opts.maxPackBytes()/2 {
+			small++
+		}
+	}
+	if small >= 2 {
+		return true
+	}
+	return total > 0 && float64(total-live)/float64(total) >= opts.minGarbage()
+}
+
+// moveKey copies the current blob for key to the active pack, if it is stored
+// in a pack older than firstNew. It reports the number of bytes copied.
+func (s *KV) moveKey(key string, firstNew int) (int64, error) {
+	s.μ.Lock()
+	defer s.μ.Unlock()
+	if s.closed {
+		return 0, errClosed
+	}
+	src, ok := s.index[key]
+	if !ok || src.p.seq >= firstNew {
+		return 0, nil // deleted, or already moved
+	}
+	_, _, data, err := src.p.readRecord(src.off, src.n)
+	if err != nil {
+		return 0, fmt.Errorf("key %q: %w", key, err)
+	}
+	at, err := s.writeLocked(opPut, key, data)
+	if err != nil {
+		return 0, err
+	}
+	src.p.live -= src.n
+	s.index[key] = at
+	at.p.live += at.n
+	return at.n, nil
+}
+
+// Compact compacts each of the keyspaces opened via s or its substores, as
+// described by [KV.Compact]. It reports the number of keyspaces compacted.
+// Compaction stops at the first error.
+func (s Store) Compact(ctx context.Context, opts *CompactOptions) (int, error) {
+	var nc int
+	for _, kv := range s.M.DB.reg.all() {
+		ok, err := kv.Compact(ctx, opts)
+		if errors.Is(err, errClosed) {
+			continue
+		} else if err != nil {
+			return nc, err
+		} else if ok {
+			nc++
+		}
+	}
+	return nc, nil
+}
+
+func (s Store) compactLoop(ctx context.Context, interval time.Duration) {
+	defer close(s.M.DB.reg.done)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			s.Compact(ctx, s.M.DB.opts.Compact)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packstore
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// A pack file is a sequence of records, each of which has the form:
+//
+//	op (1 byte) | key length (4 bytes) | data length (4 bytes) | key | data | crc32c (4 bytes)
+//
+// Lengths and the checksum are big-endian. The checksum covers all the bytes
+// of the record that precede it. Records are only ever appended to a pack, and
+// the packs of a keyspace are replayed in order of their sequence numbers.
+const (
+	opPut    = 1 // store data under key
+	opDelete = 2 // remove key; the data are empty
+
+	recHeaderSize  = 9
+	recTrailerSize = 4
+	packSuffix     = ".pack"
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// errCorrupt is reported when a record in a pack cannot be decoded.
+var errCorrupt = errors.New("corrupt pack record")
+
+// A pack is an open pack file.
+type pack struct {
+	seq  int      // sequence number, from the file name
+	f    *os.File // open for reading and writing
+	size int64    // current length of the file in bytes
+	live int64    // total bytes of records for current keys
+}
+
+func packName(seq int) string { return fmt.Sprintf("%08d%s", seq, packSuffix) }
+
+// parsePackName reports the sequence number of a pack file name, and whether
+// the name is a valid pack file name.
+func parsePackName(name string) (int, bool) {
+	base, ok := strings.CutSuffix(name, packSuffix)
+	if !ok {
+		return 0, false
+	}
+	seq, err := strconv.Atoi(base)
+	return seq, err == nil && seq >= 0
+}
+
+// createPack creates a new empty pack with the given sequence number in dir.
+func createPack(dir string, seq int) (*pack, error) {
+	f, err := os.OpenFile(filepath.Join(dir, packName(seq)), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &pack{seq: seq, f: f}, nil
+}
+
+// encodeRecord returns the encoding of a record.
+func encodeRecord(op byte, key string, data []byte) []byte {
+	buf := make([]byte, recHeaderSize, recHeaderSize+len(key)+len(data)+recTrailerSize)
+	buf[0] = op
+	binary.BigEndian.PutUint32(buf[1:], uint32(len(key)))
+	binary.BigEndian.PutUint32(buf[5:], uint32(len(data)))
+	buf = append(buf, key...)
+	buf = append(buf, data...)
+	return binary.BigEndian.AppendUint32(buf, crc32.Checksum(buf, crcTable))
+}
+
+// decodeRecord decodes a complete record from rec, which must contain exactly
+// one record as written by encodeRecord.
+func decodeRecord(rec []byte) (op byte, key string, data []byte, _ error) {
+	if len(rec) < recHeaderSize+recTrailerSize {
+		return 0, "", nil, errCorrupt
+	}
+	klen := int64(binary.BigEndian.Uint32(rec[1:]))
+	dlen := int64(binary.BigEndian.Uint32(rec[5:]))
+	if int64(len(rec)) != recHeaderSize+klen+dlen+recTrailerSize {
+		return 0, "", nil, errCorrupt
+	}
+	body, sum := rec[:len(rec)-recTrailerSize], rec[len(rec)-recTrailerSize:]
+	if crc32.Checksum(body, crcTable) != binary.BigEndian.Uint32(sum) {
+		return 0, "", nil, errCorrupt
+	}
+	op = rec[0]
+	if op != opPut && op != opDelete {
+		return 0, "", nil, errCorrupt
+	}
+	key = string(rec[recHeaderSize : recHeaderSize+klen])
+	return op, key, rec[recHeaderSize+klen : len(rec)-recTrailerSize], nil
+}
+
+// readRecord reads the complete record of length n at offset off of p.
+func (p *pack) readRecord(off, n int64) (byte, string, []byte, error) {
+	rec := make([]byte, n)
+	if _, err := p.f.ReadAt(rec, off); err != nil {
+		return 0, "", nil, err
+	}
+	return decodeRecord(rec)
+}
+
+// append writes rec to the end of p and returns its offset.
+func (p *pack) append(rec []byte) (int64, error) {
+	off := p.size
+	if _, err := p.f.WriteAt(rec, off); err != nil {
+		return 0, err
+	}
+	p.size += int64(len(rec))
+	return off, nil
+}
+
+// scan reads the records of p from the beginning, calling f for each with its
+// offset and length. It reports the offset of the end of the last complete
+// record. If the pack ends with an incomplete or invalid record, scan stops
+// and reports errCorrupt along with the offset of the end of the last good
+// record.
+func (p *pack) scan(f func(op byte, key string, off, n int64)) (int64, error) {
+	if _, err := p.f.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	r := bufio.NewReader(p.f)
+	var pos int64
+	var hdr [recHeaderSize]byte
+	for {
+		if _, err := io.ReadFull(r, hdr[:]); err == io.EOF {
+			return pos, nil
+		} else if err == io.ErrUnexpectedEOF {
+			return pos, errCorrupt
+		} else if err != nil {
+			return pos, err
+		}
+		klen := int64(binary.BigEndian.Uint32(hdr[1:]))
+		dlen := int64(binary.BigEndian.Uint32(hdr[5:]))
+		n := recHeaderSize + klen + dlen + recTrailerSize
+
+		rec := make([]byte, n)
+		copy(rec, hdr[:])
+		if _, err := io.ReadFull(r, rec[recHeaderSize:]); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return pos, errCorrupt
+		} else if err != nil {
+			return pos, err
+		}
+		op, key, _, err := decodeRecord(rec)
+		if err != nil {
+			return pos, err
+		}
+		f(op, key, pos, n)
+		pos += n
+	}
+}
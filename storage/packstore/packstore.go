@@ -0,0 +1,489 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package packstore implements the [blob.KV] interface using append-only pack
+// files. Each keyspace is a directory of packs, each of which holds many
+// blobs, similar to the pack files of a Git repository.
+//
+// Writes and deletes are appended to the current pack of a keyspace, and a
+// new pack is started when the current one reaches a size limit. An index of
+// the current keys is kept in memory, and is rebuilt by reading the packs
+// when a keyspace is opened. Space used by replaced and deleted blobs is not
+// reclaimed until the keyspace is compacted (see [KV.Compact]).
+//
+// Compared to [filestore], which stores each blob in a separate file, this
+// uses much less space and far fewer inodes for a large number of small
+// blobs, such as the nodes of a file tree. The cost is that the keys of each
+// open keyspace must fit in memory.
+//
+// [filestore]: https://godoc.org/github.com/creachadair/ffs/storage/filestore
+package packstore
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"iter"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/storage/dbkey"
+	"github.com/creachadair/ffs/storage/monitor"
+	"github.com/creachadair/mds/stree"
+)
+
+// DefaultMaxPackBytes is the default size limit for a pack file.
+const DefaultMaxPackBytes = 64 << 20
+
+// errClosed is reported by operations on a closed keyspace.
+var errClosed = errors.New("keyspace is closed")
+
+// Options are settings for a [Store] or a [KV]. A nil *Options is ready for
+// use and provides default values as described.
+type Options struct {
+	// MaxPackBytes is the size in bytes at which a new pack is started.
+	// If zero, DefaultMaxPackBytes is used. A single blob larger than this
+	// limit is stored in a pack by itself.
+	MaxPackBytes int64
+
+	// If true, each write is synced to stable storage before it returns.
+	// Otherwise, writes are synced only when a pack is finished, and during
+	// compaction.
+	Sync bool
+
+	// If positive, a [Store] checks each of its open keyspaces for compaction
+	// at this interval, in the background, until the store is closed.
+	// This setting is ignored by NewKV.
+	CompactInterval time.Duration
+
+	// Compact, if non-nil, provides options for background compaction.
+	// See [KV.Compact] for the meaning of a nil value.
+	Compact *CompactOptions
+}
+
+func (o *Options) maxPackBytes() int64 {
+	if o == nil || o.MaxPackBytes <= 0 {
+		return DefaultMaxPackBytes
+	}
+	return o.MaxPackBytes
+}
+
+func (o *Options) sync() bool { return o != nil && o.Sync }
+
+// Store implements the [blob.StoreCloser] interface using a directory of pack
+// files for each keyspace. Keyspaces derived from the store are of concrete
+// type [*KV].
+type Store struct {
+	*monitor.M[state, *KV]
+}
+
+type state struct {
+	dir  string
+	opts *Options
+	reg  *registry
+}
+
+// A registry records the open keyspaces of a store, for compaction and
+// closing.
+type registry struct {
+	μ    sync.Mutex
+	kvs  []*KV
+	stop context.CancelFunc // if non-nil, stops the background compactor
+	done chan struct{}      // closed when the background compactor exits
+}
+
+func (r *registry) add(kv *KV) {
+	r.μ.Lock()
+	defer r.μ.Unlock()
+	r.kvs = append(r.kvs, kv)
+}
+
+func (r *registry) all() []*KV {
+	r.μ.Lock()
+	defer r.μ.Unlock()
+	return append([]*KV(nil), r.kvs...)
+}
+
+// New creates a Store associated with the specified root directory, which is
+// created if it does not already exist.
+func New(dir string, opts *Options) (Store, error) {
+	path := filepath.Clean(dir)
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return Store{}, err
+	}
+	reg := new(registry)
+	s := Store{M: monitor.New(monitor.Config[state, *KV]{
+		DB: state{dir: path, opts: opts, reg: reg},
+		NewKV: func(_ context.Context, db state, _ dbkey.Prefix, name string) (*KV, error) {
+			kv, err := NewKV(subdir(db.dir, "kv", name), db.opts)
+			if err != nil {
+				return nil, err
+			}
+			db.reg.add(kv)
+			return kv, nil
+		},
+		NewSub: func(_ context.Context, db state, _ dbkey.Prefix, name string) (state, error) {
+			return state{dir: subdir(db.dir, "sub", name), opts: db.opts, reg: db.reg}, nil
+		},
+	})}
+	if opts != nil && opts.CompactInterval > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		reg.stop, reg.done = cancel, make(chan struct{})
+		go s.compactLoop(ctx, opts.CompactInterval)
+	}
+	return s, nil
+}
+
+// subdir returns the path of the directory for the named keyspace or
+// substore of dir. Names are encoded in hex, with a "_" prefix so that the
+// empty name has a non-empty path.
+func subdir(dir, kind, name string) string {
+	return filepath.Join(dir, kind, "_"+hex.EncodeToString([]byte(name)))
+}
+
+// Close implements part of the [blob.StoreCloser] interface. It stops
+// background compaction, if enabled, and closes the pack files of all the
+// keyspaces opened via s or its substores.
+func (s Store) Close(context.Context) error {
+	if reg := s.M.DB.reg; reg.stop != nil {
+		reg.stop()
+		<-reg.done
+	}
+	var errs []error
+	for _, kv := range s.M.DB.reg.all() {
+		errs = append(errs, kv.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Opener constructs a packstore from an address comprising a path, for use
+// with the [storage] registry or the [store] package. The concrete type of the
+// result is [Store].
+//
+// [storage]: https://godoc.org/github.com/creachadair/ffs/storage
+// [store]: https://godoc.org/github.com/creachadair/ffstools/lib/store
+func Opener(_ context.Context, addr string) (blob.StoreCloser, error) {
+	return New(addr, nil)
+}
+
+// KV implements the [blob.KV] interface using a directory of pack files.
+// A KV is safe for concurrent use by multiple goroutines, but at most one KV
+// at a time may use a given directory.
+type KV struct {
+	dir  string
+	opts *Options
+
+	cμ sync.Mutex // held exclusively during compaction
+
+	μ      sync.RWMutex
+	index  map[string]loc      // the location of each current key
+	keys   *stree.Tree[string] // the current keys, in order
+	packs  []*pack             // in order of sequence number
+	active *pack               // the pack receiving writes (last of packs)
+	closed bool
+}
+
+// A loc is the location of a record in a pack.
+type loc struct {
+	p   *pack
+	off int64 // offset of the record
+	n   int64 // length of the record
+}
+
+// NewKV opens a [KV] using the pack files in dir, which is created if it does
+// not already exist. If the last pack ends with an incomplete record, as can
+// happen if a write was interrupted, the partial record is discarded. Any
+// other invalid record is reported as an error.
+func NewKV(dir string, opts *Options) (*KV, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var seqs []int
+	for _, e := range ents {
+		if seq, ok := parsePackName(e.Name()); ok && e.Type().IsRegular() {
+			seqs = append(seqs, seq)
+		}
+	}
+	slices.Sort(seqs)
+
+	kv := &KV{
+		dir:   dir,
+		opts:  opts,
+		index: make(map[string]loc),
+		keys:  stree.New(300, blob.CompareKeys),
+	}
+	for i, seq := range seqs {
+		if err := kv.loadPack(seq, i == len(seqs)-1); err != nil {
+			kv.closePacks()
+			return nil, fmt.Errorf("pack %s: %w", packName(seq), err)
+		}
+	}
+	if n := len(kv.packs); n == 0 || kv.packs[n-1].size >= opts.maxPackBytes() {
+		if err := kv.rotateLocked(); err != nil {
+			kv.closePacks()
+			return nil, err
+		}
+	} else {
+		kv.active = kv.packs[n-1]
+	}
+	return kv, nil
+}
+
+// loadPack opens the pack with the given sequence number and replays its
+// records into the index. If last is true, an incomplete record at the end of
+// the pack is truncated.
+func (s *KV) loadPack(seq int, last bool) error {
+	f, err := os.OpenFile(filepath.Join(s.dir, packName(seq)), os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	p := &pack{seq: seq, f: f}
+	s.packs = append(s.packs, p)
+	end, err := p.scan(func(op byte, key string, off, n int64) {
+		if old, ok := s.index[key]; ok {
+			old.p.live -= old.n
+		}
+		switch op {
+		case opPut:
+			s.index[key] = loc{p: p, off: off, n: n}
+			s.keys.Add(key)
+			p.live += n
+		case opDelete:
+			delete(s.index, key)
+			s.keys.Remove(key)
+		}
+	})
+	if errors.Is(err, errCorrupt) && last {
+		if err := f.Truncate(end); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	p.size = end
+	return nil
+}
+
+// rotateLocked finishes the active pack, if any, and starts a new one.
+// The caller must hold s.μ exclusively.
+func (s *KV) rotateLocked() error {
+	seq := 0
+	if n := len(s.packs); n != 0 {
+		seq = s.packs[n-1].seq + 1
+	}
+	if s.active != nil {
+		if err := s.active.f.Sync(); err != nil {
+			return err
+		}
+	}
+	p, err := createPack(s.dir, seq)
+	if err != nil {
+		return err
+	}
+	s.packs = append(s.packs, p)
+	s.active = p
+	return syncDir(s.dir)
+}
+
+// writeLocked appends a record to the active pack, starting a new pack first
+// if the active pack is full. The caller must hold s.μ exclusively.
+func (s *KV) writeLocked(op byte, key string, data []byte) (loc, error) {
+	if s.closed {
+		return loc{}, errClosed
+	}
+	rec := encodeRecord(op, key, data)
+	if s.active.size > 0 && s.active.size+int64(len(rec)) > s.opts.maxPackBytes() {
+		if err := s.rotateLocked(); err != nil {
+			return loc{}, err
+		}
+	}
+	off, err := s.active.append(rec)
+	if err != nil {
+		return loc{}, err
+	}
+	if s.opts.sync() {
+		if err := s.active.f.Sync(); err != nil {
+			return loc{}, err
+		}
+	}
+	return loc{p: s.active, off: off, n: int64(len(rec))}, nil
+}
+
+// Get implements part of [blob.KV].
+func (s *KV) Get(_ context.Context, key string) ([]byte, error) {
+	s.μ.RLock()
+	defer s.μ.RUnlock()
+	at, ok := s.index[key]
+	if !ok {
+		return nil, blob.KeyNotFound(key)
+	}
+	_, _, data, err := at.p.readRecord(at.off, at.n)
+	if err != nil {
+		return nil, fmt.Errorf("key %q: %w", key, err)
+	}
+	return data, nil
+}
+
+// Has implements part of [blob.KV].
+func (s *KV) Has(_ context.Context, keys ...string) (blob.KeySet, error) {
+	s.μ.RLock()
+	defer s.μ.RUnlock()
+	var out blob.KeySet
+	for _, key := range keys {
+		if _, ok := s.index[key]; ok {
+			out.Add(key)
+		}
+	}
+	return out, nil
+}
+
+// Put implements part of [blob.KV].
+func (s *KV) Put(_ context.Context, opts blob.PutOptions) error {
+	s.μ.Lock()
+	defer s.μ.Unlock()
+	old, ok := s.index[opts.Key]
+	if ok && !opts.Replace {
+		return blob.KeyExists(opts.Key)
+	}
+	at, err := s.writeLocked(opPut, opts.Key, opts.Data)
+	if err != nil {
+		return err
+	}
+	if ok {
+		old.p.live -= old.n
+	}
+	s.index[opts.Key] = at
+	s.keys.Add(opts.Key)
+	at.p.live += at.n
+	return nil
+}
+
+// Delete implements part of [blob.KV].
+func (s *KV) Delete(_ context.Context, key string) error {
+	s.μ.Lock()
+	defer s.μ.Unlock()
+	old, ok := s.index[key]
+	if !ok {
+		return blob.KeyNotFound(key)
+	}
+	if _, err := s.writeLocked(opDelete, key, nil); err != nil {
+		return err
+	}
+	old.p.live -= old.n
+	delete(s.index, key)
+	s.keys.Remove(key)
+	return nil
+}
+
+// List implements part of [blob.KV].
+func (s *KV) List(_ context.Context, start string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		cur, ok := s.nextKey(start, false)
+		for ok {
+			if !yield(cur, nil) {
+				return
+			}
+			cur, ok = s.nextKey(cur, true)
+		}
+	}
+}
+
+// nextKey returns the least current key not less than start, or if after is
+// true, the least current key greater than start.
+func (s *KV) nextKey(start string, after bool) (string, bool) {
+	s.μ.RLock()
+	defer s.μ.RUnlock()
+	cur := s.keys.Find(start)
+	if cur == nil {
+		return "", false
+	}
+	if after && cur.Key() == start {
+		if cur = cur.Next(); !cur.Valid() {
+			return "", false
+		}
+	}
+	return cur.Key(), true
+}
+
+// Len implements part of [blob.KV].
+func (s *KV) Len(context.Context) (int64, error) {
+	s.μ.RLock()
+	defer s.μ.RUnlock()
+	return int64(len(s.index)), nil
+}
+
+// Stats record the space usage of a [KV].
+type Stats struct {
+	Packs      int   // the number of pack files
+	Keys       int64 // the number of current keys
+	LiveBytes  int64 // the total size of records for current keys
+	TotalBytes int64 // the total size of all pack files
+}
+
+// Stats reports the current space usage of s. The difference between
+// TotalBytes and LiveBytes is the space that would be reclaimed by compaction.
+func (s *KV) Stats() Stats {
+	s.μ.RLock()
+	defer s.μ.RUnlock()
+	out := Stats{Packs: len(s.packs), Keys: int64(len(s.index))}
+	for _, p := range s.packs {
+		out.LiveBytes += p.live
+		out.TotalBytes += p.size
+	}
+	return out
+}
+
+// Close closes the pack files of s. After Close, operations that read or
+// write data report errors.
+func (s *KV) Close() error {
+	s.μ.Lock()
+	defer s.μ.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	var err error
+	if s.active != nil {
+		err = s.active.f.Sync()
+	}
+	return errors.Join(err, s.closePacks())
+}
+
+func (s *KV) closePacks() error {
+	var errs []error
+	for _, p := range s.packs {
+		errs = append(errs, p.f.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// syncDir syncs the directory dir, to persist the creation or removal of
+// files within it.
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
@@ -0,0 +1,280 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package packstore_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/blob/storetest"
+	"github.com/creachadair/ffs/storage/packstore"
+)
+
+var (
+	_ blob.KV          = (*packstore.KV)(nil)
+	_ blob.StoreCloser = packstore.Store{}
+)
+
+func TestStore(t *testing.T) {
+	s, err := packstore.New(t.TempDir(), &packstore.Options{MaxPackBytes: 256})
+	if err != nil {
+		t.Fatalf("New: unexpected error: %v", err)
+	}
+	storetest.Run(t, s)
+}
+
+func FuzzStore(f *testing.F) {
+	// Use the default pack size, since every input writes a new record and
+	// small packs would accumulate an open file each.
+	s, err := packstore.New(f.TempDir(), nil)
+	if err != nil {
+		f.Fatalf("New: unexpected error: %v", err)
+	}
+	storetest.Fuzz(f, s, nil)
+}
+
+func mustOpen(t *testing.T, dir string, opts *packstore.Options) *packstore.KV {
+	t.Helper()
+	kv, err := packstore.NewKV(dir, opts)
+	if err != nil {
+		t.Fatalf("NewKV: unexpected error: %v", err)
+	}
+	return kv
+}
+
+func checkKeys(t *testing.T, kv *packstore.KV, want map[string]string) {
+	t.Helper()
+	ctx := context.Background()
+	if n, err := kv.Len(ctx); err != nil || n != int64(len(want)) {
+		t.Errorf("Len: got (%d, %v), want (%d, nil)", n, err, len(want))
+	}
+	for key, val := range want {
+		got, err := kv.Get(ctx, key)
+		if err != nil {
+			t.Errorf("Get %q: unexpected error: %v", key, err)
+		} else if string(got) != val {
+			t.Errorf("Get %q: got %q, want %q", key, got, val)
+		}
+	}
+}
+
+func TestPersist(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	opts := &packstore.Options{MaxPackBytes: 100}
+
+	kv := mustOpen(t, dir, opts)
+	want := make(map[string]string)
+	for i := range 50 {
+		key, val := fmt.Sprintf("key-%02d", i), fmt.Sprintf("value %d", i)
+		if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte(val)}); err != nil {
+			t.Fatalf("Put %q: unexpected error: %v", key, err)
+		}
+		want[key] = val
+	}
+	for i := range 50 {
+		key := fmt.Sprintf("key-%02d", i)
+		switch i % 3 {
+		case 0:
+			if err := kv.Delete(ctx, key); err != nil {
+				t.Fatalf("Delete %q: unexpected error: %v", key, err)
+			}
+			delete(want, key)
+		case 1:
+			val := "replaced " + key
+			if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte(val), Replace: true}); err != nil {
+				t.Fatalf("Put %q: unexpected error: %v", key, err)
+			}
+			want[key] = val
+		}
+	}
+	checkKeys(t, kv, want)
+	st := kv.Stats()
+	if st.Packs < 2 {
+		t.Errorf("Stats: got %d packs, want several", st.Packs)
+	}
+	if st.LiveBytes >= st.TotalBytes {
+		t.Errorf("Stats: live %d ≥ total %d, want garbage", st.LiveBytes, st.TotalBytes)
+	}
+	if err := kv.Close(); err != nil {
+		t.Fatalf("Close: unexpected error: %v", err)
+	}
+
+	// Reopening the keyspace recovers its contents.
+	kv = mustOpen(t, dir, opts)
+	checkKeys(t, kv, want)
+	if got := kv.Stats(); got != st {
+		t.Errorf("Stats after reopen: got %+v, want %+v", got, st)
+	}
+
+	// Compaction reclaims the garbage, and preserves the contents.
+	if ok, err := kv.Compact(ctx, &packstore.CompactOptions{Force: true}); err != nil || !ok {
+		t.Fatalf("Compact: got (%v, %v), want (true, nil)", ok, err)
+	}
+	checkKeys(t, kv, want)
+	cst := kv.Stats()
+	if cst.LiveBytes != st.LiveBytes || cst.TotalBytes != cst.LiveBytes {
+		t.Errorf("Stats after compact: got %+v, want live=total=%d", cst, st.LiveBytes)
+	}
+	kv.Close()
+
+	kv = mustOpen(t, dir, opts)
+	defer kv.Close()
+	checkKeys(t, kv, want)
+	if got := kv.Stats(); got != cst {
+		t.Errorf("Stats after reopen: got %+v, want %+v", got, cst)
+	}
+}
+
+func TestTornWrite(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	kv := mustOpen(t, dir, nil)
+	for _, key := range []string{"a", "b"} {
+		if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte(key + key)}); err != nil {
+			t.Fatalf("Put %q: unexpected error: %v", key, err)
+		}
+	}
+	kv.Close()
+
+	// Simulate a write interrupted partway through the last record.
+	packs, err := filepath.Glob(filepath.Join(dir, "*.pack"))
+	if err != nil || len(packs) != 1 {
+		t.Fatalf("Find packs: got (%q, %v), want one", packs, err)
+	}
+	fi, err := os.Stat(packs[0])
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if err := os.Truncate(packs[0], fi.Size()-3); err != nil {
+		t.Fatalf("Truncate: %v", err)
+	}
+
+	kv = mustOpen(t, dir, nil)
+	defer kv.Close()
+	checkKeys(t, kv, map[string]string{"a": "aa"})
+
+	// The keyspace remains writable after recovery.
+	if err := kv.Put(ctx, blob.PutOptions{Key: "c", Data: []byte("cc")}); err != nil {
+		t.Fatalf("Put c: unexpected error: %v", err)
+	}
+	checkKeys(t, kv, map[string]string{"a": "aa", "c": "cc"})
+}
+
+func TestCompact(t *testing.T) {
+	ctx := context.Background()
+	kv := mustOpen(t, t.TempDir(), &packstore.Options{MaxPackBytes: 1 << 10})
+	defer kv.Close()
+
+	want := make(map[string]string)
+	for i := range 100 {
+		key, val := fmt.Sprintf("key-%03d", i), fmt.Sprintf("value %d", i)
+		if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte(val)}); err != nil {
+			t.Fatalf("Put %q: unexpected error: %v", key, err)
+		}
+		want[key] = val
+	}
+
+	// With no garbage and full packs, compaction is not needed.
+	if ok, err := kv.Compact(ctx, nil); err != nil || ok {
+		t.Errorf("Compact: got (%v, %v), want (false, nil)", ok, err)
+	}
+
+	// Delete most of the keys, so that compaction is worthwhile.
+	for i := range 80 {
+		key := fmt.Sprintf("key-%03d", i)
+		if err := kv.Delete(ctx, key); err != nil {
+			t.Fatalf("Delete %q: unexpected error: %v", key, err)
+		}
+		delete(want, key)
+	}
+	before := kv.Stats()
+
+	// Write concurrently with compaction via the progress callback, which
+	// runs while compaction is in progress.
+	var prog []packstore.CompactProgress
+	opts := &packstore.CompactOptions{
+		Progress: func(p packstore.CompactProgress) {
+			prog = append(prog, p)
+			if p.Keys == 5 {
+				kv.Put(ctx, blob.PutOptions{Key: "key-999", Data: []byte("late"), Replace: true})
+				kv.Delete(ctx, "key-095")
+			}
+		},
+	}
+	want["key-999"] = "late"
+	delete(want, "key-095")
+
+	if ok, err := kv.Compact(ctx, opts); err != nil || !ok {
+		t.Fatalf("Compact: got (%v, %v), want (true, nil)", ok, err)
+	}
+	checkKeys(t, kv, want)
+
+	after := kv.Stats()
+	if after.TotalBytes >= before.TotalBytes {
+		t.Errorf("Compact: total bytes %d, want < %d", after.TotalBytes, before.TotalBytes)
+	}
+	if len(prog) == 0 {
+		t.Fatal("Compact: no progress reported")
+	}
+	if last := prog[len(prog)-1]; last.TotalKeys != 20 || last.Keys != 20 {
+		t.Errorf("Final progress: got %+v, want 20 of 20 keys", last)
+	}
+}
+
+func TestBackgroundCompact(t *testing.T) {
+	ctx := context.Background()
+	done := make(chan struct{}, 1)
+	s, err := packstore.New(t.TempDir(), &packstore.Options{
+		CompactInterval: 5 * time.Millisecond,
+		Compact: &packstore.CompactOptions{
+			Progress: func(packstore.CompactProgress) {
+				select {
+				case done <- struct{}{}:
+				default:
+				}
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New: unexpected error: %v", err)
+	}
+	kv := storetest.SubKV(t, ctx, s, "test").(*packstore.KV)
+	for _, key := range []string{"a", "b", "c", "d"} {
+		if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte(key)}); err != nil {
+			t.Fatalf("Put %q: unexpected error: %v", key, err)
+		}
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		if err := kv.Delete(ctx, key); err != nil {
+			t.Fatalf("Delete %q: unexpected error: %v", key, err)
+		}
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for background compaction")
+	}
+	if err := s.Close(ctx); err != nil {
+		t.Fatalf("Close: unexpected error: %v", err)
+	}
+}
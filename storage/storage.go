@@ -0,0 +1,113 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package storage provides a registry of [blob.StoreCloser] implementations
+// that can be opened from a connection string.
+//
+// A connection string has the form "scheme:addr", where scheme names a
+// registered [Opener] and addr is passed to it uninterpreted. For example:
+//
+//	storage.Register("file", filestore.Opener)
+//	s, err := storage.Open(ctx, "file:///path/to/store")
+//
+// calls filestore.Opener with the address "///path/to/store".
+//
+// No backends are registered by default: A program registers the ones it
+// supports, so that it depends only on the implementations it uses.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/creachadair/ffs/blob"
+)
+
+// An Opener constructs a [blob.StoreCloser] from an address. The format of
+// the address is defined by the implementation. The Opener functions exported
+// by the storage implementations in this module, such as filestore.Opener,
+// satisfy this type.
+type Opener func(ctx context.Context, addr string) (blob.StoreCloser, error)
+
+// A Registry maps scheme names to openers. A zero Registry is empty and ready
+// for use. A Registry is safe for concurrent use by multiple goroutines.
+type Registry struct {
+	μ sync.Mutex
+	m map[string]Opener
+}
+
+// Register adds o to r under the given scheme name. It panics if scheme is
+// empty or contains a colon, if o == nil, or if scheme is already registered.
+func (r *Registry) Register(scheme string, o Opener) {
+	if scheme == "" || strings.Contains(scheme, ":") {
+		panic(fmt.Sprintf("register: invalid scheme %q", scheme))
+	} else if o == nil {
+		panic("register: nil opener")
+	}
+	r.μ.Lock()
+	defer r.μ.Unlock()
+	if _, ok := r.m[scheme]; ok {
+		panic(fmt.Sprintf("register: duplicate scheme %q", scheme))
+	}
+	if r.m == nil {
+		r.m = make(map[string]Opener)
+	}
+	r.m[scheme] = o
+}
+
+// Open opens a store from a connection string of the form "scheme:addr",
+// using the opener registered for scheme. If the string has no colon, the
+// whole string is the scheme and the address is empty.
+func (r *Registry) Open(ctx context.Context, spec string) (blob.StoreCloser, error) {
+	scheme, addr, _ := strings.Cut(spec, ":")
+	r.μ.Lock()
+	o, ok := r.m[scheme]
+	r.μ.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("open %q: unknown storage scheme %q", spec, scheme)
+	}
+	s, err := o(ctx, addr)
+	if err != nil {
+		return nil, fmt.Errorf("open %q: %w", spec, err)
+	}
+	return s, nil
+}
+
+// Schemes returns the registered scheme names in lexicographic order.
+func (r *Registry) Schemes() []string {
+	r.μ.Lock()
+	defer r.μ.Unlock()
+	return slices.Sorted(maps.Keys(r.m))
+}
+
+// defaultRegistry is used by the package-level functions.
+var defaultRegistry Registry
+
+// Register adds o to the default registry under the given scheme name.
+// See [Registry.Register].
+func Register(scheme string, o Opener) { defaultRegistry.Register(scheme, o) }
+
+// Open opens a store from a connection string using the default registry.
+// See [Registry.Open].
+func Open(ctx context.Context, spec string) (blob.StoreCloser, error) {
+	return defaultRegistry.Open(ctx, spec)
+}
+
+// Schemes returns the scheme names in the default registry in lexicographic
+// order.
+func Schemes() []string { return defaultRegistry.Schemes() }
@@ -0,0 +1,83 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/blob/memstore"
+	"github.com/creachadair/ffs/storage"
+	"github.com/creachadair/ffs/storage/filestore"
+	"github.com/google/go-cmp/cmp"
+)
+
+var _ storage.Opener = filestore.Opener
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	var r storage.Registry
+	r.Register("mem", memstore.Opener)
+	r.Register("file", filestore.Opener)
+
+	var gotAddr string
+	r.Register("test", func(_ context.Context, addr string) (blob.StoreCloser, error) {
+		gotAddr = addr
+		return nil, errors.New("bogus")
+	})
+
+	if diff := cmp.Diff(r.Schemes(), []string{"file", "mem", "test"}); diff != "" {
+		t.Errorf("Schemes (-got, +want):\n%s", diff)
+	}
+
+	t.Run("Open", func(t *testing.T) {
+		for _, spec := range []string{"mem", "mem:", "file://" + filepath.Join(t.TempDir(), "store")} {
+			s, err := r.Open(ctx, spec)
+			if err != nil {
+				t.Fatalf("Open %q: unexpected error: %v", spec, err)
+			}
+			if err := s.Close(ctx); err != nil {
+				t.Errorf("Close %q: %v", spec, err)
+			}
+		}
+	})
+
+	t.Run("Address", func(t *testing.T) {
+		if _, err := r.Open(ctx, "test://host:123/path"); err == nil {
+			t.Error("Open: got nil, want error")
+		}
+		if want := "//host:123/path"; gotAddr != want {
+			t.Errorf("Opener address: got %q, want %q", gotAddr, want)
+		}
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		if s, err := r.Open(ctx, "nonesuch:foo"); err == nil {
+			t.Errorf("Open: got %v, want error", s)
+		}
+	})
+
+	t.Run("Duplicate", func(t *testing.T) {
+		defer func() {
+			if x := recover(); x == nil {
+				t.Error("Register duplicate: did not panic")
+			}
+		}()
+		r.Register("mem", memstore.Opener)
+	})
+}
@@ -0,0 +1,190 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package timeoutstore implements a wrapper for a [blob.Store] that applies a
+// default timeout to each operation on its keyspaces, so that a slow or stuck
+// backend does not stall callers indefinitely.
+//
+// Operations are grouped into classes, each with its own timeout:
+//
+//   - Read: Get and Has
+//   - Write: Put and Delete
+//   - List: List and Len
+//
+// A timeout bounds each call, in addition to any deadline the caller's
+// context already has; whichever ends first applies. For List, the timeout
+// bounds the whole iteration. An operation that times out reports an error
+// from the base store, typically [context.DeadlineExceeded].
+package timeoutstore
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"time"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/storage/dbkey"
+	"github.com/creachadair/ffs/storage/monitor"
+)
+
+// Options are optional settings for a [Store] or [KV]. A nil *Options is
+// ready for use and provides default values as described. A timeout ≤ 0
+// means operations of that class have no timeout.
+type Options struct {
+	Read  time.Duration // timeout for Get and Has
+	Write time.Duration // timeout for Put and Delete
+	List  time.Duration // timeout for List and Len
+}
+
+func (o *Options) read() time.Duration {
+	if o == nil {
+		return 0
+	}
+	return o.Read
+}
+
+func (o *Options) write() time.Duration {
+	if o == nil {
+		return 0
+	}
+	return o.Write
+}
+
+func (o *Options) list() time.Duration {
+	if o == nil {
+		return 0
+	}
+	return o.List
+}
+
+// withTimeout returns a context derived from ctx with the given timeout. If
+// d ≤ 0, it returns ctx unmodified.
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// Store implements the [blob.StoreCloser] interface by delegating to a base
+// store. Keyspaces derived from the store are of concrete type [*KV].
+type Store struct {
+	*monitor.M[state, *KV]
+}
+
+type state struct {
+	base blob.Store
+	opts *Options
+}
+
+// New constructs a [blob.Store] wrapper that delegates to base and applies
+// the timeouts from opts to operations on its keyspaces. New will panic if
+// base == nil.
+func New(base blob.Store, opts *Options) Store {
+	if base == nil {
+		panic("base is nil")
+	}
+	return Store{M: monitor.New(monitor.Config[state, *KV]{
+		DB: state{base: base, opts: opts},
+		NewKV: func(ctx context.Context, db state, _ dbkey.Prefix, name string) (*KV, error) {
+			kv, err := db.base.KV(ctx, name)
+			if err != nil {
+				return nil, err
+			}
+			return NewKV(kv, db.opts), nil
+		},
+		NewSub: func(ctx context.Context, db state, _ dbkey.Prefix, name string) (state, error) {
+			sub, err := db.base.Sub(ctx, name)
+			if err != nil {
+				return state{}, err
+			}
+			return state{base: sub, opts: db.opts}, nil
+		},
+	})}
+}
+
+// Close implements part of the [blob.StoreCloser] interface.
+func (s Store) Close(ctx context.Context) error {
+	kerr := s.M.CloseAll(ctx)
+	if c, ok := s.M.DB.base.(blob.Closer); ok {
+		return errors.Join(kerr, c.Close(ctx))
+	}
+	return kerr
+}
+
+// KV implements the [blob.KV] interface by delegating to a base keyspace,
+// applying a timeout to each operation.
+type KV struct {
+	base blob.KV
+	opts *Options
+}
+
+// NewKV constructs a [KV] that delegates to base, with settings from opts.
+// NewKV will panic if base == nil.
+func NewKV(base blob.KV, opts *Options) *KV {
+	if base == nil {
+		panic("base is nil")
+	}
+	return &KV{base: base, opts: opts}
+}
+
+// Get implements part of [blob.KV].
+func (s *KV) Get(ctx context.Context, key string) ([]byte, error) {
+	ctx, cancel := withTimeout(ctx, s.opts.read())
+	defer cancel()
+	return s.base.Get(ctx, key)
+}
+
+// Has implements part of [blob.KV].
+func (s *KV) Has(ctx context.Context, keys ...string) (blob.KeySet, error) {
+	ctx, cancel := withTimeout(ctx, s.opts.read())
+	defer cancel()
+	return s.base.Has(ctx, keys...)
+}
+
+// Put implements part of [blob.KV].
+func (s *KV) Put(ctx context.Context, opts blob.PutOptions) error {
+	ctx, cancel := withTimeout(ctx, s.opts.write())
+	defer cancel()
+	return s.base.Put(ctx, opts)
+}
+
+// Delete implements part of [blob.KV].
+func (s *KV) Delete(ctx context.Context, key string) error {
+	ctx, cancel := withTimeout(ctx, s.opts.write())
+	defer cancel()
+	return s.base.Delete(ctx, key)
+}
+
+// List implements part of [blob.KV]. The timeout applies to the whole
+// iteration, including the time spent by the caller between keys.
+func (s *KV) List(ctx context.Context, start string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		ctx, cancel := withTimeout(ctx, s.opts.list())
+		defer cancel()
+		for key, err := range s.base.List(ctx, start) {
+			if !yield(key, err) {
+				return
+			}
+		}
+	}
+}
+
+// Len implements part of [blob.KV].
+func (s *KV) Len(ctx context.Context) (int64, error) {
+	ctx, cancel := withTimeout(ctx, s.opts.list())
+	defer cancel()
+	return s.base.Len(ctx)
+}
@@ -0,0 +1,101 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package timeoutstore_test
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"testing"
+	"time"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/blob/memstore"
+	"github.com/creachadair/ffs/blob/storetest"
+	"github.com/creachadair/ffs/storage/timeoutstore"
+)
+
+var (
+	_ blob.KV          = (*timeoutstore.KV)(nil)
+	_ blob.StoreCloser = timeoutstore.Store{}
+)
+
+func TestStore(t *testing.T) {
+	s := timeoutstore.New(memstore.New(nil), &timeoutstore.Options{
+		Read:  time.Minute,
+		Write: time.Minute,
+		List:  time.Minute,
+	})
+	storetest.Run(t, s)
+}
+
+// stuckKV is a blob.KV whose operations block until their context ends.
+type stuckKV struct{ blob.KV }
+
+func (stuckKV) Get(ctx context.Context, _ string) ([]byte, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (stuckKV) Put(ctx context.Context, _ blob.PutOptions) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (stuckKV) List(ctx context.Context, _ string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		<-ctx.Done()
+		yield("", ctx.Err())
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	ctx := context.Background()
+	const short = 10 * time.Millisecond
+	kv := timeoutstore.NewKV(stuckKV{memstore.NewKV()}, &timeoutstore.Options{
+		Read:  short,
+		Write: short,
+		List:  short,
+	})
+
+	check := func(op string, err error) {
+		t.Helper()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s: got %v, want %v", op, err, context.DeadlineExceeded)
+		}
+	}
+	_, err := kv.Get(ctx, "key")
+	check("Get", err)
+	check("Put", kv.Put(ctx, blob.PutOptions{Key: "key", Data: []byte("data")}))
+	for _, err := range kv.List(ctx, "") {
+		check("List", err)
+	}
+
+	// An earlier deadline on the caller's context takes precedence.
+	slow := timeoutstore.NewKV(stuckKV{memstore.NewKV()}, &timeoutstore.Options{Read: time.Hour})
+	cctx, cancel := context.WithTimeout(ctx, short)
+	defer cancel()
+	_, err = slow.Get(cctx, "key")
+	check("Get with deadline", err)
+
+	// Without a timeout, operations are not bounded.
+	free := timeoutstore.NewKV(memstore.NewKV(), nil)
+	if err := free.Put(ctx, blob.PutOptions{Key: "key", Data: []byte("data")}); err != nil {
+		t.Errorf("Put: unexpected error: %v", err)
+	}
+	if got, err := free.Get(ctx, "key"); err != nil || string(got) != "data" {
+		t.Errorf("Get: got (%q, %v), want data", got, err)
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package trashstore implements a wrapper for a [blob.Store] in which deletes
+// are "soft": Instead of discarding the data for a deleted key, the wrapper
+// moves the blob into a trash keyspace along with a tombstone recording where
+// it came from and when it was deleted.
+//
+// Blobs in the trash can be put back with [KV.Restore], or permanently removed
+// with [Store.Purge].
+package trashstore
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"iter"
+	"path"
+	"time"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/storage/dbkey"
+	"github.com/creachadair/ffs/storage/monitor"
+)
+
+// Store implements the [blob.StoreCloser] interface by delegating to a base
+// store. Deletes from [blob.KV] instances derived from the store are moved to
+// a trash keyspace rather than discarded.
+type Store struct {
+	*monitor.M[state, *KV]
+}
+
+type state struct {
+	base  blob.Store
+	trash blob.KV
+	path  string // slash-separated substore names from the root
+}
+
+// New constructs a [blob.Store] wrapper that delegates to base and moves
+// deleted blobs into trash. New will panic if base == nil or trash == nil.
+//
+// The trash keyspace is shared by all the keyspaces derived from the store,
+// and should not be one of the keyspaces of base that the caller will access
+// via the wrapper.
+func New(base blob.Store, trash blob.KV) Store {
+	if base == nil {
+		panic("base is nil")
+	} else if trash == nil {
+		panic("trash is nil")
+	}
+	return Store{M: monitor.New(monitor.Config[state, *KV]{
+		DB: state{base: base, trash: trash},
+		NewKV: func(ctx context.Context, db state, pfx dbkey.Prefix, name string) (*KV, error) {
+			kv, err := db.base.KV(ctx, name)
+			if err != nil {
+				return nil, err
+			}
+			return &KV{base: kv, trash: db.trash, pfx: pfx, space: path.Join(db.path, name)}, nil
+		},
+		NewSub: func(ctx context.Context, db state, _ dbkey.Prefix, name string) (state, error) {
+			sub, err := db.base.Sub(ctx, name)
+			if err != nil {
+				return state{}, err
+			}
+			return state{base: sub, trash: db.trash, path: path.Join(db.path, name)}, nil
+		},
+	})}
+}
+
+// Close implements part of the [blob.StoreCloser] interface.
+func (s Store) Close(ctx context.Context) error {
+	kerr := s.M.CloseAll(ctx)
+	var berr, terr error
+	if c, ok := s.M.DB.base.(blob.Closer); ok {
+		berr = c.Close(ctx)
+	}
+	if c, ok := s.M.DB.trash.(blob.Closer); ok {
+		terr = c.Close(ctx)
+	}
+	return errors.Join(kerr, berr, terr)
+}
+
+// Trash returns the trash keyspace used by s.
+func (s Store) Trash() blob.KV { return s.M.DB.trash }
+
+// Tombstones returns an iterator over the tombstones of all the blobs
+// currently in the trash, in no particular order.
+func (s Store) Tombstones(ctx context.Context) iter.Seq2[Tombstone, error] {
+	return func(yield func(Tombstone, error) bool) {
+		for tkey, err := range s.M.DB.trash.List(ctx, "") {
+			if err != nil {
+				yield(Tombstone{}, err)
+				return
+			}
+			ts, _, err := loadTomb(ctx, s.M.DB.trash, tkey)
+			if blob.IsKeyNotFound(err) {
+				continue // purged or restored while we were listing
+			} else if err != nil {
+				yield(Tombstone{}, err)
+				return
+			}
+			if !yield(ts, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Purge permanently removes from the trash all blobs that were deleted more
+// than olderThan before the present. It reports the number of blobs removed.
+// If olderThan ≤ 0, all blobs in the trash are removed.
+func (s Store) Purge(ctx context.Context, olderThan time.Duration) (int, error) {
+	if olderThan <= 0 {
+		nr, err := blob.DeleteRange(ctx, s.M.DB.trash, "", "")
+		return int(nr), err
+	}
+	cutoff := time.Now().Add(-olderThan)
+
+	// Collect the victims first, since we cannot modify the trash while
+	// listing it.
+	var victims []string
+	for tkey, err := range s.M.DB.trash.List(ctx, "") {
+		if err != nil {
+			return 0, err
+		}
+		ts, _, err := loadTomb(ctx, s.M.DB.trash, tkey)
+		if blob.IsKeyNotFound(err) {
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+		if ts.Deleted.Before(cutoff) {
+			victims = append(victims, tkey)
+		}
+	}
+	var nr int
+	for _, tkey := range victims {
+		if err := s.M.DB.trash.Delete(ctx, tkey); err == nil {
+			nr++
+		} else if !blob.IsKeyNotFound(err) {
+			return nr, err
+		}
+	}
+	return nr, nil
+}
+
+// A Tombstone records the origin of a blob that was moved to the trash.
+type Tombstone struct {
+	Key      string    // the key of the blob in its original keyspace
+	Keyspace string    // the slash-separated path of the original keyspace
+	Deleted  time.Time // when the blob was moved to the trash
+}
+
+// KV implements the [blob.KV] interface by delegating to a base keyspace.
+// Deleted keys are moved to the trash keyspace of the enclosing [Store].
+type KV struct {
+	base  blob.KV
+	trash blob.KV
+	pfx   dbkey.Prefix // the key prefix for this keyspace in the trash
+	space string       // the name of this keyspace, for tombstones
+}
+
+// Get implements part of [blob.KV]. It delegates to the base store.
+func (s *KV) Get(ctx context.Context, key string) ([]byte, error) { return s.base.Get(ctx, key) }
+
+// Has implements part of [blob.KV]. It delegates to the base store.
+func (s *KV) Has(ctx context.Context, keys ...string) (blob.KeySet, error) {
+	return s.base.Has(ctx, keys...)
+}
+
+// Put implements part of [blob.KV]. It delegates to the base store.
+func (s *KV) Put(ctx context.Context, opts blob.PutOptions) error { return s.base.Put(ctx, opts) }
+
+// List implements part of [blob.KV]. It delegates to the base store.
+// Keys in the trash are not listed.
+func (s *KV) List(ctx context.Context, start string) iter.Seq2[string, error] {
+	return s.base.List(ctx, start)
+}
+
+// Len implements part of [blob.KV]. It delegates to the base store.
+// Keys in the trash are not counted.
+func (s *KV) Len(ctx context.Context) (int64, error) { return s.base.Len(ctx) }
+
+// Delete implements part of [blob.KV]. The blob is copied to the trash with a
+// tombstone before it is removed from the base store. If the key was already
+// in the trash from an earlier deletion, the older copy is replaced.
+func (s *KV) Delete(ctx context.Context, key string) error {
+	data, err := s.base.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err := s.trash.Put(ctx, blob.PutOptions{
+		Key: s.pfx.Add(key),
+		Data: encodeTomb(Tombstone{
+			Key:      key,
+			Keyspace: s.space,
+			Deleted:  time.Now(),
+		}, data),
+		Replace: true,
+	}); err != nil {
+		return err
+	}
+	return s.base.Delete(ctx, key)
+}
+
+// Restore moves the most recently deleted blob for key from the trash back to
+// the keyspace. If key is not in the trash, Restore reports ErrKeyNotFound.
+// If key already exists in the keyspace, Restore reports ErrKeyExists and the
+// trash is not modified.
+func (s *KV) Restore(ctx context.Context, key string) error {
+	tkey := s.pfx.Add(key)
+	_, data, err := loadTomb(ctx, s.trash, tkey)
+	if blob.IsKeyNotFound(err) {
+		return blob.KeyNotFound(key)
+	} else if err != nil {
+		return err
+	}
+	if err := s.base.Put(ctx, blob.PutOptions{Key: key, Data: data}); err != nil {
+		return err
+	}
+	if err := s.trash.Delete(ctx, tkey); err != nil && !blob.IsKeyNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// errBadTomb is reported when a trash entry cannot be decoded.
+var errBadTomb = errors.New("invalid tombstone")
+
+// encodeTomb packs a tombstone and blob data into a trash record:
+//
+//	uvarint len(keyspace) | keyspace | uvarint len(key) | key | varint unix-nanos | data
+func encodeTomb(ts Tombstone, data []byte) []byte {
+	buf := make([]byte, 0, 3*binary.MaxVarintLen64+len(ts.Keyspace)+len(ts.Key)+len(data))
+	buf = binary.AppendUvarint(buf, uint64(len(ts.Keyspace)))
+	buf = append(buf, ts.Keyspace...)
+	buf = binary.AppendUvarint(buf, uint64(len(ts.Key)))
+	buf = append(buf, ts.Key...)
+	buf = binary.AppendVarint(buf, ts.Deleted.UnixNano())
+	return append(buf, data...)
+}
+
+// decodeTomb unpacks a trash record produced by encodeTomb.
+func decodeTomb(rec []byte) (Tombstone, []byte, error) {
+	var ts Tombstone
+	space, rest, ok := cutString(rec)
+	if !ok {
+		return ts, nil, errBadTomb
+	}
+	key, rest, ok := cutString(rest)
+	if !ok {
+		return ts, nil, errBadTomb
+	}
+	nanos, n := binary.Varint(rest)
+	if n <= 0 {
+		return ts, nil, errBadTomb
+	}
+	ts.Keyspace = space
+	ts.Key = key
+	ts.Deleted = time.Unix(0, nanos)
+	return ts, rest[n:], nil
+}
+
+func cutString(buf []byte) (string, []byte, bool) {
+	v, n := binary.Uvarint(buf)
+	if n <= 0 || v > uint64(len(buf)-n) {
+		return "", nil, false
+	}
+	end := n + int(v)
+	return string(buf[n:end]), buf[end:], true
+}
+
+func loadTomb(ctx context.Context, trash blob.KV, tkey string) (Tombstone, []byte, error) {
+	rec, err := trash.Get(ctx, tkey)
+	if err != nil {
+		return Tombstone{}, nil, err
+	}
+	return decodeTomb(rec)
+}
@@ -0,0 +1,111 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trashstore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/blob/memstore"
+	"github.com/creachadair/ffs/blob/storetest"
+	"github.com/creachadair/ffs/storage/trashstore"
+)
+
+var (
+	_ blob.KV          = (*trashstore.KV)(nil)
+	_ blob.StoreCloser = trashstore.Store{}
+)
+
+func TestStore(t *testing.T) {
+	s := trashstore.New(memstore.New(nil), memstore.NewKV())
+	storetest.Run(t, s)
+}
+
+func TestTrash(t *testing.T) {
+	ctx := context.Background()
+	s := trashstore.New(memstore.New(nil), memstore.NewKV())
+	kv := storetest.SubKV(t, ctx, s, "sub", "test").(*trashstore.KV)
+
+	mustPut := func(key, val string) {
+		t.Helper()
+		if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte(val)}); err != nil {
+			t.Fatalf("Put %q: unexpected error: %v", key, err)
+		}
+	}
+	checkTrash := func(want int) {
+		t.Helper()
+		var got int
+		for ts, err := range s.Tombstones(ctx) {
+			if err != nil {
+				t.Fatalf("Tombstones: unexpected error: %v", err)
+			}
+			if ts.Keyspace != "sub/test" {
+				t.Errorf("Tombstone %q: got keyspace %q, want %q", ts.Key, ts.Keyspace, "sub/test")
+			}
+			got++
+		}
+		if got != want {
+			t.Errorf("Tombstones: got %d, want %d", got, want)
+		}
+	}
+
+	mustPut("apple", "red")
+	mustPut("pear", "green")
+	mustPut("plum", "purple")
+	checkTrash(0)
+
+	for _, key := range []string{"apple", "pear", "plum"} {
+		if err := kv.Delete(ctx, key); err != nil {
+			t.Fatalf("Delete %q: unexpected error: %v", key, err)
+		}
+	}
+	if err := kv.Delete(ctx, "nonesuch"); !blob.IsKeyNotFound(err) {
+		t.Errorf("Delete nonesuch: got %v, want %v", err, blob.ErrKeyNotFound)
+	}
+	if n, err := kv.Len(ctx); err != nil || n != 0 {
+		t.Errorf("Len: got (%d, %v), want (0, nil)", n, err)
+	}
+	checkTrash(3)
+
+	// Restore a key and verify that its value is back.
+	if err := kv.Restore(ctx, "pear"); err != nil {
+		t.Fatalf("Restore pear: unexpected error: %v", err)
+	}
+	if got, err := kv.Get(ctx, "pear"); err != nil || string(got) != "green" {
+		t.Errorf("Get pear: got (%q, %v), want (green, nil)", got, err)
+	}
+	if err := kv.Restore(ctx, "pear"); !blob.IsKeyNotFound(err) {
+		t.Errorf("Restore pear again: got %v, want %v", err, blob.ErrKeyNotFound)
+	}
+	checkTrash(2)
+
+	// A restore that would clobber an existing key fails.
+	mustPut("apple", "yellow")
+	if err := kv.Restore(ctx, "apple"); !blob.IsKeyExists(err) {
+		t.Errorf("Restore apple: got %v, want %v", err, blob.ErrKeyExists)
+	}
+	checkTrash(2)
+
+	// Nothing in the trash is a day old.
+	if n, err := s.Purge(ctx, 24*time.Hour); err != nil || n != 0 {
+		t.Errorf("Purge(1d): got (%d, %v), want (0, nil)", n, err)
+	}
+	if n, err := s.Purge(ctx, 0); err != nil || n != 2 {
+		t.Errorf("Purge(0): got (%d, %v), want (2, nil)", n, err)
+	}
+	checkTrash(0)
+}
@@ -15,11 +15,20 @@
 // Package wbstore implements a wrapper for a [blob.Store] that caches
 // non-replacement writes of in a buffer and pushes them to the base store
 // concurrently in the background.
+//
+// All the keyspaces derived from a wrapped store, including those of its
+// substores, share a single buffer, and the KV and CAS views of a keyspace
+// share its state. Reads through any view of a keyspace observe the writes
+// that have completed through any other view of the same keyspace, whether
+// or not they have yet been written back to the base store: A blob that has
+// been put can be read and is reported by Has, List, and Len; a replacement
+// hides any older buffered value; and a deleted blob stays deleted.
 package wbstore
 
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/creachadair/ffs/blob"
 	"github.com/creachadair/ffs/storage/dbkey"
@@ -44,11 +53,12 @@ type wbState struct {
 
 // Close implements part of the [blob.StoreCloser] interface.
 func (s Store) Close(ctx context.Context) error {
+	kerr := s.M.CloseAll(ctx)
 	var berr error
 	if c, ok := s.M.DB.base.(blob.Closer); ok {
 		berr = c.Close(ctx)
 	}
-	return errors.Join(berr, s.M.DB.wb.Close(ctx))
+	return errors.Join(kerr, berr, s.M.DB.wb.Close(ctx))
 }
 
 // New constructs a [blob.Store] wrapper that delegates to base and uses buf as
@@ -70,7 +80,9 @@ func New(ctx context.Context, base blob.Store, buf blob.KV) Store {
 		nempty:   msync.NewFlag[any](),
 		bufClean: trigger.New(),
 		kvs:      make(map[dbkey.Prefix]blob.KV),
+		active:   make(map[string]chan struct{}),
 	}
+	w.timeout.Store(int64(defaultWriteTimeout))
 	w.nempty.Set(nil) // prime
 	g := taskgroup.Go(func() error { return w.run(ctx) })
 
@@ -103,5 +115,18 @@ func New(ctx context.Context, base blob.Store, buf blob.KV) Store {
 // Buffer returns the buffer store used by s.
 func (s Store) Buffer() blob.KV { return s.M.DB.wb.buffer() }
 
+// SetBudget charges the memory used by blobs buffered by s to b. When b has
+// no room to buffer a blob, s writes it through to the base store instead.
+// Blobs that were already in the buffer when s was created are not charged.
+// SetBudget must be called before s is first used, and at most once.
+func (s Store) SetBudget(b *blob.Budget) { s.M.DB.wb.setBudget(b) }
+
+// SetWriteTimeout sets the time allowed for the background writer to write a
+// single blob to the base store. A write that takes longer is abandoned and
+// retried, up to a limited number of times. If d ≤ 0, writes have no time
+// limit. The default is 10 seconds; a longer limit may be needed for a slow
+// base store or for large blobs.
+func (s Store) SetWriteTimeout(d time.Duration) { s.M.DB.wb.timeout.Store(int64(max(d, 0))) }
+
 // Sync blocks until the buffer is empty or ctx ends.
 func (s Store) Sync(ctx context.Context) error { return s.M.DB.wb.Sync(ctx) }
@@ -17,10 +17,13 @@ package wbstore_test
 import (
 	"context"
 	"sort"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/creachadair/ffs/blob"
 	"github.com/creachadair/ffs/blob/memstore"
+	"github.com/creachadair/ffs/blob/storetest"
 	"github.com/creachadair/ffs/storage/dbkey"
 	"github.com/creachadair/ffs/storage/wbstore"
 	"github.com/google/go-cmp/cmp"
@@ -51,6 +54,12 @@ func (s slowKV) CASPut(ctx context.Context, data []byte) (string, error) {
 	}
 }
 
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	st := wbstore.New(ctx, memstore.New(nil), memstore.NewKV())
+	storetest.Run(t, st)
+}
+
 func TestStore(t *testing.T) {
 	ctx := context.Background()
 
@@ -201,3 +210,174 @@ func TestStore(t *testing.T) {
 		t.Errorf("Close: unexpected error: %v", err)
 	}
 }
+
+func TestReadYourWrites(t *testing.T) {
+	ctx := context.Background()
+	phys := memstore.NewKV()
+	next := make(chan chan struct{}, 1)
+	base := memstore.New(func() blob.KV { return slowKV{KV: phys, next: next} })
+	st := wbstore.New(ctx, base, memstore.NewKV())
+	defer st.Close(ctx)
+
+	kv := storetest.SubKV(t, ctx, st, "test")
+	cas := storetest.SubCAS(t, ctx, st, "test")
+	push := func() <-chan struct{} {
+		p := make(chan struct{})
+		next <- p
+		return p
+	}
+
+	// While the writeback is stalled, the buffered blob is visible through
+	// both views.
+	if err := kv.Put(ctx, blob.PutOptions{Key: "k", Data: []byte("old")}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if has, err := cas.Has(ctx, "k"); err != nil || !has.Has("k") || has.Len() != 1 {
+		t.Errorf("Has k: got (%v, %v), want ({k}, nil)", has, err)
+	}
+
+	// A replacement goes to the base and hides the buffered value.
+	p := push()
+	if err := kv.Put(ctx, blob.PutOptions{Key: "k", Data: []byte("new"), Replace: true}); err != nil {
+		t.Fatalf("Put replace: %v", err)
+	}
+	<-p
+	if got, err := cas.Get(ctx, "k"); err != nil || string(got) != "new" {
+		t.Errorf("Get k: got (%q, %v), want (new, nil)", got, err)
+	}
+	if err := st.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if got, err := phys.Get(ctx, "k"); err != nil || string(got) != "new" {
+		t.Errorf("Base k: got (%q, %v), want (new, nil)", got, err)
+	}
+
+	// A buffered blob that is deleted does not reappear in the base.
+	if err := kv.Put(ctx, blob.PutOptions{Key: "gone", Data: []byte("x")}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	push() // in case the writeback has already begun
+	if err := cas.Delete(ctx, "gone"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := st.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if _, err := kv.Get(ctx, "gone"); !blob.IsKeyNotFound(err) {
+		t.Errorf("Get gone: got %v, want %v", err, blob.ErrKeyNotFound)
+	}
+	if n, err := phys.Len(ctx); err != nil || n != 1 {
+		t.Errorf("Base len: got (%d, %v), want (1, nil)", n, err)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	ctx := context.Background()
+
+	phys := memstore.NewKV()
+	next := make(chan chan struct{})
+	base := memstore.New(func() blob.KV {
+		return slowKV{KV: phys, next: next}
+	})
+	buf := memstore.NewKV()
+	st := wbstore.New(ctx, base, buf)
+	defer st.Close(ctx)
+
+	budget := blob.NewBudget(10)
+	st.SetBudget(budget)
+	kv, err := st.KV(ctx, "test")
+	if err != nil {
+		t.Fatalf("Create test KV: %v", err)
+	}
+
+	// A small blob is buffered and charged to the budget.
+	if err := kv.Put(ctx, blob.PutOptions{Key: "small", Data: []byte("12345")}); err != nil {
+		t.Fatalf("Put small: %v", err)
+	}
+	if n, _ := buf.Len(ctx); n != 1 {
+		t.Errorf("Buffer length: got %d, want 1", n)
+	}
+	if got := budget.Used(); got != 5 {
+		t.Errorf("Budget used: got %d, want 5", got)
+	}
+
+	// Let writes to the base store proceed from here on.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			p := make(chan struct{})
+			select {
+			case <-stop:
+				return
+			case next <- p:
+				<-p
+			}
+		}
+	}()
+
+	// A blob that does not fit is written through to the base store.
+	if err := kv.Put(ctx, blob.PutOptions{Key: "large", Data: []byte("0123456789")}); err != nil {
+		t.Fatalf("Put large: %v", err)
+	}
+	if got, err := phys.Get(ctx, "large"); err != nil || string(got) != "0123456789" {
+		t.Errorf("Get large from base: got (%q, %v), want written through", got, err)
+	}
+	if got := budget.Used(); got > 5 {
+		t.Errorf("Budget used: got %d, want ≤ 5", got)
+	}
+
+	// Writing back the buffered blob releases its memory.
+	if err := st.Sync(ctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if got := budget.Used(); got != 0 {
+		t.Errorf("Budget used after sync: got %d, want 0", got)
+	}
+}
+
+// stallKV is a blob.KV whose first Put blocks until its context ends.
+type stallKV struct {
+	blob.KV
+	tries *atomic.Int32
+}
+
+func (s stallKV) Put(ctx context.Context, opts blob.PutOptions) error {
+	if s.tries.Add(1) == 1 {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return s.KV.Put(ctx, opts)
+}
+
+func TestWriteTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	phys := memstore.NewKV()
+	var tries atomic.Int32
+	base := memstore.New(func() blob.KV { return stallKV{KV: phys, tries: &tries} })
+	st := wbstore.New(ctx, base, memstore.NewKV())
+	defer st.Close(ctx)
+	st.SetWriteTimeout(10 * time.Millisecond)
+
+	kv, err := st.KV(ctx, "test")
+	if err != nil {
+		t.Fatalf("Create test KV: %v", err)
+	}
+	if err := kv.Put(ctx, blob.PutOptions{Key: "key", Data: []byte("data")}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	// The stalled write is abandoned after the timeout and retried.
+	sctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := st.Sync(sctx); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if got, err := phys.Get(ctx, "key"); err != nil || string(got) != "data" {
+		t.Errorf("Get from base: got (%q, %v), want data", got, err)
+	}
+	if n := tries.Load(); n != 2 {
+		t.Errorf("Write attempts: got %d, want 2", n)
+	}
+}
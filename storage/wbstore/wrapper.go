@@ -37,6 +37,8 @@ type kvWrapper struct {
 func (s kvWrapper) Get(ctx context.Context, key string) ([]byte, error) {
 	if ok, err := s.wb.checkExited(); ok {
 		return nil, err
+	} else if err := blob.CheckKey(key); err != nil {
+		return nil, err
 	}
 
 	// Fetch from the buffer and the base store concurrently.
@@ -53,6 +55,12 @@ func (s kvWrapper) Get(ctx context.Context, key string) ([]byte, error) {
 		return nil, r.err
 	}
 	r = <-base
+	if blob.IsKeyNotFound(r.err) {
+		// The base fetch may have run before the blob was written back and
+		// removed from the buffer, so check the base again. Since the buffer
+		// no longer has the key, the base now has it if anyone does.
+		return s.kv.Get(ctx, key)
+	}
 	return r.bits, r.err
 }
 
@@ -70,30 +78,47 @@ func (s kvWrapper) Has(ctx context.Context, keys ...string) (blob.KeySet, error)
 	if err != nil {
 		return nil, fmt.Errorf("buffer stat: %w", err)
 	}
-	if len(out) == len(statKeys) {
-		return out, nil // we found everything
-	}
 
-	// Collect the keys that we did not find in the buffer (the "absent").
+	// Collect the keys that we did not find in the buffer (the "absent"), and
+	// report the ones we did find by their original names.
+	found := make(blob.KeySet, len(keys))
 	statKeys = statKeys[:0] // reuse
 	for _, key := range keys {
-		if !out.Has(key) {
+		if out.Has(s.pfx.Add(key)) {
+			found.Add(key)
+		} else {
 			statKeys = append(statKeys, key) // N.B. no need to decorate base keys
 		}
 	}
+	if len(statKeys) == 0 {
+		return found, nil // we found everything
+	}
 	base, err := s.kv.Has(ctx, statKeys...)
 	if err != nil {
 		return nil, fmt.Errorf("base stat: %w", err)
 	}
-	out.AddAll(base)
-	return out, nil
+	found.AddAll(base)
+	return found, nil
 }
 
 // Delete implements part of [blob.KV]. The key is deleted from both the buffer
 // and the base store, and succeeds as long as either of those operations
 // succeeds.
 func (s kvWrapper) Delete(ctx context.Context, key string) error {
-	cerr := s.wb.buffer().Delete(ctx, s.pfx.Add(key))
+	if err := blob.CheckKey(key); err != nil {
+		return err
+	}
+	tagged := s.pfx.Add(key)
+	cerr := s.wb.buffer().Delete(ctx, tagged)
+	if cerr == nil {
+		s.wb.release(tagged)
+	}
+
+	// If the key was being written back when we removed it from the buffer,
+	// wait for that to finish, lest it restore the key after we delete it.
+	if err := s.wb.waitWriteback(ctx, tagged); err != nil {
+		return err
+	}
 	berr := s.kv.Delete(ctx, key)
 	if cerr != nil && berr != nil {
 		return berr
@@ -107,10 +132,23 @@ func (s kvWrapper) Delete(ctx context.Context, key string) error {
 func (s kvWrapper) Put(ctx context.Context, opts blob.PutOptions) error {
 	if ok, err := s.wb.checkExited(); ok {
 		return err
+	} else if err := blob.CheckKey(opts.Key); err != nil {
+		return err
 	}
 	if opts.Replace {
-		// Don't buffer writes that request replacement.
-		return s.kv.Put(ctx, opts)
+		// Don't buffer writes that request replacement. Once the new value is
+		// in the base store, discard any buffered copy of an old value, so
+		// that it does not hide the new one.
+		if err := s.kv.Put(ctx, opts); err != nil {
+			return err
+		}
+		tagged := s.pfx.Add(opts.Key)
+		if err := s.wb.buffer().Delete(ctx, tagged); err == nil {
+			s.wb.release(tagged)
+		} else if !blob.IsKeyNotFound(err) {
+			return err
+		}
+		return nil
 	}
 
 	// Preflight check: If the underlying store already has the key, we do not
@@ -119,8 +157,18 @@ func (s kvWrapper) Put(ctx context.Context, opts blob.PutOptions) error {
 	if got, _ := s.kv.Has(ctx, opts.Key); got.Has(opts.Key) {
 		return blob.KeyExists(opts.Key)
 	}
-	opts.Key = s.pfx.Add(opts.Key)
+	tagged := s.pfx.Add(opts.Key)
+	charged, ok := s.wb.reserve(tagged, int64(len(opts.Data)))
+	if !ok {
+		// The memory budget has no room to buffer this blob, so write it
+		// through to the base store directly.
+		return s.kv.Put(ctx, opts)
+	}
+	opts.Key = tagged
 	if err := s.wb.buffer().Put(ctx, opts); err != nil {
+		if charged {
+			s.wb.release(tagged)
+		}
 		return err
 	}
 	s.wb.signal()
@@ -184,6 +232,9 @@ func (s kvWrapper) List(ctx context.Context, start string) iter.Seq2[string, err
 				yield("", err)
 				return
 			}
+			// The key may have been written back since we read the buffer.
+			buf.Remove(key)
+
 			// Pull out keys from the buffer that are between prev and key, and
 			// report them to the caller before sending key itself.
 			for _, p := range keysBetween(buf, prev, key) {
@@ -22,6 +22,7 @@ import (
 	"math/rand"
 	"net"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -50,12 +51,104 @@ type writer struct {
 
 	μ   sync.Mutex // protects the fields below
 	kvs map[dbkey.Prefix]blob.KV
+
+	// Tagged keys currently being written back, and a channel for each that
+	// is closed when its writeback is finished.
+	active map[string]chan struct{}
+
+	// If acct != nil, the blobs buffered by this writer are charged to a
+	// shared memory budget, and sizes records their sizes by tagged key.
+	acct  *blob.Account
+	sizes map[string]int64
+
+	// The time allowed for a single write to the base store before it is
+	// abandoned and retried, or 0 for no limit.
+	timeout atomic.Int64 // time.Duration
 }
 
+// defaultWriteTimeout is the default time allowed for a single write to the
+// base store before it is retried.
+const defaultWriteTimeout = 10 * time.Second
+
 func (w *writer) buffer() blob.KV { return w.buf }
 
+// setBudget charges the blobs buffered by w to b. When b runs short of memory,
+// it nudges the background writer, which frees memory as it writes back.
+func (w *writer) setBudget(b *blob.Budget) {
+	w.μ.Lock()
+	defer w.μ.Unlock()
+	w.acct = b.Register(func(int64) { w.signal() })
+	w.sizes = make(map[string]int64)
+}
+
+// reserve reports whether a blob of n bytes may be buffered at tagged, and
+// whether it charged the budget for it. If charged is true and the blob is
+// not buffered after all, the caller must call release for tagged.
+func (w *writer) reserve(tagged string, n int64) (charged, ok bool) {
+	w.μ.Lock()
+	acct := w.acct
+	_, held := w.sizes[tagged]
+	w.μ.Unlock()
+	if acct == nil || held {
+		return false, true // no budget, or already charged for a copy
+	} else if !acct.Reserve(n) {
+		return false, false
+	}
+	w.μ.Lock()
+	defer w.μ.Unlock()
+	if _, ok := w.sizes[tagged]; ok {
+		acct.Release(n) // a concurrent caller beat us to it
+		return false, true
+	}
+	w.sizes[tagged] = n
+	return true, true
+}
+
+// release releases the budget reserved for tagged, if any.
+func (w *writer) release(tagged string) {
+	w.μ.Lock()
+	defer w.μ.Unlock()
+	if n, ok := w.sizes[tagged]; ok {
+		w.acct.Release(n)
+		delete(w.sizes, tagged)
+	}
+}
+
 func (w *writer) signal() { w.nempty.Set(nil) }
 
+// beginWriteback records that tagged is being written back, and returns a
+// function to call when the writeback is finished.
+func (w *writer) beginWriteback(tagged string) func() {
+	done := make(chan struct{})
+	w.μ.Lock()
+	defer w.μ.Unlock()
+	w.active[tagged] = done
+	return func() {
+		w.μ.Lock()
+		defer w.μ.Unlock()
+		delete(w.active, tagged)
+		close(done)
+	}
+}
+
+// waitWriteback blocks until any writeback of tagged in progress is finished,
+// or until ctx ends. A writeback that begins after the blob is removed from
+// the buffer does not write it.
+func (w *writer) waitWriteback(ctx context.Context, tagged string) error {
+	w.μ.Lock()
+	done, ok := w.active[tagged]
+	w.μ.Unlock()
+	if !ok {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+		return nil
+	}
+}
+
 func (w *writer) addKV(pfx dbkey.Prefix, kv blob.KV) {
 	w.μ.Lock()
 	defer w.μ.Unlock()
@@ -162,6 +255,11 @@ func (w *writer) run(ctx context.Context) error {
 				// safe to delete the blob even if another copy was written while
 				// we worked, since the content will be the same.  If Get or Delete
 				// fails, it means someone deleted the key before us. That's fine.
+				//
+				// Record the writeback before reading the blob, so that a caller
+				// who deletes the blob from the buffer either prevents us from
+				// reading it, or sees that it must wait for us to finish.
+				defer w.beginWriteback(tagged)()
 
 				data, err := w.buf.Get(ctx, tagged) // N.B. tagged in the buffer
 				if blob.IsKeyNotFound(err) {
@@ -173,7 +271,10 @@ func (w *writer) run(ctx context.Context) error {
 				const maxTries = 3
 				for try := 1; ; try++ {
 					// An individual write should not be allowed to stall for too long.
-					rtctx, cancel := context.WithTimeoutCause(ctx, 10*time.Second, errSlowWriteRetry)
+					rtctx, cancel := ctx, context.CancelFunc(func() {})
+					if d := time.Duration(w.timeout.Load()); d > 0 {
+						rtctx, cancel = context.WithTimeoutCause(ctx, d, errSlowWriteRetry)
+					}
 					err := kv.Put(rtctx, blob.PutOptions{
 						Key:     key,
 						Data:    data,
@@ -193,6 +294,10 @@ func (w *writer) run(ctx context.Context) error {
 					}
 					time.Sleep(50 * time.Millisecond)
 				}
+				// The blob is now safe in the base store, so release its memory
+				// before removing it from the buffer; that way, an observer who
+				// finds the buffer empty also sees the memory released.
+				w.release(tagged)
 				if err := w.buf.Delete(ctx, tagged); err != nil && !blob.IsKeyNotFound(err) {
 					return err
 				}
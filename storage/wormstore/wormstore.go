@@ -0,0 +1,133 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package wormstore implements a wrapper for a [blob.Store] that makes the
+// values in designated keyspaces write-once: A key can be written if it does
+// not exist, but once written it cannot be replaced or deleted through the
+// wrapper. This gives "write once, read many" (WORM) guarantees for archival
+// deployments, for example for the content-addressed keyspace holding file
+// data, while other keyspaces, such as one holding root pointers, remain
+// mutable.
+package wormstore
+
+import (
+	"context"
+	"errors"
+	"path"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/storage/dbkey"
+	"github.com/creachadair/ffs/storage/monitor"
+)
+
+// ErrReadOnlyValue is reported by a write-once [KV] for an attempt to replace
+// or delete an existing value.
+var ErrReadOnlyValue = errors.New("value is write-once")
+
+// Store implements the [blob.StoreCloser] interface by delegating to a base
+// store. Keyspaces derived from the store are of concrete type [*KV].
+type Store struct {
+	*monitor.M[state, *KV]
+}
+
+type state struct {
+	base    blob.Store
+	protect func(string) bool
+	path    string // slash-separated substore names from the root
+}
+
+// New constructs a [blob.Store] wrapper that delegates to base. The keyspaces
+// for which protect reports true are write-once. The argument to protect is
+// the slash-separated path of the keyspace, consisting of the names of its
+// enclosing substores and the keyspace itself, for example "files" or
+// "sub/files". If protect == nil, all keyspaces are write-once. New will
+// panic if base == nil.
+func New(base blob.Store, protect func(space string) bool) Store {
+	if base == nil {
+		panic("base is nil")
+	}
+	if protect == nil {
+		protect = func(string) bool { return true }
+	}
+	return Store{M: monitor.New(monitor.Config[state, *KV]{
+		DB: state{base: base, protect: protect},
+		NewKV: func(ctx context.Context, db state, _ dbkey.Prefix, name string) (*KV, error) {
+			kv, err := db.base.KV(ctx, name)
+			if err != nil {
+				return nil, err
+			}
+			return &KV{KV: kv, writeOnce: db.protect(path.Join(db.path, name))}, nil
+		},
+		NewSub: func(ctx context.Context, db state, _ dbkey.Prefix, name string) (state, error) {
+			sub, err := db.base.Sub(ctx, name)
+			if err != nil {
+				return state{}, err
+			}
+			return state{base: sub, protect: db.protect, path: path.Join(db.path, name)}, nil
+		},
+	})}
+}
+
+// Close implements part of the [blob.StoreCloser] interface.
+func (s Store) Close(ctx context.Context) error {
+	kerr := s.M.CloseAll(ctx)
+	if c, ok := s.M.DB.base.(blob.Closer); ok {
+		return errors.Join(kerr, c.Close(ctx))
+	}
+	return kerr
+}
+
+// KV implements the [blob.KV] interface by delegating to a base keyspace.
+// If the keyspace is write-once, Put does not replace existing values, and
+// Delete is not permitted.
+type KV struct {
+	blob.KV
+	writeOnce bool
+}
+
+// NewKV constructs a write-once [KV] that delegates to base. NewKV will panic
+// if base == nil.
+func NewKV(base blob.KV) *KV {
+	if base == nil {
+		panic("base is nil")
+	}
+	return &KV{KV: base, writeOnce: true}
+}
+
+// IsWriteOnce reports whether s is write-once.
+func (s *KV) IsWriteOnce() bool { return s.writeOnce }
+
+// Put implements part of [blob.KV]. If s is write-once and opts.Replace is
+// true, the value is written only if the key does not already exist;
+// otherwise Put reports an error satisfying ErrReadOnlyValue.
+func (s *KV) Put(ctx context.Context, opts blob.PutOptions) error {
+	if !s.writeOnce || !opts.Replace {
+		return s.KV.Put(ctx, opts)
+	}
+	opts.Replace = false
+	err := s.KV.Put(ctx, opts)
+	if blob.IsKeyExists(err) {
+		return &blob.KeyError{Key: opts.Key, Err: ErrReadOnlyValue}
+	}
+	return err
+}
+
+// Delete implements part of [blob.KV]. If s is write-once, Delete reports an
+// error satisfying ErrReadOnlyValue without modifying the keyspace.
+func (s *KV) Delete(ctx context.Context, key string) error {
+	if s.writeOnce {
+		return &blob.KeyError{Key: key, Err: ErrReadOnlyValue}
+	}
+	return s.KV.Delete(ctx, key)
+}
@@ -0,0 +1,89 @@
+// Copyright 2025 Michael J. Fromberger. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wormstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/creachadair/ffs/blob"
+	"github.com/creachadair/ffs/blob/memstore"
+	"github.com/creachadair/ffs/blob/storetest"
+	"github.com/creachadair/ffs/storage/wormstore"
+)
+
+var (
+	_ blob.KV          = (*wormstore.KV)(nil)
+	_ blob.StoreCloser = wormstore.Store{}
+)
+
+func TestStore(t *testing.T) {
+	s := wormstore.New(memstore.New(nil), func(string) bool { return false })
+	storetest.Run(t, s)
+}
+
+func TestWriteOnce(t *testing.T) {
+	ctx := context.Background()
+	s := wormstore.New(memstore.New(nil), func(space string) bool { return space == "sub/files" })
+	files := storetest.SubKV(t, ctx, s, "sub", "files").(*wormstore.KV)
+	roots := storetest.SubKV(t, ctx, s, "sub", "roots").(*wormstore.KV)
+	if !files.IsWriteOnce() || roots.IsWriteOnce() {
+		t.Fatalf("IsWriteOnce: got (%v, %v), want (true, false)", files.IsWriteOnce(), roots.IsWriteOnce())
+	}
+
+	for _, kv := range []*wormstore.KV{files, roots} {
+		for _, replace := range []bool{false, true} {
+			key := "key"
+			if replace {
+				key = "rkey"
+			}
+			if err := kv.Put(ctx, blob.PutOptions{Key: key, Data: []byte("v1"), Replace: replace}); err != nil {
+				t.Fatalf("Put %q: unexpected error: %v", key, err)
+			}
+		}
+	}
+
+	// Existing values in the write-once keyspace cannot be replaced.
+	if err := files.Put(ctx, blob.PutOptions{Key: "key", Data: []byte("v2")}); !blob.IsKeyExists(err) {
+		t.Errorf("Put existing: got %v, want key exists", err)
+	}
+	if err := files.Put(ctx, blob.PutOptions{Key: "key", Data: []byte("v2"), Replace: true}); !errors.Is(err, wormstore.ErrReadOnlyValue) {
+		t.Errorf("Put replace: got %v, want %v", err, wormstore.ErrReadOnlyValue)
+	}
+	if err := files.Delete(ctx, "key"); !errors.Is(err, wormstore.ErrReadOnlyValue) {
+		t.Errorf("Delete: got %v, want %v", err, wormstore.ErrReadOnlyValue)
+	}
+	if got, err := files.Get(ctx, "key"); err != nil || string(got) != "v1" {
+		t.Errorf("Get: got (%q, %v), want (v1, nil)", got, err)
+	}
+
+	// The other keyspace is mutable.
+	if err := roots.Put(ctx, blob.PutOptions{Key: "key", Data: []byte("v2"), Replace: true}); err != nil {
+		t.Errorf("Put replace: unexpected error: %v", err)
+	}
+	if err := roots.Delete(ctx, "key"); err != nil {
+		t.Errorf("Delete: unexpected error: %v", err)
+	}
+
+	// A standalone write-once keyspace.
+	kv := wormstore.NewKV(memstore.NewKV())
+	if err := kv.Put(ctx, blob.PutOptions{Key: "x", Data: []byte("1"), Replace: true}); err != nil {
+		t.Fatalf("Put: unexpected error: %v", err)
+	}
+	if err := kv.Delete(ctx, "x"); !errors.Is(err, wormstore.ErrReadOnlyValue) {
+		t.Errorf("Delete: got %v, want %v", err, wormstore.ErrReadOnlyValue)
+	}
+}